@@ -36,12 +36,17 @@ func NewCmd() *cobra.Command {
   $ werf build --introspect-error
 
   # Build images and store/use stages from repo
-  $ werf build --repo harbor.company.io/werf`,
+  $ werf build --repo harbor.company.io/werf
+
+  # Show which stages would be taken from the repo and which would be built, render images graph
+  $ werf build --repo harbor.company.io/werf --dry-run --plan-format dot | dot -Tsvg > plan.svg`,
 		Long: common.GetLongCommandDescription(`Build images that are described in werf.yaml.
 
 The result of build command is built images pushed into the specified repo (or locally if repo is not specified).
 
-If one or more IMAGE_NAME parameters specified, werf will build only these images`),
+If one or more IMAGE_NAME parameters specified, werf will build only these images.
+
+With --dry-run werf only calculates stages digests and checks the repo: the build plan contains images dependencies and status of each stage. Digests of the stages following the first stage to build cannot be calculated and are reported as unknown`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
 			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs),
@@ -68,6 +73,7 @@ If one or more IMAGE_NAME parameters specified, werf will build only these image
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 	common.SetupSSHKey(&commonCmdData, cmd)
+	common.SetupBuildkitOptions(&commonCmdData, cmd)
 
 	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
 	common.SetupStagesStorageOptions(&commonCmdData, cmd)
@@ -91,11 +97,19 @@ If one or more IMAGE_NAME parameters specified, werf will build only these image
 	common.SetupReportPath(&commonCmdData, cmd)
 	common.SetupReportFormat(&commonCmdData, cmd)
 
+	common.SetupSignOptions(&commonCmdData, cmd)
+	common.SetupSBOM(&commonCmdData, cmd)
+
+	common.SetupDryRun(&commonCmdData, cmd)
+	common.SetupPlanPath(&commonCmdData, cmd)
+	common.SetupPlanFormat(&commonCmdData, cmd)
+
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
 
 	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupPlatform(&commonCmdData, cmd)
 	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
 	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
 
@@ -165,6 +179,9 @@ func run(commonCmdData *common.CmdData, imagesToProcess []string) error {
 	if err != nil {
 		return err
 	}
+	if err := common.InitBuildkitRuntime(commonCmdData, containerRuntime, stagesStorage); err != nil {
+		return err
+	}
 
 	synchronization, err := common.GetSynchronization(ctx, commonCmdData, projectName, stagesStorage)
 	if err != nil {
@@ -200,6 +217,23 @@ func run(commonCmdData *common.CmdData, imagesToProcess []string) error {
 		return err
 	}
 
+	buildOptions.Signer, err = common.GetImageSigner(commonCmdData, projectDir)
+	if err != nil {
+		return err
+	}
+	buildOptions.GenerateSBOM = *commonCmdData.SBOM
+
+	if *commonCmdData.DryRun {
+		planFormat, err := common.GetPlanFormat(commonCmdData)
+		if err != nil {
+			return err
+		}
+
+		buildOptions.DryRun = true
+		buildOptions.PlanPath = *commonCmdData.PlanPath
+		buildOptions.PlanFormat = planFormat
+	}
+
 	conveyorOptions, err := common.GetConveyorOptionsWithParallel(commonCmdData, buildOptions)
 	if err != nil {
 		return err
@@ -66,6 +66,10 @@ type CmdData struct {
 	StubTags  *bool
 
 	Synchronization           *string
+	SynchronizationToken      *string
+	SynchronizationCACert     *string
+	SynchronizationClientCert *string
+	SynchronizationClientKey  *string
 	GitHistorySynchronization *bool
 	GitUnshallow              *bool
 	AllowGitShallowClone      *bool
@@ -83,6 +87,12 @@ type CmdData struct {
 	IntrospectAfterError  *bool
 	StagesToIntrospect    *[]string
 
+	Buildkit        *bool
+	BuildkitAddress *string
+	BuildkitSecrets *[]string
+
+	Platform *[]string
+
 	Follow *bool
 
 	LogDebug         *bool
@@ -96,6 +106,15 @@ type CmdData struct {
 	ReportPath   *string
 	ReportFormat *string
 
+	PlanPath   *string
+	PlanFormat *string
+
+	SignKey           *string
+	SignWithSecretKey *bool
+	VerifyKey         *string
+
+	SBOM *bool
+
 	VirtualMerge           *bool
 	VirtualMergeFromCommit *string
 	VirtualMergeIntoCommit *string
@@ -156,21 +175,153 @@ Defaults to $WERF_SSH_KEY*, system ssh-agent or ~/.ssh/{id_rsa|id_dsa}, see http
 
 func SetupReportPath(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.ReportPath = new(string)
-	cmd.Flags().StringVarP(cmdData.ReportPath, "report-path", "", os.Getenv("WERF_REPORT_PATH"), "Report contains image info: full docker repo, tag, ID — for each image ($WERF_REPORT_PATH by default)")
+	cmd.Flags().StringVarP(cmdData.ReportPath, "report-path", "", os.Getenv("WERF_REPORT_PATH"), "Report contains image info: full docker repo, tag, ID — for each image, and build trace: digest calculation, lock wait, fetch, build and push durations and cache usage — for each stage ($WERF_REPORT_PATH by default)")
+}
+
+func SetupSBOM(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SBOM = new(bool)
+	cmd.Flags().BoolVarP(cmdData.SBOM, "sbom", "", GetBoolEnvironmentDefaultFalse("WERF_SBOM"), "Generate SBOM (CycloneDX JSON) of the final images: OS packages (dpkg, apk, rpm) and language lockfiles are listed, the SBOM is stored in the repo next to the image and its reference is added to the report (default $WERF_SBOM)")
 }
 
 func SetupReportFormat(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.ReportFormat = new(string)
-	cmd.Flags().StringVarP(cmdData.ReportFormat, "report-format", "", "json", "Report format (only json available for now, $WERF_REPORT_FORMAT by default)")
+
+	defaultValue := os.Getenv("WERF_REPORT_FORMAT")
+	if defaultValue == "" {
+		defaultValue = string(build.ReportJSON)
+	}
+
+	cmd.Flags().StringVarP(cmdData.ReportFormat, "report-format", "", defaultValue, `Report format ($WERF_REPORT_FORMAT or json by default):
+* json or yaml — full report;
+* envfile — WERF_IMAGE_<NAME>=DOCKER_REPO:DOCKER_TAG and WERF_IMAGE_<NAME>_ID=DOCKER_IMAGE_ID lines for the next shell steps;
+* junit — JUnit XML summary with a test suite for each image and a test case for each stage for CI dashboards;
+* chrome-trace — build timeline, which can be opened in the chrome://tracing or https://ui.perfetto.dev`)
 }
 
 func GetReportFormat(cmdData *CmdData) (build.ReportFormat, error) {
-	switch format := build.ReportFormat(*cmdData.ReportFormat); format {
-	case build.ReportJSON:
-		return format, nil
-	default:
-		return "", fmt.Errorf("bad --report-format given %q, expected: \"json\"", format)
+	format := build.ReportFormat(*cmdData.ReportFormat)
+	for _, supportedFormat := range build.ReportFormats {
+		if format == supportedFormat {
+			return format, nil
+		}
+	}
+
+	var expected []string
+	for _, supportedFormat := range build.ReportFormats {
+		expected = append(expected, fmt.Sprintf("%q", supportedFormat))
+	}
+	return "", fmt.Errorf("bad --report-format given %q, expected: %s", format, strings.Join(expected, ", "))
+}
+
+func SetupPlanPath(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.PlanPath = new(string)
+	cmd.Flags().StringVarP(cmdData.PlanPath, "plan-path", "", os.Getenv("WERF_PLAN_PATH"), "Write build plan to the specified file instead of stdout when --dry-run is set ($WERF_PLAN_PATH by default)")
+}
+
+func SetupPlanFormat(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.PlanFormat = new(string)
+
+	defaultValue := os.Getenv("WERF_PLAN_FORMAT")
+	if defaultValue == "" {
+		defaultValue = string(build.PlanJSON)
 	}
+
+	cmd.Flags().StringVarP(cmdData.PlanFormat, "plan-format", "", defaultValue, `Build plan format when --dry-run is set ($WERF_PLAN_FORMAT or json by default):
+* json — images with dependencies and stages with digests and statuses: empty, cached, to-build or unknown;
+* dot — Graphviz graph of images and stages, which can be rendered with "dot -Tsvg"`)
+}
+
+func GetPlanFormat(cmdData *CmdData) (build.PlanFormat, error) {
+	format := build.PlanFormat(*cmdData.PlanFormat)
+	for _, supportedFormat := range build.PlanFormats {
+		if format == supportedFormat {
+			return format, nil
+		}
+	}
+
+	var expected []string
+	for _, supportedFormat := range build.PlanFormats {
+		expected = append(expected, fmt.Sprintf("%q", supportedFormat))
+	}
+	return "", fmt.Errorf("bad --plan-format given %q, expected: %s", format, strings.Join(expected, ", "))
+}
+
+func SetupBuildkitOptions(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Buildkit = new(bool)
+	cmd.Flags().BoolVarP(cmdData.Buildkit, "buildkit", "", GetBoolEnvironmentDefaultFalse("WERF_BUILDKIT"), "Build image-from-dockerfile images with buildkitd instead of the docker server: build cache is imported from and exported to the stages storage repo, built images are loaded into the docker server (default $WERF_BUILDKIT)")
+
+	defaultAddress := os.Getenv("WERF_BUILDKIT_ADDRESS")
+	if defaultAddress == "" {
+		defaultAddress = container_runtime.DefaultBuildkitAddress
+	}
+
+	cmdData.BuildkitAddress = new(string)
+	cmd.Flags().StringVarP(cmdData.BuildkitAddress, "buildkit-address", "", defaultAddress, fmt.Sprintf("Buildkitd address ($WERF_BUILDKIT_ADDRESS or %s by default)", container_runtime.DefaultBuildkitAddress))
+
+	buildkitSecrets := predefinedValuesByEnvNamePrefix("WERF_BUILDKIT_SECRET")
+
+	cmdData.BuildkitSecrets = &buildkitSecrets
+	cmd.Flags().StringArrayVarP(cmdData.BuildkitSecrets, "buildkit-secret", "", buildkitSecrets, `Expose secret file to the RUN --mount=type=secret Dockerfile instructions when building with buildkit, format is id=ID,src=PATH (can specify multiple).
+Also, can be specified with $WERF_BUILDKIT_SECRET* (e.g. $WERF_BUILDKIT_SECRET_1=id=npmrc,src=/home/user/.npmrc)`)
+}
+
+// InitBuildkitRuntime enables buildkit for image-from-dockerfile images when requested, build cache is stored in the stages storage repo
+func InitBuildkitRuntime(cmdData *CmdData, containerRuntime *container_runtime.LocalDockerServerRuntime, stagesStorage storage.StagesStorage) error {
+	if cmdData.Buildkit == nil || !*cmdData.Buildkit {
+		return nil
+	}
+
+	var cacheRepo string
+	if repoStagesStorage, ok := stagesStorage.(*storage.RepoStagesStorage); ok {
+		cacheRepo = repoStagesStorage.RepoAddress
+	}
+
+	buildkitRuntime, err := container_runtime.NewBuildkitRuntime(*cmdData.BuildkitAddress, container_runtime.BuildkitRuntimeOptions{
+		CacheRepo: cacheRepo,
+		Secrets:   *cmdData.BuildkitSecrets,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to init buildkit runtime: %s", err)
+	}
+
+	containerRuntime.DockerfileBuildRuntime = buildkitRuntime
+
+	return nil
+}
+
+func SetupPlatform(cmdData *CmdData, cmd *cobra.Command) {
+	platforms := predefinedValuesByEnvNamePrefix("WERF_PLATFORM")
+
+	cmdData.Platform = &platforms
+	cmd.Flags().StringArrayVarP(cmdData.Platform, "platform", "", platforms, `Build images without platform setting in werf.yaml for the specified platforms in the OS/ARCH[/VARIANT] format (e.g. linux/amd64,linux/arm64), manifest list is published for the images with several platforms.
+Non-native platforms are emulated with QEMU (binfmt_misc handlers should be registered on the host).
+Also, can be specified with $WERF_PLATFORM* (e.g. $WERF_PLATFORM=linux/amd64,linux/arm64)`)
+}
+
+func GetTargetPlatforms(cmdData *CmdData) ([]string, error) {
+	if cmdData.Platform == nil {
+		return nil, nil
+	}
+
+	var platforms []string
+	for _, value := range *cmdData.Platform {
+		for _, platform := range strings.Split(value, ",") {
+			platform = strings.TrimSpace(platform)
+			if platform == "" {
+				continue
+			}
+
+			if err := config.ValidatePlatform(platform); err != nil {
+				return nil, fmt.Errorf("bad --platform given: %s", err)
+			}
+
+			if !util.IsStringsContainValue(platforms, platform) {
+				platforms = append(platforms, platform)
+			}
+		}
+	}
+
+	return platforms, nil
 }
 
 func SetupWithoutKube(cmdData *CmdData, cmd *cobra.Command) {
@@ -272,6 +423,8 @@ func SetupCommonRepoData(cmdData *CmdData, cmd *cobra.Command) {
 	SetupHarborUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-username", []string{"WERF_REPO_HARBOR_USERNAME"})
 	SetupHarborPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-password", []string{"WERF_REPO_HARBOR_PASSWORD"})
 	SetupQuayTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-quay-token", []string{"WERF_REPO_QUAY_TOKEN"})
+	SetupS3EndpointForRepoData(cmdData.CommonRepoData, cmd, "repo-s3-endpoint", []string{"WERF_REPO_S3_ENDPOINT"})
+	SetupS3RegionForRepoData(cmdData.CommonRepoData, cmd, "repo-s3-region", []string{"WERF_REPO_S3_REGION", "AWS_REGION"})
 }
 
 func SetupSecondaryStagesStorageOptions(cmdData *CmdData, cmd *cobra.Command) {
@@ -289,7 +442,7 @@ func SetupStagesStorageOptions(cmdData *CmdData, cmd *cobra.Command) {
 
 func setupStagesStorage(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.StagesStorage = new(string)
-	cmd.Flags().StringVarP(cmdData.StagesStorage, "repo", "", os.Getenv("WERF_REPO"), fmt.Sprintf("Docker Repo to store stages (default $WERF_REPO)"))
+	cmd.Flags().StringVarP(cmdData.StagesStorage, "repo", "", os.Getenv("WERF_REPO"), fmt.Sprintf("Docker Repo, s3://BUCKET/PREFIX object storage address or oci-layout:PATH directory to store stages (default $WERF_REPO)"))
 }
 
 func SetupStatusProgressPeriod(cmdData *CmdData, cmd *cobra.Command) {
@@ -795,7 +948,11 @@ func GetOptionalStagesStorageAddress(cmdData *CmdData) string {
 }
 
 func GetStagesStorage(stagesStorageAddress string, containerRuntime container_runtime.ContainerRuntime, cmdData *CmdData) (storage.StagesStorage, error) {
-	if err := ValidateRepoImplementation(*cmdData.CommonRepoData.Implementation); err != nil {
+	return GetStagesStorageByRepoData(stagesStorageAddress, containerRuntime, cmdData.CommonRepoData, *cmdData.InsecureRegistry, *cmdData.SkipTlsVerifyRegistry)
+}
+
+func GetStagesStorageByRepoData(stagesStorageAddress string, containerRuntime container_runtime.ContainerRuntime, repoData *RepoData, insecureRegistry, skipTlsVerifyRegistry bool) (storage.StagesStorage, error) {
+	if err := ValidateRepoImplementation(*repoData.Implementation); err != nil {
 		return nil, err
 	}
 
@@ -804,19 +961,23 @@ func GetStagesStorage(stagesStorageAddress string, containerRuntime container_ru
 		containerRuntime,
 		storage.StagesStorageOptions{
 			RepoStagesStorageOptions: storage.RepoStagesStorageOptions{
-				Implementation: *cmdData.CommonRepoData.Implementation,
+				Implementation: *repoData.Implementation,
 				DockerRegistryOptions: docker_registry.DockerRegistryOptions{
-					InsecureRegistry:      *cmdData.InsecureRegistry,
-					SkipTlsVerifyRegistry: *cmdData.SkipTlsVerifyRegistry,
-					DockerHubUsername:     *cmdData.CommonRepoData.DockerHubUsername,
-					DockerHubPassword:     *cmdData.CommonRepoData.DockerHubPassword,
-					DockerHubToken:        *cmdData.CommonRepoData.DockerHubToken,
-					GitHubToken:           *cmdData.CommonRepoData.GitHubToken,
-					HarborUsername:        *cmdData.CommonRepoData.HarborUsername,
-					HarborPassword:        *cmdData.CommonRepoData.HarborPassword,
-					QuayToken:             *cmdData.CommonRepoData.QuayToken,
+					InsecureRegistry:      insecureRegistry,
+					SkipTlsVerifyRegistry: skipTlsVerifyRegistry,
+					DockerHubUsername:     *repoData.DockerHubUsername,
+					DockerHubPassword:     *repoData.DockerHubPassword,
+					DockerHubToken:        *repoData.DockerHubToken,
+					GitHubToken:           *repoData.GitHubToken,
+					HarborUsername:        *repoData.HarborUsername,
+					HarborPassword:        *repoData.HarborPassword,
+					QuayToken:             *repoData.QuayToken,
 				},
 			},
+			S3StagesStorageOptions: storage.S3StagesStorageOptions{
+				Endpoint: *repoData.S3Endpoint,
+				Region:   *repoData.S3Region,
+			},
 		},
 	)
 }
@@ -832,7 +993,12 @@ func GetSecondaryStagesStorageList(stagesStorage storage.StagesStorage, containe
 	}
 
 	for _, address := range *cmdData.SecondaryStagesStorage {
-		repoStagesStorage, err := storage.NewStagesStorage(address, containerRuntime, storage.StagesStorageOptions{})
+		repoStagesStorage, err := storage.NewStagesStorage(address, containerRuntime, storage.StagesStorageOptions{
+			S3StagesStorageOptions: storage.S3StagesStorageOptions{
+				Endpoint: *cmdData.CommonRepoData.S3Endpoint,
+				Region:   *cmdData.CommonRepoData.S3Region,
+			},
+		})
 		if err != nil {
 			return nil, fmt.Errorf("unable to create secondary stages storage at %s: %s", address, err)
 		}
@@ -9,7 +9,12 @@ import (
 	"github.com/werf/werf/pkg/container_runtime"
 )
 
-func GetConveyorOptions(commonCmdData *CmdData) build.ConveyorOptions {
+func GetConveyorOptions(commonCmdData *CmdData) (build.ConveyorOptions, error) {
+	targetPlatforms, err := GetTargetPlatforms(commonCmdData)
+	if err != nil {
+		return build.ConveyorOptions{}, err
+	}
+
 	return build.ConveyorOptions{
 		LocalGitRepoVirtualMergeOptions: stage.VirtualMergeOptions{
 			VirtualMerge:           *commonCmdData.VirtualMerge,
@@ -18,11 +23,15 @@ func GetConveyorOptions(commonCmdData *CmdData) build.ConveyorOptions {
 		},
 		GitUnshallow:         *commonCmdData.GitUnshallow,
 		AllowGitShallowClone: *commonCmdData.AllowGitShallowClone,
-	}
+		TargetPlatforms:      targetPlatforms,
+	}, nil
 }
 
 func GetConveyorOptionsWithParallel(commonCmdData *CmdData, buildStagesOptions build.BuildOptions) (build.ConveyorOptions, error) {
-	conveyorOptions := GetConveyorOptions(commonCmdData)
+	conveyorOptions, err := GetConveyorOptions(commonCmdData)
+	if err != nil {
+		return conveyorOptions, err
+	}
 	conveyorOptions.Parallel = !(buildStagesOptions.ImageBuildOptions.IntrospectAfterError || buildStagesOptions.ImageBuildOptions.IntrospectBeforeError || len(buildStagesOptions.Targets) != 0) && *commonCmdData.Parallel
 
 	parallelTasksLimit, err := GetParallelTasksLimit(commonCmdData)
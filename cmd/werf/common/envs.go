@@ -17,16 +17,27 @@ const (
 	WerfDebugAnsibleArgs Env = "WERF_DEBUG_ANSIBLE_ARGS"
 	WerfSecretKey        Env = "WERF_SECRET_KEY"
 	WerfOldSecretKey     Env = "WERF_OLD_SECRET_KEY"
+	WerfSecretIdentity   Env = "WERF_SECRET_IDENTITY"
 )
 
 var envDescription = map[Env]string{
 	WerfDebugAnsibleArgs: "Pass specified cli args to ansible ($ANSIBLE_ARGS)",
 	WerfSecretKey: `Use specified secret key to extract secrets for the deploy. Recommended way to set secret key in CI-system. 
 
+Secret key also can be fetched from the external provider:
+* the stdout of the helper command $WERF_SECRET_KEY_COMMAND,
+* the HashiCorp Vault compatible KV secret $WERF_SECRET_KEY_VAULT_ADDR, $WERF_SECRET_KEY_VAULT_PATH, $WERF_SECRET_KEY_VAULT_FIELD (default secret_key) with $WERF_SECRET_KEY_VAULT_TOKEN or $VAULT_TOKEN,
+* the KMS decrypt endpoint $WERF_SECRET_KEY_KMS_URL with $WERF_SECRET_KEY_KMS_TOKEN for the ciphertext from $WERF_SECRET_KEY_KMS_CIPHERTEXT or .werf_secret_key.encrypted file.
+
 Secret key also can be defined in files:
 * ~/.werf/global_secret_key (globally),
-* .werf_secret_key (per project)`,
+* .werf_secret_key (per project).
+
+Secret values and files of the environment (.helm/secret-values.<env>.yaml and .helm/secret/<env>/) can be encrypted with the separate key from $WERF_ENV_SECRET_KEY_<ENV> or .werf_secret_key.<env> file`,
 	WerfOldSecretKey: "Use specified old secret key to rotate secrets",
+	WerfSecretIdentity: `Use specified identity (X25519 private key) to unwrap the secret key from .werf_secret_recipients.yaml file.
+
+Identity also can be defined in ~/.werf/secret_identity file`,
 }
 
 func EnvsDescription(envs ...Env) string {
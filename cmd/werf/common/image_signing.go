@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/image_signing"
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/storage"
+)
+
+func SetupSignOptions(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SignKey = new(string)
+	cmdData.SignWithSecretKey = new(bool)
+
+	cmd.Flags().StringVarP(cmdData.SignKey, "sign-key", "", os.Getenv("WERF_SIGN_KEY"), "Sign the final images with the ECDSA private key from the PEM file, the key generated by cosign is supported. The password of the encrypted key is read from $WERF_SIGN_KEY_PASSWORD (default $WERF_SIGN_KEY)")
+	cmd.Flags().BoolVarP(cmdData.SignWithSecretKey, "sign-with-secret-key", "", GetBoolEnvironmentDefaultFalse("WERF_SIGN_WITH_SECRET_KEY"), "Sign the final images with the ECDSA key derived from the project secret key (default $WERF_SIGN_WITH_SECRET_KEY)")
+}
+
+func SetupVerifyKey(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.VerifyKey = new(string)
+
+	cmd.Flags().StringVarP(cmdData.VerifyKey, "verify-key", "", os.Getenv("WERF_VERIFY_KEY"), "Refuse to deploy images which are not signed with the ECDSA key: the PEM file with the public key or the private key. The key of --sign-key or --sign-with-secret-key option is used by default (default $WERF_VERIFY_KEY)")
+}
+
+// GetImageSigner returns nil if the signing is not enabled
+func GetImageSigner(cmdData *CmdData, projectDir string) (*image_signing.Signer, error) {
+	if *cmdData.SignKey != "" && *cmdData.SignWithSecretKey {
+		return nil, fmt.Errorf("unable to use --sign-key and --sign-with-secret-key options at the same time")
+	}
+
+	switch {
+	case *cmdData.SignKey != "":
+		privateKey, err := image_signing.LoadPrivateKey(*cmdData.SignKey, []byte(os.Getenv("WERF_SIGN_KEY_PASSWORD")))
+		if err != nil {
+			return nil, err
+		}
+
+		return image_signing.NewSigner(privateKey), nil
+	case *cmdData.SignWithSecretKey:
+		secretKey, err := secret.GetSecretKey(projectDir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get secret key for image signing: %s", err)
+		}
+
+		privateKey, err := image_signing.DerivePrivateKey(secretKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return image_signing.NewSigner(privateKey), nil
+	default:
+		return nil, nil
+	}
+}
+
+// GetImageVerifier returns nil if the verification is not enabled
+func GetImageVerifier(cmdData *CmdData, projectDir string) (*image_signing.Verifier, error) {
+	if *cmdData.VerifyKey != "" {
+		publicKey, err := image_signing.LoadPublicKey(*cmdData.VerifyKey, []byte(os.Getenv("WERF_SIGN_KEY_PASSWORD")))
+		if err != nil {
+			return nil, err
+		}
+
+		return image_signing.NewVerifier(publicKey), nil
+	}
+
+	signer, err := GetImageSigner(cmdData, projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if signer == nil {
+		return nil, nil
+	}
+
+	return image_signing.NewVerifier(signer.PublicKey()), nil
+}
+
+// VerifyImages returns an error if any of the images has no valid signature in the stages storage repo
+func VerifyImages(ctx context.Context, verifier *image_signing.Verifier, stagesStorage storage.StagesStorage, images []*image.InfoGetter) error {
+	repoStagesStorage, ok := stagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return fmt.Errorf("unable to verify image signatures: stages storage %s is not a docker repo", stagesStorage.String())
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Verifying image signatures").DoError(func() error {
+		for _, info := range images {
+			if err := verifier.VerifyImage(ctx, repoStagesStorage.DockerRegistry, info.GetName()); err != nil {
+				return fmt.Errorf("refusing to deploy image %s: %s", logging.ImageLogName(info.GetWerfImageName(), false), err)
+			}
+
+			logboek.Context(ctx).Default().LogF("Image %s: signature verified\n", logging.ImageLogName(info.GetWerfImageName(), false))
+		}
+
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
@@ -34,10 +35,20 @@ func SetupSynchronization(cmdData *CmdData, cmd *cobra.Command) {
 
 Default:
 * $WERF_SYNCHRONIZATION or
-* :local if --repo is not specified or
-* %s if --repo is specified
+* :local if --repo is not specified or --repo=oci-layout:PATH is used or
+* %s if --repo is specified (except --repo=s3://BUCKET[/PREFIX], which requires an explicit address)
 
 The same address should be specified for all werf processes that work with a single repo. :local address allows execution of werf processes from a single host only`, storage.DefaultKubernetesStorageAddress))
+
+	cmdData.SynchronizationToken = new(string)
+	cmdData.SynchronizationCACert = new(string)
+	cmdData.SynchronizationClientCert = new(string)
+	cmdData.SynchronizationClientKey = new(string)
+
+	cmd.Flags().StringVarP(cmdData.SynchronizationToken, "synchronization-token", "", os.Getenv("WERF_SYNCHRONIZATION_TOKEN"), "Bearer token for the http synchronization server (default $WERF_SYNCHRONIZATION_TOKEN)")
+	cmd.Flags().StringVarP(cmdData.SynchronizationCACert, "synchronization-ca-cert", "", os.Getenv("WERF_SYNCHRONIZATION_CA_CERT"), "Verify certificate of the https synchronization server using specified CA certificate file instead of the system CAs (default $WERF_SYNCHRONIZATION_CA_CERT)")
+	cmd.Flags().StringVarP(cmdData.SynchronizationClientCert, "synchronization-client-cert", "", os.Getenv("WERF_SYNCHRONIZATION_CLIENT_CERT"), "Client certificate file for mTLS authentication on the https synchronization server (default $WERF_SYNCHRONIZATION_CLIENT_CERT)")
+	cmd.Flags().StringVarP(cmdData.SynchronizationClientKey, "synchronization-client-key", "", os.Getenv("WERF_SYNCHRONIZATION_CLIENT_KEY"), "Client key file for mTLS authentication on the https synchronization server (default $WERF_SYNCHRONIZATION_CLIENT_KEY)")
 }
 
 type SynchronizationType string
@@ -52,6 +63,7 @@ type SynchronizationParams struct {
 	Address             string
 	SynchronizationType SynchronizationType
 	KubeParams          *storage.KubernetesSynchronizationParams
+	HttpClient          *http.Client
 }
 
 func checkSynchronizationKubernetesParamsForWarnings(cmdData *CmdData) {
@@ -135,10 +147,25 @@ func GetSynchronization(ctx context.Context, cmdData *CmdData, projectName strin
 	}
 
 	getHttpParamsFunc := func(synchronization string, stagesStorage storage.StagesStorage) (*SynchronizationParams, error) {
+		clientOptions := synchronization_server.SynchronizationClientOptions{
+			Token:          *cmdData.SynchronizationToken,
+			CACertFile:     *cmdData.SynchronizationCACert,
+			ClientCertFile: *cmdData.SynchronizationClientCert,
+			ClientKeyFile:  *cmdData.SynchronizationClientKey,
+		}
+
+		httpClient, err := synchronization_server.NewHttpClient(clientOptions)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create http client for the synchronization server: %s", err)
+		}
+
+		synchronizationClient := synchronization_server.NewSynchronizationClient(synchronization, httpClient)
+		synchronizationClient.Authenticated = clientOptions.IsAuthenticated()
+
 		var address string
 		if err := logboek.Default().LogProcess(fmt.Sprintf("Getting client id for the http synchronization server")).
 			DoError(func() error {
-				if clientID, err := synchronization_server.GetOrCreateClientID(ctx, projectName, synchronization_server.NewSynchronizationClient(synchronization), stagesStorage); err != nil {
+				if clientID, err := synchronization_server.GetOrCreateClientID(ctx, projectName, synchronizationClient, stagesStorage); err != nil {
 					return fmt.Errorf("unable to get synchronization client id: %s", err)
 				} else {
 					address = fmt.Sprintf("%s/%s", synchronization, clientID)
@@ -149,12 +176,14 @@ func GetSynchronization(ctx context.Context, cmdData *CmdData, projectName strin
 			return nil, err
 		}
 
-		return &SynchronizationParams{Address: address, SynchronizationType: HttpSynchronization}, nil
+		return &SynchronizationParams{Address: address, SynchronizationType: HttpSynchronization, HttpClient: httpClient}, nil
 	}
 
 	if *cmdData.Synchronization == "" {
-		if stagesStorage.Address() == storage.LocalStorageAddress {
+		if stagesStorage.Address() == storage.LocalStorageAddress || storage.IsOCILayoutStorageAddress(stagesStorage.Address()) {
 			return &SynchronizationParams{SynchronizationType: LocalSynchronization, Address: storage.LocalStorageAddress}, nil
+		} else if storage.IsS3StorageAddress(stagesStorage.Address()) {
+			return nil, fmt.Errorf("--synchronization param is required when --repo=%sBUCKET[/PREFIX] is used: specify --synchronization=%s for a single host or the shared synchronizer address (kubernetes://NAMESPACE or http[s]://HOST:PORT)", storage.S3StorageAddressPrefix, storage.LocalStorageAddress)
 		} else {
 			return getHttpParamsFunc("https://synchronization.werf.io", stagesStorage)
 		}
@@ -189,7 +218,7 @@ func GetStagesStorageCache(synchronization *SynchronizationParams) (storage.Stag
 			}), nil
 		}
 	case HttpSynchronization:
-		return synchronization_server.NewStagesStorageCacheHttpClient(fmt.Sprintf("%s/stages-storage-cache", synchronization.Address)), nil
+		return synchronization_server.NewStagesStorageCacheHttpClient(fmt.Sprintf("%s/stages-storage-cache", synchronization.Address), synchronization.HttpClient), nil
 	default:
 		panic(fmt.Sprintf("unsupported synchronization address %q", synchronization.Address))
 	}
@@ -216,7 +245,9 @@ func GetStorageLockManager(ctx context.Context, synchronization *Synchronization
 			}), nil
 		}
 	case HttpSynchronization:
-		locker := distributed_locker.NewHttpLocker(fmt.Sprintf("%s/locker", synchronization.Address))
+		backend := distributed_locker.NewHttpBackend(fmt.Sprintf("%s/locker", synchronization.Address))
+		backend.HttpClient = synchronization.HttpClient
+		locker := distributed_locker.NewDistributedLocker(backend)
 		lockerWithRetry := locker_with_retry.NewLockerWithRetry(ctx, locker, locker_with_retry.LockerWithRetryOptions{MaxAcquireAttempts: 10, MaxReleaseAttempts: 10})
 		return storage.NewGenericLockManager(lockerWithRetry), nil
 	default:
@@ -80,6 +80,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 	common.SetupSSHKey(&commonCmdData, cmd)
+	common.SetupBuildkitOptions(&commonCmdData, cmd)
 
 	common.SetupIntrospectAfterError(&commonCmdData, cmd)
 	common.SetupIntrospectBeforeError(&commonCmdData, cmd)
@@ -121,11 +122,16 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	common.SetupReportPath(&commonCmdData, cmd)
 	common.SetupReportFormat(&commonCmdData, cmd)
 
+	common.SetupSignOptions(&commonCmdData, cmd)
+	common.SetupSBOM(&commonCmdData, cmd)
+	common.SetupVerifyKey(&commonCmdData, cmd)
+
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
 
 	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupPlatform(&commonCmdData, cmd)
 	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
 	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
 
@@ -232,6 +238,17 @@ func run(ctx context.Context, projectDir, chartDir string) error {
 		return err
 	}
 
+	buildOptions.Signer, err = common.GetImageSigner(&commonCmdData, projectDir)
+	if err != nil {
+		return err
+	}
+	buildOptions.GenerateSBOM = *commonCmdData.SBOM
+
+	imageVerifier, err := common.GetImageVerifier(&commonCmdData, projectDir)
+	if err != nil {
+		return err
+	}
+
 	var imagesInfoGetters []*image.InfoGetter
 	var imagesRepository string
 	if len(werfConfig.StapelImages) != 0 || len(werfConfig.ImagesFromDockerfile) != 0 {
@@ -244,6 +261,9 @@ func run(ctx context.Context, projectDir, chartDir string) error {
 		if err != nil {
 			return err
 		}
+		if err := common.InitBuildkitRuntime(&commonCmdData, containerRuntime, stagesStorage); err != nil {
+			return err
+		}
 		logboek.LogOptionalLn()
 		synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
 		if err != nil {
@@ -292,6 +312,12 @@ func run(ctx context.Context, projectDir, chartDir string) error {
 			return err
 		}
 
+		if imageVerifier != nil {
+			if err := common.VerifyImages(ctx, imageVerifier, stagesStorage, imagesInfoGetters); err != nil {
+				return err
+			}
+		}
+
 		logboek.LogOptionalLn()
 	}
 
@@ -302,6 +328,13 @@ func run(ctx context.Context, projectDir, chartDir string) error {
 		secretsManager = m
 	}
 
+	var envSecretsManager secret.Manager
+	if m, err := deploy.GetSafeEnvSecretManager(context.Background(), projectDir, chartDir, *commonCmdData.Environment, *commonCmdData.IgnoreSecretKey); err != nil {
+		return err
+	} else {
+		envSecretsManager = m
+	}
+
 	releaseName, err := common.GetHelmRelease(*commonCmdData.Release, *commonCmdData.Environment, werfConfig)
 	if err != nil {
 		return err
@@ -337,8 +370,9 @@ func run(ctx context.Context, projectDir, chartDir string) error {
 		ExtraAnnotations: userExtraAnnotations,
 		ExtraLabels:      userExtraLabels,
 
-		LockManager:    lockManager,
-		SecretsManager: secretsManager,
+		LockManager:       lockManager,
+		SecretsManager:    secretsManager,
+		EnvSecretsManager: envSecretsManager,
 	})
 	if err := wc.SetEnv(*commonCmdData.Environment); err != nil {
 		return err
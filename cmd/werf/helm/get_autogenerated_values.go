@@ -65,6 +65,7 @@ These values includes project name, docker images ids and other`),
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
 
 	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupPlatform(&commonCmdData, cmd)
 	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
 
 	common.SetupEnvironment(&commonCmdData, cmd)
@@ -181,7 +182,12 @@ func runGetServiceValues() error {
 
 		storageManager := manager.NewStorageManager(projectName, stagesStorage, secondaryStagesStorageList, storageLockManager, stagesStorageCache)
 
-		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, common.GetConveyorOptions(&commonCmdData))
+		conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+		if err != nil {
+			return err
+		}
+
+		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, conveyorOptions)
 		defer conveyorWithRetry.Terminate()
 
 		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
@@ -7,11 +7,16 @@ import (
 	"time"
 
 	helm_secret_decrypt "github.com/werf/werf/cmd/werf/helm/secret/decrypt"
+	helm_secret_diff "github.com/werf/werf/cmd/werf/helm/secret/diff"
 	helm_secret_encrypt "github.com/werf/werf/cmd/werf/helm/secret/encrypt"
 	helm_secret_file_decrypt "github.com/werf/werf/cmd/werf/helm/secret/file/decrypt"
 	helm_secret_file_edit "github.com/werf/werf/cmd/werf/helm/secret/file/edit"
 	helm_secret_file_encrypt "github.com/werf/werf/cmd/werf/helm/secret/file/encrypt"
 	helm_secret_generate_secret_key "github.com/werf/werf/cmd/werf/helm/secret/generate_secret_key"
+	helm_secret_recipients_add "github.com/werf/werf/cmd/werf/helm/secret/recipients/add"
+	helm_secret_recipients_generate_identity "github.com/werf/werf/cmd/werf/helm/secret/recipients/generate_identity"
+	helm_secret_recipients_ls "github.com/werf/werf/cmd/werf/helm/secret/recipients/ls"
+	helm_secret_recipients_rm "github.com/werf/werf/cmd/werf/helm/secret/recipients/rm"
 	helm_secret_rotate_secret_key "github.com/werf/werf/cmd/werf/helm/secret/rotate_secret_key"
 	helm_secret_values_decrypt "github.com/werf/werf/cmd/werf/helm/secret/values/decrypt"
 	helm_secret_values_edit "github.com/werf/werf/cmd/werf/helm/secret/values/edit"
@@ -182,13 +187,27 @@ func secretCmd() *cobra.Command {
 		helm_secret_values_edit.NewCmd(),
 	)
 
+	recipientsCmd := &cobra.Command{
+		Use:   "recipients",
+		Short: "Work with secret recipients: the secret key is wrapped for each recipient public key",
+	}
+
+	recipientsCmd.AddCommand(
+		helm_secret_recipients_add.NewCmd(),
+		helm_secret_recipients_rm.NewCmd(),
+		helm_secret_recipients_ls.NewCmd(),
+		helm_secret_recipients_generate_identity.NewCmd(),
+	)
+
 	cmd.AddCommand(
 		fileCmd,
 		valuesCmd,
+		recipientsCmd,
 		helm_secret_generate_secret_key.NewCmd(),
 		helm_secret_encrypt.NewCmd(),
 		helm_secret_decrypt.NewCmd(),
 		helm_secret_rotate_secret_key.NewCmd(),
+		helm_secret_diff.NewCmd(),
 	)
 
 	return cmd
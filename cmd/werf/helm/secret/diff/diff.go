@@ -0,0 +1,416 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/deploy/werf_chart"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	RevealValues bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "diff [REV_A] [REV_B]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show changed keys of secret values and secret files between revisions",
+		Long: common.GetLongCommandDescription(`Decrypt secret values files (.helm/secret-values.yaml, .helm/secret-values.<env>.yaml) and secret files (.helm/secret directory) of two revisions of the local git repository and show the changed keys.
+
+REV_A is HEAD by default, REV_B is the working tree by default.
+Values are masked unless --reveal-values option is specified.
+
+Secret values and files of the environment are decrypted with the environment key from $WERF_ENV_SECRET_KEY_<ENV> or .werf_secret_key.<env> file if it is found.
+The files which cannot be decrypted with the available keys are reported and skipped`),
+		Example: `  # Show changed keys between HEAD and the working tree
+  $ werf helm secret diff
+  .helm/secret-values.yaml
+    ~ mysql.password: *** -> ***
+    + mysql.port: ***
+  .helm/secret/tls.key
+    ~ changed: 1 lines removed, 1 lines added
+
+  # Show changed keys and values between the main branch and HEAD
+  $ werf helm secret diff main HEAD --reveal-values`,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretIdentity),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) > 2 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("accepts at most 2 positional arguments, received %d", len(args))
+			}
+
+			revA, revB := "HEAD", ""
+			if len(args) > 0 {
+				revA = args[0]
+			}
+			if len(args) > 1 {
+				revB = args[1]
+			}
+
+			return runSecretDiff(revA, revB)
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupHelmChartDir(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.RevealValues, "reveal-values", "", common.GetBoolEnvironmentDefaultFalse("WERF_REVEAL_VALUES"), "Show decrypted values instead of *** (default $WERF_REVEAL_VALUES)")
+
+	return cmd
+}
+
+func runSecretDiff(revA, revB string) error {
+	ctx := context.Background()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	helmChartDir, err := common.GetHelmChartDir(projectDir, &commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting helm chart dir failed: %s", err)
+	}
+
+	relHelmChartDir, err := filepath.Rel(projectDir, helmChartDir)
+	if err != nil || strings.HasPrefix(relHelmChartDir, "..") {
+		return fmt.Errorf("helm chart dir %s should be inside the project dir %s", helmChartDir, projectDir)
+	}
+
+	localGitRepo, err := git_repo.OpenLocalRepo("own", projectDir)
+	if err != nil {
+		return fmt.Errorf("unable to open local repo %s: %s", projectDir, err)
+	} else if localGitRepo == nil {
+		return fmt.Errorf("git repository not found in %s", projectDir)
+	}
+
+	oldFiles, err := getSecretFiles(ctx, localGitRepo, helmChartDir, relHelmChartDir, revA)
+	if err != nil {
+		return err
+	}
+
+	newFiles, err := getSecretFiles(ctx, localGitRepo, helmChartDir, relHelmChartDir, revB)
+	if err != nil {
+		return err
+	}
+
+	d := &secretsDiff{projectDir: projectDir, relHelmChartDir: relHelmChartDir, managers: map[string]secret.Manager{}}
+
+	var paths []string
+	for path := range oldFiles {
+		paths = append(paths, path)
+	}
+	for path := range newFiles {
+		if _, exist := oldFiles[path]; !exist {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := d.diffFile(path, oldFiles[path], newFiles[path]); err != nil {
+			return err
+		}
+	}
+
+	if len(d.lines) == 0 {
+		logboek.Default().LogLnDetails("No changes in secret values and secret files")
+		return nil
+	}
+
+	fmt.Println(strings.Join(d.lines, "\n"))
+
+	return nil
+}
+
+// getSecretFiles returns the secret values files and the secret files by the paths relative to the helm chart dir,
+// the files are read from the working tree if the revision is not specified
+func getSecretFiles(ctx context.Context, localGitRepo *git_repo.Local, helmChartDir, relHelmChartDir, rev string) (map[string][]byte, error) {
+	res := map[string][]byte{}
+
+	if rev == "" {
+		if _, err := os.Stat(helmChartDir); os.IsNotExist(err) {
+			return res, nil
+		}
+
+		if err := filepath.Walk(helmChartDir, func(path string, info os.FileInfo, accessErr error) error {
+			if accessErr != nil {
+				return fmt.Errorf("error accessing file %s: %s", path, accessErr)
+			}
+
+			if info.Mode().IsDir() {
+				return nil
+			}
+
+			relPath, err := filepath.Rel(helmChartDir, path)
+			if err != nil {
+				return err
+			}
+
+			relPath = filepath.ToSlash(relPath)
+			if !isSecretFile(relPath) {
+				return nil
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("error reading file %s: %s", path, err)
+			}
+
+			res[relPath] = data
+
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	}
+
+	commit, err := localGitRepo.ResolveCommit(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	chartPath := filepath.ToSlash(relHelmChartDir)
+	files, err := localGitRepo.ReadCommitFiles(ctx, commit, []string{chartPath})
+	if err != nil {
+		return nil, err
+	}
+
+	for path, data := range files {
+		relPath := strings.TrimPrefix(path, chartPath+"/")
+		if isSecretFile(relPath) {
+			res[relPath] = data
+		}
+	}
+
+	return res, nil
+}
+
+func isSecretFile(relPath string) bool {
+	if strings.HasPrefix(relPath, werf_chart.SecretDirName+"/") {
+		return true
+	}
+
+	return getSecretValuesFileEnv(relPath) != "" || relPath == werf_chart.DefaultSecretValuesFileName
+}
+
+// getSecretValuesFileEnv returns the environment of the secret-values.<env>.yaml file
+func getSecretValuesFileEnv(relPath string) string {
+	if strings.HasPrefix(relPath, "secret-values.") && strings.HasSuffix(relPath, ".yaml") && !strings.Contains(relPath, "/") {
+		return strings.TrimSuffix(strings.TrimPrefix(relPath, "secret-values."), ".yaml")
+	}
+
+	return ""
+}
+
+type secretsDiff struct {
+	projectDir      string
+	relHelmChartDir string
+	managers        map[string]secret.Manager
+
+	lines []string
+}
+
+// getManager returns the manager of the environment, the project secret key is used if the environment has no separate key
+func (d *secretsDiff) getManager(env string) (secret.Manager, error) {
+	if m, ok := d.managers[env]; ok {
+		return m, nil
+	}
+
+	var key []byte
+	var err error
+	if env == "" {
+		key, err = secret.GetSecretKey(d.projectDir)
+	} else {
+		key, err = secret.GetEnvSecretKey(d.projectDir, env)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := secret.NewManager(key)
+	if err != nil {
+		return nil, err
+	}
+
+	d.managers[env] = m
+
+	return m, nil
+}
+
+func (d *secretsDiff) diffFile(relPath string, oldData, newData []byte) error {
+	// the files of the .helm/secret/<env>/ directory are decrypted with the environment key
+	var env string
+	isSecretValuesFile := !strings.HasPrefix(relPath, werf_chart.SecretDirName+"/")
+	if isSecretValuesFile {
+		env = getSecretValuesFileEnv(relPath)
+	} else if parts := strings.SplitN(strings.TrimPrefix(relPath, werf_chart.SecretDirName+"/"), "/", 2); len(parts) == 2 {
+		env = parts[0]
+	}
+
+	m, err := d.getManager(env)
+	if err != nil {
+		return err
+	}
+
+	displayPath := filepath.ToSlash(filepath.Join(d.relHelmChartDir, relPath))
+
+	var changes []string
+	if isSecretValuesFile {
+		changes, err = d.diffSecretValuesFile(m, oldData, newData)
+	} else {
+		changes, err = d.diffSecretFile(m, oldData, newData)
+	}
+
+	if err != nil {
+		logboek.Warn().LogF("WARNING: Unable to decrypt %s: %s\n", displayPath, err)
+		return nil
+	}
+
+	if len(changes) != 0 {
+		d.lines = append(d.lines, displayPath)
+		for _, change := range changes {
+			d.lines = append(d.lines, "  "+change)
+		}
+	}
+
+	return nil
+}
+
+func (d *secretsDiff) diffSecretValuesFile(m secret.Manager, oldData, newData []byte) ([]string, error) {
+	oldValues, err := d.decodeSecretValues(m, oldData)
+	if err != nil {
+		return nil, err
+	}
+
+	newValues, err := d.decodeSecretValues(m, newData)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, change := range secret.DiffSecretValues(oldValues, newValues) {
+		switch change.Type {
+		case secret.SecretValueAdded:
+			res = append(res, fmt.Sprintf("%s %s: %s", change.Type, change.Key, d.formatValue(change.NewValue)))
+		case secret.SecretValueRemoved:
+			res = append(res, fmt.Sprintf("%s %s: %s", change.Type, change.Key, d.formatValue(change.OldValue)))
+		case secret.SecretValueChanged:
+			res = append(res, fmt.Sprintf("%s %s: %s -> %s", change.Type, change.Key, d.formatValue(change.OldValue), d.formatValue(change.NewValue)))
+		}
+	}
+
+	return res, nil
+}
+
+func (d *secretsDiff) decodeSecretValues(m secret.Manager, data []byte) (map[string]string, error) {
+	if data == nil {
+		return map[string]string{}, nil
+	}
+
+	decodedData, err := m.DecryptYamlData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(decodedData, &values); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal secret values: %s", err)
+	}
+
+	return secret.FlattenSecretValues(values), nil
+}
+
+func (d *secretsDiff) diffSecretFile(m secret.Manager, oldData, newData []byte) ([]string, error) {
+	oldDecodedData, err := d.decodeSecretFile(m, oldData)
+	if err != nil {
+		return nil, err
+	}
+
+	newDecodedData, err := d.decodeSecretFile(m, newData)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case oldData == nil:
+		return []string{"+ added"}, nil
+	case newData == nil:
+		return []string{"- removed"}, nil
+	case oldDecodedData == newDecodedData:
+		return nil, nil
+	}
+
+	lines := secret.DiffSecretFileLines(oldDecodedData, newDecodedData)
+	if !cmdData.RevealValues {
+		var removed, added int
+		for _, line := range lines {
+			if strings.HasPrefix(line, "-") {
+				removed++
+			} else {
+				added++
+			}
+		}
+
+		return []string{fmt.Sprintf("~ changed: %d lines removed, %d lines added", removed, added)}, nil
+	}
+
+	return append([]string{"~ changed"}, lines...), nil
+}
+
+func (d *secretsDiff) decodeSecretFile(m secret.Manager, data []byte) (string, error) {
+	if data == nil {
+		return "", nil
+	}
+
+	decodedData, err := m.Decrypt([]byte(strings.TrimRightFunc(string(data), unicode.IsSpace)))
+	if err != nil {
+		return "", err
+	}
+
+	return string(decodedData), nil
+}
+
+func (d *secretsDiff) formatValue(value string) string {
+	if cmdData.RevealValues {
+		return value
+	}
+
+	return "***"
+}
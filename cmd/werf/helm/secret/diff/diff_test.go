@@ -0,0 +1,105 @@
+package secret
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/werf/werf/pkg/git_repo"
+)
+
+func TestSecretsDiffFormatValue(t *testing.T) {
+	defer func(revealValues bool) { cmdData.RevealValues = revealValues }(cmdData.RevealValues)
+
+	d := &secretsDiff{}
+
+	for _, value := range []string{"", "a", "password", "line1\nline2"} {
+		cmdData.RevealValues = false
+		if formattedValue := d.formatValue(value); formattedValue != "***" {
+			t.Errorf("value %q: expected masked value ***, got %q", value, formattedValue)
+		}
+
+		cmdData.RevealValues = true
+		if formattedValue := d.formatValue(value); formattedValue != value {
+			t.Errorf("value %q: expected revealed value, got %q", value, formattedValue)
+		}
+	}
+}
+
+func TestGetSecretFiles(t *testing.T) {
+	for _, relHelmChartDir := range []string{".helm", "."} {
+		projectDir, err := ioutil.TempDir("", "werf-secret-diff-test-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(projectDir)
+
+		helmChartDir := filepath.Join(projectDir, relHelmChartDir)
+		for path, data := range map[string]string{
+			"Chart.yaml":                 "name: test",
+			"secret-values.yaml":         "values",
+			"secret-values.staging.yaml": "staging values",
+			"secret/tls.key":             "key",
+			"secret/production/tls.key":  "production key",
+		} {
+			absPath := filepath.Join(helmChartDir, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ioutil.WriteFile(absPath, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		repository, err := git.PlainInit(projectDir, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		worktree, err := repository.Worktree()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := worktree.AddGlob("."); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := worktree.Commit("init", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		localGitRepo, err := git_repo.OpenLocalRepo("own", projectDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string][]byte{
+			"secret-values.yaml":         []byte("values"),
+			"secret-values.staging.yaml": []byte("staging values"),
+			"secret/tls.key":             []byte("key"),
+			"secret/production/tls.key":  []byte("production key"),
+		}
+
+		for _, rev := range []string{"HEAD", ""} {
+			files, err := getSecretFiles(context.Background(), localGitRepo, helmChartDir, relHelmChartDir, rev)
+			if err != nil {
+				t.Fatalf("chart dir %q, rev %q: %s", relHelmChartDir, rev, err)
+			}
+
+			if !reflect.DeepEqual(files, expected) {
+				t.Errorf("chart dir %q, rev %q: expected files %v, got %v", relHelmChartDir, rev, expected, files)
+			}
+		}
+	}
+}
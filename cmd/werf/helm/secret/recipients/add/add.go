@@ -0,0 +1,90 @@
+package secret
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "add NAME PUBLIC_KEY",
+		DisableFlagsInUseLine: true,
+		Short:                 "Add secret recipient",
+		Long: common.GetLongCommandDescription(fmt.Sprintf(`Add secret recipient: the secret key is wrapped for the recipient public key and saved in %s file, secret files are not re-encrypted.
+
+The secret key is unwrapped with the identity from $WERF_SECRET_IDENTITY or ~/.werf/secret_identity file.
+The first recipient is added with the secret key from $WERF_SECRET_KEY or .werf_secret_key file`, secret.SecretRecipientsFileName)),
+		Example: `  # Add recipient with the public key generated by werf helm secret recipients generate-identity
+  $ werf helm secret recipients add alice werf-x25519:Zm9vYmFyZm9vYmFyZm9vYmFyZm9vYmFyZm9vYmFyMDA=`,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretIdentity),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) != 2 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("accepts 2 positional arguments, received %d", len(args))
+			}
+
+			return runAdd(args[0], args[1])
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runAdd(name, publicKey string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	recipients, err := secret.LoadSecretRecipients(projectDir)
+	if err != nil {
+		return err
+	}
+
+	if recipients == nil {
+		recipients = &secret.SecretRecipients{}
+	}
+
+	secretKey, err := secret.GetSecretKey(projectDir)
+	if err != nil {
+		return err
+	}
+
+	if err := recipients.AddRecipient(name, publicKey, secretKey); err != nil {
+		return err
+	}
+
+	if err := recipients.Save(projectDir); err != nil {
+		return err
+	}
+
+	logboek.Default().LogFDetails("Recipient %q is added to %s\n", name, secret.SecretRecipientsFileName)
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/secret"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "generate-identity",
+		DisableFlagsInUseLine: true,
+		Short:                 "Generate X25519 identity for secret recipients",
+		Long: common.GetLongCommandDescription(`Generate X25519 identity (private key) and print it with the public key.
+For further usage, the identity should be saved in $WERF_SECRET_IDENTITY or ~/.werf/secret_identity file, and the public key should be added to the project secret recipients with werf helm secret recipients add command`),
+		Example: `  # Save identity in ~/.werf/secret_identity file
+  $ werf helm secret recipients generate-identity > ~/.werf/secret_identity`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runGenerateIdentity()
+		},
+	}
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runGenerateIdentity() error {
+	identity, err := secret.GenerateX25519Identity()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("# public key: %s\n", identity.Recipient())
+	fmt.Println(identity.String())
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package secret
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "ls",
+		DisableFlagsInUseLine: true,
+		Short:                 "List secret recipients",
+		Long:                  common.GetLongCommandDescription(fmt.Sprintf("List names and public keys of the secret recipients from %s file", secret.SecretRecipientsFileName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runLs()
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runLs() error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	recipients, err := secret.LoadSecretRecipients(projectDir)
+	if err != nil {
+		return err
+	}
+
+	if recipients == nil {
+		return nil
+	}
+
+	for _, recipient := range recipients.Recipients {
+		fmt.Printf("%s\t%s\n", recipient.Name, recipient.PublicKey)
+	}
+
+	return nil
+}
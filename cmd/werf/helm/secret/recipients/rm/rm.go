@@ -0,0 +1,84 @@
+package secret
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "rm NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 "Remove secret recipient",
+		Long: common.GetLongCommandDescription(fmt.Sprintf(`Remove secret recipient from %s file.
+
+The removed recipient could have saved the secret key, so the secret key should be rotated with werf helm secret rotate-secret-key command: the new secret key is wrapped for the remaining recipients`, secret.SecretRecipientsFileName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) != 1 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("accepts 1 positional argument, received %d", len(args))
+			}
+
+			return runRm(args[0])
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runRm(name string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	recipients, err := secret.LoadSecretRecipients(projectDir)
+	if err != nil {
+		return err
+	}
+
+	if recipients == nil {
+		return fmt.Errorf("%s file not found", secret.SecretRecipientsFileName)
+	}
+
+	if err := recipients.RemoveRecipient(name); err != nil {
+		return err
+	}
+
+	if len(recipients.Recipients) == 0 {
+		return fmt.Errorf("unable to remove the last recipient %q: nobody would be able to unwrap the secret key", name)
+	}
+
+	if err := recipients.Save(projectDir); err != nil {
+		return err
+	}
+
+	logboek.Default().LogFDetails("Recipient %q is removed from %s\n", name, secret.SecretRecipientsFileName)
+	logboek.Warn().LogF("WARNING: Rotate the secret key with werf helm secret rotate-secret-key command, the removed recipient could have saved the secret key\n")
+
+	return nil
+}
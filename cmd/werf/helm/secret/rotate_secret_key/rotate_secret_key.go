@@ -27,14 +27,17 @@ func NewCmd() *cobra.Command {
 		Long: common.GetLongCommandDescription(`Regenerate secret files with new secret key.
 
 Old key should be specified in the $WERF_OLD_SECRET_KEY.
-New key should reside either in the $WERF_SECRET_KEY or .werf_secret_key file.
+New key should reside either in the $WERF_SECRET_KEY or .werf_secret_key file, the command fails if the new key is the same as the old one.
+If $WERF_OLD_SECRET_KEY is not specified, the secret key is not changed and the files are only migrated to the current encryption format.
 
-Command will extract data with the old key, generate new secret data and rewrite files:
+If the project has .werf_secret_recipients.yaml file, the old key is unwrapped with the identity from $WERF_SECRET_IDENTITY or ~/.werf/secret_identity file (unless $WERF_OLD_SECRET_KEY is specified) and the new key is wrapped for all recipients. The new key is generated if it is specified neither in the $WERF_SECRET_KEY nor in the .werf_secret_key file.
+
+Command will extract data with the old key (data in the legacy format is decrypted transparently), generate new secret data in the authenticated encryption format and rewrite files:
 * standard raw secret files in the .helm/secret folder;
 * standard secret values yaml file .helm/secret-values.yaml;
 * additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params`),
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfOldSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfOldSecretKey, common.WerfSecretIdentity),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
@@ -72,23 +75,110 @@ func runRotateSecretKey(cmd *cobra.Command, secretValuesPaths ...string) error {
 		return fmt.Errorf("getting helm chart dir failed: %s", err)
 	}
 
-	newSecret, err := secret.GetManager(projectDir)
+	return rotateSecretKey(projectDir, helmChartDir, secretValuesPaths...)
+}
+
+func rotateSecretKey(projectDir, helmChartDir string, secretValuesPaths ...string) error {
+	recipients, err := secret.LoadSecretRecipients(projectDir)
 	if err != nil {
 		return err
 	}
 
-	oldSecretKey := os.Getenv("WERF_OLD_SECRET_KEY")
-	if oldSecretKey == "" {
-		common.PrintHelp(cmd)
-		return fmt.Errorf("WERF_OLD_SECRET_KEY environment required")
+	oldSecretKey := []byte(os.Getenv("WERF_OLD_SECRET_KEY"))
+	if len(oldSecretKey) == 0 && recipients != nil {
+		identity, err := secret.GetSecretIdentity()
+		if err != nil {
+			return err
+		}
+
+		if identity != nil {
+			oldSecretKey, err = recipients.UnwrapSecretKey(identity)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	newSecretKey, err := getNewSecretKey(projectDir, recipients, oldSecretKey)
+	if err != nil {
+		return err
 	}
 
-	oldSecret, err := secret.NewManager([]byte(oldSecretKey))
+	newSecret, err := secret.NewManager(newSecretKey)
 	if err != nil {
 		return err
 	}
 
-	return secretsRegenerate(newSecret, oldSecret, helmChartDir, secretValuesPaths...)
+	oldSecret := newSecret
+	if len(oldSecretKey) != 0 {
+		oldSecret, err = secret.NewManager(oldSecretKey)
+		if err != nil {
+			return err
+		}
+	} else {
+		logboek.Default().LogLnDetails("WERF_OLD_SECRET_KEY is not specified: secret files will be migrated to the current encryption format with the same secret key")
+	}
+
+	if err := secretsRegenerate(newSecret, oldSecret, helmChartDir, secretValuesPaths...); err != nil {
+		return err
+	}
+
+	if recipients != nil {
+		if err := recipients.RewrapSecretKey(newSecretKey); err != nil {
+			return err
+		}
+
+		if err := recipients.Save(projectDir); err != nil {
+			return err
+		}
+
+		logboek.Default().LogLnDetails(fmt.Sprintf("Secret key is wrapped for %d recipients in %s", len(recipients.Recipients), secret.SecretRecipientsFileName))
+	}
+
+	return nil
+}
+
+// getNewSecretKey returns the new secret key. If the project has recipients, the key wrapped for them is the old one,
+// so the new key is read only from $WERF_SECRET_KEY or .werf_secret_key file or generated if it is not specified
+func getNewSecretKey(projectDir string, recipients *secret.SecretRecipients, oldSecretKey []byte) ([]byte, error) {
+	if recipients == nil {
+		newSecretKey, err := secret.GetSecretKey(projectDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(oldSecretKey) != 0 && bytes.Equal(newSecretKey, oldSecretKey) {
+			return nil, fmt.Errorf("new secret key is the same as the old one: specify the new key in the $WERF_SECRET_KEY or .werf_secret_key file")
+		}
+
+		return newSecretKey, nil
+	}
+
+	newSecretKey, err := secret.GetProjectSecretKey(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if newSecretKey == nil {
+		if len(oldSecretKey) == 0 {
+			return nil, fmt.Errorf("old secret key not found: specify $WERF_OLD_SECRET_KEY or the identity of one of the recipients in $WERF_SECRET_IDENTITY or %s file", secret.GetSecretIdentityFilePath())
+		}
+
+		newSecretKey, err = secret.GenerateSecretKey()
+		if err != nil {
+			return nil, err
+		}
+
+		logboek.Default().LogLnDetails("New secret key is generated, it is available to the recipients only")
+
+		return newSecretKey, nil
+	}
+
+	if len(oldSecretKey) != 0 && bytes.Equal(newSecretKey, oldSecretKey) {
+		return nil, fmt.Errorf("new secret key is the same as the old one: specify the new key in the $WERF_SECRET_KEY or .werf_secret_key file or remove it to generate a new key")
+	}
+
+	return newSecretKey, nil
 }
 
 func secretsRegenerate(newManager, oldManager secret.Manager, helmChartDir string, secretValuesPaths ...string) error {
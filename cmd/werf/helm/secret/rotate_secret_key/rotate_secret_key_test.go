@@ -0,0 +1,146 @@
+package secret
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/werf/pkg/deploy/secret"
+	secretPkg "github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func setTestEnv(t *testing.T, name, value string) func() {
+	oldValue, isSet := os.LookupEnv(name)
+
+	var err error
+	if value == "" {
+		err = os.Unsetenv(name)
+	} else {
+		err = os.Setenv(name, value)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		if isSet {
+			os.Setenv(name, oldValue)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestRotateSecretKeyAfterRecipientRemoval(t *testing.T) {
+	projectDir, err := ioutil.TempDir("", "werf-rotate-secret-key-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(projectDir)
+
+	if err := werf.Init(filepath.Join(projectDir, "tmp"), filepath.Join(projectDir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	alice, err := secretPkg.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bob, err := secretPkg.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldSecretKey, err := secret.GenerateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipients := &secret.SecretRecipients{}
+	for name, identity := range map[string]*secretPkg.X25519Identity{"alice": alice, "bob": bob} {
+		if err := recipients.AddRecipient(name, identity.Recipient(), oldSecretKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// werf helm secret recipients rm bob
+	if err := recipients.RemoveRecipient("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recipients.Save(projectDir); err != nil {
+		t.Fatal(err)
+	}
+
+	oldManager, err := secret.NewManager(oldSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helmChartDir := filepath.Join(projectDir, ".helm")
+	secretFilePath := filepath.Join(helmChartDir, "secret", "tls.key")
+	encryptedData, err := oldManager.Encrypt([]byte("tls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(secretFilePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(secretFilePath, encryptedData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer setTestEnv(t, "WERF_SECRET_KEY", "")()
+	defer setTestEnv(t, "WERF_OLD_SECRET_KEY", "")()
+	defer setTestEnv(t, "WERF_SECRET_IDENTITY", alice.String())()
+
+	if err := rotateSecretKey(projectDir, helmChartDir); err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err = secret.LoadSecretRecipients(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSecretKey, err := recipients.UnwrapSecretKey(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(newSecretKey, oldSecretKey) {
+		t.Fatalf("expected the secret key to be changed")
+	}
+
+	if _, err := recipients.UnwrapSecretKey(bob); err == nil {
+		t.Errorf("expected the removed recipient to be unable to unwrap the new secret key")
+	}
+
+	newManager, err := secret.NewManager(newSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(secretFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decryptedData, err := newManager.Decrypt(bytes.TrimSpace(data)); err != nil {
+		t.Errorf("unable to decrypt the secret file with the new secret key: %s", err)
+	} else if string(decryptedData) != "tls" {
+		t.Errorf("expected decrypted data %q, got %q", "tls", decryptedData)
+	}
+
+	// the secret key wrapped for the recipients is specified as the new one
+	defer setTestEnv(t, "WERF_SECRET_KEY", string(newSecretKey))()
+	if err := rotateSecretKey(projectDir, helmChartDir); err == nil {
+		t.Errorf("expected error when the new secret key is the same as the old one")
+	}
+}
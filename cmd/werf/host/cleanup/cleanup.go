@@ -27,6 +27,7 @@ func NewCmd() *cobra.Command {
 The data include:
 * Lost docker containers and images from interrupted builds.
 * Old service tmp dirs, which werf creates during every build, converge and other commands.
+* Cache mounts (mounts from cache_dir) which have not been used for a week.
 * Local cache:
   * Remote git clones cache.
   * Git worktree cache.
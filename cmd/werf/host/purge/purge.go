@@ -32,7 +32,7 @@ The data include:
   * Remote git clones cache.
   * Git worktree cache.
 * Shared context:
-  * Mounts which persists between several builds (mounts from build_dir).
+  * Mounts which persists between several builds (mounts from build_dir and cache_dir).
 
 WARNING: Do not run this command during any other werf command is working on the host machine. This command is supposed to be run manually.`),
 		DisableFlagsInUseLine: true,
@@ -46,6 +46,9 @@ import (
 	"github.com/werf/werf/cmd/werf/version"
 
 	stage_image "github.com/werf/werf/cmd/werf/stage/image"
+	stage_sbom "github.com/werf/werf/cmd/werf/stage/sbom"
+	stages_sync "github.com/werf/werf/cmd/werf/stages/sync"
+	stages_verify "github.com/werf/werf/cmd/werf/stages/verify"
 
 	"github.com/werf/werf/cmd/werf/common"
 	"github.com/werf/werf/cmd/werf/common/templates"
@@ -114,6 +117,7 @@ Find more information at https://werf.io`),
 			Commands: []*cobra.Command{
 				configCmd(),
 				managedImagesCmd(),
+				stagesCmd(),
 				hostCmd(),
 				helm.NewCmd(),
 			},
@@ -163,6 +167,19 @@ func managedImagesCmd() *cobra.Command {
 	return cmd
 }
 
+func stagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stages",
+		Short: "Work with project stages in the stages storage",
+	}
+	cmd.AddCommand(
+		stages_sync.NewCmd(),
+		stages_verify.NewCmd(),
+	)
+
+	return cmd
+}
+
 func stageCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:    "stage",
@@ -170,6 +187,7 @@ func stageCmd() *cobra.Command {
 	}
 	cmd.AddCommand(
 		stage_image.NewCmd(),
+		stage_sbom.NewCmd(),
 	)
 
 	return cmd
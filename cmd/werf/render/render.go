@@ -126,6 +126,7 @@ func NewCmd() *cobra.Command {
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
 
 	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupPlatform(&commonCmdData, cmd)
 	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
 	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
 
@@ -305,6 +306,13 @@ func runRender() error {
 		secretsManager = m
 	}
 
+	var envSecretsManager secret.Manager
+	if m, err := deploy.GetSafeEnvSecretManager(context.Background(), projectDir, chartDir, *commonCmdData.Environment, *commonCmdData.IgnoreSecretKey); err != nil {
+		return err
+	} else {
+		envSecretsManager = m
+	}
+
 	wc := werf_chart.NewWerfChart(werf_chart.WerfChartOptions{
 		ReleaseName: releaseName,
 		ChartDir:    chartDir,
@@ -313,7 +321,8 @@ func runRender() error {
 		ExtraAnnotations: userExtraAnnotations,
 		ExtraLabels:      userExtraLabels,
 
-		SecretsManager: secretsManager,
+		SecretsManager:    secretsManager,
+		EnvSecretsManager: envSecretsManager,
 	})
 	if err := wc.SetEnv(*commonCmdData.Environment); err != nil {
 		return err
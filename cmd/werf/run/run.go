@@ -108,6 +108,7 @@ func NewCmd() *cobra.Command {
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 	common.SetupSSHKey(&commonCmdData, cmd)
+	common.SetupBuildkitOptions(&commonCmdData, cmd)
 
 	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
 	common.SetupStagesStorageOptions(&commonCmdData, cmd)
@@ -135,6 +136,7 @@ func NewCmd() *cobra.Command {
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
 
 	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupPlatform(&commonCmdData, cmd)
 	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
 
 	cmd.Flags().BoolVarP(&cmdData.Shell, "shell", "", false, "Use predefined docker options and command for debug")
@@ -323,6 +325,9 @@ func run(ctx context.Context, projectDir string) error {
 	if err != nil {
 		return err
 	}
+	if err := common.InitBuildkitRuntime(&commonCmdData, containerRuntime, stagesStorage); err != nil {
+		return err
+	}
 	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
 	if err != nil {
 		return err
@@ -344,7 +349,12 @@ func run(ctx context.Context, projectDir string) error {
 
 	logboek.Context(ctx).Info().LogOptionalLn()
 
-	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{imageName}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, common.GetConveyorOptions(&commonCmdData))
+	conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{imageName}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, conveyorOptions)
 	defer conveyorWithRetry.Terminate()
 
 	var dockerImageName string
@@ -363,7 +373,7 @@ func run(ctx context.Context, projectDir string) error {
 			return err
 		}
 
-		dockerImageName = c.GetImageNameForLastImageStage(imageName)
+		dockerImageName = c.GetImageNameForLastImageStage("", imageName)
 		return nil
 	}); err != nil {
 		return err
@@ -76,6 +76,7 @@ func NewCmd() *cobra.Command {
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
 
 	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupPlatform(&commonCmdData, cmd)
 	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
 
 	return cmd
@@ -175,7 +176,12 @@ func run(imageName string) error {
 
 	storageManager := manager.NewStorageManager(projectName, stagesStorage, secondaryStagesStorageList, storageLockManager, stagesStorageCache)
 
-	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{imageName}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, common.GetConveyorOptions(&commonCmdData))
+	conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{imageName}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, conveyorOptions)
 	defer conveyorWithRetry.Terminate()
 
 	if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
@@ -183,7 +189,7 @@ func run(imageName string) error {
 			return err
 		}
 
-		fmt.Println(c.GetImageNameForLastImageStage(imageName))
+		fmt.Println(c.GetImageNameForLastImageStage("", imageName))
 
 		return nil
 	}); err != nil {
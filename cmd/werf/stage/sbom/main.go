@@ -0,0 +1,217 @@
+package sbom
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/logboek/pkg/level"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/build"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/ssh_agent"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "sbom [options] [IMAGE_NAME]",
+		Short:                 "Print SBOM of the image",
+		Long:                  common.GetLongCommandDescription("Print SBOM (CycloneDX JSON) of the image built with --sbom option. The SBOM of the image built for the first target platform is printed for a multi-platform image"),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.DisableOptionsInUseLineAnno: "1",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logboek.SetAcceptedLevel(level.Error)
+
+			var imageName string
+			if len(args) > 1 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("%d position argument can be specified, received %d", 1, len(args))
+			} else if len(args) == 1 {
+				imageName = args[0]
+			}
+
+			return run(imageName)
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read images from the specified repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	common.SetupVirtualMerge(&commonCmdData, cmd)
+	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
+	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+
+	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupPlatform(&commonCmdData, cmd)
+	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func run(imageName string) error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = ctxWithDockerCli
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, projectDir, &commonCmdData, false)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	if err := ssh_agent.Init(ctx, *commonCmdData.SSHKeys); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logboek.Warn().LogF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	if imageName == "" && len(werfConfig.StapelImages) == 1 {
+		imageName = werfConfig.StapelImages[0].Name
+	}
+
+	if !werfConfig.HasImage(imageName) {
+		return fmt.Errorf("image '%s' is not defined in werf.yaml", logging.ImageLogName(imageName, false))
+	}
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+
+	stagesStorageAddress, err := common.GetStagesStorageAddress(&commonCmdData)
+	if err != nil {
+		return err
+	}
+	stagesStorage, err := common.GetStagesStorage(stagesStorageAddress, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return err
+	}
+	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(stagesStorage, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	storageManager := manager.NewStorageManager(projectName, stagesStorage, secondaryStagesStorageList, storageLockManager, stagesStorageCache)
+
+	conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{imageName}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, conveyorOptions)
+	defer conveyorWithRetry.Terminate()
+
+	if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+		if err = c.ShouldBeBuilt(ctx); err != nil {
+			return err
+		}
+
+		var targetPlatform string
+		if len(conveyorOptions.TargetPlatforms) != 0 {
+			targetPlatform = conveyorOptions.TargetPlatforms[0]
+		}
+
+		content, err := c.GetImageSBOM(ctx, targetPlatform, imageName)
+		if err != nil {
+			return fmt.Errorf("unable to get image %s SBOM: %s", logging.ImageLogName(imageName, false), err)
+		}
+
+		if content == nil {
+			return fmt.Errorf("SBOM of image %s is not found: the image should be built with --sbom option", logging.ImageLogName(imageName, false))
+		}
+
+		fmt.Println(string(content))
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
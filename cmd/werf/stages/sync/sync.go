@@ -0,0 +1,255 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/werf"
+)
+
+type cmdDataType struct {
+	FromStagesStorage string
+	ToStagesStorage   string
+
+	ToRepoData              *common.RepoData
+	ToInsecureRegistry      bool
+	ToSkipTlsVerifyRegistry bool
+
+	ImageNames   []string
+	Commits      []string
+	OlderThan    string
+	NewerThan    string
+	RemoveSource bool
+	RemoveAbsent bool
+	ProgressLog  string
+}
+
+var cmdData cmdDataType
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "sync",
+		DisableFlagsInUseLine: true,
+		Short:                 "Sync project stages from one stages storage to another",
+		Long: common.GetLongCommandDescription(`Sync project stages from one stages storage to another.
+
+Copies stages which are absent in the destination storage along with managed images and images metadata records. Synced stages could be limited by the image names, git commits and stages age.
+
+The source stages storage is accessed with the --repo-*, --insecure-registry and --skip-tls-verify-registry options, the destination stages storage with the --to-* options, which default to the source ones.
+
+The sync could be interrupted and continued later: already synced stages are recorded into the --progress-log file and skipped on the next run`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := common.ValidateArgumentCount(0, args, cmd); err != nil {
+				return err
+			}
+
+			return run(cmd)
+		},
+	}
+
+	common.SetupProjectName(&commonCmdData, cmd)
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupCommonRepoData(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read images from the source repo and to write images to the destination repo")
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.FromStagesStorage, "from", "", os.Getenv("WERF_FROM"), "Source stages storage address: Docker Repo, s3://BUCKET/PREFIX, oci-layout:PATH or :local (default $WERF_FROM)")
+	cmd.Flags().StringVarP(&cmdData.ToStagesStorage, "to", "", os.Getenv("WERF_TO"), "Destination stages storage address: Docker Repo, s3://BUCKET/PREFIX, oci-layout:PATH or :local (default $WERF_TO)")
+	setupToRepoOptions(cmd)
+
+	cmd.Flags().StringArrayVarP(&cmdData.ImageNames, "image", "", getEnvListValue("WERF_SYNC_IMAGES"), "Sync only stages of the specified images (default separated by comma $WERF_SYNC_IMAGES)")
+	cmd.Flags().StringArrayVarP(&cmdData.Commits, "commit", "", getEnvListValue("WERF_SYNC_COMMITS"), "Sync only stages of the images built for the specified git commits (default separated by comma $WERF_SYNC_COMMITS)")
+	cmd.Flags().StringVarP(&cmdData.OlderThan, "older-than", "", os.Getenv("WERF_SYNC_OLDER_THAN"), "Sync only stages created earlier than the specified duration ago, e.g. 720h (default $WERF_SYNC_OLDER_THAN)")
+	cmd.Flags().StringVarP(&cmdData.NewerThan, "newer-than", "", os.Getenv("WERF_SYNC_NEWER_THAN"), "Sync only stages created within the specified duration, e.g. 168h (default $WERF_SYNC_NEWER_THAN)")
+	cmd.Flags().BoolVarP(&cmdData.RemoveSource, "remove-source", "", common.GetBoolEnvironmentDefaultFalse("WERF_SYNC_REMOVE_SOURCE"), "Remove synced stages from the source stages storage (default $WERF_SYNC_REMOVE_SOURCE)")
+	cmd.Flags().BoolVarP(&cmdData.RemoveAbsent, "remove-absent", "", common.GetBoolEnvironmentDefaultFalse("WERF_SYNC_REMOVE_ABSENT"), "Remove stages which do not exist in the source stages storage from the destination stages storage (default $WERF_SYNC_REMOVE_ABSENT)")
+	cmd.Flags().StringVarP(&cmdData.ProgressLog, "progress-log", "", os.Getenv("WERF_SYNC_PROGRESS_LOG"), "Record synced stages into the specified file and skip already recorded stages to continue interrupted sync (default $WERF_SYNC_PROGRESS_LOG)")
+
+	return cmd
+}
+
+// setupToRepoOptions sets up the destination stages storage options, the --repo-* options of the source are used for the destination if not specified
+func setupToRepoOptions(cmd *cobra.Command) {
+	cmdData.ToRepoData = &common.RepoData{DesignationStorageName: "destination stages storage"}
+
+	common.SetupImplementationForRepoData(cmdData.ToRepoData, cmd, "to-repo-implementation", []string{"WERF_TO_REPO_IMPLEMENTATION"})
+	common.SetupDockerHubUsernameForRepoData(cmdData.ToRepoData, cmd, "to-repo-docker-hub-username", []string{"WERF_TO_REPO_DOCKER_HUB_USERNAME"})
+	common.SetupDockerHubPasswordForRepoData(cmdData.ToRepoData, cmd, "to-repo-docker-hub-password", []string{"WERF_TO_REPO_DOCKER_HUB_PASSWORD"})
+	common.SetupDockerHubTokenForRepoData(cmdData.ToRepoData, cmd, "to-repo-docker-hub-token", []string{"WERF_TO_REPO_DOCKER_HUB_TOKEN"})
+	common.SetupGithubTokenForRepoData(cmdData.ToRepoData, cmd, "to-repo-github-token", []string{"WERF_TO_REPO_GITHUB_TOKEN"})
+	common.SetupHarborUsernameForRepoData(cmdData.ToRepoData, cmd, "to-repo-harbor-username", []string{"WERF_TO_REPO_HARBOR_USERNAME"})
+	common.SetupHarborPasswordForRepoData(cmdData.ToRepoData, cmd, "to-repo-harbor-password", []string{"WERF_TO_REPO_HARBOR_PASSWORD"})
+	common.SetupQuayTokenForRepoData(cmdData.ToRepoData, cmd, "to-repo-quay-token", []string{"WERF_TO_REPO_QUAY_TOKEN"})
+	common.SetupS3EndpointForRepoData(cmdData.ToRepoData, cmd, "to-repo-s3-endpoint", []string{"WERF_TO_REPO_S3_ENDPOINT"})
+	common.SetupS3RegionForRepoData(cmdData.ToRepoData, cmd, "to-repo-s3-region", []string{"WERF_TO_REPO_S3_REGION"})
+
+	cmd.Flags().BoolVarP(&cmdData.ToInsecureRegistry, "to-insecure-registry", "", common.GetBoolEnvironmentDefaultFalse("WERF_TO_INSECURE_REGISTRY"), "Use plain HTTP requests when accessing the destination registry, --insecure-registry is used if not specified (default $WERF_TO_INSECURE_REGISTRY)")
+	cmd.Flags().BoolVarP(&cmdData.ToSkipTlsVerifyRegistry, "to-skip-tls-verify-registry", "", common.GetBoolEnvironmentDefaultFalse("WERF_TO_SKIP_TLS_VERIFY_REGISTRY"), "Skip TLS certificate validation when accessing the destination registry, --skip-tls-verify-registry is used if not specified (default $WERF_TO_SKIP_TLS_VERIFY_REGISTRY)")
+}
+
+func getToBoolOption(cmd *cobra.Command, name, envName string, value, commonValue bool) bool {
+	if cmd.Flags().Changed(name) || os.Getenv(envName) != "" {
+		return value
+	}
+	return commonValue
+}
+
+func getEnvListValue(envName string) []string {
+	if v := os.Getenv(envName); v != "" {
+		return strings.Split(v, ",")
+	}
+	return nil
+}
+
+func parseDurationOption(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if d, err := time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("bad --%s value %q: %s", name, value, err)
+	} else {
+		return d, nil
+	}
+}
+
+func run(cmd *cobra.Command) error {
+	ctx := common.BackgroundContext()
+
+	if cmdData.FromStagesStorage == "" {
+		return fmt.Errorf("--from=ADDRESS param required")
+	}
+	if cmdData.ToStagesStorage == "" {
+		return fmt.Errorf("--to=ADDRESS param required")
+	}
+
+	olderThan, err := parseDurationOption("older-than", cmdData.OlderThan)
+	if err != nil {
+		return err
+	}
+	newerThan, err := parseDurationOption("newer-than", cmdData.NewerThan)
+	if err != nil {
+		return err
+	}
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = ctxWithDockerCli
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	werfConfig, err := common.GetOptionalWerfConfig(ctx, projectDir, &commonCmdData, false)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	var projectName string
+	if werfConfig != nil {
+		projectName = werfConfig.Meta.Project
+	} else if *commonCmdData.ProjectName != "" {
+		projectName = *commonCmdData.ProjectName
+	} else {
+		return fmt.Errorf("run command in the project directory with werf.yaml or specify --project-name=PROJECT_NAME param")
+	}
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+
+	fromStagesStorage, err := common.GetStagesStorage(cmdData.FromStagesStorage, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	toStagesStorage, err := common.GetStagesStorageByRepoData(
+		cmdData.ToStagesStorage,
+		containerRuntime,
+		common.MergeRepoData(cmdData.ToRepoData, commonCmdData.CommonRepoData),
+		getToBoolOption(cmd, "to-insecure-registry", "WERF_TO_INSECURE_REGISTRY", cmdData.ToInsecureRegistry, *commonCmdData.InsecureRegistry),
+		getToBoolOption(cmd, "to-skip-tls-verify-registry", "WERF_TO_SKIP_TLS_VERIFY_REGISTRY", cmdData.ToSkipTlsVerifyRegistry, *commonCmdData.SkipTlsVerifyRegistry),
+	)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, fromStagesStorage)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+
+	var imageNames []string
+	for _, imageName := range cmdData.ImageNames {
+		imageNames = append(imageNames, common.GetManagedImageName(imageName))
+	}
+
+	return manager.SyncStages(ctx, projectName, fromStagesStorage, toStagesStorage, storageLockManager, containerRuntime, manager.SyncStagesOptions{
+		RemoveSource:      cmdData.RemoveSource,
+		RemoveAbsent:      cmdData.RemoveAbsent,
+		CleanupLocalCache: true,
+		ImageNames:        imageNames,
+		Commits:           cmdData.Commits,
+		OlderThan:         olderThan,
+		NewerThan:         newerThan,
+		ProgressLogPath:   cmdData.ProgressLog,
+	})
+}
@@ -0,0 +1,202 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/werf"
+)
+
+type cmdDataType struct {
+	RepairCache          bool
+	DropOrphanedMetadata bool
+	ReportPath           string
+}
+
+var cmdData cmdDataType
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "verify",
+		DisableFlagsInUseLine: true,
+		Short:                 "Verify integrity of the project stages storage",
+		Long: common.GetLongCommandDescription(`Verify integrity of the project stages storage.
+
+Cross-checks storage cache and manifest cache records, stages listed in the stages storage, stages labels and parents chains, images and import metadata records.
+
+Command fails when found inconsistencies are not repaired. Storage cache could be repaired with --repair-cache option, orphaned metadata records could be dropped with --drop-orphaned-metadata option`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := common.ValidateArgumentCount(0, args, cmd); err != nil {
+				return err
+			}
+
+			return run()
+		},
+	}
+
+	common.SetupProjectName(&commonCmdData, cmd)
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultCleanupParallelTasksLimit)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read images from the specified repo")
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.RepairCache, "repair-cache", "", common.GetBoolEnvironmentDefaultFalse("WERF_REPAIR_CACHE"), "Reset inconsistent storage cache and manifest cache records (default $WERF_REPAIR_CACHE)")
+	cmd.Flags().BoolVarP(&cmdData.DropOrphanedMetadata, "drop-orphaned-metadata", "", common.GetBoolEnvironmentDefaultFalse("WERF_DROP_ORPHANED_METADATA"), "Remove images and import metadata records which refer to non-existing stages (default $WERF_DROP_ORPHANED_METADATA)")
+	cmd.Flags().StringVarP(&cmdData.ReportPath, "report-path", "", os.Getenv("WERF_REPORT_PATH"), "Write found inconsistencies in json format into the specified file ($WERF_REPORT_PATH by default)")
+
+	return cmd
+}
+
+func run() error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = ctxWithDockerCli
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	werfConfig, err := common.GetOptionalWerfConfig(ctx, projectDir, &commonCmdData, false)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	var projectName string
+	if werfConfig != nil {
+		projectName = werfConfig.Meta.Project
+	} else if *commonCmdData.ProjectName != "" {
+		projectName = *commonCmdData.ProjectName
+	} else {
+		return fmt.Errorf("run command in the project directory with werf.yaml or specify --project-name=PROJECT_NAME param")
+	}
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+
+	stagesStorageAddress := common.GetOptionalStagesStorageAddress(&commonCmdData)
+	stagesStorage, err := common.GetStagesStorage(stagesStorageAddress, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return err
+	}
+	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+
+	storageManager := manager.NewStorageManager(projectName, stagesStorage, nil, storageLockManager, stagesStorageCache)
+
+	if stagesStorage.Address() != storage.LocalStorageAddress && *commonCmdData.Parallel {
+		storageManager.StagesStorageManager.EnableParallel(int(*commonCmdData.ParallelTasksLimit))
+	}
+
+	report, err := storageManager.VerifyStages(ctx, manager.VerifyStagesOptions{
+		RepairCache:          cmdData.RepairCache,
+		DropOrphanedMetadata: cmdData.DropOrphanedMetadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	logboek.LogOptionalLn()
+	for _, problem := range report.Problems {
+		var subject string
+		switch {
+		case problem.StageID != "":
+			subject = fmt.Sprintf("stage %s", problem.StageID)
+		case problem.ImportID != "":
+			subject = fmt.Sprintf("import %s", problem.ImportID)
+		}
+
+		if problem.Repaired {
+			logboek.Default().LogF("%s: %s: %s (repaired)\n", problem.Type, subject, problem.Message)
+		} else {
+			logboek.Warn().LogF("%s: %s: %s\n", problem.Type, subject, problem.Message)
+		}
+	}
+	logboek.Default().LogFHighlight("Verified %d stages: found %d inconsistencies, %d not repaired\n", report.StagesCount, len(report.Problems), report.UnrepairedProblemsCount())
+
+	if cmdData.ReportPath != "" {
+		if data, err := json.MarshalIndent(report, "", "\t"); err != nil {
+			return fmt.Errorf("unable to prepare report: %s", err)
+		} else if err := ioutil.WriteFile(cmdData.ReportPath, append(data, []byte("\n")...), 0644); err != nil {
+			return fmt.Errorf("unable to write report to %s: %s", cmdData.ReportPath, err)
+		}
+	}
+
+	if count := report.UnrepairedProblemsCount(); count > 0 {
+		return fmt.Errorf("stages storage %s has %d inconsistencies", stagesStorage.String(), count)
+	}
+
+	return nil
+}
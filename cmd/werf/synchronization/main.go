@@ -1,9 +1,13 @@
 package synchronization
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
@@ -12,6 +16,7 @@ import (
 	"github.com/werf/lockgate/pkg/distributed_locker"
 
 	"github.com/werf/kubedog/pkg/kube"
+	"github.com/werf/logboek"
 
 	"github.com/werf/werf/pkg/kubeutils"
 	"github.com/werf/werf/pkg/storage"
@@ -31,9 +36,18 @@ var cmdData struct {
 	LocalLockManagerBaseDir        string
 	LocalStagesStorageCacheBaseDir string
 
+	Persistent                 bool
+	PersistentDBPath           string
+	PersistentSnapshotInterval string
+
 	TTL  string
 	Host string
 	Port string
+
+	TLSCert      string
+	TLSKey       string
+	ClientCACert string
+	AuthConfig   string
 }
 
 var commonCmdData common.CmdData
@@ -54,7 +68,7 @@ func NewCmd() *cobra.Command {
 			common.LogVersion()
 
 			return common.LogRunningTime(func() error {
-				return runSynchronization()
+				return common.WithoutTerminationSignalsTrap(runSynchronization)
 			})
 		},
 	}
@@ -72,6 +86,10 @@ func NewCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&cmdData.LocalLockManagerBaseDir, "local-lock-manager-base-dir", "", os.Getenv("WERF_LOCAL_LOCK_MANAGER_BASE_DIR"), "Use specified directory as base for file lock-manager (~/.werf/synchronization_server/lock_manager by default or $WERF_LOCAL_LOCK_MANAGER_BASE_DIR)")
 	cmd.Flags().StringVarP(&cmdData.LocalStagesStorageCacheBaseDir, "local-stages-storage-cache-base-dir", "", os.Getenv("WERF_LOCAL_STAGES_STORAGE_CACHE_BASE_DIR"), "Use specified directory as base for file stages-storage-cache (~/.werf/synchronization_server/stages_storage_cache by default or $WERF_LOCAL_STAGES_STORAGE_CACHE_BASE_DIR)")
 
+	cmd.Flags().BoolVarP(&cmdData.Persistent, "persistent", "", common.GetBoolEnvironmentDefaultFalse("WERF_PERSISTENT"), "Keep lock-manager locks and stages-storage-cache records in the bolt database between server restarts when --local option is used (default $WERF_PERSISTENT)")
+	cmd.Flags().StringVarP(&cmdData.PersistentDBPath, "persistent-db-path", "", os.Getenv("WERF_PERSISTENT_DB_PATH"), "Use specified bolt database file for --persistent option, snapshots are saved near the database with .snapshot suffix (~/.werf/synchronization_server/synchronization.db by default or $WERF_PERSISTENT_DB_PATH)")
+	cmd.Flags().StringVarP(&cmdData.PersistentSnapshotInterval, "persistent-snapshot-interval", "", os.Getenv("WERF_PERSISTENT_SNAPSHOT_INTERVAL"), "Save snapshot of the bolt database with the specified interval, 0 to disable periodic snapshots (5m by default or $WERF_PERSISTENT_SNAPSHOT_INTERVAL)")
+
 	cmd.Flags().BoolVarP(&cmdData.Kubernetes, "kubernetes", "", common.GetBoolEnvironmentDefaultFalse("WERF_KUBERNETES"), "Use kubernetes lock-manager stages-storage-cache (default $WERF_KUBERNETES)")
 	cmd.Flags().StringVarP(&cmdData.KubernetesNamespacePrefix, "kubernetes-namespace-prefix", "", os.Getenv("WERF_KUBERNETES_NAMESPACE_PREFIX"), "Use specified prefix for namespaces created for lock-manager and stages-storage-cache (defaults to 'werf-synchronization-' when --kubernetes option is used or $WERF_KUBERNETES_NAMESPACE_PREFIX)")
 
@@ -79,16 +97,61 @@ func NewCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&cmdData.Host, "host", "", os.Getenv("WERF_HOST"), "Bind synchronization server to the specified host (default localhost or $WERF_HOST)")
 	cmd.Flags().StringVarP(&cmdData.Port, "port", "", os.Getenv("WERF_PORT"), "Bind synchronization server to the specified port (default 55581 or $WERF_PORT)")
 
+	cmd.Flags().StringVarP(&cmdData.TLSCert, "tls-cert", "", os.Getenv("WERF_TLS_CERT"), "Serve https using specified certificate file, requires --tls-key (default $WERF_TLS_CERT)")
+	cmd.Flags().StringVarP(&cmdData.TLSKey, "tls-key", "", os.Getenv("WERF_TLS_KEY"), "Serve https using specified key file, requires --tls-cert (default $WERF_TLS_KEY)")
+	cmd.Flags().StringVarP(&cmdData.ClientCACert, "client-ca-cert", "", os.Getenv("WERF_CLIENT_CA_CERT"), "Require clients to present certificate signed by the specified CA certificate file (mTLS). Clients with verified certificate get access to all projects unless --auth-config is specified (default $WERF_CLIENT_CA_CERT)")
+	cmd.Flags().StringVarP(&cmdData.AuthConfig, "auth-config", "", os.Getenv("WERF_AUTH_CONFIG"), `Enable authentication and per-project authorization using specified yaml config with the allowed bearer tokens and client certificates common names (default $WERF_AUTH_CONFIG):
+
+tokens:
+- token: TOKEN
+  projects: [myproject, team-a-*]
+clientCertificates:
+- commonName: ci.team-b.example.com
+  projects: [team-b-*]
+
+clientCertificates require --client-ca-cert. ClientIDs issued by the server with enabled authorization are bound to the project, clientIDs issued before are available only with projects: ["*"]`)
+
 	return cmd
 }
 
 func runSynchronization() error {
-	ctx := common.BackgroundContext()
+	ctx, cancel := context.WithCancel(common.BackgroundContext())
+	defer cancel()
+
+	terminationSignalsChan := make(chan os.Signal, 1)
+	signal.Notify(terminationSignalsChan, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(terminationSignalsChan)
+
+	go func() {
+		select {
+		case <-terminationSignalsChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
 	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
 		return fmt.Errorf("initialization error: %s", err)
 	}
 
+	if (cmdData.TLSCert == "") != (cmdData.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key should be specified together")
+	}
+
+	serverOptions := synchronization_server.SynchronizationServerOptions{
+		TLSCertFile:  cmdData.TLSCert,
+		TLSKeyFile:   cmdData.TLSKey,
+		ClientCAFile: cmdData.ClientCACert,
+	}
+
+	if cmdData.AuthConfig != "" {
+		if authConfig, err := synchronization_server.LoadAuthConfig(cmdData.AuthConfig); err != nil {
+			return err
+		} else {
+			serverOptions.AuthConfig = authConfig
+		}
+	}
+
 	host, port := cmdData.Host, cmdData.Port
 	if host == "" {
 		host = "localhost"
@@ -136,6 +199,41 @@ func runSynchronization() error {
 				return fmt.Sprintf("werf-%s", clientID)
 			}), nil
 		}
+	} else if cmdData.Persistent {
+		dbPath := cmdData.PersistentDBPath
+		if dbPath == "" {
+			dbPath = filepath.Join(werf.GetHomeDir(), "synchronization_server", "synchronization.db")
+		}
+
+		snapshotInterval := 5 * time.Minute
+		if cmdData.PersistentSnapshotInterval != "" {
+			if d, err := time.ParseDuration(cmdData.PersistentSnapshotInterval); err != nil {
+				return fmt.Errorf("bad --persistent-snapshot-interval value %q: %s", cmdData.PersistentSnapshotInterval, err)
+			} else {
+				snapshotInterval = d
+			}
+		}
+
+		backend, err := synchronization_server.NewBoltBackend(ctx, dbPath, synchronization_server.BoltBackendOptions{OpenTimeout: time.Minute})
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := backend.Snapshot(ctx); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: %s\n", err)
+			}
+			if err := backend.Close(); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: unable to close %s: %s\n", dbPath, err)
+			}
+		}()
+
+		if snapshotInterval > 0 {
+			go backend.RunPeriodicSnapshots(ctx, snapshotInterval)
+		}
+
+		distributedLockerBackendFactoryFunc = backend.NewDistributedLockerBackend
+		stagesStorageCacheFactoryFunc = backend.NewStagesStorageCache
 	} else {
 		stagesStorageCacheBaseDir := cmdData.LocalStagesStorageCacheBaseDir
 		if stagesStorageCacheBaseDir == "" {
@@ -152,5 +250,5 @@ func runSynchronization() error {
 		}
 	}
 
-	return synchronization_server.RunSynchronizationServer(ctx, host, port, distributedLockerBackendFactoryFunc, stagesStorageCacheFactoryFunc)
+	return synchronization_server.RunSynchronizationServer(ctx, host, port, distributedLockerBackendFactoryFunc, stagesStorageCacheFactoryFunc, serverOptions)
 }
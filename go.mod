@@ -51,6 +51,7 @@ require (
 	github.com/otiai10/copy v1.0.1
 	github.com/otiai10/curr v1.0.0 // indirect
 	github.com/prashantv/gostub v1.0.0
+	github.com/prometheus/client_golang v1.7.1
 	github.com/rodaine/table v1.0.0
 	github.com/rubenv/sql-migrate v0.0.0-20200616145509-8d140a17f351 // indirect
 	github.com/satori/go.uuid v1.2.0
@@ -66,6 +67,7 @@ require (
 	github.com/werf/logboek v0.4.6
 	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
 	github.com/xeipuuv/gojsonschema v1.2.0 // indirect
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/net v0.0.0-20200822124328-c89045814202
 	gopkg.in/dancannon/gorethink.v3 v3.0.5 // indirect
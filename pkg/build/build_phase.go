@@ -1,6 +1,7 @@
 package build
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -21,8 +22,10 @@ import (
 
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/image"
 	imagePkg "github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/image_signing"
 	"github.com/werf/werf/pkg/stapel"
 	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/util"
@@ -41,7 +44,14 @@ type BuildOptions struct {
 	ReportPath   string
 	ReportFormat ReportFormat
 
-	DryRun bool
+	DryRun     bool
+	PlanPath   string
+	PlanFormat PlanFormat
+
+	// Signer signs the final images after the build, images are not signed if Signer is not set
+	Signer *image_signing.Signer
+
+	GenerateSBOM bool
 }
 
 type IntrospectOptions struct {
@@ -67,7 +77,7 @@ func NewBuildPhase(c *Conveyor, opts BuildPhaseOptions) *BuildPhase {
 	return &BuildPhase{
 		BasePhase:         BasePhase{c},
 		BuildPhaseOptions: opts,
-		ImagesReport:      &ImagesReport{Images: make(map[string]ReportImageRecord)},
+		ImagesReport:      NewImagesReport(),
 	}
 }
 
@@ -81,25 +91,59 @@ type BuildPhase struct {
 	ImagesReport *ImagesReport
 	ReportPath   string
 	ReportFormat ReportFormat
+
+	stageRecord *ReportStageRecord
+
+	// sbomReferences are the references of the generated SBOMs by the report image name
+	sbomReferences map[string]string
+	// manifestLists are the published manifest lists of the multi-platform images by image name
+	manifestLists map[string]*docker_registry.ManifestListInfo
 }
 
 const (
-	ReportJSON ReportFormat = "json"
+	ReportJSON        ReportFormat = "json"
+	ReportChromeTrace ReportFormat = "chrome-trace"
 )
 
 type ReportFormat string
 
 type ImagesReport struct {
-	mux    sync.Mutex
-	Images map[string]ReportImageRecord
+	mux       sync.Mutex
+	StartedAt time.Time
+	Images    map[string]ReportImageRecord
+	Artifacts map[string]ReportArtifactRecord
+
+	stages map[string][]*ReportStageRecord
+}
+
+func NewImagesReport() *ImagesReport {
+	return &ImagesReport{
+		StartedAt: time.Now(),
+		Images:    make(map[string]ReportImageRecord),
+		Artifacts: make(map[string]ReportArtifactRecord),
+		stages:    make(map[string][]*ReportStageRecord),
+	}
 }
 
 func (report *ImagesReport) SetImageRecord(name string, imageRecord ReportImageRecord) {
 	report.mux.Lock()
 	defer report.mux.Unlock()
+	imageRecord.Stages = report.stages[name]
 	report.Images[name] = imageRecord
 }
 
+func (report *ImagesReport) SetArtifactRecord(name string) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+	report.Artifacts[name] = ReportArtifactRecord{Stages: report.stages[name]}
+}
+
+func (report *ImagesReport) AddStageRecord(imageName string, stageRecord *ReportStageRecord) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+	report.stages[imageName] = append(report.stages[imageName], stageRecord)
+}
+
 func (report *ImagesReport) ToJson() ([]byte, error) {
 	report.mux.Lock()
 	defer report.mux.Unlock()
@@ -111,6 +155,12 @@ type ReportImageRecord struct {
 	DockerRepo    string
 	DockerTag     string
 	DockerImageID string
+	SBOM          string `json:",omitempty"`
+	Stages        []*ReportStageRecord
+}
+
+type ReportArtifactRecord struct {
+	Stages []*ReportStageRecord
 }
 
 func (phase *BuildPhase) Name() string {
@@ -118,35 +168,76 @@ func (phase *BuildPhase) Name() string {
 }
 
 func (phase *BuildPhase) BeforeImages(_ context.Context) error {
+	phase.ImagesReport.StartedAt = time.Now()
 	return nil
 }
 
 func (phase *BuildPhase) AfterImages(ctx context.Context) error {
+	if err := phase.publishManifestLists(ctx); err != nil {
+		return err
+	}
+
+	if err := phase.signImages(ctx); err != nil {
+		return err
+	}
+
+	if err := phase.generateSBOMs(ctx); err != nil {
+		return err
+	}
+
 	return phase.createReport(ctx)
 }
 
 func (phase *BuildPhase) createReport(ctx context.Context) error {
 	for _, img := range phase.Conveyor.images {
 		if img.isArtifact {
+			phase.ImagesReport.SetArtifactRecord(img.reportName())
 			continue
 		}
 
 		desc := img.GetLastNonEmptyStage().GetImage().GetStageDescription()
-		phase.ImagesReport.SetImageRecord(img.GetName(), ReportImageRecord{
+		record := ReportImageRecord{
 			WerfImageName: desc.Info.Name,
 			DockerRepo:    desc.Info.Repository,
 			DockerTag:     desc.Info.Tag,
 			DockerImageID: desc.Info.ID,
-		})
+			SBOM:          phase.sbomReferences[img.reportName()],
+		}
+		phase.ImagesReport.SetImageRecord(img.reportName(), record)
+
+		if img.reportName() == img.GetName() {
+			continue
+		}
+
+		// the image built for the target platforms is also reported by the image name:
+		// the record refers to the manifest list or to the image which is used instead of it (the first platform image)
+		if manifestList, ok := phase.manifestLists[img.GetName()]; ok {
+			phase.ImagesReport.SetImageRecord(img.GetName(), ReportImageRecord{
+				WerfImageName: desc.Info.Name,
+				DockerRepo:    manifestList.Info.Repository,
+				DockerTag:     manifestList.Info.Tag,
+				DockerImageID: manifestList.Info.RepoDigest,
+			})
+		} else if phase.Conveyor.GetImage(img.GetName()) == img {
+			phase.ImagesReport.SetImageRecord(img.GetName(), record)
+		}
 	}
 
 	if data, err := phase.ImagesReport.ToJson(); err != nil {
 		return fmt.Errorf("unable to prepare report json: %s", err)
 	} else {
 		logboek.Context(ctx).Debug().LogF("ImagesReport:\n%s\n", data)
+	}
+
+	if phase.ReportPath != "" {
+		if data, err := phase.ImagesReport.Render(phase.ReportFormat); err != nil {
+			return fmt.Errorf("unable to prepare %s report: %s", phase.ReportFormat, err)
+		} else {
+			if !bytes.HasSuffix(data, []byte("\n")) {
+				data = append(data, []byte("\n")...)
+			}
 
-		if phase.ReportPath != "" && phase.ReportFormat == ReportJSON {
-			if err := ioutil.WriteFile(phase.ReportPath, append(data, []byte("\n")...), 0644); err != nil {
+			if err := ioutil.WriteFile(phase.ReportPath, data, 0644); err != nil {
 				return fmt.Errorf("unable to write report to %s: %s", phase.ReportPath, err)
 			}
 		}
@@ -244,11 +335,23 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 		return nil
 	}
 
-	if err := stg.FetchDependencies(ctx, phase.Conveyor, phase.Conveyor.ContainerRuntime); err != nil {
+	phase.stageRecord = newReportStageRecord(string(stg.Name()))
+	defer func() {
+		phase.stageRecord.done()
+		phase.stageRecord.Digest = stg.GetDigest()
+		if stg.GetImage() != nil {
+			phase.stageRecord.DockerImageName = stg.GetImage().Name()
+		}
+		phase.ImagesReport.AddStageRecord(img.reportName(), phase.stageRecord)
+	}()
+
+	if err := phase.stageRecord.measure(stageSpanFetch, func() error {
+		return stg.FetchDependencies(ctx, phase.Conveyor, phase.Conveyor.ContainerRuntime)
+	}); err != nil {
 		return fmt.Errorf("unable to fetch dependencies for stage %s: %s", stg.LogDetailedName(), err)
 	}
 
-	if stg.Name() != "from" && stg.Name() != "dockerfile" {
+	if stg.Name() != "from" && !isDockerfileStage(stg) {
 		if phase.StagesIterator.PrevNonEmptyStage == nil {
 			panic(fmt.Sprintf("expected PrevNonEmptyStage to be set for image %q stage %s", img.GetName(), stg.Name()))
 		}
@@ -270,6 +373,9 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 
 	// Stage is cached in the stages storage
 	if foundSuitableStage {
+		phase.stageRecord.CacheHit = true
+		phase.stageRecord.CacheStorage = ReportStageCacheStoragePrimary
+
 		logboek.Context(ctx).Default().LogFHighlight("Use cache image for %s\n", stg.LogDetailedName())
 		logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), true)
 
@@ -289,7 +395,10 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 		return err
 	}
 
-	if !foundSuitableSecondaryStage {
+	if foundSuitableSecondaryStage {
+		phase.stageRecord.CacheHit = true
+		phase.stageRecord.CacheStorage = ReportStageCacheStorageSecondary
+	} else {
 		if phase.ShouldBeBuiltMode {
 			phase.printShouldBeBuiltError(ctx, img, stg)
 			return fmt.Errorf("stages required")
@@ -299,7 +408,9 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 		i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), uuid.New().String())
 		stg.SetImage(i)
 
-		if err := phase.fetchBaseImageForStage(ctx, img, stg); err != nil {
+		if err := phase.stageRecord.measure(stageSpanFetch, func() error {
+			return phase.fetchBaseImageForStage(ctx, img, stg)
+		}); err != nil {
 			return err
 		}
 		if err := phase.prepareStageInstructions(ctx, img, stg); err != nil {
@@ -325,11 +436,14 @@ func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context
 
 	atomicCopySuitableStageFromSecondaryStagesStorage := func(secondaryStageDesc *image.StageDescription, secondaryStagesStorage storage.StagesStorage) error {
 		// Lock the primary stages storage
-		if lock, err := phase.Conveyor.StorageLockManager.LockStage(ctx, phase.Conveyor.projectName(), stg.GetDigest()); err != nil {
+		var lock storage.LockHandle
+		if err := phase.stageRecord.measure(stageSpanLockWait, func() (err error) {
+			lock, err = phase.Conveyor.StorageLockManager.LockStage(ctx, phase.Conveyor.projectName(), stg.GetDigest())
+			return err
+		}); err != nil {
 			return fmt.Errorf("unable to lock project %s digest %s: %s", phase.Conveyor.projectName(), stg.GetDigest(), err)
-		} else {
-			defer phase.Conveyor.StorageLockManager.Unlock(ctx, lock)
 		}
+		defer phase.Conveyor.StorageLockManager.Unlock(ctx, lock)
 
 		// Query the primary stages storage for suitable stage again.
 		// Suitable stage can be found this time and should be used in this case
@@ -349,33 +463,35 @@ func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context
 				return nil
 			}
 
-			return logboek.Context(ctx).Default().LogProcess("Copy suitable stage from %s", secondaryStagesStorage.String()).DoError(func() error {
-				// Copy suitable stage from a secondary stages storage to the primary stages storage
-				// while primary stages storage lock for this digest is held
-				if copiedStageDesc, err := phase.Conveyor.StorageManager.CopySuitableByDigestStage(ctx, secondaryStageDesc, secondaryStagesStorage, phase.Conveyor.StorageManager.StagesStorage, phase.Conveyor.ContainerRuntime); err != nil {
-					return fmt.Errorf("unable to copy suitable stage %s from %s to %s: %s", secondaryStageDesc.StageID.String(), secondaryStagesStorage.String(), phase.Conveyor.StorageManager.StagesStorage.String(), err)
-				} else {
-					i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), copiedStageDesc.Info.Name)
-					i.SetStageDescription(copiedStageDesc)
-					stg.SetImage(i)
-
-					var stageIDs []image.StageID
-					for _, stageDesc := range stages {
-						stageIDs = append(stageIDs, *stageDesc.StageID)
+			return phase.stageRecord.measure(stageSpanFetch, func() error {
+				return logboek.Context(ctx).Default().LogProcess("Copy suitable stage from %s", secondaryStagesStorage.String()).DoError(func() error {
+					// Copy suitable stage from a secondary stages storage to the primary stages storage
+					// while primary stages storage lock for this digest is held
+					if copiedStageDesc, err := phase.Conveyor.StorageManager.CopySuitableByDigestStage(ctx, secondaryStageDesc, secondaryStagesStorage, phase.Conveyor.StorageManager.StagesStorage, phase.Conveyor.ContainerRuntime); err != nil {
+						return fmt.Errorf("unable to copy suitable stage %s from %s to %s: %s", secondaryStageDesc.StageID.String(), secondaryStagesStorage.String(), phase.Conveyor.StorageManager.StagesStorage.String(), err)
+					} else {
+						i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), copiedStageDesc.Info.Name)
+						i.SetStageDescription(copiedStageDesc)
+						stg.SetImage(i)
+
+						var stageIDs []image.StageID
+						for _, stageDesc := range stages {
+							stageIDs = append(stageIDs, *stageDesc.StageID)
+						}
+						stageIDs = append(stageIDs, *copiedStageDesc.StageID)
+
+						if err := phase.Conveyor.StorageManager.AtomicStoreStagesByDigestToCache(ctx, string(stg.Name()), stg.GetDigest(), stageIDs); err != nil {
+							return err
+						}
+
+						logboek.Context(ctx).Default().LogFHighlight("Use cache image for %s\n", stg.LogDetailedName())
+						logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), true)
+
+						return nil
 					}
-					stageIDs = append(stageIDs, *copiedStageDesc.StageID)
-
-					if err := phase.Conveyor.StorageManager.AtomicStoreStagesByDigestToCache(ctx, string(stg.Name()), stg.GetDigest(), stageIDs); err != nil {
-						return err
-					}
-
-					logboek.Context(ctx).Default().LogFHighlight("Use cache image for %s\n", stg.LogDetailedName())
-					logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), true)
 
 					return nil
-				}
-
-				return nil
+				})
 			})
 		}
 	}
@@ -405,7 +521,7 @@ func (phase *BuildPhase) fetchBaseImageForStage(ctx context.Context, img *Image,
 		if err := img.FetchBaseImage(ctx, phase.Conveyor); err != nil {
 			return fmt.Errorf("unable to fetch base image %s for stage %s: %s", img.GetBaseImage().Name(), stg.LogDetailedName(), err)
 		}
-	} else if stg.Name() == "dockerfile" {
+	} else if isDockerfileStage(stg) {
 		return nil
 	} else {
 		return phase.Conveyor.StorageManager.FetchStage(ctx, phase.StagesIterator.PrevBuiltStage)
@@ -422,41 +538,61 @@ func castToStageImage(img container_runtime.ImageInterface) *container_runtime.S
 }
 
 func (phase *BuildPhase) calculateStage(ctx context.Context, img *Image, stg stage.Interface) (bool, func(), error) {
-	stageDependencies, err := stg.GetDependencies(ctx, phase.Conveyor, phase.StagesIterator.GetPrevImage(img, stg), phase.StagesIterator.GetPrevBuiltImage(img, stg))
-	if err != nil {
-		return false, nil, err
-	}
+	var stageDigest string
+	if err := phase.stageRecord.measure(stageSpanDigest, func() error {
+		stageDependencies, err := stg.GetDependencies(ctx, phase.Conveyor, phase.StagesIterator.GetPrevImage(img, stg), phase.StagesIterator.GetPrevBuiltImage(img, stg))
+		if err != nil {
+			return err
+		}
 
-	stageDigest, err := calculateDigest(ctx, string(stg.Name()), stageDependencies, phase.StagesIterator.PrevNonEmptyStage, phase.Conveyor)
-	if err != nil {
+		prevNonEmptyStage := phase.StagesIterator.PrevNonEmptyStage
+		if isDockerfileStage(stg) {
+			// dockerfile stage dependencies already include dependencies of the dockerfile stages it is based on
+			prevNonEmptyStage = nil
+		}
+
+		stageDigest, err = calculateDigest(ctx, string(stg.Name()), stageDependencies, prevNonEmptyStage, phase.Conveyor)
+		return err
+	}); err != nil {
 		return false, nil, err
 	}
 	stg.SetDigest(stageDigest)
 
-	logboek.Context(ctx).Info().LogProcessInline("Locking stage %s handling", stg.LogDetailedName()).
-		Options(func(options types.LogProcessInlineOptionsInterface) {
-			if !phase.Conveyor.Parallel {
-				options.Mute()
-			}
-		}).
-		Do(phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Lock)
+	_ = phase.stageRecord.measure(stageSpanLockWait, func() error {
+		logboek.Context(ctx).Info().LogProcessInline("Locking stage %s handling", stg.LogDetailedName()).
+			Options(func(options types.LogProcessInlineOptionsInterface) {
+				if !phase.Conveyor.Parallel {
+					options.Mute()
+				}
+			}).
+			Do(phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Lock)
+		return nil
+	})
 
 	foundSuitableStage := false
-	if stages, err := phase.Conveyor.StorageManager.GetStagesByDigest(ctx, stg.LogDetailedName(), stageDigest); err != nil {
-		return false, phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Unlock, err
-	} else {
-		if stageDesc, err := phase.Conveyor.StorageManager.SelectSuitableStage(ctx, phase.Conveyor, stg, stages); err != nil {
-			return false, phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Unlock, err
-		} else if stageDesc != nil {
-			i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), stageDesc.Info.Name)
-			i.SetStageDescription(stageDesc)
-			stg.SetImage(i)
-			foundSuitableStage = true
+	if err := phase.stageRecord.measure(stageSpanCacheLookup, func() error {
+		if stages, err := phase.Conveyor.StorageManager.GetStagesByDigest(ctx, stg.LogDetailedName(), stageDigest); err != nil {
+			return err
+		} else {
+			if stageDesc, err := phase.Conveyor.StorageManager.SelectSuitableStage(ctx, phase.Conveyor, stg, stages); err != nil {
+				return err
+			} else if stageDesc != nil {
+				i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), stageDesc.Info.Name)
+				i.SetStageDescription(stageDesc)
+				stg.SetImage(i)
+				foundSuitableStage = true
+			}
 		}
+		return nil
+	}); err != nil {
+		return false, phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Unlock, err
 	}
 
-	stageContentSig, err := calculateDigest(ctx, fmt.Sprintf("%s-content", stg.Name()), "", stg, phase.Conveyor)
-	if err != nil {
+	var stageContentSig string
+	if err := phase.stageRecord.measure(stageSpanDigest, func() (err error) {
+		stageContentSig, err = calculateDigest(ctx, fmt.Sprintf("%s-content", stg.Name()), "", stg, phase.Conveyor)
+		return err
+	}); err != nil {
 		return false, phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Unlock, fmt.Errorf("unable to calculate stage %s content digest: %s", stg.Name(), err)
 	}
 	stg.SetContentDigest(stageContentSig)
@@ -490,6 +626,7 @@ func (phase *BuildPhase) prepareStageInstructions(ctx context.Context, img *Imag
 		}
 
 		stageImage.DockerfileImageBuilder().AppendBuildArgs(buildArgs...)
+		stageImage.DockerfileImageBuilder().CacheID = img.platformSuffixedName()
 
 		phase.Conveyor.AppendOnTerminateFunc(func() error {
 			return stageImage.DockerfileImageBuilder().Cleanup(ctx)
@@ -572,8 +709,16 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 		time.Sleep(time.Duration(seconds) * time.Second)
 	}
 
-	if err := logboek.Context(ctx).Streams().DoErrorWithTag(fmt.Sprintf("%s/%s", img.LogName(), stg.Name()), img.LogTagStyle(), func() error {
-		return stageImage.Build(ctx, phase.ImageBuildOptions)
+	if err := phase.stageRecord.measure(stageSpanBuild, func() error {
+		releaseCacheMounts, err := stg.AcquireCacheMounts(ctx)
+		if err != nil {
+			return err
+		}
+		defer releaseCacheMounts()
+
+		return logboek.Context(ctx).Streams().DoErrorWithTag(fmt.Sprintf("%s/%s", img.LogName(), stg.Name()), img.LogTagStyle(), func() error {
+			return stageImage.Build(ctx, phase.ImageBuildOptions)
+		})
 	}); err != nil {
 		return fmt.Errorf("failed to build image for stage %s with digest %s: %s", stg.Name(), stg.GetDigest(), err)
 	}
@@ -585,11 +730,14 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 		time.Sleep(time.Duration(seconds) * time.Second)
 	}
 
-	if lock, err := phase.Conveyor.StorageLockManager.LockStage(ctx, phase.Conveyor.projectName(), stg.GetDigest()); err != nil {
+	var lock storage.LockHandle
+	if err := phase.stageRecord.measure(stageSpanLockWait, func() (err error) {
+		lock, err = phase.Conveyor.StorageLockManager.LockStage(ctx, phase.Conveyor.projectName(), stg.GetDigest())
+		return err
+	}); err != nil {
 		return fmt.Errorf("unable to lock project %s digest %s: %s", phase.Conveyor.projectName(), stg.GetDigest(), err)
-	} else {
-		defer phase.Conveyor.StorageLockManager.Unlock(ctx, lock)
 	}
+	defer phase.Conveyor.StorageLockManager.Unlock(ctx, lock)
 
 	if stages, err := phase.Conveyor.StorageManager.GetStagesByDigest(ctx, stg.LogDetailedName(), stg.GetDigest()); err != nil {
 		return err
@@ -613,16 +761,18 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 			stageImageObj.SetName(newStageImageName)
 			phase.Conveyor.SetStageImage(stageImageObj)
 
-			if err := logboek.Context(ctx).Info().LogProcess("Store stage").DoError(func() error {
-				if err := phase.Conveyor.StorageManager.StagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: stageImage}); err != nil {
-					return fmt.Errorf("unable to store stage %s digest %s image %s into repo %s: %s", stg.LogDetailedName(), stg.GetDigest(), stageImage.Name(), phase.Conveyor.StorageManager.StagesStorage.String(), err)
-				}
-				if desc, err := phase.Conveyor.StorageManager.StagesStorage.GetStageDescription(ctx, phase.Conveyor.projectName(), stg.GetDigest(), uniqueID); err != nil {
-					return fmt.Errorf("unable to get stage %s digest %s image %s description from repo %s after stages has been stored into repo: %s", stg.LogDetailedName(), stg.GetDigest(), stageImage.Name(), phase.Conveyor.StorageManager.StagesStorage.String(), err)
-				} else {
-					stageImageObj.SetStageDescription(desc)
-				}
-				return nil
+			if err := phase.stageRecord.measure(stageSpanPush, func() error {
+				return logboek.Context(ctx).Info().LogProcess("Store stage").DoError(func() error {
+					if err := phase.Conveyor.StorageManager.StagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: stageImage}); err != nil {
+						return fmt.Errorf("unable to store stage %s digest %s image %s into repo %s: %s", stg.LogDetailedName(), stg.GetDigest(), stageImage.Name(), phase.Conveyor.StorageManager.StagesStorage.String(), err)
+					}
+					if desc, err := phase.Conveyor.StorageManager.StagesStorage.GetStageDescription(ctx, phase.Conveyor.projectName(), stg.GetDigest(), uniqueID); err != nil {
+						return fmt.Errorf("unable to get stage %s digest %s image %s description from repo %s after stages has been stored into repo: %s", stg.LogDetailedName(), stg.GetDigest(), stageImage.Name(), phase.Conveyor.StorageManager.StagesStorage.String(), err)
+					} else {
+						stageImageObj.SetStageDescription(desc)
+					}
+					return nil
+				})
 			}); err != nil {
 				return err
 			}
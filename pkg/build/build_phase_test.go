@@ -0,0 +1,86 @@
+package build
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+)
+
+type reportTestStageImage struct {
+	container_runtime.ImageInterface
+	desc *image.StageDescription
+}
+
+func (i *reportTestStageImage) GetStageDescription() *image.StageDescription {
+	return i.desc
+}
+
+type reportTestStage struct {
+	stage.Interface
+	img container_runtime.ImageInterface
+}
+
+func (s *reportTestStage) GetImage() container_runtime.ImageInterface {
+	return s.img
+}
+
+func newReportTestImage(name, targetPlatform, tag string) *Image {
+	return &Image{
+		name:           name,
+		targetPlatform: targetPlatform,
+		lastNonEmptyStage: &reportTestStage{img: &reportTestStageImage{desc: &image.StageDescription{
+			Info: &image.Info{Name: name, Repository: "registry.example.com/project", Tag: tag, ID: "sha256:" + tag},
+		}}},
+	}
+}
+
+func TestBuildPhase_createReportMultiPlatformImages(t *testing.T) {
+	phase := NewBuildPhase(&Conveyor{images: []*Image{
+		newReportTestImage("backend", "linux/amd64", "amd64"),
+		newReportTestImage("backend", "linux/arm64", "arm64"),
+		newReportTestImage("frontend", "linux/amd64", "frontend-amd64"),
+		newReportTestImage("frontend", "linux/arm64", "frontend-arm64"),
+	}}, BuildPhaseOptions{})
+	phase.manifestLists = map[string]*docker_registry.ManifestListInfo{
+		"backend": {Info: &image.Info{Repository: "registry.example.com/project", Tag: "manifest-list-123", RepoDigest: "sha256:123"}},
+	}
+
+	if err := phase.createReport(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for reportName, expected := range map[string]ReportImageRecord{
+		"backend@linux/amd64":  {WerfImageName: "backend", DockerRepo: "registry.example.com/project", DockerTag: "amd64", DockerImageID: "sha256:amd64"},
+		"backend@linux/arm64":  {WerfImageName: "backend", DockerRepo: "registry.example.com/project", DockerTag: "arm64", DockerImageID: "sha256:arm64"},
+		"backend":              {WerfImageName: "backend", DockerRepo: "registry.example.com/project", DockerTag: "manifest-list-123", DockerImageID: "sha256:123"},
+		"frontend@linux/arm64": {WerfImageName: "frontend", DockerRepo: "registry.example.com/project", DockerTag: "frontend-arm64", DockerImageID: "sha256:frontend-arm64"},
+		// there is no manifest list, the first platform image is used
+		"frontend": {WerfImageName: "frontend", DockerRepo: "registry.example.com/project", DockerTag: "frontend-amd64", DockerImageID: "sha256:frontend-amd64"},
+	} {
+		record, ok := phase.ImagesReport.Images[reportName]
+		if !ok {
+			t.Errorf("%s: expected report record", reportName)
+			continue
+		}
+
+		if !reflect.DeepEqual(record, expected) {
+			t.Errorf("%s: expected record %+v, got %+v", reportName, expected, record)
+		}
+	}
+
+	data, err := phase.ImagesReport.ToEnvFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedEnvFileLine := "WERF_IMAGE_BACKEND=registry.example.com/project:manifest-list-123\n"
+	if !strings.Contains(string(data), expectedEnvFileLine) {
+		t.Errorf("expected envfile line %q, got:\n%s", expectedEnvFileLine, data)
+	}
+}
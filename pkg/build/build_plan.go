@@ -0,0 +1,317 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/werf/werf/pkg/build/stage"
+)
+
+type PlanFormat string
+
+const (
+	PlanJSON PlanFormat = "json"
+	PlanDot  PlanFormat = "dot"
+)
+
+var PlanFormats = []PlanFormat{PlanJSON, PlanDot}
+
+type BuildPlanStageStatus string
+
+const (
+	// BuildPlanStageEmpty stage will be skipped
+	BuildPlanStageEmpty BuildPlanStageStatus = "empty"
+	// BuildPlanStageCached suitable stage exists in the primary or secondary stages storage
+	BuildPlanStageCached BuildPlanStageStatus = "cached"
+	// BuildPlanStageToBuild suitable stage does not exist and will be built
+	BuildPlanStageToBuild BuildPlanStageStatus = "to-build"
+	// BuildPlanStageUnknown digest cannot be calculated until the previous stages or dependencies are built
+	BuildPlanStageUnknown BuildPlanStageStatus = "unknown"
+)
+
+type BuildPlan struct {
+	mux    sync.Mutex
+	Images []*BuildPlanImage `json:"images"`
+}
+
+type BuildPlanImage struct {
+	Name              string                `json:"name"`
+	Platform          string                `json:"platform,omitempty"`
+	IsArtifact        bool                  `json:"isArtifact"`
+	IsDockerfileImage bool                  `json:"isDockerfileImage"`
+	Dependencies      []BuildPlanDependency `json:"dependencies"`
+	Stages            []*BuildPlanStage     `json:"stages"`
+
+	// Complete is true when digests of all image stages are known and all non-empty stages exist in the stages storage
+	Complete bool `json:"complete"`
+}
+
+type BuildPlanDependency struct {
+	ImageName string `json:"imageName"`
+	Type      string `json:"type"`
+}
+
+type BuildPlanStage struct {
+	Name   string               `json:"name"`
+	Digest string               `json:"digest,omitempty"`
+	Status BuildPlanStageStatus `json:"status"`
+	// CacheStorage is the stages storage where the suitable stage has been found: primary or secondary
+	CacheStorage string `json:"cacheStorage,omitempty"`
+}
+
+func (plan *BuildPlan) addImage(planImage *BuildPlanImage) {
+	plan.mux.Lock()
+	defer plan.mux.Unlock()
+	plan.Images = append(plan.Images, planImage)
+}
+
+// isImageComplete checks the image for the target platform or the image which is used instead (see Conveyor.GetImageForPlatform)
+func (plan *BuildPlan) isImageComplete(targetPlatform, imageName string) bool {
+	plan.mux.Lock()
+	defer plan.mux.Unlock()
+
+	var res *BuildPlanImage
+	for _, planImage := range plan.Images {
+		if planImage.Name != imageName {
+			continue
+		}
+
+		if planImage.Platform == targetPlatform {
+			return planImage.Complete
+		}
+
+		if res == nil || planImage.Platform == "" {
+			res = planImage
+		}
+	}
+
+	return res != nil && res.Complete
+}
+
+func (plan *BuildPlan) ToJson() ([]byte, error) {
+	plan.mux.Lock()
+	defer plan.mux.Unlock()
+	return json.MarshalIndent(plan, "", "\t")
+}
+
+// Render returns plan data in the specified format
+func (plan *BuildPlan) Render(format PlanFormat) ([]byte, error) {
+	switch format {
+	case PlanJSON:
+		return plan.ToJson()
+	case PlanDot:
+		return plan.ToDot(), nil
+	default:
+		return nil, fmt.Errorf("unsupported plan format %q", format)
+	}
+}
+
+// ToDot renders Graphviz digraph: cluster of stages for each image and edges between dependent images
+func (plan *BuildPlan) ToDot() []byte {
+	plan.mux.Lock()
+	defer plan.mux.Unlock()
+
+	images := append([]*BuildPlanImage{}, plan.Images...)
+	sort.Slice(images, func(i, j int) bool { return images[i].Name < images[j].Name })
+
+	stageNodeID := func(imageName, stageName string) string {
+		return fmt.Sprintf("%q", fmt.Sprintf("%s/%s", imageName, stageName))
+	}
+
+	imageLastNodeID := map[string]string{}
+	imageFirstNodeID := map[string]string{}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph werf {\n")
+	fmt.Fprintf(&buf, "\tcompound=true;\n\trankdir=LR;\n\tnode [shape=box, style=filled];\n")
+
+	for i, planImage := range images {
+		label := planImage.Name
+		if label == "" {
+			label = "~"
+		}
+		if planImage.IsArtifact {
+			label = fmt.Sprintf("artifact %s", label)
+		}
+
+		fmt.Fprintf(&buf, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", i, label)
+
+		var prevNodeID string
+		for _, planStage := range planImage.Stages {
+			nodeID := stageNodeID(planImage.Name, planStage.Name)
+
+			nodeLabel := planStage.Name
+			if planStage.Digest != "" {
+				nodeLabel = fmt.Sprintf("%s\n%s", planStage.Name, planStage.Digest)
+			}
+			fmt.Fprintf(&buf, "\t\t%s [label=%q, fillcolor=%q];\n", nodeID, nodeLabel, buildPlanStageColor(planStage.Status))
+
+			if prevNodeID != "" {
+				fmt.Fprintf(&buf, "\t\t%s -> %s;\n", prevNodeID, nodeID)
+			} else {
+				imageFirstNodeID[planImage.Name] = nodeID
+			}
+			prevNodeID = nodeID
+		}
+		imageLastNodeID[planImage.Name] = prevNodeID
+
+		fmt.Fprintf(&buf, "\t}\n")
+	}
+
+	for _, planImage := range images {
+		for _, dep := range planImage.Dependencies {
+			from, to := imageLastNodeID[dep.ImageName], imageFirstNodeID[planImage.Name]
+			if from == "" || to == "" {
+				continue
+			}
+			fmt.Fprintf(&buf, "\t%s -> %s [label=%q, style=dashed];\n", from, to, dep.Type)
+		}
+	}
+
+	fmt.Fprintf(&buf, "}\n")
+
+	return buf.Bytes()
+}
+
+func buildPlanStageColor(status BuildPlanStageStatus) string {
+	switch status {
+	case BuildPlanStageCached:
+		return "palegreen"
+	case BuildPlanStageToBuild:
+		return "lightsalmon"
+	case BuildPlanStageEmpty:
+		return "white"
+	default:
+		return "lightgrey"
+	}
+}
+
+func NewPlanPhase(c *Conveyor) *PlanPhase {
+	return &PlanPhase{
+		BuildPhase: *NewBuildPhase(c, BuildPhaseOptions{}),
+		Plan:       &BuildPlan{},
+	}
+}
+
+// PlanPhase calculates stages digests and checks stages storage without building anything.
+// Calculation of the image stages is stopped on the first stage which should be built,
+// because digests of the next stages depend on the built stage.
+type PlanPhase struct {
+	BuildPhase
+
+	Plan *BuildPlan
+
+	planImage *BuildPlanImage
+}
+
+func (phase *PlanPhase) Name() string {
+	return "plan"
+}
+
+func (phase *PlanPhase) AfterImages(_ context.Context) error {
+	return nil
+}
+
+func (phase *PlanPhase) BeforeImageStages(ctx context.Context, img *Image) error {
+	phase.planImage = &BuildPlanImage{
+		Name:              img.GetName(),
+		Platform:          img.targetPlatform,
+		IsArtifact:        img.isArtifact,
+		IsDockerfileImage: img.isDockerfileImage,
+		Dependencies:      []BuildPlanDependency{},
+		Stages:            []*BuildPlanStage{},
+		Complete:          true,
+	}
+
+	for _, dep := range phase.Conveyor.werfConfig.GetImageDependencies(img.GetName()) {
+		phase.planImage.Dependencies = append(phase.planImage.Dependencies, BuildPlanDependency{ImageName: dep.ImageName, Type: string(dep.Type)})
+
+		if !phase.Plan.isImageComplete(img.targetPlatform, dep.ImageName) {
+			phase.planImage.Complete = false
+		}
+	}
+
+	return phase.BuildPhase.BeforeImageStages(ctx, img)
+}
+
+func (phase *PlanPhase) OnImageStage(ctx context.Context, img *Image, stg stage.Interface) error {
+	planStage := &BuildPlanStage{Name: string(stg.Name()), Status: BuildPlanStageUnknown}
+	phase.planImage.Stages = append(phase.planImage.Stages, planStage)
+
+	if !phase.planImage.Complete {
+		return nil
+	}
+
+	return phase.StagesIterator.OnImageStage(ctx, img, stg, func(img *Image, stg stage.Interface, isEmpty bool) error {
+		if isEmpty {
+			planStage.Status = BuildPlanStageEmpty
+			return nil
+		}
+
+		phase.stageRecord = newReportStageRecord(string(stg.Name()))
+
+		if err := stg.FetchDependencies(ctx, phase.Conveyor, phase.Conveyor.ContainerRuntime); err != nil {
+			return fmt.Errorf("unable to fetch dependencies for stage %s: %s", stg.LogDetailedName(), err)
+		}
+
+		foundSuitableStage, cleanupFunc, err := phase.calculateStage(ctx, img, stg)
+		if cleanupFunc != nil {
+			defer cleanupFunc()
+		}
+		if err != nil {
+			return err
+		}
+		planStage.Digest = stg.GetDigest()
+
+		if foundSuitableStage {
+			planStage.Status = BuildPlanStageCached
+			planStage.CacheStorage = ReportStageCacheStoragePrimary
+			return nil
+		}
+
+		for _, secondaryStagesStorage := range phase.Conveyor.StorageManager.SecondaryStagesStorageList {
+			if secondaryStages, err := phase.Conveyor.StorageManager.GetStagesByDigestFromStagesStorage(ctx, stg.LogDetailedName(), stg.GetDigest(), secondaryStagesStorage); err != nil {
+				return err
+			} else if secondaryStageDesc, err := phase.Conveyor.StorageManager.SelectSuitableStage(ctx, phase.Conveyor, stg, secondaryStages); err != nil {
+				return err
+			} else if secondaryStageDesc != nil {
+				i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), secondaryStageDesc.Info.Name)
+				i.SetStageDescription(secondaryStageDesc)
+				stg.SetImage(i)
+
+				planStage.Status = BuildPlanStageCached
+				planStage.CacheStorage = ReportStageCacheStorageSecondary
+				return nil
+			}
+		}
+
+		planStage.Status = BuildPlanStageToBuild
+		phase.planImage.Complete = false
+
+		// the stage should have an image to be handled by the stages iterator
+		stg.SetImage(phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), uuid.New().String()))
+
+		return nil
+	})
+}
+
+func (phase *PlanPhase) AfterImageStages(_ context.Context, img *Image) error {
+	if phase.planImage.Complete && phase.StagesIterator.PrevNonEmptyStage != nil {
+		img.SetLastNonEmptyStage(phase.StagesIterator.PrevNonEmptyStage)
+		img.SetContentDigest(phase.StagesIterator.PrevNonEmptyStage.GetContentDigest())
+	}
+
+	phase.Plan.addImage(phase.planImage)
+	return nil
+}
+
+func (phase *PlanPhase) Clone() Phase {
+	u := *phase
+	return &u
+}
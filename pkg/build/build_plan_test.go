@@ -0,0 +1,86 @@
+package build
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestBuildPlan() *BuildPlan {
+	plan := &BuildPlan{}
+
+	plan.addImage(&BuildPlanImage{
+		Name:       "assets",
+		IsArtifact: true,
+		Stages: []*BuildPlanStage{
+			{Name: "from", Digest: "aaa", Status: BuildPlanStageCached, CacheStorage: ReportStageCacheStoragePrimary},
+			{Name: "install", Digest: "bbb", Status: BuildPlanStageToBuild},
+		},
+	})
+	plan.addImage(&BuildPlanImage{
+		Name:         "backend",
+		Dependencies: []BuildPlanDependency{{ImageName: "assets", Type: "import"}},
+		Stages: []*BuildPlanStage{
+			{Name: "from", Status: BuildPlanStageUnknown},
+			{Name: "beforeInstall", Status: BuildPlanStageUnknown},
+		},
+	})
+
+	return plan
+}
+
+func TestBuildPlan_ToJson(t *testing.T) {
+	data, err := newTestBuildPlan().ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &BuildPlan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		t.Fatalf("unable to unmarshal plan: %s\n%s", err, data)
+	}
+
+	if len(plan.Images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(plan.Images))
+	}
+	if got := plan.Images[0].Stages[1].Status; got != BuildPlanStageToBuild {
+		t.Errorf("expected %q status, got %q", BuildPlanStageToBuild, got)
+	}
+}
+
+func TestBuildPlan_ToDot(t *testing.T) {
+	dot := string(newTestBuildPlan().ToDot())
+
+	for _, expected := range []string{
+		"digraph werf {",
+		`label="artifact assets";`,
+		`"assets/from" -> "assets/install";`,
+		`"backend/from" -> "backend/beforeInstall";`,
+		`"assets/install" -> "backend/from" [label="import", style=dashed];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("expected %q in dot:\n%s", expected, dot)
+		}
+	}
+}
+
+func TestBuildPlan_isImageComplete(t *testing.T) {
+	plan := newTestBuildPlan()
+	plan.Images[0].Complete = true
+
+	if !plan.isImageComplete("", "assets") {
+		t.Errorf("expected assets to be complete")
+	}
+	if plan.isImageComplete("", "backend") || plan.isImageComplete("", "unknown") {
+		t.Errorf("expected backend and unknown images to be incomplete")
+	}
+
+	plan.addImage(&BuildPlanImage{Name: "backend", Platform: "linux/arm64", Complete: true})
+
+	if !plan.isImageComplete("linux/arm64", "backend") {
+		t.Errorf("expected backend for linux/arm64 to be complete")
+	}
+	if plan.isImageComplete("linux/amd64", "backend") {
+		t.Errorf("expected backend for linux/amd64 to fall back to the native platform image")
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/logging"
@@ -69,6 +70,9 @@ type Conveyor struct {
 	onTerminateFuncs []func() error
 	importServers    map[string]import_server.ImportServer
 
+	// manifestLists are the names of the published manifest lists of the multi-platform images by image name
+	manifestLists map[string]string
+
 	ConveyorOptions
 
 	mutex            sync.Mutex
@@ -82,6 +86,8 @@ type ConveyorOptions struct {
 	LocalGitRepoVirtualMergeOptions stage.VirtualMergeOptions
 	GitUnshallow                    bool
 	AllowGitShallowClone            bool
+	// TargetPlatforms are used for the images without platform setting in werf.yaml
+	TargetPlatforms []string
 }
 
 func NewConveyor(werfConfig *config.WerfConfig, imageNamesToProcess []string, projectDir, baseTmpDir, sshAuthSock string, containerRuntime container_runtime.ContainerRuntime, storageManager *manager.StorageManager, storageLockManager storage.LockManager, opts ConveyorOptions) (*Conveyor, error) {
@@ -104,6 +110,7 @@ func NewConveyor(werfConfig *config.WerfConfig, imageNamesToProcess []string, pr
 		remoteGitRepos:         make(map[string]*git_repo.Remote),
 		tmpDir:                 filepath.Join(baseTmpDir, util.GenerateConsistentRandomString(10)),
 		importServers:          make(map[string]import_server.ImportServer),
+		manifestLists:          make(map[string]string),
 
 		ContainerRuntime:   containerRuntime,
 		StorageLockManager: storageLockManager,
@@ -192,11 +199,13 @@ func (c *Conveyor) GetLocalGitRepoVirtualMergeOptions() stage.VirtualMergeOption
 	return c.ConveyorOptions.LocalGitRepoVirtualMergeOptions
 }
 
-func (c *Conveyor) GetImportServer(ctx context.Context, imageName, stageName string) (import_server.ImportServer, error) {
+func (c *Conveyor) GetImportServer(ctx context.Context, targetPlatform, imageName, stageName string) (import_server.ImportServer, error) {
 	c.getServiceRWMutex("ImportServer").Lock()
 	defer c.getServiceRWMutex("ImportServer").Unlock()
 
-	importServerName := imageName
+	img := c.GetImageForPlatform(targetPlatform, imageName)
+
+	importServerName := img.platformSuffixedName()
 	if stageName != "" {
 		importServerName += "/" + stageName
 	}
@@ -206,13 +215,13 @@ func (c *Conveyor) GetImportServer(ctx context.Context, imageName, stageName str
 
 	var srv *import_server.RsyncServer
 
-	if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Firing up import rsync server for image %s", imageName)).
+	if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Firing up import rsync server for image %s", img.LogName())).
 		DoError(func() error {
 			var tmpDir string
 			if stageName == "" {
-				tmpDir = filepath.Join(c.tmpDir, "import-server", imageName)
+				tmpDir = filepath.Join(c.tmpDir, "import-server", img.platformSuffixedName())
 			} else {
-				tmpDir = filepath.Join(c.tmpDir, "import-server", fmt.Sprintf("%s-%s", imageName, stageName))
+				tmpDir = filepath.Join(c.tmpDir, "import-server", fmt.Sprintf("%s-%s", img.platformSuffixedName(), stageName))
 			}
 
 			if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
@@ -221,9 +230,9 @@ func (c *Conveyor) GetImportServer(ctx context.Context, imageName, stageName str
 
 			var dockerImageName string
 			if stageName == "" {
-				dockerImageName = c.GetImageNameForLastImageStage(imageName)
+				dockerImageName = c.GetImageNameForLastImageStage(targetPlatform, imageName)
 			} else {
-				dockerImageName = c.GetImageNameForImageStage(imageName, stageName)
+				dockerImageName = c.GetImageNameForImageStage(targetPlatform, imageName, stageName)
 			}
 
 			var err error
@@ -367,11 +376,20 @@ func (c *Conveyor) FetchLastImageStage(ctx context.Context, imageName string) er
 }
 
 func (c *Conveyor) GetImageInfoGetters() (images []*image.InfoGetter) {
+	processedImages := map[string]bool{}
 	for _, img := range c.images {
-		if img.isArtifact {
+		if img.isArtifact || processedImages[img.GetName()] {
+			continue
+		}
+		processedImages[img.GetName()] = true
+
+		if manifestListName, ok := c.manifestLists[img.GetName()]; ok {
+			_, tag := image.ParseRepositoryAndTag(manifestListName)
+			images = append(images, image.NewInfoGetter(img.GetName(), manifestListName, tag))
 			continue
 		}
-		images = append(images, img.GetImageInfoGetter())
+
+		images = append(images, c.GetImage(img.GetName()).GetImageInfoGetter())
 	}
 
 	return images
@@ -382,18 +400,67 @@ func (c *Conveyor) Build(ctx context.Context, opts BuildOptions) error {
 		return err
 	}
 
+	if opts.DryRun {
+		return c.dryRun(ctx, opts)
+	}
+
 	phases := []Phase{
 		NewBuildPhase(c, BuildPhaseOptions{
 			BuildOptions: opts,
 		}),
 	}
 
-	if opts.DryRun {
-		fmt.Printf("Build DryRun\n")
+	return c.runPhases(ctx, phases, true)
+}
+
+// Plan calculates stages digests and checks which stages exist in the stages storage without building anything
+func (c *Conveyor) Plan(ctx context.Context) (*BuildPlan, error) {
+	if err := c.determineStages(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.plan(ctx)
+}
+
+func (c *Conveyor) plan(ctx context.Context) (*BuildPlan, error) {
+	planPhase := NewPlanPhase(c)
+	if err := c.runPhases(ctx, []Phase{planPhase}, false); err != nil {
+		return nil, err
+	}
+
+	return planPhase.Plan, nil
+}
+
+func (c *Conveyor) dryRun(ctx context.Context, opts BuildOptions) error {
+	plan, err := c.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	format := opts.PlanFormat
+	if format == "" {
+		format = PlanJSON
+	}
+
+	data, err := plan.Render(format)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, []byte("\n")...)
+	}
+
+	if opts.PlanPath == "" {
+		fmt.Printf("%s", data)
 		return nil
 	}
 
-	return c.runPhases(ctx, phases, true)
+	if err := ioutil.WriteFile(opts.PlanPath, data, 0644); err != nil {
+		return fmt.Errorf("unable to write build plan to %s: %s", opts.PlanPath, err)
+	}
+
+	return nil
 }
 
 func (c *Conveyor) determineStages(ctx context.Context) error {
@@ -409,54 +476,104 @@ func (c *Conveyor) determineStages(ctx context.Context) error {
 func (c *Conveyor) doDetermineStages(ctx context.Context) error {
 	imageConfigsToProcess := getImageConfigsToProcess(ctx, c)
 	configSets := c.werfConfig.ImagesWithDependenciesBySets(imageConfigsToProcess)
+	targetPlatformsByImageName := c.getTargetPlatformsByImageName(configSets)
 
 	for _, iteration := range configSets {
 		var imageSet []*Image
 
 		for _, imageInterfaceConfig := range iteration {
-			var img *Image
-			var imageLogName string
-			var style *style.Style
-
-			switch imageConfig := imageInterfaceConfig.(type) {
-			case config.StapelImageInterface:
-				imageLogName = logging.ImageLogProcessName(imageConfig.ImageBaseConfig().Name, imageConfig.IsArtifact())
-				style = ImageLogProcessStyle(imageConfig.IsArtifact())
-			case *config.ImageFromDockerfile:
-				imageLogName = logging.ImageLogProcessName(imageConfig.Name, false)
-				style = ImageLogProcessStyle(false)
+			for _, targetPlatform := range targetPlatformsByImageName[imageInterfaceConfig.GetName()] {
+				var img *Image
+				var imageLogName string
+				var style *style.Style
+
+				switch imageConfig := imageInterfaceConfig.(type) {
+				case config.StapelImageInterface:
+					imageLogName = logging.ImageLogProcessName(imagePlatformLogName(imageConfig.ImageBaseConfig().Name, imageConfig.IsArtifact(), targetPlatform), imageConfig.IsArtifact())
+					style = ImageLogProcessStyle(imageConfig.IsArtifact())
+				case *config.ImageFromDockerfile:
+					imageLogName = logging.ImageLogProcessName(imagePlatformLogName(imageConfig.Name, false, targetPlatform), false)
+					style = ImageLogProcessStyle(false)
+				}
+
+				err := logboek.Context(ctx).Info().LogProcess(imageLogName).
+					Options(func(options types.LogProcessOptionsInterface) {
+						options.Style(style)
+					}).
+					DoError(func() error {
+						var err error
+
+						switch imageConfig := imageInterfaceConfig.(type) {
+						case config.StapelImageInterface:
+							img, err = prepareImageBasedOnStapelImageConfig(ctx, imageConfig, targetPlatform, c)
+						case *config.ImageFromDockerfile:
+							img, err = prepareImageBasedOnImageFromDockerfile(ctx, imageConfig, targetPlatform, c)
+						}
+
+						if err != nil {
+							return err
+						}
+
+						c.images = append(c.images, img)
+						imageSet = append(imageSet, img)
+
+						return nil
+					})
+
+				if err != nil {
+					return err
+				}
 			}
+		}
 
-			err := logboek.Context(ctx).Info().LogProcess(imageLogName).
-				Options(func(options types.LogProcessOptionsInterface) {
-					options.Style(style)
-				}).
-				DoError(func() error {
-					var err error
+		c.imageSets = append(c.imageSets, imageSet)
+	}
 
-					switch imageConfig := imageInterfaceConfig.(type) {
-					case config.StapelImageInterface:
-						img, err = prepareImageBasedOnStapelImageConfig(ctx, imageConfig, c)
-					case *config.ImageFromDockerfile:
-						img, err = prepareImageBasedOnImageFromDockerfile(ctx, imageConfig, c)
-					}
+	return nil
+}
 
-					if err != nil {
-						return err
-					}
+// getTargetPlatformsByImageName returns platforms from werf.yaml or --platform option for each image,
+// images and artifacts without own platforms are built for all platforms of the images which depend on them
+func (c *Conveyor) getTargetPlatformsByImageName(configSets [][]config.ImageInterface) map[string][]string {
+	res := map[string][]string{}
+	requiredPlatforms := map[string][]string{}
 
-					c.images = append(c.images, img)
-					imageSet = append(imageSet, img)
+	for i := len(configSets) - 1; i >= 0; i-- {
+		for _, imageInterfaceConfig := range configSets[i] {
+			imageName := imageInterfaceConfig.GetName()
 
-					return nil
-				})
+			platforms := getImageConfigPlatforms(imageInterfaceConfig)
+			if len(platforms) == 0 {
+				platforms = requiredPlatforms[imageName]
+			}
+			if len(platforms) == 0 {
+				platforms = c.TargetPlatforms
+			}
+			if len(platforms) == 0 {
+				platforms = []string{""}
+			}
 
-			if err != nil {
-				return err
+			res[imageName] = platforms
+
+			for _, dep := range c.werfConfig.GetImageDependencies(imageName) {
+				for _, platform := range platforms {
+					if !util.IsStringsContainValue(requiredPlatforms[dep.ImageName], platform) {
+						requiredPlatforms[dep.ImageName] = append(requiredPlatforms[dep.ImageName], platform)
+					}
+				}
 			}
 		}
+	}
 
-		c.imageSets = append(c.imageSets, imageSet)
+	return res
+}
+
+func getImageConfigPlatforms(imageInterfaceConfig config.ImageInterface) []string {
+	switch imageConfig := imageInterfaceConfig.(type) {
+	case config.StapelImageInterface:
+		return imageConfig.ImageBaseConfig().Platform
+	case *config.ImageFromDockerfile:
+		return imageConfig.Platform
 	}
 
 	return nil
@@ -657,55 +774,82 @@ func (c *Conveyor) GetOrCreateStageImage(fromImage *container_runtime.StageImage
 	return img
 }
 
+func (c *Conveyor) SetManifestList(imageName, manifestListName string) {
+	c.getServiceRWMutex("ManifestLists").Lock()
+	defer c.getServiceRWMutex("ManifestLists").Unlock()
+
+	c.manifestLists[imageName] = manifestListName
+}
+
+// GetImage returns the image built for the native platform or the first one if there is no such image
 func (c *Conveyor) GetImage(name string) *Image {
+	return c.GetImageForPlatform("", name)
+}
+
+// GetImageForPlatform returns the image built for the target platform,
+// the image built for the native platform or the first one is returned if there is no such image
+func (c *Conveyor) GetImageForPlatform(targetPlatform, name string) *Image {
+	var res *Image
 	for _, img := range c.images {
-		if img.GetName() == name {
+		if img.GetName() != name {
+			continue
+		}
+
+		if img.targetPlatform == targetPlatform {
 			return img
 		}
+
+		if res == nil || img.targetPlatform == "" {
+			res = img
+		}
+	}
+
+	if res == nil {
+		panic(fmt.Sprintf("Image '%s' not found!", name))
 	}
 
-	panic(fmt.Sprintf("Image '%s' not found!", name))
+	return res
 }
 
-func (c *Conveyor) GetImageStageContentDigest(imageName, stageName string) string {
-	return c.getImageStage(imageName, stageName).GetContentDigest()
+func (c *Conveyor) GetImageStageContentDigest(targetPlatform, imageName, stageName string) string {
+	return c.getImageStage(targetPlatform, imageName, stageName).GetContentDigest()
 }
 
-func (c *Conveyor) GetImageContentDigest(imageName string) string {
-	return c.GetImage(imageName).GetContentDigest()
+func (c *Conveyor) GetImageContentDigest(targetPlatform, imageName string) string {
+	return c.GetImageForPlatform(targetPlatform, imageName).GetContentDigest()
 }
 
-func (c *Conveyor) getImageStage(imageName, stageName string) stage.Interface {
-	if stg := c.GetImage(imageName).GetStage(stage.StageName(stageName)); stg != nil {
+func (c *Conveyor) getImageStage(targetPlatform, imageName, stageName string) stage.Interface {
+	if stg := c.GetImageForPlatform(targetPlatform, imageName).GetStage(stage.StageName(stageName)); stg != nil {
 		return stg
 	} else {
 		// FIXME: find first existing stage after specified unexisting
-		return c.GetImage(imageName).GetLastNonEmptyStage()
+		return c.GetImageForPlatform(targetPlatform, imageName).GetLastNonEmptyStage()
 	}
 }
 
-func (c *Conveyor) GetImageNameForLastImageStage(imageName string) string {
-	return c.GetImage(imageName).GetLastNonEmptyStage().GetImage().Name()
+func (c *Conveyor) GetImageNameForLastImageStage(targetPlatform, imageName string) string {
+	return c.GetImageForPlatform(targetPlatform, imageName).GetLastNonEmptyStage().GetImage().Name()
 }
 
-func (c *Conveyor) GetImageNameForImageStage(imageName, stageName string) string {
-	return c.getImageStage(imageName, stageName).GetImage().Name()
+func (c *Conveyor) GetImageNameForImageStage(targetPlatform, imageName, stageName string) string {
+	return c.getImageStage(targetPlatform, imageName, stageName).GetImage().Name()
 }
 
 func (c *Conveyor) GetStageID(imageName string) string {
 	return c.GetImage(imageName).GetStageID()
 }
 
-func (c *Conveyor) GetImageIDForLastImageStage(imageName string) string {
-	return c.GetImage(imageName).GetLastNonEmptyStage().GetImage().GetStageDescription().Info.ID
+func (c *Conveyor) GetImageIDForLastImageStage(targetPlatform, imageName string) string {
+	return c.GetImageForPlatform(targetPlatform, imageName).GetLastNonEmptyStage().GetImage().GetStageDescription().Info.ID
 }
 
-func (c *Conveyor) GetImageIDForImageStage(imageName, stageName string) string {
-	return c.getImageStage(imageName, stageName).GetImage().GetStageDescription().Info.ID
+func (c *Conveyor) GetImageIDForImageStage(targetPlatform, imageName, stageName string) string {
+	return c.getImageStage(targetPlatform, imageName, stageName).GetImage().GetStageDescription().Info.ID
 }
 
-func (c *Conveyor) GetImageTmpDir(imageName string) string {
-	return filepath.Join(c.tmpDir, "image", imageName)
+func (c *Conveyor) GetImageTmpDir(img *Image) string {
+	return filepath.Join(c.tmpDir, "image", img.platformSuffixedName())
 }
 
 func (c *Conveyor) GetProjectRepoCommit(ctx context.Context) (string, error) {
@@ -729,7 +873,7 @@ func (c *Conveyor) RmImportMetadata(ctx context.Context, projectName, id string)
 	return c.StorageManager.StagesStorage.RmImportMetadata(ctx, projectName, id)
 }
 
-func prepareImageBasedOnStapelImageConfig(ctx context.Context, imageInterfaceConfig config.StapelImageInterface, c *Conveyor) (*Image, error) {
+func prepareImageBasedOnStapelImageConfig(ctx context.Context, imageInterfaceConfig config.StapelImageInterface, targetPlatform string, c *Conveyor) (*Image, error) {
 	image := &Image{}
 
 	imageBaseConfig := imageInterfaceConfig.ImageBaseConfig()
@@ -739,6 +883,15 @@ func prepareImageBasedOnStapelImageConfig(ctx context.Context, imageInterfaceCon
 	from, fromImageName, fromLatest := getFromFields(imageBaseConfig)
 
 	image.name = imageName
+	image.targetPlatform = targetPlatform
+
+	if from != "" && targetPlatform != "" {
+		platformFrom, err := getBaseImagePlatformReference(ctx, from, targetPlatform)
+		if err != nil {
+			return nil, err
+		}
+		from = platformFrom
+	}
 
 	if from != "" {
 		if err := handleImageFromName(ctx, from, fromLatest, image, c); err != nil {
@@ -770,6 +923,23 @@ func handleImageFromName(ctx context.Context, from string, fromLatest bool, imag
 	return nil
 }
 
+// getBaseImagePlatformReference pins the base image to the digest of the target platform image,
+// so each platform has its own base image locally and its own stage digests
+func getBaseImagePlatformReference(ctx context.Context, from, targetPlatform string) (string, error) {
+	var reference string
+	if err := logboek.Context(ctx).Info().LogProcessInline("Resolving base image %s for platform %s", from, targetPlatform).DoError(func() error {
+		var err error
+		reference, err = docker_registry.API().GetRepoImagePlatformReference(ctx, from, targetPlatform)
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("unable to resolve base image %s for platform %s: %s", from, targetPlatform, err)
+	}
+
+	logboek.Context(ctx).Info().LogFDetails("Using base image %s for platform %s\n", reference, targetPlatform)
+
+	return reference, nil
+}
+
 func getFromFields(imageBaseConfig *config.StapelImageBase) (string, string, bool) {
 	var from string
 	var fromImageName string
@@ -818,29 +988,34 @@ func initStages(ctx context.Context, image *Image, imageInterfaceConfig config.S
 
 	baseStageOptions := &stage.NewBaseStageOptions{
 		ImageName:        imageName,
+		TargetPlatform:   image.targetPlatform,
 		ConfigMounts:     imageBaseConfig.Mount,
-		ImageTmpDir:      c.GetImageTmpDir(imageBaseConfig.Name),
+		ConfigSecrets:    imageBaseConfig.Secrets,
+		ImageTmpDir:      c.GetImageTmpDir(image),
 		ContainerWerfDir: c.containerWerfDir,
 		ProjectName:      c.werfConfig.Meta.Project,
+		ProjectDir:       c.projectDir,
 	}
 
+	imageDirName := image.platformSuffixedName()
+
 	gitArchiveStageOptions := &stage.NewGitArchiveStageOptions{
-		ArchivesDir:          getImageArchivesDir(imageName, c),
-		ScriptsDir:           getImageScriptsDir(imageName, c),
+		ArchivesDir:          getImageArchivesDir(imageDirName, c),
+		ScriptsDir:           getImageScriptsDir(imageDirName, c),
 		ContainerArchivesDir: getImageArchivesContainerDir(c),
 		ContainerScriptsDir:  getImageScriptsContainerDir(c),
 	}
 
 	gitPatchStageOptions := &stage.NewGitPatchStageOptions{
-		PatchesDir:           getImagePatchesDir(imageName, c),
-		ArchivesDir:          getImageArchivesDir(imageName, c),
-		ScriptsDir:           getImageScriptsDir(imageName, c),
+		PatchesDir:           getImagePatchesDir(imageDirName, c),
+		ArchivesDir:          getImageArchivesDir(imageDirName, c),
+		ScriptsDir:           getImageScriptsDir(imageDirName, c),
 		ContainerPatchesDir:  getImagePatchesContainerDir(c),
 		ContainerArchivesDir: getImageArchivesContainerDir(c),
 		ContainerScriptsDir:  getImageScriptsContainerDir(c),
 	}
 
-	gitMappings, err := generateGitMappings(ctx, imageBaseConfig, c)
+	gitMappings, err := generateGitMappings(ctx, imageBaseConfig, imageDirName, c)
 	if err != nil {
 		return err
 	}
@@ -884,7 +1059,7 @@ func initStages(ctx context.Context, image *Image, imageInterfaceConfig config.S
 	return nil
 }
 
-func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImageBase, c *Conveyor) ([]*stage.GitMapping, error) {
+func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImageBase, imageDirName string, c *Conveyor) ([]*stage.GitMapping, error) {
 	var gitMappings []*stage.GitMapping
 
 	if len(imageBaseConfig.Git.Local) != 0 {
@@ -914,7 +1089,7 @@ func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImag
 		}
 
 		for _, localGitMappingConfig := range imageBaseConfig.Git.Local {
-			gitMappings = append(gitMappings, gitLocalPathInit(localGitMappingConfig, localGitRepo, imageBaseConfig.Name, c))
+			gitMappings = append(gitMappings, gitLocalPathInit(localGitMappingConfig, localGitRepo, imageDirName, c))
 		}
 	}
 
@@ -937,7 +1112,7 @@ func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImag
 			c.SetRemoteGitRepo(remoteGitMappingConfig.Name, remoteGitRepo)
 		}
 
-		gitMappings = append(gitMappings, gitRemoteArtifactInit(remoteGitMappingConfig, remoteGitRepo, imageBaseConfig.Name, c))
+		gitMappings = append(gitMappings, gitRemoteArtifactInit(remoteGitMappingConfig, remoteGitRepo, imageDirName, c))
 	}
 
 	var res []*stage.GitMapping
@@ -1144,9 +1319,10 @@ func appendIfExist(ctx context.Context, stages []stage.Interface, stage stage.In
 	return stages
 }
 
-func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDockerfileConfig *config.ImageFromDockerfile, c *Conveyor) (*Image, error) {
+func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDockerfileConfig *config.ImageFromDockerfile, targetPlatform string, c *Conveyor) (*Image, error) {
 	img := &Image{}
 	img.name = imageFromDockerfileConfig.Name
+	img.targetPlatform = targetPlatform
 	img.isDockerfileImage = true
 
 	contextDir := filepath.Join(c.projectDir, imageFromDockerfileConfig.Context)
@@ -1222,13 +1398,40 @@ func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDocker
 		return nil, err
 	}
 
-	resolveDockerStagesFromValue(dockerStages)
-
 	dockerTargetIndex, err := getDockerTargetStageIndex(dockerStages, imageFromDockerfileConfig.Target)
 	if err != nil {
 		return nil, err
 	}
 
+	imageBuildArgs := map[string]string{}
+	for arg, imageName := range imageFromDockerfileConfig.ImageArgs {
+		imageBuildArgs[arg] = imageName
+	}
+
+	// the base image of the target dockerfile stage is replaced with the meta ARG, which is set to the built werf image
+	var modifiedDockerfile []byte
+	if baseImageImageName := getDockerfileImageBaseImageName(imageFromDockerfileConfig); baseImageImageName != "" {
+		modifiedDockerfile, err = replaceDockerfileStageBaseName(data, p.AST, dockerTargetIndex, dockerStages[dockerTargetIndex].Name, dockerfileFromImageBuildArg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to replace base image of the target stage in dockerfile %s: %s", dockerfilePath, err)
+		}
+
+		p, err = parser.Parse(bytes.NewReader(modifiedDockerfile))
+		if err != nil {
+			return nil, err
+		}
+
+		dockerStages, dockerMetaArgs, err = instructions.Parse(p.AST)
+		if err != nil {
+			return nil, err
+		}
+
+		imageBuildArgs[dockerfileFromImageBuildArg] = baseImageImageName
+		img.baseImageImageName = baseImageImageName
+	}
+
+	resolveDockerStagesFromValue(dockerStages)
+
 	dockerTargetStage := dockerStages[dockerTargetIndex]
 
 	ds, err := stage.NewDockerStages(
@@ -1241,35 +1444,99 @@ func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDocker
 		return nil, err
 	}
 
-	resolvedBaseName, err := ds.ShlexProcessWordWithMetaArgs(dockerTargetStage.BaseName)
-	if err != nil {
-		return nil, err
-	}
+	if img.baseImageImageName == "" {
+		resolvedBaseName, err := ds.ShlexProcessWordWithMetaArgs(dockerTargetStage.BaseName)
+		if err != nil {
+			return nil, err
+		}
 
-	if err := handleImageFromName(ctx, resolvedBaseName, false, img, c); err != nil {
-		return nil, err
+		if err := handleImageFromName(ctx, resolvedBaseName, false, img, c); err != nil {
+			return nil, err
+		}
 	}
 
 	baseStageOptions := &stage.NewBaseStageOptions{
-		ImageName:   imageFromDockerfileConfig.Name,
-		ProjectName: c.werfConfig.Meta.Project,
+		ImageName:      imageFromDockerfileConfig.Name,
+		TargetPlatform: targetPlatform,
+		ProjectName:    c.werfConfig.Meta.Project,
+	}
+
+	contextChecksum := stage.NewContextChecksum(c.projectDir, dockerignorePathMatcher, localGitRepo)
+
+	// each named dockerfile stage, which the target dockerfile stage depends on or other images import files from, is the separate stage
+	isIntermediateDockerStage := map[int]bool{}
+	for _, ind := range ds.DockerStageDependencies(dockerTargetIndex) {
+		isIntermediateDockerStage[ind] = true
+	}
+
+	for _, dockerStageName := range getImportedDockerfileStages(c.werfConfig, imageFromDockerfileConfig.Name) {
+		ind := ds.DockerStageIndexByName(dockerStageName)
+		if ind == -1 {
+			return nil, fmt.Errorf("dockerfile stage %q imported from image %q is not found in dockerfile %s", dockerStageName, imageFromDockerfileConfig.Name, dockerfilePath)
+		}
+
+		isIntermediateDockerStage[ind] = true
+		for _, dependencyInd := range ds.DockerStageDependencies(ind) {
+			isIntermediateDockerStage[dependencyInd] = true
+		}
 	}
 
-	dockerfileStage := stage.GenerateDockerfileStage(
-		stage.NewDockerRunArgs(
+	dockerfileStageByIndex := map[int]*stage.DockerfileStage{}
+	for ind := range dockerStages {
+		if !isIntermediateDockerStage[ind] || ind == dockerTargetIndex || ds.DockerStageNameByIndex(ind) == "" {
+			continue
+		}
+
+		intermediateDs, err := stage.NewDockerStages(
+			dockerStages,
+			util.MapStringInterfaceToMapStringString(imageFromDockerfileConfig.Args),
+			dockerMetaArgs,
+			ind,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		dockerRunArgs := stage.NewDockerRunArgs(
 			dockerfilePath,
-			imageFromDockerfileConfig.Target,
+			ds.DockerStageNameByIndex(ind),
 			contextDir,
 			imageFromDockerfileConfig.Args,
 			imageFromDockerfileConfig.AddHost,
 			imageFromDockerfileConfig.Network,
 			imageFromDockerfileConfig.SSH,
-		),
-		ds,
-		stage.NewContextChecksum(c.projectDir, dockerignorePathMatcher, localGitRepo),
-		baseStageOptions,
+		)
+		dockerRunArgs.SetDockerfile(modifiedDockerfile)
+
+		dockerfileStageByIndex[ind] = stage.GenerateDockerfileIntermediateStage(dockerRunArgs, intermediateDs, contextChecksum, baseStageOptions)
+		dockerfileStageByIndex[ind].SetImageBuildArgs(imageBuildArgs)
+	}
+
+	for ind := range dockerStages {
+		if dockerfileStage, ok := dockerfileStageByIndex[ind]; ok {
+			// the target dockerfile stage is built after the intermediate stages, so it is not used as the dependency stage
+			dockerfileStage.SetDependencyStages(getDockerfileDependencyStages(ds, ind, dockerfileStageByIndex))
+			img.stages = append(img.stages, dockerfileStage)
+
+			logboek.Context(ctx).Info().LogFDetails("Using stage %s\n", dockerfileStage.Name())
+		}
+	}
+
+	dockerRunArgs := stage.NewDockerRunArgs(
+		dockerfilePath,
+		imageFromDockerfileConfig.Target,
+		contextDir,
+		imageFromDockerfileConfig.Args,
+		imageFromDockerfileConfig.AddHost,
+		imageFromDockerfileConfig.Network,
+		imageFromDockerfileConfig.SSH,
 	)
+	dockerRunArgs.SetDockerfile(modifiedDockerfile)
 
+	dockerfileStage := stage.GenerateDockerfileStage(dockerRunArgs, ds, contextChecksum, baseStageOptions)
+
+	dockerfileStage.SetImageBuildArgs(imageBuildArgs)
+	dockerfileStage.SetDependencyStages(getDockerfileDependencyStages(ds, dockerTargetIndex, dockerfileStageByIndex))
 	img.stages = append(img.stages, dockerfileStage)
 
 	logboek.Context(ctx).Info().LogFDetails("Using stage %s\n", dockerfileStage.Name())
@@ -1277,6 +1544,37 @@ func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDocker
 	return img, nil
 }
 
+func getDockerfileDependencyStages(ds *stage.DockerStages, dockerStageIndex int, dockerfileStageByIndex map[int]*stage.DockerfileStage) []*stage.DockerfileStage {
+	var res []*stage.DockerfileStage
+	for _, dependencyInd := range ds.DockerStageDependencies(dockerStageIndex) {
+		if dependencyStage, ok := dockerfileStageByIndex[dependencyInd]; ok {
+			res = append(res, dependencyStage)
+		}
+	}
+
+	return res
+}
+
+// getImportedDockerfileStages returns names of the dockerfile stages of the image which other images import files from
+func getImportedDockerfileStages(werfConfig *config.WerfConfig, imageName string) []string {
+	var imports []*config.Import
+	for _, img := range werfConfig.StapelImages {
+		imports = append(imports, img.Import...)
+	}
+	for _, artifact := range werfConfig.Artifacts {
+		imports = append(imports, artifact.Import...)
+	}
+
+	var res []string
+	for _, imp := range imports {
+		if imp.ImageName == imageName && imp.DockerfileStage != "" && !util.IsStringsContainValue(res, imp.DockerfileStage) {
+			res = append(res, imp.DockerfileStage)
+		}
+	}
+
+	return res
+}
+
 func resolveDockerStagesFromValue(stages []instructions.Stage) {
 	nameToIndex := make(map[string]string)
 	for i, s := range stages {
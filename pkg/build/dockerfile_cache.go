@@ -0,0 +1,78 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/container_runtime"
+)
+
+// dockerfileCacheFromStagesCheckLimit is the number of the most recent image stages checked for the target platform
+// when selecting the cache-from source for the image-from-dockerfile image
+const dockerfileCacheFromStagesCheckLimit = 5
+
+// isDockerfileCacheFromEnabled returns false for the DockerfileBuildRuntime, which imports and exports build cache by itself
+func (c *Conveyor) isDockerfileCacheFromEnabled() bool {
+	runtime, ok := c.ContainerRuntime.(*container_runtime.LocalDockerServerRuntime)
+	return ok && runtime.DockerfileBuildRuntime == nil
+}
+
+// FetchDockerfileCacheFromImages fetches images of the dependency dockerfile stages and the most recent stage of the image for the target platform,
+// the fetched images are used as --cache-from sources
+func (c *Conveyor) FetchDockerfileCacheFromImages(ctx context.Context, targetPlatform, imageName string, dependencyStages []stage.Interface) ([]string, error) {
+	if !c.isDockerfileCacheFromEnabled() {
+		return nil, nil
+	}
+
+	var res []string
+
+	for _, dependencyStage := range dependencyStages {
+		if err := c.StorageManager.FetchStage(ctx, dependencyStage); err != nil {
+			return nil, err
+		}
+
+		res = append(res, dependencyStage.GetImage().Name())
+	}
+
+	stageIDs, err := c.StorageManager.GetImageStagesIDs(ctx, imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	containerRuntime := c.ContainerRuntime.(*container_runtime.LocalDockerServerRuntime)
+	for ind, stageID := range stageIDs {
+		if ind == dockerfileCacheFromStagesCheckLimit {
+			break
+		}
+
+		stageDesc, err := c.StorageManager.StagesStorage.GetStageDescription(ctx, c.projectName(), stageID.Digest, stageID.UniqueID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get stage %s description from %s: %s", stageID.String(), c.StorageManager.StagesStorage.String(), err)
+		} else if stageDesc == nil {
+			continue
+		}
+
+		if targetPlatform != "" && stageDesc.Info.GetPlatform() != "" && stageDesc.Info.GetPlatform() != targetPlatform {
+			continue
+		}
+
+		stageImage := &container_runtime.DockerImage{Image: container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime)}
+		if shouldFetch, err := c.StorageManager.StagesStorage.ShouldFetchImage(ctx, stageImage); err != nil {
+			return nil, err
+		} else if shouldFetch {
+			if err := logboek.Context(ctx).Default().LogProcess("Fetching cache-from stage %s", stageDesc.Info.Name).DoError(func() error {
+				return c.StorageManager.StagesStorage.FetchImage(ctx, stageImage)
+			}); err != nil {
+				return nil, fmt.Errorf("unable to fetch stage %s from %s: %s", stageDesc.Info.Name, c.StorageManager.StagesStorage.String(), err)
+			}
+		}
+
+		res = append(res, stageDesc.Info.Name)
+		break
+	}
+
+	return res, nil
+}
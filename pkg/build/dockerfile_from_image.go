@@ -0,0 +1,56 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+
+	"github.com/werf/werf/pkg/config"
+)
+
+// dockerfileFromImageBuildArg is the meta ARG which replaces the base image of the target dockerfile stage,
+// the value is the docker image name of the image or artifact specified by the fromImage or fromArtifact directive
+const dockerfileFromImageBuildArg = "WERF_FROM_IMAGE"
+
+func getDockerfileImageBaseImageName(imageFromDockerfileConfig *config.ImageFromDockerfile) string {
+	if imageFromDockerfileConfig.FromImageName != "" {
+		return imageFromDockerfileConfig.FromImageName
+	}
+
+	return imageFromDockerfileConfig.FromArtifactName
+}
+
+// replaceDockerfileStageBaseName returns the dockerfile in which the base image of the dockerfile stage is replaced with the meta ARG,
+// the ARG instruction is added before the first FROM instruction
+func replaceDockerfileStageBaseName(data []byte, ast *parser.Node, dockerStageIndex int, dockerStageName, argName string) ([]byte, error) {
+	var fromNodes []*parser.Node
+	for _, node := range ast.Children {
+		if strings.ToLower(node.Value) == "from" {
+			fromNodes = append(fromNodes, node)
+		}
+	}
+
+	if dockerStageIndex < 0 || dockerStageIndex >= len(fromNodes) {
+		return nil, fmt.Errorf("FROM instruction of the dockerfile stage %d is not found", dockerStageIndex)
+	}
+
+	firstFromNode := fromNodes[0]
+	stageFromNode := fromNodes[dockerStageIndex]
+
+	from := fmt.Sprintf("FROM ${%s}", argName)
+	if dockerStageName != "" {
+		from += fmt.Sprintf(" AS %s", dockerStageName)
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	var res []string
+	res = append(res, lines[:firstFromNode.StartLine-1]...)
+	res = append(res, fmt.Sprintf("ARG %s", argName))
+	res = append(res, lines[firstFromNode.StartLine-1:stageFromNode.StartLine-1]...)
+	res = append(res, from)
+	res = append(res, lines[stageFromNode.EndLine:]...)
+
+	return []byte(strings.Join(res, "\n")), nil
+}
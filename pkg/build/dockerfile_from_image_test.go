@@ -0,0 +1,47 @@
+package build
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+)
+
+func TestReplaceDockerfileStageBaseName(t *testing.T) {
+	dockerfile := []byte(`# syntax=docker/dockerfile:1
+ARG VERSION=1.0
+FROM golang AS builder
+RUN make
+
+FROM --platform=$BUILDPLATFORM \
+  alpine:${VERSION} AS app
+COPY --from=builder /app /app
+`)
+
+	p, err := parser.Parse(bytes.NewReader(dockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := replaceDockerfileStageBaseName(dockerfile, p.AST, 1, "app", "WERF_FROM_IMAGE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# syntax=docker/dockerfile:1
+ARG VERSION=1.0
+ARG WERF_FROM_IMAGE
+FROM golang AS builder
+RUN make
+
+FROM ${WERF_FROM_IMAGE} AS app
+COPY --from=builder /app /app
+`
+	if string(res) != expected {
+		t.Errorf("unexpected dockerfile:\n%s", res)
+	}
+
+	if _, err := replaceDockerfileStageBaseName(dockerfile, p.AST, 2, "", "WERF_FROM_IMAGE"); err == nil {
+		t.Errorf("expected error for unknown dockerfile stage")
+	}
+}
@@ -3,6 +3,7 @@ package build
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/fatih/color"
 
@@ -26,6 +27,8 @@ const (
 
 type Image struct {
 	name string
+	// targetPlatform is the OS/ARCH[/VARIANT] platform the image is built for, empty for the native platform
+	targetPlatform string
 
 	baseImageName      string
 	baseImageImageName string
@@ -43,11 +46,20 @@ type Image struct {
 }
 
 func (i *Image) LogName() string {
-	return logging.ImageLogName(i.name, i.isArtifact)
+	return imagePlatformLogName(i.name, i.isArtifact, i.targetPlatform)
 }
 
 func (i *Image) LogDetailedName() string {
-	return logging.ImageLogProcessName(i.name, i.isArtifact)
+	return logging.ImageLogProcessName(imagePlatformLogName(i.name, i.isArtifact, i.targetPlatform), i.isArtifact)
+}
+
+func imagePlatformLogName(name string, isArtifact bool, targetPlatform string) string {
+	logName := logging.ImageLogName(name, isArtifact)
+	if targetPlatform != "" {
+		logName = fmt.Sprintf("%s [%s]", logName, targetPlatform)
+	}
+
+	return logName
 }
 
 func (i *Image) LogProcessStyle() *style.Style {
@@ -106,6 +118,11 @@ func (i *Image) GetStage(name stage.StageName) stage.Interface {
 		if s.Name() == name {
 			return s
 		}
+
+		// the target dockerfile stage is also available by the name of the named dockerfile stage
+		if dockerfileStage, ok := s.(*stage.DockerfileStage); ok && dockerfileStage.DockerStageName() != "" && stage.DockerfileStageName(dockerfileStage.DockerStageName()) == name {
+			return s
+		}
 	}
 
 	return nil
@@ -128,6 +145,28 @@ func (i *Image) GetName() string {
 	return i.name
 }
 
+func (i *Image) GetTargetPlatform() string {
+	return i.targetPlatform
+}
+
+// platformSuffixedName is unique for each image and target platform pair, it is used for the image tmp dirs and build cache ids
+func (i *Image) platformSuffixedName() string {
+	if i.targetPlatform == "" {
+		return i.name
+	}
+
+	return fmt.Sprintf("%s-%s", i.name, strings.ReplaceAll(i.targetPlatform, "/", "-"))
+}
+
+// reportName is the image key in the build report
+func (i *Image) reportName() string {
+	if i.targetPlatform == "" {
+		return i.name
+	}
+
+	return fmt.Sprintf("%s@%s", i.name, i.targetPlatform)
+}
+
 func (i *Image) GetLogName() string {
 	return i.LogName()
 }
@@ -135,7 +174,7 @@ func (i *Image) GetLogName() string {
 func (i *Image) SetupBaseImage(c *Conveyor) {
 	if i.baseImageImageName != "" {
 		i.baseImageType = StageAsBaseImage
-		i.stageAsBaseImage = c.GetImage(i.baseImageImageName).GetLastNonEmptyStage()
+		i.stageAsBaseImage = c.GetImageForPlatform(i.targetPlatform, i.baseImageImageName).GetLastNonEmptyStage()
 		i.baseImage = c.GetOrCreateStageImage(nil, i.stageAsBaseImage.GetImage().Name())
 	} else {
 		i.baseImageType = ImageFromRegistryAsBaseImage
@@ -0,0 +1,36 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/storage"
+)
+
+// signImages signs the final images (or the manifest lists of the multi-platform images),
+// the signatures are stored in the stages storage repo next to the images
+func (phase *BuildPhase) signImages(ctx context.Context) error {
+	if phase.Signer == nil || phase.ShouldBeBuiltMode {
+		return nil
+	}
+
+	repoStagesStorage, ok := phase.Conveyor.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return fmt.Errorf("unable to sign images: stages storage %s is not a docker repo", phase.Conveyor.StorageManager.StagesStorage.String())
+	}
+
+	for _, info := range phase.Conveyor.GetImageInfoGetters() {
+		logName := logging.ImageLogName(info.GetWerfImageName(), false)
+
+		if err := logboek.Context(ctx).Default().LogProcess("Signing image %s", logName).DoError(func() error {
+			return phase.Signer.SignImage(ctx, repoStagesStorage.DockerRegistry, info.GetName())
+		}); err != nil {
+			return fmt.Errorf("unable to sign image %s: %s", logName, err)
+		}
+	}
+
+	return nil
+}
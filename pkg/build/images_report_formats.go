@@ -0,0 +1,203 @@
+package build
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ReportEnvFile ReportFormat = "envfile"
+	ReportYAML    ReportFormat = "yaml"
+	ReportJUnit   ReportFormat = "junit"
+)
+
+var ReportFormats = []ReportFormat{ReportJSON, ReportYAML, ReportEnvFile, ReportJUnit, ReportChromeTrace}
+
+// Render returns report data in the specified format
+func (report *ImagesReport) Render(format ReportFormat) ([]byte, error) {
+	switch format {
+	case ReportJSON:
+		return report.ToJson()
+	case ReportYAML:
+		return report.ToYaml()
+	case ReportEnvFile:
+		return report.ToEnvFile()
+	case ReportJUnit:
+		return report.ToJUnit()
+	case ReportChromeTrace:
+		return report.ToChromeTrace()
+	default:
+		return nil, fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+func (report *ImagesReport) ToYaml() ([]byte, error) {
+	data, err := report.ToJson()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(data)
+}
+
+var envNameUnsafeCharsRegexp = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// ReportEnvName returns env name for the werf image: WERF_IMAGE_<NAME>, where name is upper-cased and unsafe chars are replaced with underscores
+func ReportEnvName(imageName string) string {
+	if imageName == "" {
+		return "WERF_IMAGE"
+	}
+	return "WERF_IMAGE_" + envNameUnsafeCharsRegexp.ReplaceAllString(strings.ToUpper(imageName), "_")
+}
+
+// ToEnvFile renders WERF_IMAGE_<NAME>=DOCKER_REPO:DOCKER_TAG, WERF_IMAGE_<NAME>_ID=DOCKER_IMAGE_ID and WERF_IMAGE_<NAME>_SBOM=SBOM lines, which could be sourced by shell or passed to docker --env-file.
+// Different image names could produce the same env name (e.g. "a-b" and "a.b"), such collisions are reported as an error
+func (report *ImagesReport) ToEnvFile() ([]byte, error) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	var buf bytes.Buffer
+	envNameImageName := map[string]string{}
+	writeEnv := func(imageName, envName, value string) error {
+		if otherImageName, hasKey := envNameImageName[envName]; hasKey {
+			return fmt.Errorf("images %q and %q have the same env name %s in the envfile report: rename one of the images", otherImageName, imageName, envName)
+		}
+		envNameImageName[envName] = imageName
+
+		fmt.Fprintf(&buf, "%s=%s\n", envName, value)
+		return nil
+	}
+
+	for _, imageName := range report.sortedImageNames() {
+		record := report.Images[imageName]
+		envName := ReportEnvName(imageName)
+
+		if err := writeEnv(imageName, envName, fmt.Sprintf("%s:%s", record.DockerRepo, record.DockerTag)); err != nil {
+			return nil, err
+		}
+
+		if err := writeEnv(imageName, envName+"_ID", record.DockerImageID); err != nil {
+			return nil, err
+		}
+
+		if record.SBOM != "" {
+			if err := writeEnv(imageName, envName+"_SBOM", record.SBOM); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       string           `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string `xml:"name,attr"`
+	ClassName string `xml:"classname,attr"`
+	Time      string `xml:"time,attr"`
+	SystemOut string `xml:"system-out,omitempty"`
+}
+
+// ToJUnit renders a test suite for each image and artifact with a test case for each stage
+func (report *ImagesReport) ToJUnit() ([]byte, error) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	suites := junitTestSuites{Name: "werf build"}
+	var totalSeconds float64
+
+	addSuite := func(name string, properties []junitProperty, stages []*ReportStageRecord) {
+		suite := junitTestSuite{Name: name, Properties: properties}
+
+		var suiteSeconds float64
+		for _, stageRecord := range stages {
+			suiteSeconds += stageRecord.DurationSeconds
+
+			cacheInfo := "built"
+			if stageRecord.CacheHit {
+				cacheInfo = fmt.Sprintf("cache hit (%s stages storage)", stageRecord.CacheStorage)
+			}
+
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      stageRecord.Name,
+				ClassName: name,
+				Time:      formatJUnitSeconds(stageRecord.DurationSeconds),
+				SystemOut: fmt.Sprintf("digest: %s\nimage: %s\nstatus: %s\n", stageRecord.Digest, stageRecord.DockerImageName, cacheInfo),
+			})
+		}
+
+		suite.Tests = len(suite.TestCases)
+		suite.Time = formatJUnitSeconds(suiteSeconds)
+
+		suites.Tests += suite.Tests
+		suites.TestSuites = append(suites.TestSuites, suite)
+		totalSeconds += suiteSeconds
+	}
+
+	for _, imageName := range report.sortedImageNames() {
+		record := report.Images[imageName]
+		addSuite(imageName, []junitProperty{
+			{Name: "DockerRepo", Value: record.DockerRepo},
+			{Name: "DockerTag", Value: record.DockerTag},
+			{Name: "DockerImageID", Value: record.DockerImageID},
+		}, record.Stages)
+	}
+
+	var artifactNames []string
+	for name := range report.Artifacts {
+		artifactNames = append(artifactNames, name)
+	}
+	sort.Strings(artifactNames)
+
+	for _, name := range artifactNames {
+		addSuite(name, nil, report.Artifacts[name].Stages)
+	}
+
+	suites.Time = formatJUnitSeconds(totalSeconds)
+
+	data, err := xml.MarshalIndent(suites, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (report *ImagesReport) sortedImageNames() []string {
+	var res []string
+	for name := range report.Images {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func formatJUnitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
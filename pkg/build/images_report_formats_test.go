@@ -0,0 +1,95 @@
+package build
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func newTestImagesReport() *ImagesReport {
+	report := NewImagesReport()
+
+	stageRecord := newReportStageRecord("from")
+	stageRecord.CacheHit = true
+	stageRecord.CacheStorage = ReportStageCacheStoragePrimary
+	report.AddStageRecord("backend-api", stageRecord)
+	report.AddStageRecord("backend-api", newReportStageRecord("install"))
+
+	report.SetImageRecord("backend-api", ReportImageRecord{
+		WerfImageName: "backend-api",
+		DockerRepo:    "registry.example.com/project",
+		DockerTag:     "tag",
+		DockerImageID: "sha256:123",
+		SBOM:          "registry.example.com/project:sha256-789.sbom",
+	})
+	report.SetImageRecord("", ReportImageRecord{DockerRepo: "registry.example.com/project", DockerTag: "tag2", DockerImageID: "sha256:456"})
+
+	return report
+}
+
+func TestImagesReport_ToEnvFile(t *testing.T) {
+	expected := `WERF_IMAGE=registry.example.com/project:tag2
+WERF_IMAGE_ID=sha256:456
+WERF_IMAGE_BACKEND_API=registry.example.com/project:tag
+WERF_IMAGE_BACKEND_API_ID=sha256:123
+WERF_IMAGE_BACKEND_API_SBOM=registry.example.com/project:sha256-789.sbom
+`
+	data, err := newTestImagesReport().ToEnvFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(data); got != expected {
+		t.Errorf("unexpected envfile:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestImagesReport_ToEnvFileEnvNameCollision(t *testing.T) {
+	for _, imageNames := range [][]string{
+		{"a-b", "a.b"},
+		{"a", "a-id"},
+	} {
+		report := NewImagesReport()
+		for _, imageName := range imageNames {
+			report.SetImageRecord(imageName, ReportImageRecord{WerfImageName: imageName, DockerRepo: "registry.example.com/project", DockerTag: "tag", DockerImageID: "sha256:123"})
+		}
+
+		if _, err := report.ToEnvFile(); err == nil {
+			t.Errorf("images %v: expected env name collision error", imageNames)
+		} else if !strings.Contains(err.Error(), "have the same env name") {
+			t.Errorf("images %v: unexpected error: %s", imageNames, err)
+		}
+	}
+}
+
+func TestImagesReport_ToJUnit(t *testing.T) {
+	data, err := newTestImagesReport().ToJUnit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suites := &junitTestSuites{}
+	if err := xml.Unmarshal(data, suites); err != nil {
+		t.Fatalf("unable to unmarshal junit report: %s\n%s", err, data)
+	}
+
+	if suites.Tests != 2 || len(suites.TestSuites) != 2 {
+		t.Fatalf("unexpected junit report:\n%s", data)
+	}
+
+	suite := suites.TestSuites[1]
+	if suite.Name != "backend-api" || suite.Tests != 2 || suite.TestCases[0].Name != "from" || !strings.Contains(suite.TestCases[0].SystemOut, "cache hit (primary stages storage)") {
+		t.Errorf("unexpected backend-api suite: %#v", suite)
+	}
+}
+
+func TestImagesReport_ToYaml(t *testing.T) {
+	data, err := newTestImagesReport().Render(ReportYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), "DockerImageID: sha256:123") {
+		t.Errorf("unexpected yaml report:\n%s", data)
+	}
+}
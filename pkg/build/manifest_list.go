@@ -0,0 +1,78 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/storage"
+)
+
+// publishManifestLists publishes the manifest list into the stages storage repo for each image built for several platforms,
+// the manifest list refers to the last non-empty stage images of all target platforms
+func (phase *BuildPhase) publishManifestLists(ctx context.Context) error {
+	phase.manifestLists = map[string]*docker_registry.ManifestListInfo{}
+
+	var imageNames []string
+	imagesByName := map[string][]*Image{}
+	for _, img := range phase.Conveyor.images {
+		if img.isArtifact {
+			continue
+		}
+
+		if _, exist := imagesByName[img.GetName()]; !exist {
+			imageNames = append(imageNames, img.GetName())
+		}
+		imagesByName[img.GetName()] = append(imagesByName[img.GetName()], img)
+	}
+
+	for _, imageName := range imageNames {
+		images := imagesByName[imageName]
+		if len(images) < 2 {
+			continue
+		}
+
+		logName := logging.ImageLogName(imageName, false)
+
+		repoStagesStorage, ok := phase.Conveyor.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+		if !ok {
+			logboek.Context(ctx).Warn().LogF("WARNING: Manifest list for image %s is not published: stages storage %s is not a docker repo\n", logName, phase.Conveyor.StorageManager.StagesStorage.String())
+			logboek.Context(ctx).Warn().LogF("WARNING: Image %s for platform %s is used\n", logName, images[0].targetPlatform)
+			continue
+		}
+
+		var stageImageNames []string
+		for _, img := range images {
+			stageImageNames = append(stageImageNames, img.GetLastNonEmptyStage().GetImage().Name())
+		}
+
+		manifestListName := repoStagesStorage.ConstructManifestListName(stageImageNames)
+
+		if phase.ShouldBeBuiltMode {
+			if exist, err := repoStagesStorage.IsManifestListExist(ctx, manifestListName); err != nil {
+				return fmt.Errorf("unable to check manifest list %s existence: %s", manifestListName, err)
+			} else if !exist {
+				return fmt.Errorf("manifest list %s for image %s is not published", manifestListName, logName)
+			}
+		} else if err := logboek.Context(ctx).Default().LogProcess("Publishing manifest list %s for image %s", manifestListName, logName).DoError(func() error {
+			return repoStagesStorage.PublishManifestList(ctx, manifestListName, stageImageNames)
+		}); err != nil {
+			return fmt.Errorf("unable to publish manifest list for image %s: %s", logName, err)
+		}
+
+		manifestList, err := repoStagesStorage.GetManifestList(ctx, manifestListName)
+		if err != nil {
+			return fmt.Errorf("unable to get manifest list %s: %s", manifestListName, err)
+		} else if manifestList == nil {
+			return fmt.Errorf("manifest list %s for image %s is not found", manifestListName, logName)
+		}
+
+		phase.Conveyor.SetManifestList(imageName, manifestListName)
+		phase.manifestLists[imageName] = manifestList
+	}
+
+	return nil
+}
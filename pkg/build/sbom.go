@@ -0,0 +1,106 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/sbom"
+	"github.com/werf/werf/pkg/storage"
+)
+
+// generateSBOMs generates the SBOM of the last stage image of each final image,
+// the SBOM is attached to the stage image in the stages storage repo by the sha256-HEX.sbom tag
+func (phase *BuildPhase) generateSBOMs(ctx context.Context) error {
+	if !phase.GenerateSBOM || phase.ShouldBeBuiltMode {
+		return nil
+	}
+
+	repoStagesStorage, ok := phase.Conveyor.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return fmt.Errorf("unable to generate SBOM: stages storage %s is not a docker repo", phase.Conveyor.StorageManager.StagesStorage.String())
+	}
+
+	phase.sbomReferences = map[string]string{}
+	for _, img := range phase.Conveyor.images {
+		if img.isArtifact {
+			continue
+		}
+
+		stageImageName := img.GetLastNonEmptyStage().GetImage().Name()
+
+		if err := logboek.Context(ctx).Default().LogProcess("Generating SBOM for image %s", img.GetLogName()).DoError(func() error {
+			sbomReference, err := getImageSBOMReference(ctx, repoStagesStorage, stageImageName)
+			if err != nil {
+				return err
+			}
+
+			if content, err := repoStagesStorage.DockerRegistry.GetImageArtifact(ctx, sbomReference); err != nil {
+				return fmt.Errorf("unable to get SBOM %s: %s", sbomReference, err)
+			} else if content != nil {
+				logboek.Context(ctx).Default().LogF("Use existing SBOM %s\n", sbomReference)
+				phase.sbomReferences[img.reportName()] = sbomReference
+				return nil
+			}
+
+			content, err := generateImageSBOM(ctx, repoStagesStorage.DockerRegistry, stageImageName)
+			if err != nil {
+				return err
+			}
+
+			if err := repoStagesStorage.DockerRegistry.PushImageArtifact(ctx, sbomReference, content, sbom.CycloneDXMediaType); err != nil {
+				return fmt.Errorf("unable to publish SBOM %s: %s", sbomReference, err)
+			}
+
+			logboek.Context(ctx).Default().LogF("SBOM: %s\n", sbomReference)
+			phase.sbomReferences[img.reportName()] = sbomReference
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to generate SBOM for image %s: %s", img.GetLogName(), err)
+		}
+	}
+
+	return nil
+}
+
+func generateImageSBOM(ctx context.Context, dockerRegistry docker_registry.DockerRegistry, imageName string) ([]byte, error) {
+	fs, err := dockerRegistry.GetRepoImageFilesystem(ctx, imageName)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	res, err := sbom.Scan(fs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan image %s filesystem: %s", imageName, err)
+	}
+
+	return res.CycloneDX(imageName)
+}
+
+func getImageSBOMReference(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage, stageImageName string) (string, error) {
+	manifestDigest, err := repoStagesStorage.DockerRegistry.GetRepoImageManifestDigest(ctx, stageImageName)
+	if err != nil {
+		return "", fmt.Errorf("unable to get image %s manifest digest: %s", stageImageName, err)
+	}
+
+	return docker_registry.ImageArtifactReference(repoStagesStorage.RepoAddress, manifestDigest, docker_registry.ImageSBOMTagSuffix), nil
+}
+
+// GetImageSBOM returns the SBOM of the image built for the target platform, nil is returned if the SBOM is not generated
+func (c *Conveyor) GetImageSBOM(ctx context.Context, targetPlatform, imageName string) ([]byte, error) {
+	repoStagesStorage, ok := c.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return nil, fmt.Errorf("stages storage %s is not a docker repo", c.StorageManager.StagesStorage.String())
+	}
+
+	sbomReference, err := getImageSBOMReference(ctx, repoStagesStorage, c.GetImageNameForLastImageStage(targetPlatform, imageName))
+	if err != nil {
+		return nil, err
+	}
+
+	return repoStagesStorage.DockerRegistry.GetImageArtifact(ctx, sbomReference)
+}
@@ -9,8 +9,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/werf/lockgate"
 	"github.com/werf/logboek"
 
+	"github.com/werf/werf/pkg/cache_mounts"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
@@ -62,26 +64,33 @@ var (
 
 type NewBaseStageOptions struct {
 	ImageName        string
+	TargetPlatform   string
 	ConfigMounts     []*config.Mount
+	ConfigSecrets    []*config.Secret
 	ImageTmpDir      string
 	ContainerWerfDir string
 	ProjectName      string
+	ProjectDir       string
 }
 
 func newBaseStage(name StageName, options *NewBaseStageOptions) *BaseStage {
 	s := &BaseStage{}
 	s.name = name
 	s.imageName = options.ImageName
+	s.targetPlatform = options.TargetPlatform
 	s.configMounts = options.ConfigMounts
+	s.configSecrets = options.ConfigSecrets
 	s.imageTmpDir = options.ImageTmpDir
 	s.containerWerfDir = options.ContainerWerfDir
 	s.projectName = options.ProjectName
+	s.projectDir = options.ProjectDir
 	return s
 }
 
 type BaseStage struct {
 	name             StageName
 	imageName        string
+	targetPlatform   string
 	digest           string
 	contentDigest    string
 	image            container_runtime.ImageInterface
@@ -89,7 +98,9 @@ type BaseStage struct {
 	imageTmpDir      string
 	containerWerfDir string
 	configMounts     []*config.Mount
+	configSecrets    []*config.Secret
 	projectName      string
+	projectDir       string
 }
 
 func (s *BaseStage) LogDetailedName() string {
@@ -98,6 +109,10 @@ func (s *BaseStage) LogDetailedName() string {
 		imageName = "~"
 	}
 
+	if s.targetPlatform != "" {
+		imageName = fmt.Sprintf("%s [%s]", imageName, s.targetPlatform)
+	}
+
 	return fmt.Sprintf("%s/%s", imageName, s.Name())
 }
 
@@ -247,6 +262,8 @@ func (s *BaseStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage
 		return fmt.Errorf("error adding mounts volumes: %s", err)
 	}
 
+	s.addCacheMountsVolumes(image)
+
 	return nil
 }
 
@@ -263,6 +280,59 @@ func (s *BaseStage) PreRunHook(_ context.Context, _ Conveyor) error {
 	return nil
 }
 
+func (s *BaseStage) getCacheMountsFromConfig() []*config.Mount {
+	var mounts []*config.Mount
+	for _, mountCfg := range s.configMounts {
+		if mountCfg.Type == "cache_dir" {
+			mounts = append(mounts, mountCfg)
+		}
+	}
+
+	return mounts
+}
+
+// addCacheMountsVolumes mounts persistent caches into the build container without labels:
+// cache mounts are not inherited from the previous stages and do not affect stage digests
+func (s *BaseStage) addCacheMountsVolumes(image container_runtime.ImageInterface) {
+	for _, mountCfg := range s.getCacheMountsFromConfig() {
+		absoluteFrom := cache_mounts.GetDir(s.projectName, s.imageName, mountCfg.ID)
+		absoluteMountpoint := path.Join("/", path.Clean(mountCfg.To))
+		image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s", absoluteFrom, absoluteMountpoint))
+	}
+}
+
+// AcquireCacheMounts locks cache mounts of the stage until the returned release function is called,
+// so concurrent builds on the host do not use the same cache at the same time
+func (s *BaseStage) AcquireCacheMounts(ctx context.Context) (func(), error) {
+	var locks []lockgate.LockHandle
+	release := func() {
+		for _, lock := range locks {
+			if err := cache_mounts.Release(lock); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: unable to release cache mount lock %s: %s\n", lock.LockName, err)
+			}
+		}
+	}
+
+	// the same cache can be mounted to several paths, it is locked once to avoid the deadlock
+	isAcquired := map[string]bool{}
+	for _, mountCfg := range s.getCacheMountsFromConfig() {
+		lockName := cache_mounts.LockName(s.projectName, s.imageName, mountCfg.ID)
+		if isAcquired[lockName] {
+			continue
+		}
+		isAcquired[lockName] = true
+
+		_, lock, err := cache_mounts.Acquire(ctx, s.projectName, s.imageName, mountCfg.ID)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+
+	return release, nil
+}
+
 func (s *BaseStage) getServiceMounts(prevBuiltImage container_runtime.ImageInterface) map[string][]string {
 	return mergeMounts(s.getServiceMountsFromLabels(prevBuiltImage), s.getServiceMountsFromConfig())
 }
@@ -0,0 +1,52 @@
+package stage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestBaseStageAcquireCacheMountsWithSameID(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-cache-mounts-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := werf.Init(tmpDir, tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newBaseStage(Install, &NewBaseStageOptions{
+		ProjectName: "project",
+		ImageName:   "backend",
+		ConfigMounts: []*config.Mount{
+			{Type: "cache_dir", ID: "npm", To: "/root/.npm"},
+			{Type: "cache_dir", ID: "npm", To: "/app/.npm"},
+			{Type: "build_dir", To: "/app/build"},
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		release, err := s.AcquireCacheMounts(context.Background())
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("cache mounts with the same id are not acquired")
+	}
+}
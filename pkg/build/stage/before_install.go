@@ -32,7 +32,7 @@ func (s *BeforeInstallStage) GetDependencies(ctx context.Context, _ Conveyor, _,
 }
 
 func (s *BeforeInstallStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
-	if err := s.BaseStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
+	if err := s.UserStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
 		return err
 	}
 
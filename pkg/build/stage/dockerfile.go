@@ -25,30 +25,61 @@ import (
 	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/git_repo/status"
+	imagePkg "github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/path_matcher"
 	"github.com/werf/werf/pkg/true_git/ls_tree"
 	"github.com/werf/werf/pkg/util"
 )
 
 func GenerateDockerfileStage(dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
-	return newDockerfileStage(dockerRunArgs, dockerStages, contextChecksum, baseStageOptions)
+	return newDockerfileStage(Dockerfile, dockerRunArgs, dockerStages, contextChecksum, baseStageOptions)
 }
 
-func newDockerfileStage(dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
+// GenerateDockerfileIntermediateStage generates the stage for the named dockerfile stage which the target dockerfile stage depends on,
+// the stage is built by the --target option and stored independently of the target stage
+func GenerateDockerfileIntermediateStage(dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
+	return newDockerfileStage(DockerfileStageName(dockerStages.DockerStageNameByIndex(dockerStages.dockerTargetStageIndex)), dockerRunArgs, dockerStages, contextChecksum, baseStageOptions)
+}
+
+func newDockerfileStage(name StageName, dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
 	s := &DockerfileStage{}
 	s.DockerRunArgs = dockerRunArgs
 	s.DockerStages = dockerStages
 	s.ContextChecksum = contextChecksum
-	s.BaseStage = newBaseStage(Dockerfile, baseStageOptions)
+	s.BaseStage = newBaseStage(name, baseStageOptions)
 
 	return s
 }
 
+// DockerfileStageName returns the name of the stage for the named dockerfile stage
+func DockerfileStageName(dockerStageName string) StageName {
+	return StageName(fmt.Sprintf("%s-%s", Dockerfile, strings.ToLower(dockerStageName)))
+}
+
 type DockerfileStage struct {
 	*DockerRunArgs
 	*DockerStages
 	*ContextChecksum
 	*BaseStage
+
+	// dependencyStages are the stages of the named dockerfile stages which the target dockerfile stage depends on
+	dependencyStages []*DockerfileStage
+	// imageBuildArgs are the build args with the names of werf images and artifacts,
+	// the values are replaced with the docker image names of the built images
+	imageBuildArgs map[string]string
+}
+
+func (s *DockerfileStage) SetDependencyStages(dependencyStages []*DockerfileStage) {
+	s.dependencyStages = dependencyStages
+}
+
+func (s *DockerfileStage) SetImageBuildArgs(imageBuildArgs map[string]string) {
+	s.imageBuildArgs = imageBuildArgs
+}
+
+// DockerStageName returns the name of the target dockerfile stage, unnamed dockerfile stage has empty name
+func (s *DockerfileStage) DockerStageName() string {
+	return s.DockerStageNameByIndex(s.dockerTargetStageIndex)
 }
 
 func NewDockerRunArgs(dockerfilePath, target, context string, buildArgs map[string]interface{}, addHost []string, network, ssh string) *DockerRunArgs {
@@ -63,8 +94,14 @@ func NewDockerRunArgs(dockerfilePath, target, context string, buildArgs map[stri
 	}
 }
 
+// SetDockerfile sets the dockerfile modified by werf, which is used instead of the dockerfile from the dockerfile path
+func (d *DockerRunArgs) SetDockerfile(dockerfile []byte) {
+	d.dockerfile = dockerfile
+}
+
 type DockerRunArgs struct {
 	dockerfilePath string
+	dockerfile     []byte
 	target         string
 	context        string
 	buildArgs      map[string]interface{}
@@ -77,6 +114,7 @@ type DockerStages struct {
 	dockerStages           []instructions.Stage
 	dockerTargetStageIndex int
 	dockerBuildArgsHash    map[string]string
+	dockerMetaArgs         []instructions.ArgCommand
 	dockerMetaArgsHash     map[string]string
 	dockerStageArgsHash    map[int]map[string]string
 	dockerStageEnvs        map[int]map[string]string
@@ -89,19 +127,102 @@ func NewDockerStages(dockerStages []instructions.Stage, dockerBuildArgsHash map[
 		dockerStages:             dockerStages,
 		dockerTargetStageIndex:   dockerTargetStageIndex,
 		dockerBuildArgsHash:      dockerBuildArgsHash,
+		dockerMetaArgs:           dockerMetaArgs,
 		dockerStageArgsHash:      map[int]map[string]string{},
 		dockerStageEnvs:          map[int]map[string]string{},
 		imageOnBuildInstructions: map[string][]string{},
 	}
 
+	if err := ds.resolveDockerMetaArgs(); err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+func (ds *DockerStages) resolveDockerMetaArgs() error {
 	ds.dockerMetaArgsHash = map[string]string{}
-	for _, arg := range dockerMetaArgs {
+	for _, arg := range ds.dockerMetaArgs {
 		if _, _, err := ds.addDockerMetaArg(arg.Key, arg.ValueString()); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	return ds, nil
+	return nil
+}
+
+// AddDockerBuildArgs sets --build-arg values which are known only when the stage is being built and resolves meta ARG values again
+func (ds *DockerStages) AddDockerBuildArgs(buildArgs map[string]string) error {
+	if ds.dockerBuildArgsHash == nil {
+		ds.dockerBuildArgsHash = map[string]string{}
+	}
+
+	for key, value := range buildArgs {
+		ds.dockerBuildArgsHash[key] = value
+	}
+
+	return ds.resolveDockerMetaArgs()
+}
+
+func (ds *DockerStages) DockerStageNameByIndex(dockerStageIndex int) string {
+	return ds.dockerStages[dockerStageIndex].Name
+}
+
+// DockerStageIndexByName returns -1 if there is no dockerfile stage with the specified name
+func (ds *DockerStages) DockerStageIndexByName(dockerStageName string) int {
+	for ind, stage := range ds.dockerStages {
+		if stage.Name != "" && stage.Name == strings.ToLower(dockerStageName) {
+			return ind
+		}
+	}
+
+	return -1
+}
+
+// DockerStageDependencies returns indexes of the dockerfile stages which the specified dockerfile stage is based on or copies files from,
+// directly or indirectly, in the dockerfile order
+func (ds *DockerStages) DockerStageDependencies(dockerStageIndex int) []int {
+	isDependency := map[int]bool{}
+
+	var markDependencies func(ind int)
+	markDependencies = func(ind int) {
+		var relatedStageIndexes []int
+
+		for relatedStageIndex, relatedStage := range ds.dockerStages {
+			if ind != relatedStageIndex && ds.dockerStages[ind].BaseName == relatedStage.Name {
+				relatedStageIndexes = append(relatedStageIndexes, relatedStageIndex)
+			}
+		}
+
+		for _, cmd := range ds.dockerStages[ind].Commands {
+			switch c := cmd.(type) {
+			case *instructions.CopyCommand:
+				if c.From != "" {
+					relatedStageIndex, err := strconv.Atoi(c.From)
+					if err == nil && relatedStageIndex < len(ds.dockerStages) {
+						relatedStageIndexes = append(relatedStageIndexes, relatedStageIndex)
+					}
+				}
+			}
+		}
+
+		for _, relatedStageIndex := range relatedStageIndexes {
+			if !isDependency[relatedStageIndex] && relatedStageIndex != dockerStageIndex {
+				isDependency[relatedStageIndex] = true
+				markDependencies(relatedStageIndex)
+			}
+		}
+	}
+	markDependencies(dockerStageIndex)
+
+	var result []int
+	for ind := range ds.dockerStages {
+		if isDependency[ind] {
+			result = append(result, ind)
+		}
+	}
+
+	return result
 }
 
 // addDockerMetaArg function sets --build-arg value or resolved meta ARG value
@@ -249,9 +370,13 @@ type dockerfileInstructionInterface interface {
 	Name() string
 }
 
-func (s *DockerfileStage) FetchDependencies(ctx context.Context, _ Conveyor, cr container_runtime.ContainerRuntime) error {
+func (s *DockerfileStage) FetchDependencies(ctx context.Context, c Conveyor, cr container_runtime.ContainerRuntime) error {
 	containerRuntime := cr.(*container_runtime.LocalDockerServerRuntime)
 
+	if err := s.resolveImageBuildArgs(c); err != nil {
+		return err
+	}
+
 outerLoop:
 	for ind, stage := range s.dockerStages {
 		for relatedStageIndex, relatedStage := range s.dockerStages {
@@ -326,13 +451,53 @@ outerLoop:
 	return nil
 }
 
+// resolveImageBuildArgs replaces the names of werf images and artifacts with the docker image names of the built images,
+// the images are built before the stage
+func (s *DockerfileStage) resolveImageBuildArgs(c Conveyor) error {
+	if len(s.imageBuildArgs) == 0 {
+		return nil
+	}
+
+	buildArgs := map[string]string{}
+	for arg, imageName := range s.imageBuildArgs {
+		buildArgs[arg] = c.GetImageNameForLastImageStage(s.targetPlatform, imageName)
+	}
+
+	return s.AddDockerBuildArgs(buildArgs)
+}
+
+// imageBuildArgsDependencies returns the content digests of the images passed by the build args
+// and the replacer of the docker image names of these images with the content digests:
+// the docker image name contains the unique id, which differs for the same image built by different werf runs,
+// so the stage digest depends only on the content digests of the images
+func (s *DockerfileStage) imageBuildArgsDependencies(c Conveyor) ([]string, *strings.Replacer) {
+	var args []string
+	for arg := range s.imageBuildArgs {
+		args = append(args, arg)
+	}
+	sort.Strings(args)
+
+	var dependencies []string
+	var oldnew []string
+	for _, arg := range args {
+		contentDigest := c.GetImageContentDigest(s.targetPlatform, s.imageBuildArgs[arg])
+		dependencies = append(dependencies, fmt.Sprintf("%s=%s", arg, contentDigest))
+
+		if dockerImageName := s.dockerBuildArgsHash[arg]; dockerImageName != "" {
+			oldnew = append(oldnew, dockerImageName, contentDigest)
+		}
+	}
+
+	return dependencies, strings.NewReplacer(oldnew...)
+}
+
 func isUnsupportedMediaTypeError(err error) bool {
 	return strings.Contains(err.Error(), "unsupported MediaType")
 }
 
 var imageNotExistLocally = errors.New("IMAGE_NOT_EXIST_LOCALLY")
 
-func (s *DockerfileStage) GetDependencies(ctx context.Context, _ Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
+func (s *DockerfileStage) GetDependencies(ctx context.Context, c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
 	var stagesDependencies [][]string
 	var stagesOnBuildDependencies [][]string
 
@@ -402,6 +567,19 @@ func (s *DockerfileStage) GetDependencies(ctx context.Context, _ Conveyor, _, _
 
 	dockerfileStageDependencies := stagesDependencies[s.dockerTargetStageIndex]
 
+	if len(s.imageBuildArgs) != 0 {
+		imageBuildArgsDependencies, imageNamesReplacer := s.imageBuildArgsDependencies(c)
+		for ind, dependency := range dockerfileStageDependencies {
+			dockerfileStageDependencies[ind] = imageNamesReplacer.Replace(dependency)
+		}
+
+		dockerfileStageDependencies = append(dockerfileStageDependencies, imageBuildArgsDependencies...)
+	}
+
+	if s.targetPlatform != "" {
+		dockerfileStageDependencies = append(dockerfileStageDependencies, s.targetPlatform)
+	}
+
 	if dockerfileStageDependenciesDebug() {
 		logboek.Context(ctx).LogLn(dockerfileStageDependencies)
 	}
@@ -587,15 +765,40 @@ func (s *DockerfileStage) dockerfileOnBuildInstructionDependencies(ctx context.C
 	return []string{expression}, onBuildDependencies, nil
 }
 
-func (s *DockerfileStage) PrepareImage(_ context.Context, c Conveyor, prevBuiltImage, img container_runtime.ImageInterface) error {
+func (s *DockerfileStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, img container_runtime.ImageInterface) error {
+	var dependencyStages []Interface
+	for _, dependencyStage := range s.dependencyStages {
+		dependencyStages = append(dependencyStages, dependencyStage)
+	}
+
+	cacheFromImages, err := c.FetchDockerfileCacheFromImages(ctx, s.targetPlatform, s.imageName, dependencyStages)
+	if err != nil {
+		return err
+	}
+
+	for _, cacheFromImage := range cacheFromImages {
+		img.DockerfileImageBuilder().AppendBuildArgs(fmt.Sprintf("--cache-from=%s", cacheFromImage))
+	}
+
+	// cleanup keeps the dependency stages of the kept stage by these labels
+	for _, dependencyStage := range s.dependencyStages {
+		img.DockerfileImageBuilder().AppendBuildArgs(fmt.Sprintf("--label=%s%s=%s", imagePkg.WerfDockerfileDependencyStageLabelPrefix, dependencyStage.DockerStageName(), dependencyStage.GetImage().GetStageDescription().Info.ID))
+	}
+
+	if s.dockerfile != nil {
+		img.DockerfileImageBuilder().SetDockerfile(s.dockerfile)
+	}
+
 	img.DockerfileImageBuilder().AppendBuildArgs(s.DockerBuildArgs()...)
+
 	return nil
 }
 
 func (s *DockerfileStage) DockerBuildArgs() []string {
 	var result []string
 
-	if s.dockerfilePath != "" {
+	// the modified dockerfile is passed by the image builder
+	if s.dockerfilePath != "" && s.dockerfile == nil {
 		result = append(result, fmt.Sprintf("--file=%s", s.dockerfilePath))
 	}
 
@@ -609,6 +812,16 @@ func (s *DockerfileStage) DockerBuildArgs() []string {
 		}
 	}
 
+	var imageBuildArgs []string
+	for arg := range s.imageBuildArgs {
+		imageBuildArgs = append(imageBuildArgs, arg)
+	}
+	sort.Strings(imageBuildArgs)
+
+	for _, arg := range imageBuildArgs {
+		result = append(result, fmt.Sprintf("--build-arg=%s=%s", arg, s.dockerBuildArgsHash[arg]))
+	}
+
 	for _, addHost := range s.addHost {
 		result = append(result, fmt.Sprintf("--add-host=%s", addHost))
 	}
@@ -621,6 +834,10 @@ func (s *DockerfileStage) DockerBuildArgs() []string {
 		result = append(result, fmt.Sprintf("--ssh=%s", s.ssh))
 	}
 
+	if s.targetPlatform != "" {
+		result = append(result, fmt.Sprintf("--platform=%s", s.targetPlatform))
+	}
+
 	result = append(result, s.context)
 
 	return result
@@ -0,0 +1,135 @@
+package stage
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+)
+
+func TestDockerStagesDockerStageDependencies(t *testing.T) {
+	dockerfile := `
+FROM alpine AS base
+RUN apk add git
+
+FROM base AS builder
+RUN make
+
+FROM alpine
+RUN echo unnamed
+
+FROM golang AS unused
+RUN go version
+
+FROM alpine AS app
+COPY --from=1 /app /app
+COPY --from=2 /unnamed /unnamed
+`
+
+	p, err := parser.Parse(strings.NewReader(dockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dockerStages, dockerMetaArgs, err := instructions.Parse(p.AST)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := NewDockerStages(dockerStages, nil, dockerMetaArgs, len(dockerStages)-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deps := ds.DockerStageDependencies(4); !reflect.DeepEqual(deps, []int{0, 1, 2}) {
+		t.Errorf("unexpected target dockerfile stage dependencies: %v", deps)
+	}
+
+	if deps := ds.DockerStageDependencies(1); !reflect.DeepEqual(deps, []int{0}) {
+		t.Errorf("unexpected builder dockerfile stage dependencies: %v", deps)
+	}
+
+	if deps := ds.DockerStageDependencies(0); len(deps) != 0 {
+		t.Errorf("unexpected first dockerfile stage dependencies: %v", deps)
+	}
+
+	if ind := ds.DockerStageIndexByName("Builder"); ind != 1 {
+		t.Errorf("unexpected builder dockerfile stage index: %d", ind)
+	}
+
+	if ind := ds.DockerStageIndexByName("unknown"); ind != -1 {
+		t.Errorf("unexpected unknown dockerfile stage index: %d", ind)
+	}
+}
+
+type imageBuildArgsTestConveyor struct {
+	Conveyor
+	contentDigest   string
+	dockerImageName string
+}
+
+func (c *imageBuildArgsTestConveyor) GetImageContentDigest(_, _ string) string {
+	return c.contentDigest
+}
+
+func (c *imageBuildArgsTestConveyor) GetImageNameForLastImageStage(_, _ string) string {
+	return c.dockerImageName
+}
+
+func TestDockerfileStageGetDependenciesWithImageBuildArgs(t *testing.T) {
+	dockerfile := `
+ARG BASE_IMAGE
+FROM ${BASE_IMAGE}
+ARG BASE_IMAGE
+RUN echo ${BASE_IMAGE}
+`
+
+	getDependencies := func(c *imageBuildArgsTestConveyor) string {
+		p, err := parser.Parse(strings.NewReader(dockerfile))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dockerStages, dockerMetaArgs, err := instructions.Parse(p.AST)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ds, err := NewDockerStages(dockerStages, nil, dockerMetaArgs, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := GenerateDockerfileStage(NewDockerRunArgs("Dockerfile", "", ".", nil, nil, "", ""), ds, nil, &NewBaseStageOptions{ImageName: "app"})
+		s.SetImageBuildArgs(map[string]string{"BASE_IMAGE": "base"})
+
+		if err := s.resolveImageBuildArgs(c); err != nil {
+			t.Fatal(err)
+		}
+
+		if buildArgs := strings.Join(s.DockerBuildArgs(), " "); !strings.Contains(buildArgs, "--build-arg=BASE_IMAGE="+c.dockerImageName) {
+			t.Errorf("expected docker image name in build args, got %q", buildArgs)
+		}
+
+		digest, err := s.GetDependencies(context.Background(), c, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return digest
+	}
+
+	digest := getDependencies(&imageBuildArgsTestConveyor{contentDigest: "aaa", dockerImageName: "registry.example.com/project:aaa-1"})
+
+	// the same image built by another werf run has another unique id in the docker image name
+	if rebuiltDigest := getDependencies(&imageBuildArgsTestConveyor{contentDigest: "aaa", dockerImageName: "registry.example.com/project:aaa-2"}); rebuiltDigest != digest {
+		t.Errorf("expected the same digest for the same image content, got %s and %s", digest, rebuiltDigest)
+	}
+
+	if changedDigest := getDependencies(&imageBuildArgsTestConveyor{contentDigest: "bbb", dockerImageName: "registry.example.com/project:bbb-1"}); changedDigest == digest {
+		t.Errorf("expected another digest for the changed image content")
+	}
+}
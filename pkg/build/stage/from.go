@@ -58,15 +58,23 @@ func (s *FromStage) GetDependencies(_ context.Context, c Conveyor, prevImage, _
 	}
 
 	for _, mount := range s.configMounts {
+		if mount.Type == "cache_dir" {
+			continue
+		}
+
 		args = append(args, filepath.ToSlash(filepath.Clean(mount.From)), path.Clean(mount.To), mount.Type)
 	}
 
 	if s.fromImageOrArtifactImageName != "" {
-		args = append(args, c.GetImageContentDigest(s.fromImageOrArtifactImageName))
+		args = append(args, c.GetImageContentDigest(s.targetPlatform, s.fromImageOrArtifactImageName))
 	} else {
 		args = append(args, prevImage.Name())
 	}
 
+	if s.targetPlatform != "" {
+		args = append(args, s.targetPlatform)
+	}
+
 	return util.Sha256Hash(args...), nil
 }
 
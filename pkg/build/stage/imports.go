@@ -75,7 +75,7 @@ func (s *ImportsStage) GetDependencies(ctx context.Context, c Conveyor, _, _ con
 func (s *ImportsStage) PrepareImage(ctx context.Context, c Conveyor, _, image container_runtime.ImageInterface) error {
 	for _, elm := range s.imports {
 		sourceImageName := getSourceImageName(elm)
-		srv, err := c.GetImportServer(ctx, sourceImageName, elm.Stage)
+		srv, err := c.GetImportServer(ctx, s.targetPlatform, sourceImageName, getSourceImageStageName(elm))
 		if err != nil {
 			return fmt.Errorf("unable to get import server for image %q: %s", sourceImageName, err)
 		}
@@ -87,7 +87,7 @@ func (s *ImportsStage) PrepareImage(ctx context.Context, c Conveyor, _, image co
 
 		labelKey := imagePkg.WerfImportChecksumLabelPrefix + getImportID(elm)
 
-		importSourceID := getImportSourceID(c, elm)
+		importSourceID := getImportSourceID(c, s.targetPlatform, elm)
 		importMetadata, err := c.GetImportMetadata(ctx, s.projectName, importSourceID)
 		if err != nil {
 			return fmt.Errorf("unable to get import source checksum: %s", err)
@@ -103,7 +103,7 @@ func (s *ImportsStage) PrepareImage(ctx context.Context, c Conveyor, _, image co
 }
 
 func (s *ImportsStage) getImportSourceChecksum(ctx context.Context, c Conveyor, importElm *config.Import) (string, error) {
-	importSourceID := getImportSourceID(c, importElm)
+	importSourceID := getImportSourceID(c, s.targetPlatform, importElm)
 	importMetadata, err := c.GetImportMetadata(ctx, s.projectName, importSourceID)
 	if err != nil {
 		return "", fmt.Errorf("unable to get import metadata: %s", err)
@@ -115,7 +115,7 @@ func (s *ImportsStage) getImportSourceChecksum(ctx context.Context, c Conveyor,
 			return "", fmt.Errorf("unable to generate import source checksum: %s", err)
 		}
 
-		sourceImageID := getSourceImageID(c, importElm)
+		sourceImageID := getSourceImageID(c, s.targetPlatform, importElm)
 		importMetadata = &storage.ImportMetadata{
 			ImportSourceID: importSourceID,
 			SourceImageID:  sourceImageID,
@@ -131,8 +131,8 @@ func (s *ImportsStage) getImportSourceChecksum(ctx context.Context, c Conveyor,
 }
 
 func (s *ImportsStage) generateImportChecksum(ctx context.Context, c Conveyor, importElm *config.Import) (string, error) {
-	sourceImageDockerImageName := getSourceImageDockerImageName(c, importElm)
-	importSourceID := getImportSourceID(c, importElm)
+	sourceImageDockerImageName := getSourceImageDockerImageName(c, s.targetPlatform, importElm)
+	importSourceID := getImportSourceID(c, s.targetPlatform, importElm)
 
 	stapelContainerName, err := stapel.GetOrCreateContainer(ctx)
 	if err != nil {
@@ -217,7 +217,7 @@ func generateChecksumCommand(from string, includePaths, excludePaths []string, r
 }
 
 func getImportID(importElm *config.Import) string {
-	return util.Sha256Hash(
+	args := []string{
 		"ImageName", importElm.ImageName,
 		"ArtifactName", importElm.ArtifactName,
 		"Stage", importElm.Stage,
@@ -229,52 +229,58 @@ func getImportID(importElm *config.Import) string {
 		"Owner", importElm.Owner,
 		"IncludePaths", strings.Join(importElm.IncludePaths, "///"),
 		"ExcludePaths", strings.Join(importElm.ExcludePaths, "///"),
-	)
+	}
+
+	if importElm.DockerfileStage != "" {
+		args = append(args, "DockerfileStage", importElm.DockerfileStage)
+	}
+
+	return util.Sha256Hash(args...)
 }
 
-func getImportSourceID(c Conveyor, importElm *config.Import) string {
+func getImportSourceID(c Conveyor, targetPlatform string, importElm *config.Import) string {
 	return util.Sha256Hash(
-		"SourceImageContentDigest", getSourceImageContentDigest(c, importElm),
+		"SourceImageContentDigest", getSourceImageContentDigest(c, targetPlatform, importElm),
 		"Add", importElm.Add,
 		"IncludePaths", strings.Join(importElm.IncludePaths, "///"),
 		"ExcludePaths", strings.Join(importElm.ExcludePaths, "///"),
 	)
 }
 
-func getSourceImageDockerImageName(c Conveyor, importElm *config.Import) string {
+func getSourceImageDockerImageName(c Conveyor, targetPlatform string, importElm *config.Import) string {
 	sourceImageName := getSourceImageName(importElm)
 
 	var sourceImageDockerImageName string
-	if importElm.Stage == "" {
-		sourceImageDockerImageName = c.GetImageNameForLastImageStage(sourceImageName)
+	if sourceImageStageName := getSourceImageStageName(importElm); sourceImageStageName == "" {
+		sourceImageDockerImageName = c.GetImageNameForLastImageStage(targetPlatform, sourceImageName)
 	} else {
-		sourceImageDockerImageName = c.GetImageNameForImageStage(sourceImageName, importElm.Stage)
+		sourceImageDockerImageName = c.GetImageNameForImageStage(targetPlatform, sourceImageName, sourceImageStageName)
 	}
 
 	return sourceImageDockerImageName
 }
 
-func getSourceImageID(c Conveyor, importElm *config.Import) string {
+func getSourceImageID(c Conveyor, targetPlatform string, importElm *config.Import) string {
 	sourceImageName := getSourceImageName(importElm)
 
 	var sourceImageID string
-	if importElm.Stage == "" {
-		sourceImageID = c.GetImageIDForLastImageStage(sourceImageName)
+	if sourceImageStageName := getSourceImageStageName(importElm); sourceImageStageName == "" {
+		sourceImageID = c.GetImageIDForLastImageStage(targetPlatform, sourceImageName)
 	} else {
-		sourceImageID = c.GetImageIDForImageStage(sourceImageName, importElm.Stage)
+		sourceImageID = c.GetImageIDForImageStage(targetPlatform, sourceImageName, sourceImageStageName)
 	}
 
 	return sourceImageID
 }
 
-func getSourceImageContentDigest(c Conveyor, importElm *config.Import) string {
+func getSourceImageContentDigest(c Conveyor, targetPlatform string, importElm *config.Import) string {
 	sourceImageName := getSourceImageName(importElm)
 
 	var sourceImageContentDigest string
-	if importElm.Stage == "" {
-		sourceImageContentDigest = c.GetImageContentDigest(sourceImageName)
+	if sourceImageStageName := getSourceImageStageName(importElm); sourceImageStageName == "" {
+		sourceImageContentDigest = c.GetImageContentDigest(targetPlatform, sourceImageName)
 	} else {
-		sourceImageContentDigest = c.GetImageStageContentDigest(sourceImageName, importElm.Stage)
+		sourceImageContentDigest = c.GetImageStageContentDigest(targetPlatform, sourceImageName, sourceImageStageName)
 	}
 
 	return sourceImageContentDigest
@@ -291,6 +297,15 @@ func getSourceImageName(importElm *config.Import) string {
 	return sourceImageName
 }
 
+// getSourceImageStageName returns empty name if files are imported from the last stage of the source image
+func getSourceImageStageName(importElm *config.Import) string {
+	if importElm.DockerfileStage != "" {
+		return string(DockerfileStageName(importElm.DockerfileStage))
+	}
+
+	return importElm.Stage
+}
+
 func debugImportSourceChecksum() bool {
 	return os.Getenv("WERF_DEBUG_IMPORT_SOURCE_CHECKSUM") == "1"
 }
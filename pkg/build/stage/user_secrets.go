@@ -0,0 +1,99 @@
+package stage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/util"
+)
+
+func (s *UserStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
+	if err := s.BaseStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
+		return err
+	}
+
+	if err := s.addSecretsVolumes(image); err != nil {
+		return fmt.Errorf("error adding secrets volumes: %s", err)
+	}
+
+	return nil
+}
+
+// addSecretsVolumes mounts secrets into the build container read-only:
+// secrets are neither committed into the stage image nor used in the stage digest
+func (s *UserStage) addSecretsVolumes(image container_runtime.ImageInterface) error {
+	var secretManager secret.Manager
+
+	for _, secretCfg := range s.configSecrets {
+		var hostPath string
+		var data []byte
+
+		switch {
+		case secretCfg.Src != "" && !secretCfg.Encrypted:
+			hostPath = s.secretSrcPath(secretCfg)
+			if exist, err := util.FileExists(hostPath); err != nil {
+				return err
+			} else if !exist {
+				return fmt.Errorf("secret %s file %s not found", secretCfg.ID, hostPath)
+			}
+		case secretCfg.Src != "":
+			encryptedData, err := ioutil.ReadFile(s.secretSrcPath(secretCfg))
+			if err != nil {
+				return fmt.Errorf("unable to read secret %s file: %s", secretCfg.ID, err)
+			}
+			data = []byte(strings.TrimSpace(string(encryptedData)))
+		default:
+			value, isSet := os.LookupEnv(secretCfg.Env)
+			if !isSet {
+				return fmt.Errorf("secret %s environment variable %s is not set", secretCfg.ID, secretCfg.Env)
+			}
+			data = []byte(value)
+		}
+
+		if secretCfg.Encrypted {
+			if secretManager == nil {
+				m, err := secret.GetManager(s.projectDir)
+				if err != nil {
+					return fmt.Errorf("unable to get secret manager to decrypt secret %s: %s", secretCfg.ID, err)
+				}
+				secretManager = m
+			}
+
+			decryptedData, err := secretManager.Decrypt(data)
+			if err != nil {
+				return fmt.Errorf("unable to decrypt secret %s: %s", secretCfg.ID, err)
+			}
+			data = decryptedData
+		}
+
+		if hostPath == "" {
+			hostPath = filepath.Join(s.imageTmpDir, "secrets", secretCfg.ID)
+			if err := os.MkdirAll(filepath.Dir(hostPath), 0700); err != nil {
+				return err
+			}
+
+			if err := ioutil.WriteFile(hostPath, data, 0600); err != nil {
+				return fmt.Errorf("unable to write secret %s: %s", secretCfg.ID, err)
+			}
+		}
+
+		image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s:ro", hostPath, secretCfg.To))
+	}
+
+	return nil
+}
+
+// secretSrcPath returns absolute host path, relative path is relative to the project dir
+func (s *UserStage) secretSrcPath(secretCfg *config.Secret) string {
+	if strings.HasPrefix(secretCfg.Src, "~") || filepath.IsAbs(secretCfg.Src) {
+		return util.ExpandPath(secretCfg.Src)
+	}
+	return filepath.Join(s.projectDir, secretCfg.Src)
+}
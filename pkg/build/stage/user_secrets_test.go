@@ -0,0 +1,143 @@
+package stage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/deploy/secret"
+)
+
+func newSecretsTestUserStage(t *testing.T, configSecrets ...*config.Secret) (*UserStage, string) {
+	tmpDir, err := ioutil.TempDir("", "werf-user-secrets-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newUserStage(nil, Install, &NewBaseStageOptions{
+		ImageName:     "backend",
+		ProjectDir:    projectDir,
+		ImageTmpDir:   filepath.Join(tmpDir, "image"),
+		ConfigSecrets: configSecrets,
+	})
+
+	return s, tmpDir
+}
+
+// getSecretsVolumes returns the data of the host files of the read-only volumes by the container paths
+func getSecretsVolumes(t *testing.T, image *container_runtime.StageImage) map[string]string {
+	res := map[string]string{}
+	for _, volume := range image.Container().RunOptions().(*container_runtime.StageImageContainerOptions).Volume {
+		parts := strings.Split(volume, ":")
+		if len(parts) != 3 || parts[2] != "ro" {
+			t.Fatalf("unexpected volume %q", volume)
+		}
+
+		data, err := ioutil.ReadFile(parts[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res[parts[1]] = string(data)
+	}
+
+	return res
+}
+
+func TestUserStageAddSecretsVolumes(t *testing.T) {
+	key, err := secret.GenerateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := secret.NewManager(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedData, err := m.Encrypt([]byte("encrypted-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(value string, isSet bool) {
+		if isSet {
+			os.Setenv("WERF_SECRET_KEY", value)
+		} else {
+			os.Unsetenv("WERF_SECRET_KEY")
+		}
+	}(os.LookupEnv("WERF_SECRET_KEY"))
+	os.Setenv("WERF_SECRET_KEY", string(key))
+
+	envName := "WERF_USER_SECRETS_TEST_TOKEN"
+	os.Setenv(envName, "env-token")
+	defer os.Unsetenv(envName)
+
+	s, tmpDir := newSecretsTestUserStage(t,
+		&config.Secret{ID: "env", Env: envName, To: "/run/secrets/env"},
+		&config.Secret{ID: "file", Src: "token", To: "/run/secrets/file"},
+		&config.Secret{ID: "encrypted", Src: "token.encrypted", Encrypted: true, To: "/run/secrets/encrypted"},
+	)
+	defer os.RemoveAll(tmpDir)
+
+	if err := ioutil.WriteFile(filepath.Join(s.projectDir, "token"), []byte("file-token"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(s.projectDir, "token.encrypted"), append(encryptedData, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	image := container_runtime.NewStageImage(nil, "test", nil)
+	if err := s.addSecretsVolumes(image); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"/run/secrets/env":       "env-token",
+		"/run/secrets/file":      "file-token",
+		"/run/secrets/encrypted": "encrypted-token",
+	}
+	volumes := getSecretsVolumes(t, image)
+	if !reflect.DeepEqual(volumes, expected) {
+		t.Errorf("expected secrets volumes %v, got %v", expected, volumes)
+	}
+
+	for _, volume := range image.Container().RunOptions().(*container_runtime.StageImageContainerOptions).Volume {
+		if strings.HasPrefix(volume, filepath.Join(s.projectDir, "token.encrypted")) {
+			t.Errorf("encrypted secret file is mounted instead of the decrypted one: %s", volume)
+		}
+	}
+}
+
+func TestUserStageAddSecretsVolumesErrors(t *testing.T) {
+	envName := "WERF_USER_SECRETS_TEST_UNSET"
+	os.Unsetenv(envName)
+
+	for _, secretCfg := range []*config.Secret{
+		{ID: "absent", Src: "absent", To: "/run/secrets/absent"},
+		{ID: "absent-encrypted", Src: "absent", Encrypted: true, To: "/run/secrets/absent"},
+		{ID: "unset-env", Env: envName, To: "/run/secrets/unset"},
+	} {
+		s, tmpDir := newSecretsTestUserStage(t, secretCfg)
+
+		err := s.addSecretsVolumes(container_runtime.NewStageImage(nil, "test", nil))
+		if err == nil {
+			t.Errorf("secret %s: expected error", secretCfg.ID)
+		} else if !strings.Contains(err.Error(), secretCfg.ID) {
+			t.Errorf("secret %s: expected error with the secret id, got: %s", secretCfg.ID, err)
+		}
+
+		os.RemoveAll(tmpDir)
+	}
+}
@@ -36,7 +36,7 @@ func (s *UserWithGitPatchStage) GetNextStageDependencies(ctx context.Context, c
 }
 
 func (s *UserWithGitPatchStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
-	if err := s.BaseStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
+	if err := s.UserStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
 		return err
 	}
 
@@ -26,6 +26,8 @@ func NewStagesIterator(conveyor *Conveyor) *StagesIterator {
 func (iterator *StagesIterator) GetPrevImage(img *Image, stg stage.Interface) container_runtime.ImageInterface {
 	if stg.Name() == "from" {
 		return img.GetBaseImage()
+	} else if isDockerfileStage(stg) {
+		return nil
 	} else if iterator.PrevNonEmptyStage != nil {
 		return iterator.PrevNonEmptyStage.GetImage()
 	}
@@ -35,6 +37,8 @@ func (iterator *StagesIterator) GetPrevImage(img *Image, stg stage.Interface) co
 func (iterator *StagesIterator) GetPrevBuiltImage(img *Image, stg stage.Interface) container_runtime.ImageInterface {
 	if stg.Name() == "from" {
 		return img.GetBaseImage()
+	} else if isDockerfileStage(stg) {
+		return nil
 	} else if iterator.PrevBuiltStage != nil {
 		return iterator.PrevBuiltStage.GetImage()
 	}
@@ -48,7 +52,7 @@ func (iterator *StagesIterator) OnImageStage(ctx context.Context, img *Image, st
 	}
 	logboek.Context(ctx).Debug().LogF("%s stage is empty: %v\n", stg.LogDetailedName(), isEmpty)
 
-	if stg.Name() != "from" && stg.Name() != "dockerfile" {
+	if stg.Name() != "from" && !isDockerfileStage(stg) {
 		if iterator.PrevStage == nil {
 			panic(fmt.Sprintf("expected PrevStage to be set for image %q stage %s!", img.GetName(), stg.Name()))
 		}
@@ -78,3 +82,9 @@ func (iterator *StagesIterator) OnImageStage(ctx context.Context, img *Image, st
 
 	return nil
 }
+
+// isDockerfileStage returns true for the stages of the dockerfile image, each of them is built from the dockerfile independently of the previous stages
+func isDockerfileStage(stg stage.Interface) bool {
+	_, ok := stg.(*stage.DockerfileStage)
+	return ok
+}
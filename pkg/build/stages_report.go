@@ -0,0 +1,162 @@
+package build
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const (
+	ReportStageCacheStoragePrimary   = "primary"
+	ReportStageCacheStorageSecondary = "secondary"
+)
+
+const (
+	stageSpanDigest      = "digest"
+	stageSpanCacheLookup = "cache-lookup"
+	stageSpanLockWait    = "lock-wait"
+	stageSpanFetch       = "fetch"
+	stageSpanBuild       = "build"
+	stageSpanPush        = "push"
+)
+
+// ReportStageRecord describes how the stage has been handled during the build: durations are in seconds
+type ReportStageRecord struct {
+	Name            string
+	Digest          string
+	DockerImageName string
+	CacheHit        bool
+	// CacheStorage is the stages storage where the suitable stage has been found: primary or secondary
+	CacheStorage string `json:",omitempty"`
+
+	StartedAt                time.Time
+	DurationSeconds          float64
+	DigestCalculationSeconds float64
+	CacheLookupSeconds       float64
+	LockWaitSeconds          float64
+	FetchSeconds             float64
+	BuildSeconds             float64
+	PushSeconds              float64
+
+	spans []reportStageSpan
+}
+
+type reportStageSpan struct {
+	Name      string
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+func newReportStageRecord(name string) *ReportStageRecord {
+	return &ReportStageRecord{Name: name, StartedAt: time.Now()}
+}
+
+// measure runs f and adds its duration into the stage record timings
+func (rec *ReportStageRecord) measure(spanName string, f func() error) error {
+	startedAt := time.Now()
+	err := f()
+	duration := time.Since(startedAt)
+
+	switch spanName {
+	case stageSpanDigest:
+		rec.DigestCalculationSeconds += duration.Seconds()
+	case stageSpanCacheLookup:
+		rec.CacheLookupSeconds += duration.Seconds()
+	case stageSpanLockWait:
+		rec.LockWaitSeconds += duration.Seconds()
+	case stageSpanFetch:
+		rec.FetchSeconds += duration.Seconds()
+	case stageSpanBuild:
+		rec.BuildSeconds += duration.Seconds()
+	case stageSpanPush:
+		rec.PushSeconds += duration.Seconds()
+	}
+
+	rec.spans = append(rec.spans, reportStageSpan{Name: spanName, StartedAt: startedAt, Duration: duration})
+
+	return err
+}
+
+func (rec *ReportStageRecord) done() {
+	rec.DurationSeconds = time.Since(rec.StartedAt).Seconds()
+}
+
+// chromeTraceEvent is an event of the Trace Event Format, which can be opened in chrome://tracing or https://ui.perfetto.dev
+type chromeTraceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	Pid       int                    `json:"pid"`
+	Tid       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+type chromeTrace struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+// ToChromeTrace renders stages of each image as a separate thread of the timeline
+func (report *ImagesReport) ToChromeTrace() ([]byte, error) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	var imageNames []string
+	for imageName := range report.stages {
+		imageNames = append(imageNames, imageName)
+	}
+	sort.Strings(imageNames)
+
+	toMicroseconds := func(t time.Time) int64 {
+		return t.Sub(report.StartedAt).Microseconds()
+	}
+
+	trace := chromeTrace{TraceEvents: []chromeTraceEvent{}, DisplayTimeUnit: "ms"}
+	for i, imageName := range imageNames {
+		tid := i + 1
+
+		trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			Pid:   1,
+			Tid:   tid,
+			Args:  map[string]interface{}{"name": imageName},
+		})
+
+		for _, rec := range report.stages[imageName] {
+			trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+				Name:      rec.Name,
+				Category:  "stage",
+				Phase:     "X",
+				Timestamp: toMicroseconds(rec.StartedAt),
+				Duration:  time.Duration(rec.DurationSeconds * float64(time.Second)).Microseconds(),
+				Pid:       1,
+				Tid:       tid,
+				Args: map[string]interface{}{
+					"image":        imageName,
+					"digest":       rec.Digest,
+					"dockerImage":  rec.DockerImageName,
+					"cacheHit":     rec.CacheHit,
+					"cacheStorage": rec.CacheStorage,
+				},
+			})
+
+			for _, span := range rec.spans {
+				trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+					Name:      span.Name,
+					Category:  span.Name,
+					Phase:     "X",
+					Timestamp: toMicroseconds(span.StartedAt),
+					Duration:  span.Duration.Microseconds(),
+					Pid:       1,
+					Tid:       tid,
+					Args:      map[string]interface{}{"image": imageName, "stage": rec.Name},
+				})
+			}
+		}
+	}
+
+	return json.MarshalIndent(trace, "", "\t")
+}
@@ -0,0 +1,65 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestReportStageRecord_Measure(t *testing.T) {
+	rec := newReportStageRecord("from")
+
+	if err := rec.measure(stageSpanFetch, func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.measure(stageSpanBuild, func() error { return errors.New("build failed") }); err == nil || err.Error() != "build failed" {
+		t.Errorf("expected measured function error to be returned, got %v", err)
+	}
+	rec.done()
+
+	if len(rec.spans) != 2 || rec.spans[0].Name != stageSpanFetch || rec.spans[1].Name != stageSpanBuild {
+		t.Errorf("unexpected spans: %#v", rec.spans)
+	}
+	if rec.DurationSeconds < rec.FetchSeconds+rec.BuildSeconds {
+		t.Errorf("stage duration %f should include spans durations", rec.DurationSeconds)
+	}
+}
+
+func TestImagesReport_ToChromeTrace(t *testing.T) {
+	report := NewImagesReport()
+
+	rec := newReportStageRecord("from")
+	_ = rec.measure(stageSpanDigest, func() error { return nil })
+	rec.done()
+	report.AddStageRecord("backend", rec)
+	report.AddStageRecord("frontend", newReportStageRecord("dockerfile"))
+
+	data, err := report.ToChromeTrace()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := &chromeTrace{}
+	if err := json.Unmarshal(data, trace); err != nil {
+		t.Fatal(err)
+	}
+
+	var threads, stages, spans int
+	for _, event := range trace.TraceEvents {
+		switch {
+		case event.Phase == "M":
+			threads++
+		case event.Category == "stage":
+			stages++
+		default:
+			spans++
+			if event.Tid != 1 {
+				t.Errorf("expected span of backend image to be in the thread 1, got %d", event.Tid)
+			}
+		}
+	}
+
+	if threads != 2 || stages != 2 || spans != 1 {
+		t.Errorf("unexpected trace events: threads=%d stages=%d spans=%d", threads, stages, spans)
+	}
+}
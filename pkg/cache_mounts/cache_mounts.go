@@ -0,0 +1,203 @@
+package cache_mounts
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// DefaultKeepPeriod is the period since the last use after which the cache mount is removed by the host cleanup
+const DefaultKeepPeriod = 7 * 24 * time.Hour
+
+// CacheMount is the persistent named cache directory which is mounted into the stapel stage build containers,
+// cache content is never stored into the stage images and does not affect stage digests
+type CacheMount struct {
+	// ProjectName, ImageName and ID are the names of the cache mount dirs (image name and cache id are slugified)
+	ProjectName string
+	ImageName   string
+	ID          string
+	Path        string
+	LastUsedAt  time.Time
+}
+
+func GetBaseDir() string {
+	return filepath.Join(werf.GetSharedContextDir(), "mounts", "cache", "1")
+}
+
+func GetDir(projectName, imageName, cacheID string) string {
+	return filepath.Join(GetBaseDir(), projectName, imageDirName(imageName), slug.LimitedSlug(cacheID, slug.DefaultSlugMaxSize))
+}
+
+func LockName(projectName, imageName, cacheID string) string {
+	return lockName(projectName, imageDirName(imageName), slug.LimitedSlug(cacheID, slug.DefaultSlugMaxSize))
+}
+
+func lockName(projectDirName, imageDirName, cacheDirName string) string {
+	return fmt.Sprintf("cache_mount.%s.%s.%s", projectDirName, imageDirName, cacheDirName)
+}
+
+func imageDirName(imageName string) string {
+	if imageName == "" {
+		return "~"
+	}
+	return slug.LimitedSlug(imageName, slug.DefaultSlugMaxSize)
+}
+
+// Acquire creates the cache mount dir if needed and locks it exclusively until Release,
+// so concurrent builds on the host do not use the same cache at the same time
+func Acquire(ctx context.Context, projectName, imageName, cacheID string) (string, lockgate.LockHandle, error) {
+	dir := GetDir(projectName, imageName, cacheID)
+
+	_, lock, err := werf.AcquireHostLock(ctx, LockName(projectName, imageName, cacheID), lockgate.AcquireOptions{})
+	if err != nil {
+		return "", lockgate.LockHandle{}, fmt.Errorf("unable to lock cache mount %s: %s", dir, err)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		_ = werf.ReleaseHostLock(lock)
+		return "", lockgate.LockHandle{}, fmt.Errorf("unable to create cache mount dir %s: %s", dir, err)
+	}
+
+	// modification time of the dir is the last use time of the cache mount
+	now := time.Now()
+	if err := os.Chtimes(dir, now, now); err != nil {
+		_ = werf.ReleaseHostLock(lock)
+		return "", lockgate.LockHandle{}, fmt.Errorf("unable to update cache mount dir %s modification time: %s", dir, err)
+	}
+
+	return dir, lock, nil
+}
+
+func Release(lock lockgate.LockHandle) error {
+	return werf.ReleaseHostLock(lock)
+}
+
+// List returns all cache mounts on the host sorted by the last use time
+func List() ([]*CacheMount, error) {
+	var res []*CacheMount
+
+	projectDirs, err := readDirs(GetBaseDir())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, projectDir := range projectDirs {
+		imageDirs, err := readDirs(filepath.Join(GetBaseDir(), projectDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, imageDir := range imageDirs {
+			cacheDirs, err := readDirs(filepath.Join(GetBaseDir(), projectDir.Name(), imageDir.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, cacheDir := range cacheDirs {
+				res = append(res, &CacheMount{
+					ProjectName: projectDir.Name(),
+					ImageName:   imageDir.Name(),
+					ID:          cacheDir.Name(),
+					Path:        filepath.Join(GetBaseDir(), projectDir.Name(), imageDir.Name(), cacheDir.Name()),
+					LastUsedAt:  cacheDir.ModTime(),
+				})
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].LastUsedAt.Before(res[j].LastUsedAt) })
+
+	return res, nil
+}
+
+func readDirs(dir string) ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read dir %s: %s", dir, err)
+	}
+
+	var dirs []os.FileInfo
+	for _, info := range infos {
+		if info.IsDir() {
+			dirs = append(dirs, info)
+		}
+	}
+
+	return dirs, nil
+}
+
+// GC removes cache mounts which have not been used longer than keepPeriod and are not used by another werf process at the moment
+func GC(ctx context.Context, keepPeriod time.Duration, dryRun bool) error {
+	return logboek.Context(ctx).LogProcess("Running GC for cache mounts").DoError(func() error {
+		return gc(ctx, keepPeriod, dryRun)
+	})
+}
+
+func gc(ctx context.Context, keepPeriod time.Duration, dryRun bool) error {
+	cacheMounts, err := List()
+	if err != nil {
+		return err
+	}
+
+	var pathsToRemove []string
+	var locks []lockgate.LockHandle
+	defer func() {
+		for _, lock := range locks {
+			_ = Release(lock)
+		}
+	}()
+
+	for _, cacheMount := range cacheMounts {
+		if time.Since(cacheMount.LastUsedAt) < keepPeriod {
+			logboek.Context(ctx).Info().LogF("Keep cache mount %s (last used at %s)\n", cacheMount.Path, cacheMount.LastUsedAt.Format(time.RFC3339))
+			continue
+		}
+
+		cacheMountLockName := lockName(cacheMount.ProjectName, cacheMount.ImageName, cacheMount.ID)
+		isLocked, lock, err := werf.AcquireHostLock(ctx, cacheMountLockName, lockgate.AcquireOptions{NonBlocking: true})
+		if err != nil {
+			return fmt.Errorf("failed to lock %s for cache mount %s: %s", cacheMountLockName, cacheMount.Path, err)
+		}
+
+		if !isLocked {
+			logboek.Context(ctx).Default().LogFDetails("Ignore cache mount %s used by another process\n", cacheMount.Path)
+			continue
+		}
+		locks = append(locks, lock)
+
+		logboek.Context(ctx).LogF("%s (last used at %s)\n", cacheMount.Path, cacheMount.LastUsedAt.Format(time.RFC3339))
+		pathsToRemove = append(pathsToRemove, cacheMount.Path)
+	}
+
+	if dryRun || len(pathsToRemove) == 0 {
+		return nil
+	}
+
+	// cache content is created by the build containers and can be owned by root
+	if runtime.GOOS == "windows" {
+		for _, path := range pathsToRemove {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("unable to remove cache mount %s: %s", path, err)
+			}
+		}
+	} else if err := util.RemoveHostDirsWithLinuxContainer(ctx, werf.GetSharedContextDir(), pathsToRemove); err != nil {
+		return fmt.Errorf("unable to remove cache mounts %s: %s", strings.Join(pathsToRemove, ", "), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package cleaning
+
+import (
+	"context"
+	"strings"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+// cleanupBuildkitCache deletes the build cache records of the images which are not managed anymore,
+// the record of the image is exported by the buildkit runtime with the image name (and the target platform) as the cache id
+func (m *cleanupManager) cleanupBuildkitCache(ctx context.Context) error {
+	repoStagesStorage, ok := m.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return nil
+	}
+
+	cacheImagesNames, err := repoStagesStorage.GetBuildkitCacheImagesNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var cacheImagesNamesToDelete []string
+	for _, cacheImageName := range cacheImagesNames {
+		_, tag := image.ParseRepositoryAndTag(cacheImageName)
+		if !isBuildkitCacheTagOfImages(tag, m.ImageNameList) {
+			cacheImagesNamesToDelete = append(cacheImagesNamesToDelete, cacheImageName)
+		}
+	}
+
+	if len(cacheImagesNamesToDelete) == 0 {
+		return nil
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Deleting buildkit cache").DoError(func() error {
+		return deleteBuildkitCache(ctx, repoStagesStorage, m.DryRun, cacheImagesNamesToDelete)
+	})
+}
+
+func isBuildkitCacheTagOfImages(tag string, imageNameList []string) bool {
+	for _, imageName := range imageNameList {
+		imageTag := slug.DockerTag(container_runtime.BuildkitCacheTagPrefix + imageName)
+		if tag == imageTag || strings.HasPrefix(tag, imageTag+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func purgeBuildkitCache(ctx context.Context, storageManager *manager.StorageManager, dryRun bool) error {
+	repoStagesStorage, ok := storageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return nil
+	}
+
+	cacheImagesNames, err := repoStagesStorage.GetBuildkitCacheImagesNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	return deleteBuildkitCache(ctx, repoStagesStorage, dryRun, cacheImagesNames)
+}
+
+func deleteBuildkitCache(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage, dryRun bool, cacheImagesNames []string) error {
+	for _, cacheImageName := range cacheImagesNames {
+		if !dryRun {
+			if err := repoStagesStorage.DeleteImageByName(ctx, cacheImageName); err != nil {
+				if err := handleDeletionError(err); err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: Buildkit cache %s deletion failed: %s\n", cacheImageName, err)
+
+				continue
+			}
+		}
+
+		_, tag := image.ParseRepositoryAndTag(cacheImageName)
+		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", tag)
+		logboek.Context(ctx).LogOptionalLn()
+	}
+
+	return nil
+}
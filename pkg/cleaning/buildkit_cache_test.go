@@ -0,0 +1,95 @@
+package cleaning
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+type cleaningTestDockerRegistry struct {
+	docker_registry.DockerRegistry
+	tags        []string
+	deletedTags []string
+}
+
+func (r *cleaningTestDockerRegistry) Tags(_ context.Context, _ string) ([]string, error) {
+	return r.tags, nil
+}
+
+func (r *cleaningTestDockerRegistry) GetRepoImageManifestDigest(_ context.Context, reference string) (string, error) {
+	_, tag := image.ParseRepositoryAndTag(reference)
+	return "sha256:" + tag, nil
+}
+
+func (r *cleaningTestDockerRegistry) DeleteRepoImage(_ context.Context, repoImage *image.Info) error {
+	if repoImage.RepoDigest != "sha256:"+repoImage.Tag {
+		return nil
+	}
+
+	r.deletedTags = append(r.deletedTags, repoImage.Tag)
+	return nil
+}
+
+func newCleaningTestStorageManager(tags []string) (*manager.StorageManager, *cleaningTestDockerRegistry) {
+	dockerRegistry := &cleaningTestDockerRegistry{tags: tags}
+	stagesStorage := &storage.RepoStagesStorage{
+		RepoAddress:    "registry.example.com/project",
+		DockerRegistry: dockerRegistry,
+	}
+
+	return manager.NewStorageManager("project", stagesStorage, nil, nil, nil), dockerRegistry
+}
+
+func TestCleanupBuildkitCache(t *testing.T) {
+	storageManager, dockerRegistry := newCleaningTestStorageManager([]string{
+		"buildkit-cache-backend",
+		"buildkit-cache-backend-linux-amd64",
+		// cannot be distinguished from the platform cache of the backend image, so it is kept
+		"buildkit-cache-backend-api",
+		"buildkit-cache-frontend",
+		"a1b2c3-1600000000000",
+		"managed-image-backend",
+	})
+
+	m := newCleanupManager("project", storageManager, CleanupOptions{ImageNameList: []string{"backend"}})
+	if err := m.cleanupBuildkitCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"buildkit-cache-frontend"}
+	if !reflect.DeepEqual(dockerRegistry.deletedTags, expected) {
+		t.Errorf("expected deleted tags %v, got %v", expected, dockerRegistry.deletedTags)
+	}
+}
+
+func TestPurgeBuildkitCache(t *testing.T) {
+	storageManager, dockerRegistry := newCleaningTestStorageManager([]string{
+		"buildkit-cache-backend",
+		"buildkit-cache-frontend-linux-arm64",
+		"a1b2c3-1600000000000",
+	})
+
+	if err := purgeBuildkitCache(context.Background(), storageManager, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dockerRegistry.deletedTags) != 0 {
+		t.Errorf("expected no deleted tags in dry run mode, got %v", dockerRegistry.deletedTags)
+	}
+
+	if err := purgeBuildkitCache(context.Background(), storageManager, false); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(dockerRegistry.deletedTags)
+	expected := []string{"buildkit-cache-backend", "buildkit-cache-frontend-linux-arm64"}
+	if !reflect.DeepEqual(dockerRegistry.deletedTags, expected) {
+		t.Errorf("expected deleted tags %v, got %v", expected, dockerRegistry.deletedTags)
+	}
+}
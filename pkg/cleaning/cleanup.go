@@ -77,6 +77,10 @@ type cleanupManager struct {
 	checksumSourceImageIDs       map[string][]string
 	nonexistentImportMetadataIDs []string
 
+	// deletedRepoDigests are the manifest digests of the deleted stages images and manifest lists,
+	// the artifacts attached to these manifests are deleted at the end of the cleanup
+	deletedRepoDigests []string
+
 	ProjectName                             string
 	StorageManager                          *manager.StorageManager
 	ImageNameList                           []string
@@ -305,6 +309,11 @@ func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context
 		return err
 	}
 
+	deployedDockerImagesNames, err = m.resolveDeployedManifestLists(ctx, deployedDockerImagesNames)
+	if err != nil {
+		return err
+	}
+
 	skippedDeployedImages := map[string]bool{}
 	for imageName, stageIDCommitList := range m.imageNameStageIDCommitListToCleanup {
 	Loop:
@@ -501,6 +510,10 @@ func (m *cleanupManager) deleteStages(ctx context.Context, stages []*image.Stage
 
 	m.deleteStagesFromCache(stages)
 
+	for _, stage := range stages {
+		m.deletedRepoDigests = append(m.deletedRepoDigests, stage.Info.RepoDigest)
+	}
+
 	return deleteStages(ctx, m.StorageManager, m.DryRun, deleteStageOptions, stages)
 }
 
@@ -683,6 +696,10 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}
 	}
 
+	if err := m.cleanupManifestLists(ctx); err != nil {
+		return err
+	}
+
 	if len(m.nonexistentImportMetadataIDs) != 0 {
 		if err := logboek.Context(ctx).Default().LogProcess("Cleaning imports metadata").DoError(func() error {
 			return m.deleteImportsMetadata(ctx, m.nonexistentImportMetadataIDs)
@@ -691,6 +708,14 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}
 	}
 
+	if err := m.cleanupImageArtifacts(ctx); err != nil {
+		return err
+	}
+
+	if err := m.cleanupBuildkitCache(ctx); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -792,14 +817,16 @@ func findStageByImageID(stages []*image.StageDescription, imageID string) *image
 }
 
 func (m *cleanupManager) excludeStageAndRelativesByStage(stages []*image.StageDescription, stage *image.StageDescription) []*image.StageDescription {
-	for label, checksum := range stage.Info.Labels {
+	for label, value := range stage.Info.Labels {
 		if strings.HasPrefix(label, image.WerfImportChecksumLabelPrefix) {
-			sourceImageIDs, ok := m.checksumSourceImageIDs[checksum]
+			sourceImageIDs, ok := m.checksumSourceImageIDs[value]
 			if ok {
 				for _, sourceImageID := range sourceImageIDs {
 					stages = m.excludeStageAndRelativesByImageID(stages, sourceImageID)
 				}
 			}
+		} else if strings.HasPrefix(label, image.WerfDockerfileDependencyStageLabelPrefix) {
+			stages = m.excludeStageAndRelativesByImageID(stages, value)
 		}
 	}
 
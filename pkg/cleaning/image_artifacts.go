@@ -0,0 +1,86 @@
+package cleaning
+
+import (
+	"context"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+// imageArtifactsTagSuffixes are the tag suffixes of the artifacts which are attached to the stages images and manifest lists
+var imageArtifactsTagSuffixes = []string{docker_registry.CosignSignatureTagSuffix, docker_registry.ImageSBOMTagSuffix}
+
+// cleanupImageArtifacts deletes the artifacts (signatures and SBOMs) attached to the stages images and manifest lists deleted by the cleanup
+func (m *cleanupManager) cleanupImageArtifacts(ctx context.Context) error {
+	repoStagesStorage, ok := m.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok || len(m.deletedRepoDigests) == 0 {
+		return nil
+	}
+
+	artifactsNames, err := repoStagesStorage.GetImageArtifactsNames(ctx, imageArtifactsTagSuffixes)
+	if err != nil {
+		return err
+	}
+
+	deletedArtifactsNames := map[string]bool{}
+	for _, repoDigest := range m.deletedRepoDigests {
+		for _, tagSuffix := range imageArtifactsTagSuffixes {
+			deletedArtifactsNames[docker_registry.ImageArtifactReference(repoStagesStorage.RepoAddress, repoDigest, tagSuffix)] = true
+		}
+	}
+
+	var artifactsNamesToDelete []string
+	for _, artifactName := range artifactsNames {
+		if deletedArtifactsNames[artifactName] {
+			artifactsNamesToDelete = append(artifactsNamesToDelete, artifactName)
+		}
+	}
+
+	if len(artifactsNamesToDelete) == 0 {
+		return nil
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Deleting image artifacts").DoError(func() error {
+		return deleteImageArtifacts(ctx, repoStagesStorage, m.DryRun, artifactsNamesToDelete)
+	})
+}
+
+func purgeImageArtifacts(ctx context.Context, storageManager *manager.StorageManager, dryRun bool) error {
+	repoStagesStorage, ok := storageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return nil
+	}
+
+	artifactsNames, err := repoStagesStorage.GetImageArtifactsNames(ctx, imageArtifactsTagSuffixes)
+	if err != nil {
+		return err
+	}
+
+	return deleteImageArtifacts(ctx, repoStagesStorage, dryRun, artifactsNames)
+}
+
+func deleteImageArtifacts(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage, dryRun bool, artifactsNames []string) error {
+	for _, artifactName := range artifactsNames {
+		if !dryRun {
+			if err := repoStagesStorage.DeleteImageByName(ctx, artifactName); err != nil {
+				if err := handleDeletionError(err); err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: Image artifact %s deletion failed: %s\n", artifactName, err)
+
+				continue
+			}
+		}
+
+		_, tag := image.ParseRepositoryAndTag(artifactName)
+		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", tag)
+		logboek.Context(ctx).LogOptionalLn()
+	}
+
+	return nil
+}
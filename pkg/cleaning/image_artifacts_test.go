@@ -0,0 +1,59 @@
+package cleaning
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+func TestCleanupImageArtifacts(t *testing.T) {
+	storageManager, dockerRegistry := newCleaningTestStorageManager([]string{
+		"a1b2c3-1600000000000",
+		"d4e5f6-1600000000000",
+		"sha256-aaa.sig",
+		"sha256-aaa.sbom",
+		"sha256-bbb.sig",
+		"sha256-bbb.sbom",
+		"sha256-ccc.sig",
+	})
+
+	deletedStage := &image.StageDescription{Info: &image.Info{Tag: "a1b2c3-1600000000000", RepoDigest: "sha256:aaa"}}
+	keptStage := &image.StageDescription{Info: &image.Info{Tag: "d4e5f6-1600000000000", RepoDigest: "sha256:bbb"}}
+
+	m := newCleanupManager("project", storageManager, CleanupOptions{DryRun: true})
+	m.stages = []*image.StageDescription{deletedStage, keptStage}
+	if err := m.deleteStages(context.Background(), []*image.StageDescription{deletedStage}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.DryRun = false
+	if err := m.cleanupImageArtifacts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the signature of the unknown manifest (sha256-ccc.sig) is not deleted, it might be pushed by the running build
+	expected := []string{"sha256-aaa.sig", "sha256-aaa.sbom"}
+	if !reflect.DeepEqual(dockerRegistry.deletedTags, expected) {
+		t.Errorf("expected deleted tags %v, got %v", expected, dockerRegistry.deletedTags)
+	}
+}
+
+func TestPurgeImageArtifacts(t *testing.T) {
+	storageManager, dockerRegistry := newCleaningTestStorageManager([]string{
+		"a1b2c3-1600000000000",
+		"sha256-aaa.sig",
+		"sha256-aaa.sbom",
+		"buildkit-cache-backend",
+	})
+
+	if err := purgeImageArtifacts(context.Background(), storageManager, false); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"sha256-aaa.sig", "sha256-aaa.sbom"}
+	if !reflect.DeepEqual(dockerRegistry.deletedTags, expected) {
+		t.Errorf("expected deleted tags %v, got %v", expected, dockerRegistry.deletedTags)
+	}
+}
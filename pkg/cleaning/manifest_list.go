@@ -0,0 +1,137 @@
+package cleaning
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+// resolveDeployedManifestLists adds the names of the platform stages images referred by the deployed manifest lists,
+// so these stages are skipped the same way as the deployed single platform stages
+func (m *cleanupManager) resolveDeployedManifestLists(ctx context.Context, deployedDockerImagesNames []string) ([]string, error) {
+	repoStagesStorage, ok := m.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return deployedDockerImagesNames, nil
+	}
+
+	res := deployedDockerImagesNames
+	for _, deployedDockerImageName := range deployedDockerImagesNames {
+		if !repoStagesStorage.IsManifestListName(deployedDockerImageName) {
+			continue
+		}
+
+		manifestList, err := repoStagesStorage.GetManifestList(ctx, deployedDockerImageName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get manifest list %s: %s", deployedDockerImageName, err)
+		} else if manifestList == nil {
+			continue
+		}
+
+		for _, stage := range findStagesByRepoDigests(m.stages, manifestList.ManifestDigests) {
+			res = append(res, fmt.Sprintf("%s:%s", m.StorageManager.StagesStorage.String(), stage.Info.Tag))
+		}
+	}
+
+	return res, nil
+}
+
+// cleanupManifestLists deletes the manifest lists which refer to the deleted or nonexistent platform stages images
+func (m *cleanupManager) cleanupManifestLists(ctx context.Context) error {
+	repoStagesStorage, ok := m.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return nil
+	}
+
+	manifestListsNames, err := repoStagesStorage.GetManifestListsNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var manifestListsToDelete []*docker_registry.ManifestListInfo
+	for _, manifestListName := range manifestListsNames {
+		manifestList, err := repoStagesStorage.GetManifestList(ctx, manifestListName)
+		if err != nil {
+			return fmt.Errorf("unable to get manifest list %s: %s", manifestListName, err)
+		} else if manifestList == nil {
+			continue
+		}
+
+		if len(findStagesByRepoDigests(m.stages, manifestList.ManifestDigests)) != len(manifestList.ManifestDigests) {
+			manifestListsToDelete = append(manifestListsToDelete, manifestList)
+			m.deletedRepoDigests = append(m.deletedRepoDigests, manifestList.Info.RepoDigest)
+		}
+	}
+
+	if len(manifestListsToDelete) == 0 {
+		return nil
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Deleting manifest lists").DoError(func() error {
+		return deleteManifestLists(ctx, repoStagesStorage, m.DryRun, manifestListsToDelete)
+	})
+}
+
+func purgeManifestLists(ctx context.Context, storageManager *manager.StorageManager, dryRun bool) error {
+	repoStagesStorage, ok := storageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return nil
+	}
+
+	manifestListsNames, err := repoStagesStorage.GetManifestListsNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var manifestLists []*docker_registry.ManifestListInfo
+	for _, manifestListName := range manifestListsNames {
+		manifestList, err := repoStagesStorage.GetManifestList(ctx, manifestListName)
+		if err != nil {
+			return fmt.Errorf("unable to get manifest list %s: %s", manifestListName, err)
+		} else if manifestList != nil {
+			manifestLists = append(manifestLists, manifestList)
+		}
+	}
+
+	return deleteManifestLists(ctx, repoStagesStorage, dryRun, manifestLists)
+}
+
+func deleteManifestLists(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage, dryRun bool, manifestLists []*docker_registry.ManifestListInfo) error {
+	for _, manifestList := range manifestLists {
+		if !dryRun {
+			if err := repoStagesStorage.DeleteManifestList(ctx, manifestList); err != nil {
+				if err := handleDeletionError(err); err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: Manifest list %s deletion failed: %s\n", manifestList.Info.Name, err)
+
+				continue
+			}
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", manifestList.Info.Tag)
+		logboek.Context(ctx).LogOptionalLn()
+	}
+
+	return nil
+}
+
+func findStagesByRepoDigests(stages []*image.StageDescription, repoDigests []string) []*image.StageDescription {
+	var res []*image.StageDescription
+	for _, repoDigest := range repoDigests {
+		for _, stage := range stages {
+			if stage.Info.RepoDigest == repoDigest {
+				res = append(res, stage)
+				break
+			}
+		}
+	}
+
+	return res
+}
@@ -56,6 +56,12 @@ func (m *purgeManager) run(ctx context.Context) error {
 		return err
 	}
 
+	if err := logboek.Context(ctx).Default().LogProcess("Deleting manifest lists").DoError(func() error {
+		return purgeManifestLists(ctx, m.StorageManager, m.DryRun)
+	}); err != nil {
+		return err
+	}
+
 	if err := logboek.Context(ctx).Default().LogProcess("Deleting imports metadata").DoError(func() error {
 		importMetadataIDs, err := m.StorageManager.StagesStorage.GetImportMetadataIDs(ctx, m.ProjectName)
 		if err != nil {
@@ -99,6 +105,18 @@ func (m *purgeManager) run(ctx context.Context) error {
 		return err
 	}
 
+	if err := logboek.Context(ctx).Default().LogProcess("Deleting image artifacts").DoError(func() error {
+		return purgeImageArtifacts(ctx, m.StorageManager, m.DryRun)
+	}); err != nil {
+		return err
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Deleting buildkit cache").DoError(func() error {
+		return purgeBuildkitCache(ctx, m.StorageManager, m.DryRun)
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
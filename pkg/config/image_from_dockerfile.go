@@ -1,5 +1,7 @@
 package config
 
+import "sort"
+
 type ImageFromDockerfile struct {
 	Name       string
 	Dockerfile string
@@ -9,6 +11,13 @@ type ImageFromDockerfile struct {
 	AddHost    []string
 	Network    string
 	SSH        string
+	Platform   []string
+
+	// FromImageName and FromArtifactName replace the base image of the target dockerfile stage
+	FromImageName    string
+	FromArtifactName string
+	// ImageArgs are the build args with the docker image names of the built werf images and artifacts
+	ImageArgs map[string]string
 
 	raw *rawImageFromDockerfile
 }
@@ -16,3 +25,14 @@ type ImageFromDockerfile struct {
 func (c *ImageFromDockerfile) GetName() string {
 	return c.Name
 }
+
+// ImageArgsNames returns sorted names of the build args from the imageArgs directive
+func (c *ImageFromDockerfile) ImageArgsNames() []string {
+	var res []string
+	for arg := range c.ImageArgs {
+		res = append(res, arg)
+	}
+	sort.Strings(res)
+
+	return res
+}
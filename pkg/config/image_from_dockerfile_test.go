@@ -0,0 +1,52 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type dockerfileImageDependenciesEntry struct {
+	image                *ImageFromDockerfile
+	expectedDependencies []ImageDependency
+	expectedError        bool
+}
+
+var _ = DescribeTable("image from dockerfile dependencies", func(e dockerfileImageDependenciesEntry) {
+	e.image.Name = "app"
+	e.image.raw = &rawImageFromDockerfile{doc: &doc{}}
+
+	werfConfig := &WerfConfig{
+		StapelImages:         []*StapelImage{{StapelImageBase: &StapelImageBase{Name: "base"}}},
+		ImagesFromDockerfile: []*ImageFromDockerfile{e.image},
+		Artifacts:            []*StapelImageArtifact{{StapelImageBase: &StapelImageBase{Name: "builder"}}},
+	}
+
+	err := werfConfig.validateDockerfileImageFrom(e.image)
+	if e.expectedError {
+		Ω(err).Should(HaveOccurred())
+		return
+	}
+
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(werfConfig.GetImageDependencies("app")).Should(Equal(e.expectedDependencies))
+},
+	Entry("fromImage", dockerfileImageDependenciesEntry{
+		image:                &ImageFromDockerfile{FromImageName: "base"},
+		expectedDependencies: []ImageDependency{{ImageName: "base", Type: FromImageDependency}},
+	}),
+	Entry("fromArtifact and imageArgs", dockerfileImageDependenciesEntry{
+		image: &ImageFromDockerfile{FromArtifactName: "builder", ImageArgs: map[string]string{"BUILDER_IMAGE": "builder", "BASE_IMAGE": "base"}},
+		expectedDependencies: []ImageDependency{
+			{ImageName: "builder", Type: FromArtifactDependency},
+			{ImageName: "base", Type: ImageArgDependency},
+			{ImageName: "builder", Type: ImageArgDependency},
+		},
+	}),
+	Entry("own image name", dockerfileImageDependenciesEntry{
+		image:         &ImageFromDockerfile{FromImageName: "app"},
+		expectedError: true,
+	}),
+	Entry("unknown image arg image", dockerfileImageDependenciesEntry{
+		image:         &ImageFromDockerfile{ImageArgs: map[string]string{"BASE_IMAGE": "unknown"}},
+		expectedError: true,
+	}))
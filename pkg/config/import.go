@@ -11,6 +11,8 @@ type Import struct {
 	Before       string
 	After        string
 	Stage        string
+	// DockerfileStage is the name of the dockerfile stage of the dockerfile image to import files from
+	DockerfileStage string
 
 	raw *rawImport
 }
@@ -38,6 +40,10 @@ func (c *Import) validate() error {
 		return newDetailedConfigError(fmt.Sprintf("invalid artifact stage `after: %s` for import: expected install or setup!", c.After), c.raw, c.raw.rawStapelImage.doc)
 	} else if c.Stage != "" && checkInvalidStage(c.Stage) {
 		return newDetailedConfigError(fmt.Sprintf("invalid stage `stage: %s` for import: expected beforeInstall, install, beforeSetup or setup", c.Stage), c.raw, c.raw.rawStapelImage.doc)
+	} else if c.DockerfileStage != "" && c.ImageName == "" {
+		return newDetailedConfigError("dockerfile stage `dockerfileStage: NAME` can be imported only from the dockerfile image specified by `image: NAME`!", c.raw, c.raw.rawStapelImage.doc)
+	} else if c.DockerfileStage != "" && c.Stage != "" {
+		return newDetailedConfigError("specify only one stage using `stage: NAME` or dockerfile stage using `dockerfileStage: NAME` for import!", c.raw, c.raw.rawStapelImage.doc)
 	}
 	return nil
 }
@@ -8,6 +8,8 @@ type Mount struct {
 	To   string
 	From string
 	Type string
+	// ID of the persistent cache for the cache_dir mount type
+	ID string
 
 	raw *rawMount
 }
@@ -19,8 +21,16 @@ func (c *Mount) validate() error {
 		if c.From == "" {
 			return newDetailedConfigError("`fromPath: PATH` absolute or relative path required for mount!", c.raw, c.raw.rawStapelImage.doc)
 		}
+	} else if c.Type == "cache_dir" {
+		if c.ID == "" {
+			return newDetailedConfigError("`id: CACHE_ID` required for `from: cache_dir` mount!", c.raw, c.raw.rawStapelImage.doc)
+		}
 	} else if c.Type != "tmp_dir" && c.Type != "build_dir" {
-		return newDetailedConfigError(fmt.Sprintf("invalid `from: %s` for mount: expected `tmp_dir` or `build_dir`!", c.Type), c.raw, c.raw.rawStapelImage.doc)
+		return newDetailedConfigError(fmt.Sprintf("invalid `from: %s` for mount: expected `tmp_dir`, `build_dir` or `cache_dir`!", c.Type), c.raw, c.raw.rawStapelImage.doc)
+	}
+
+	if c.ID != "" && c.Type != "cache_dir" {
+		return newDetailedConfigError("`id: CACHE_ID` can be used only for `from: cache_dir` mount!", c.raw, c.raw.rawStapelImage.doc)
 	}
 	return nil
 }
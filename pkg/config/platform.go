@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var platformRegexp = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// ValidatePlatform checks that platform is specified in the OS/ARCH[/VARIANT] format (e.g. linux/amd64, linux/arm/v7)
+func ValidatePlatform(platform string) error {
+	if !platformRegexp.MatchString(platform) {
+		return fmt.Errorf("invalid platform %q: expected OS/ARCH[/VARIANT] (e.g. linux/amd64, linux/arm64, linux/arm/v7)", platform)
+	}
+
+	return nil
+}
+
+func platformsToDirective(platform interface{}, configSection interface{}, doc *doc) ([]string, error) {
+	platforms, err := InterfaceToStringArray(platform, configSection, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	exist := map[string]bool{}
+	var res []string
+	for _, p := range platforms {
+		if err := ValidatePlatform(p); err != nil {
+			return nil, newDetailedConfigError(fmt.Sprintf("%s!", err), configSection, doc)
+		}
+
+		if exist[p] {
+			return nil, newDetailedConfigError(fmt.Sprintf("duplicate platform `%s`!", p), configSection, doc)
+		}
+		exist[p] = true
+
+		res = append(res, p)
+	}
+
+	return res, nil
+}
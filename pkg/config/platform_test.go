@@ -0,0 +1,42 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type platformEntry struct {
+	platform          interface{}
+	expectedPlatforms []string
+	expectedError     bool
+}
+
+var _ = DescribeTable("platform directive", func(e platformEntry) {
+	platforms, err := platformsToDirective(e.platform, nil, &doc{})
+	if e.expectedError {
+		Ω(err).Should(HaveOccurred())
+		return
+	}
+
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(platforms).Should(Equal(e.expectedPlatforms))
+},
+	Entry("not specified", platformEntry{
+		platform: nil,
+	}),
+	Entry("single platform", platformEntry{
+		platform:          "linux/arm64",
+		expectedPlatforms: []string{"linux/arm64"},
+	}),
+	Entry("several platforms with variant", platformEntry{
+		platform:          []interface{}{"linux/amd64", "linux/arm/v7"},
+		expectedPlatforms: []string{"linux/amd64", "linux/arm/v7"},
+	}),
+	Entry("without arch", platformEntry{
+		platform:      "linux",
+		expectedError: true,
+	}),
+	Entry("duplicate platform", platformEntry{
+		platform:      []interface{}{"linux/amd64", "linux/amd64"},
+		expectedError: true,
+	}))
@@ -6,14 +6,18 @@ import (
 )
 
 type rawImageFromDockerfile struct {
-	Images     []string               `yaml:"-"`
-	Dockerfile string                 `yaml:"dockerfile,omitempty"`
-	Context    string                 `yaml:"context,omitempty"`
-	Target     string                 `yaml:"target,omitempty"`
-	Args       map[string]interface{} `yaml:"args,omitempty"`
-	AddHost    interface{}            `yaml:"addHost,omitempty"`
-	Network    string                 `yaml:"network,omitempty"`
-	SSH        string                 `yaml:"ssh,omitempty"`
+	Images       []string               `yaml:"-"`
+	Dockerfile   string                 `yaml:"dockerfile,omitempty"`
+	Context      string                 `yaml:"context,omitempty"`
+	Target       string                 `yaml:"target,omitempty"`
+	Args         map[string]interface{} `yaml:"args,omitempty"`
+	AddHost      interface{}            `yaml:"addHost,omitempty"`
+	Network      string                 `yaml:"network,omitempty"`
+	SSH          string                 `yaml:"ssh,omitempty"`
+	Platform     interface{}            `yaml:"platform,omitempty"`
+	FromImage    string                 `yaml:"fromImage,omitempty"`
+	FromArtifact string                 `yaml:"fromArtifact,omitempty"`
+	ImageArgs    map[string]string      `yaml:"imageArgs,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -61,6 +65,28 @@ func (c *rawImageFromDockerfile) UnmarshalYAML(unmarshal func(interface{}) error
 		return err
 	}
 
+	if err := c.validateImageDependencies(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawImageFromDockerfile) validateImageDependencies() error {
+	if c.FromImage != "" && c.FromArtifact != "" {
+		return newDetailedConfigError("specify only one base image using `fromImage` or `fromArtifact` directive!", c, c.doc)
+	}
+
+	for arg, imageName := range c.ImageArgs {
+		if imageName == "" {
+			return newDetailedConfigError(fmt.Sprintf("image or artifact name is required for the build arg `%s` in `imageArgs` directive!", arg), c, c.doc)
+		}
+
+		if _, ok := c.Args[arg]; ok {
+			return newDetailedConfigError(fmt.Sprintf("build arg `%s` cannot be specified in both `args` and `imageArgs` directives!", arg), c, c.doc)
+		}
+	}
+
 	return nil
 }
 
@@ -92,6 +118,15 @@ func (c *rawImageFromDockerfile) toImageFromDockerfileDirective(imageName string
 
 	image.Network = c.Network
 	image.SSH = c.SSH
+	image.FromImageName = c.FromImage
+	image.FromArtifactName = c.FromArtifact
+	image.ImageArgs = c.ImageArgs
+
+	if platforms, err := platformsToDirective(c.Platform, c, c.doc); err != nil {
+		return nil, err
+	} else {
+		image.Platform = platforms
+	}
 
 	image.raw = c
 
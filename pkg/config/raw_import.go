@@ -7,6 +7,8 @@ type rawImport struct {
 	After        string `yaml:"after,omitempty"`
 	Stage        string `yaml:"stage,omitempty"`
 
+	DockerfileStage string `yaml:"dockerfileStage,omitempty"`
+
 	rawArtifactExport `yaml:",inline"`
 	rawStapelImage    *rawStapelImage `yaml:"-"` // parent
 
@@ -61,6 +63,7 @@ func (c *rawImport) toDirective() (imp *Import, err error) {
 	imp.Before = c.Before
 	imp.After = c.After
 	imp.Stage = c.Stage
+	imp.DockerfileStage = c.DockerfileStage
 
 	imp.raw = c
 
@@ -0,0 +1,41 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type importEntry struct {
+	rawImport     *rawImport
+	expectedError bool
+}
+
+var _ = DescribeTable("import directive", func(e importEntry) {
+	e.rawImport.rawStapelImage = &rawStapelImage{doc: &doc{}}
+	e.rawImport.rawArtifactExport.rawExportBase.Add = "/app"
+	e.rawImport.rawArtifactExport.rawExportBase.To = "/app"
+	e.rawImport.rawArtifactExport.inlinedIntoRaw(e.rawImport)
+
+	imp, err := e.rawImport.toDirective()
+	if e.expectedError {
+		Ω(err).Should(HaveOccurred())
+		return
+	}
+
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(imp.DockerfileStage).Should(Equal(e.rawImport.DockerfileStage))
+},
+	Entry("stage", importEntry{
+		rawImport: &rawImport{ImageName: "backend", Stage: "install", After: "install"},
+	}),
+	Entry("dockerfile stage", importEntry{
+		rawImport: &rawImport{ImageName: "backend", DockerfileStage: "builder", After: "install"},
+	}),
+	Entry("dockerfile stage from artifact", importEntry{
+		rawImport:     &rawImport{ArtifactName: "backend", DockerfileStage: "builder", After: "install"},
+		expectedError: true,
+	}),
+	Entry("both stage and dockerfile stage", importEntry{
+		rawImport:     &rawImport{ImageName: "backend", Stage: "install", DockerfileStage: "builder", After: "install"},
+		expectedError: true,
+	}))
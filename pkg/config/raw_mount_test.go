@@ -0,0 +1,50 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type mountEntry struct {
+	rawMount      *rawMount
+	expectedType  string
+	expectedError bool
+}
+
+var _ = DescribeTable("mount directive", func(e mountEntry) {
+	e.rawMount.rawStapelImage = &rawStapelImage{doc: &doc{}}
+
+	mount, err := e.rawMount.toDirective()
+	if e.expectedError {
+		Ω(err).Should(HaveOccurred())
+		return
+	}
+
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(mount.Type).Should(Equal(e.expectedType))
+	Ω(mount.ID).Should(Equal(e.rawMount.ID))
+},
+	Entry("build_dir", mountEntry{
+		rawMount:     &rawMount{From: "build_dir", To: "/app/cache"},
+		expectedType: "build_dir",
+	}),
+	Entry("custom_dir", mountEntry{
+		rawMount:     &rawMount{FromPath: "~/.cache", To: "/app/cache"},
+		expectedType: "custom_dir",
+	}),
+	Entry("cache_dir", mountEntry{
+		rawMount:     &rawMount{From: "cache_dir", ID: "npm", To: "/root/.npm"},
+		expectedType: "cache_dir",
+	}),
+	Entry("cache_dir without id", mountEntry{
+		rawMount:      &rawMount{From: "cache_dir", To: "/root/.npm"},
+		expectedError: true,
+	}),
+	Entry("id for build_dir", mountEntry{
+		rawMount:      &rawMount{From: "build_dir", ID: "npm", To: "/root/.npm"},
+		expectedError: true,
+	}),
+	Entry("unknown service dir", mountEntry{
+		rawMount:      &rawMount{From: "unknown_dir", To: "/root/.npm"},
+		expectedError: true,
+	}))
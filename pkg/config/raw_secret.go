@@ -0,0 +1,52 @@
+package config
+
+type rawSecret struct {
+	ID        string `yaml:"id,omitempty"`
+	Src       string `yaml:"src,omitempty"`
+	Env       string `yaml:"env,omitempty"`
+	Encrypted bool   `yaml:"encrypted,omitempty"`
+	To        string `yaml:"to,omitempty"`
+
+	rawStapelImage *rawStapelImage `yaml:"-"` // parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawSecret) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawStapelImage); ok {
+		c.rawStapelImage = parent
+	}
+
+	type plain rawSecret
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawStapelImage.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawSecret) toDirective() (secret *Secret, err error) {
+	secret = &Secret{}
+	secret.ID = c.ID
+	secret.Src = c.Src
+	secret.Env = c.Env
+	secret.Encrypted = c.Encrypted
+
+	if c.To == "" {
+		secret.To = DefaultSecretsDir + "/" + c.ID
+	} else {
+		secret.To = c.To
+	}
+
+	secret.raw = c
+
+	if err := secret.validate(); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type secretEntry struct {
+	rawSecret     *rawSecret
+	expectedTo    string
+	expectedError bool
+}
+
+var _ = DescribeTable("secrets directive", func(e secretEntry) {
+	e.rawSecret.rawStapelImage = &rawStapelImage{doc: &doc{}}
+
+	secret, err := e.rawSecret.toDirective()
+	if e.expectedError {
+		Ω(err).Should(HaveOccurred())
+		return
+	}
+
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(secret.To).Should(Equal(e.expectedTo))
+},
+	Entry("file", secretEntry{
+		rawSecret:  &rawSecret{ID: "npmrc", Src: "~/.npmrc"},
+		expectedTo: "/run/secrets/npmrc",
+	}),
+	Entry("env with custom path", secretEntry{
+		rawSecret:  &rawSecret{ID: "token", Env: "TOKEN", To: "/root/token"},
+		expectedTo: "/root/token",
+	}),
+	Entry("without source", secretEntry{
+		rawSecret:     &rawSecret{ID: "token"},
+		expectedError: true,
+	}),
+	Entry("with file and env", secretEntry{
+		rawSecret:     &rawSecret{ID: "token", Src: "token", Env: "TOKEN"},
+		expectedError: true,
+	}),
+	Entry("invalid id", secretEntry{
+		rawSecret:     &rawSecret{ID: "../token", Env: "TOKEN"},
+		expectedError: true,
+	}),
+	Entry("relative path", secretEntry{
+		rawSecret:     &rawSecret{ID: "token", Env: "TOKEN", To: "token"},
+		expectedError: true,
+	}))
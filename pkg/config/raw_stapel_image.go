@@ -18,9 +18,11 @@ type rawStapelImage struct {
 	RawShell                                            *rawShell    `yaml:"shell,omitempty"`
 	RawAnsible                                          *rawAnsible  `yaml:"ansible,omitempty"`
 	RawMount                                            []*rawMount  `yaml:"mount,omitempty"`
+	RawSecrets                                          []*rawSecret `yaml:"secrets,omitempty"`
 	RawDocker                                           *rawDocker   `yaml:"docker,omitempty"`
 	RawImport                                           []*rawImport `yaml:"import,omitempty"`
 	AsLayers                                            bool         `yaml:"asLayers,omitempty"`
+	Platform                                            interface{}  `yaml:"platform,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -673,6 +675,20 @@ func (c *rawStapelImage) toBaseStapelImageBaseDirective(name string) (imageBase
 		}
 	}
 
+	for _, rawSecret := range c.RawSecrets {
+		if imageSecret, err := rawSecret.toDirective(); err != nil {
+			return nil, err
+		} else {
+			imageBase.Secrets = append(imageBase.Secrets, imageSecret)
+		}
+	}
+
+	if platforms, err := platformsToDirective(c.Platform, nil, c.doc); err != nil {
+		return nil, err
+	} else {
+		imageBase.Platform = platforms
+	}
+
 	imageBase.Git = &GitManager{}
 
 	imageBase.raw = c
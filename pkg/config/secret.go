@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultSecretsDir is the build container dir where secrets are mounted when `to` is not specified
+const DefaultSecretsDir = "/run/secrets"
+
+var secretIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Secret is the build-time secret which is mounted into the stapel stages build containers,
+// secret content is not stored into the stage images and does not affect stage digests
+type Secret struct {
+	ID string
+	// Src is the host file path
+	Src string
+	// Env is the host environment variable name
+	Env string
+	// Encrypted source is decrypted with the project secret key
+	Encrypted bool
+	// To is the absolute path of the secret file in the build container
+	To string
+
+	raw *rawSecret
+}
+
+func (c *Secret) validate() error {
+	if !secretIDRegexp.MatchString(c.ID) {
+		return newDetailedConfigError(fmt.Sprintf("invalid `id: %s` for secret: expected letters, digits, `_`, `.` and `-`!", c.ID), c.raw, c.raw.rawStapelImage.doc)
+	}
+
+	if c.Src == "" && c.Env == "" {
+		return newDetailedConfigError("`src: PATH` or `env: NAME` required for secret!", c.raw, c.raw.rawStapelImage.doc)
+	} else if c.Src != "" && c.Env != "" {
+		return newDetailedConfigError(fmt.Sprintf("cannot use `src: %s` and `env: %s` at the same time for secret!", c.Src, c.Env), c.raw, c.raw.rawStapelImage.doc)
+	}
+
+	if !isAbsolutePath(c.To) {
+		return newDetailedConfigError("`to: PATH` absolute path required for secret!", c.raw, c.raw.rawStapelImage.doc)
+	}
+
+	return nil
+}
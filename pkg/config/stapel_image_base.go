@@ -16,7 +16,9 @@ type StapelImageBase struct {
 	Shell                                               *Shell
 	Ansible                                             *Ansible
 	Mount                                               []*Mount
+	Secrets                                             []*Secret
 	Import                                              []*Import
+	Platform                                            []string
 
 	raw *rawStapelImage
 }
@@ -103,6 +105,19 @@ We do not recommend using the actual base image such way. Use a particular uncha
 		mountByTo[mount.To] = true
 	}
 
+	secretByID := map[string]bool{}
+	for _, secret := range c.Secrets {
+		if secretByID[secret.ID] {
+			return newDetailedConfigError(fmt.Sprintf("duplicate secret `id: %s`!", secret.ID), nil, c.raw.doc)
+		}
+		secretByID[secret.ID] = true
+
+		if mountByTo[secret.To] {
+			return newDetailedConfigError(fmt.Sprintf("conflict between secret `to: %s` and mounts!", secret.To), nil, c.raw.doc)
+		}
+		mountByTo[secret.To] = true
+	}
+
 	if !oneOrNone([]bool{c.From != "", c.raw.FromImage != "", c.raw.FromArtifact != ""}) {
 		return newDetailedConfigError("conflict between `from`, `fromImage` and `fromArtifact` directives!", nil, c.raw.doc)
 	}
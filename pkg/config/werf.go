@@ -205,6 +205,8 @@ func (c *WerfConfig) validateImportImage(i *Import) error {
 			}
 
 			return newDetailedConfigError(fmt.Sprintf("no such image `%s`!", imageName), i.raw, i.raw.rawStapelImage.doc)
+		} else if _, isDockerfileImage := interf.(*ImageFromDockerfile); i.DockerfileStage != "" && !isDockerfileImage {
+			return newDetailedConfigError(fmt.Sprintf("cannot import dockerfile stage `%s` from image `%s`: image is not built from dockerfile!", i.DockerfileStage, i.ImageName), i.raw, i.raw.rawStapelImage.doc)
 		}
 	} else if i.ArtifactName != "" {
 		if imageArtifact := c.GetArtifact(i.ArtifactName); imageArtifact == nil {
@@ -228,6 +230,42 @@ func (c *WerfConfig) validateImagesFrom() error {
 		}
 	}
 
+	for _, image := range c.ImagesFromDockerfile {
+		if err := c.validateDockerfileImageFrom(image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *WerfConfig) validateDockerfileImageFrom(i *ImageFromDockerfile) error {
+	if i.FromImageName != "" {
+		if i.FromImageName == i.Name {
+			return newDetailedConfigError(fmt.Sprintf("cannot use own image name as `fromImage` directive value!"), nil, i.raw.doc)
+		}
+
+		if interf := c.GetImage(i.FromImageName); interf == nil {
+			return newDetailedConfigError(fmt.Sprintf("no such image `%s`!", i.FromImageName), i.raw, i.raw.doc)
+		}
+	} else if i.FromArtifactName != "" {
+		if imageArtifact := c.GetArtifact(i.FromArtifactName); imageArtifact == nil {
+			return newDetailedConfigError(fmt.Sprintf("no such image artifact `%s`!", i.FromArtifactName), i.raw, i.raw.doc)
+		}
+	}
+
+	for _, arg := range i.ImageArgsNames() {
+		imageName := i.ImageArgs[arg]
+
+		if imageName == i.Name {
+			return newDetailedConfigError(fmt.Sprintf("cannot use own image name as `imageArgs` directive value!"), nil, i.raw.doc)
+		}
+
+		if !c.HasImageOrArtifact(imageName) {
+			return newDetailedConfigError(fmt.Sprintf("no such image or artifact `%s` for the build arg `%s`!", imageName, arg), i.raw, i.raw.doc)
+		}
+	}
+
 	return nil
 }
 
@@ -268,6 +306,10 @@ func (c *WerfConfig) validateInfiniteLoopBetweenRelatedImages() error {
 		imageAndArtifactNames = append(imageAndArtifactNames, artifact.Name)
 	}
 
+	for _, image := range c.ImagesFromDockerfile {
+		imageAndArtifactNames = append(imageAndArtifactNames, image.Name)
+	}
+
 	for _, imageOrArtifactName := range imageAndArtifactNames {
 		if err, errImagesStack := c.validateImageInfiniteLoop(imageOrArtifactName, []string{}); err != nil {
 			return fmt.Errorf("%s: %s", err, strings.Join(errImagesStack, " -> "))
@@ -352,11 +394,78 @@ func (c *WerfConfig) imageDependencies(interf ImageInterface) (deps []ImageInter
 			}
 		}
 	case *ImageFromDockerfile:
+		for _, dep := range c.getDockerfileImageDependencies(i) {
+			deps = append(deps, c.getImageOrArtifact(dep.ImageName))
+		}
 	}
 
 	return deps
 }
 
+type ImageDependencyType string
+
+const (
+	FromImageDependency    ImageDependencyType = "fromImage"
+	FromArtifactDependency ImageDependencyType = "fromArtifact"
+	ImportDependency       ImageDependencyType = "import"
+	ImageArgDependency     ImageDependencyType = "imageArg"
+)
+
+type ImageDependency struct {
+	ImageName string
+	Type      ImageDependencyType
+}
+
+// GetImageDependencies returns images and artifacts which should be built before the specified image or artifact
+func (c *WerfConfig) GetImageDependencies(imageName string) (deps []ImageDependency) {
+	switch i := c.getImageOrArtifact(imageName).(type) {
+	case StapelImageInterface:
+		if i.ImageBaseConfig().FromImageName != "" {
+			deps = append(deps, ImageDependency{ImageName: i.ImageBaseConfig().FromImageName, Type: FromImageDependency})
+		}
+
+		if i.ImageBaseConfig().FromArtifactName != "" {
+			deps = append(deps, ImageDependency{ImageName: i.ImageBaseConfig().FromArtifactName, Type: FromArtifactDependency})
+		}
+
+		for _, imp := range i.imports() {
+			if imp.ImageName != "" {
+				deps = append(deps, ImageDependency{ImageName: imp.ImageName, Type: ImportDependency})
+			} else if imp.ArtifactName != "" {
+				deps = append(deps, ImageDependency{ImageName: imp.ArtifactName, Type: ImportDependency})
+			}
+		}
+	case *ImageFromDockerfile:
+		deps = c.getDockerfileImageDependencies(i)
+	}
+
+	return deps
+}
+
+func (c *WerfConfig) getDockerfileImageDependencies(i *ImageFromDockerfile) (deps []ImageDependency) {
+	if i.FromImageName != "" {
+		deps = append(deps, ImageDependency{ImageName: i.FromImageName, Type: FromImageDependency})
+	}
+
+	if i.FromArtifactName != "" {
+		deps = append(deps, ImageDependency{ImageName: i.FromArtifactName, Type: FromArtifactDependency})
+	}
+
+	for _, arg := range i.ImageArgsNames() {
+		deps = append(deps, ImageDependency{ImageName: i.ImageArgs[arg], Type: ImageArgDependency})
+	}
+
+	return deps
+}
+
+func (c *WerfConfig) getImageOrArtifact(imageName string) ImageInterface {
+	if artifact := c.GetArtifact(imageName); artifact != nil {
+		return artifact
+	}
+
+	return c.GetImage(imageName)
+}
+
 func (c *WerfConfig) relatedImageImages(interf ImageInterface) (images []ImageInterface) {
 	images = append(images, interf)
 	switch i := interf.(type) {
@@ -387,6 +496,12 @@ func (c *WerfConfig) validateImageInfiniteLoop(imageOrArtifactName string, image
 	if interf != nil {
 		switch i := interf.(type) {
 		case *ImageFromDockerfile:
+			for _, dep := range c.getDockerfileImageDependencies(i) {
+				if err, errImagesStack := c.validateImageInfiniteLoop(dep.ImageName, imageNameStack); err != nil {
+					return err, append([]string{imageOrArtifactName}, errImagesStack...)
+				}
+			}
+
 			return nil, imageNameStack
 		case *StapelImage:
 			image = i
@@ -0,0 +1,360 @@
+package container_runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/auth/authprovider"
+	"github.com/moby/buildkit/session/secrets/secretsprovider"
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+	"github.com/moby/buildkit/util/entitlements"
+	"github.com/moby/buildkit/util/progress/progressui"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/ssh_agent"
+)
+
+const (
+	DefaultBuildkitAddress = "unix:///run/buildkit/buildkitd.sock"
+
+	// BuildkitCacheTagPrefix is the tag prefix of the build cache records stored in the stages storage repo
+	BuildkitCacheTagPrefix = "buildkit-cache-"
+)
+
+// DockerfileBuildRuntime builds image-from-dockerfile images instead of the docker server,
+// built image should be loaded into the docker server by the specified tag
+type DockerfileBuildRuntime interface {
+	BuildDockerfileImage(ctx context.Context, tag, cacheID string, dockerBuildArgs []string) error
+	String() string
+}
+
+// BuildkitRuntime builds image-from-dockerfile images with buildkitd
+type BuildkitRuntime struct {
+	Address string
+	// CacheRepo is the docker repo to import build cache from and export build cache to, usually the stages storage repo
+	CacheRepo string
+	// Secrets are available for the RUN --mount=type=secret instructions
+	Secrets []secretsprovider.FileSource
+}
+
+type BuildkitRuntimeOptions struct {
+	CacheRepo string
+	// Secrets in the docker build format: id=ID,src=PATH
+	Secrets []string
+}
+
+func NewBuildkitRuntime(address string, opts BuildkitRuntimeOptions) (*BuildkitRuntime, error) {
+	if address == "" {
+		address = DefaultBuildkitAddress
+	}
+
+	runtime := &BuildkitRuntime{Address: address, CacheRepo: opts.CacheRepo}
+
+	for _, spec := range opts.Secrets {
+		secret, err := parseBuildkitSecret(spec)
+		if err != nil {
+			return nil, err
+		}
+		runtime.Secrets = append(runtime.Secrets, secret)
+	}
+
+	return runtime, nil
+}
+
+func (runtime *BuildkitRuntime) String() string {
+	return fmt.Sprintf("buildkit %s", runtime.Address)
+}
+
+func (runtime *BuildkitRuntime) BuildDockerfileImage(ctx context.Context, tag, cacheID string, dockerBuildArgs []string) error {
+	buildOptions, err := parseDockerBuildArgs(dockerBuildArgs)
+	if err != nil {
+		return err
+	}
+
+	solveOpt, err := runtime.solveOpt(buildOptions, cacheID)
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New(ctx, runtime.Address)
+	if err != nil {
+		return fmt.Errorf("unable to connect to buildkitd %s: %s", runtime.Address, err)
+	}
+	defer c.Close()
+
+	// docker exporter output is loaded into the docker server as is, so the built image is used the same way as the image built by the docker server
+	loadReader, loadWriter := io.Pipe()
+	loadErrCh := make(chan error, 1)
+	go func() {
+		err := docker.ImageLoad(ctx, loadReader)
+		loadReader.CloseWithError(err)
+		loadErrCh <- err
+	}()
+
+	solveOpt.Exports = []client.ExportEntry{
+		{
+			Type:  client.ExporterDocker,
+			Attrs: map[string]string{"name": tag},
+			Output: func(map[string]string) (io.WriteCloser, error) {
+				return loadWriter, nil
+			},
+		},
+	}
+
+	statusCh := make(chan *client.SolveStatus)
+	var displayWg sync.WaitGroup
+	displayWg.Add(1)
+	go func() {
+		defer displayWg.Done()
+		_ = progressui.DisplaySolveStatus(ctx, "", nil, logboek.Context(ctx).ProxyOutStream(), statusCh)
+	}()
+
+	_, solveErr := c.Solve(ctx, nil, solveOpt, statusCh)
+	displayWg.Wait()
+
+	if solveErr != nil {
+		loadWriter.CloseWithError(solveErr)
+		<-loadErrCh
+		return fmt.Errorf("buildkit build failed: %s", solveErr)
+	}
+
+	loadWriter.Close()
+	if err := <-loadErrCh; err != nil {
+		return fmt.Errorf("unable to load built image %s into docker server: %s", tag, err)
+	}
+
+	return nil
+}
+
+func (runtime *BuildkitRuntime) solveOpt(buildOptions *dockerfileBuildOptions, cacheID string) (client.SolveOpt, error) {
+	contextDir, err := filepath.Abs(buildOptions.ContextDir)
+	if err != nil {
+		return client.SolveOpt{}, err
+	}
+
+	dockerfilePath := buildOptions.DockerfilePath
+	if dockerfilePath == "" {
+		dockerfilePath = filepath.Join(contextDir, "Dockerfile")
+	} else if !filepath.IsAbs(dockerfilePath) {
+		dockerfilePath = filepath.Join(contextDir, dockerfilePath)
+	}
+
+	frontendAttrs := map[string]string{"filename": filepath.Base(dockerfilePath)}
+	if buildOptions.Target != "" {
+		frontendAttrs["target"] = buildOptions.Target
+	}
+	if buildOptions.Platform != "" {
+		frontendAttrs["platform"] = buildOptions.Platform
+	}
+	for key, value := range buildOptions.BuildArgs {
+		frontendAttrs["build-arg:"+key] = value
+	}
+	for key, value := range buildOptions.Labels {
+		frontendAttrs["label:"+key] = value
+	}
+	if len(buildOptions.AddHosts) != 0 {
+		var hosts []string
+		for _, addHost := range buildOptions.AddHosts {
+			// docker format is HOST:IP and buildkit expects HOST=IP
+			hosts = append(hosts, strings.Replace(addHost, ":", "=", 1))
+		}
+		frontendAttrs["add-hosts"] = strings.Join(hosts, ",")
+	}
+
+	var allowedEntitlements []entitlements.Entitlement
+	if buildOptions.Network != "" {
+		frontendAttrs["force-network-mode"] = buildOptions.Network
+		if buildOptions.Network == "host" {
+			allowedEntitlements = append(allowedEntitlements, entitlements.EntitlementNetworkHost)
+		}
+	}
+
+	attachables := []session.Attachable{authprovider.NewDockerAuthProvider(os.Stderr)}
+
+	secrets := append(append([]secretsprovider.FileSource{}, runtime.Secrets...), buildOptions.Secrets...)
+	if len(secrets) != 0 {
+		store, err := secretsprovider.NewFileStore(secrets)
+		if err != nil {
+			return client.SolveOpt{}, fmt.Errorf("unable to load buildkit secrets: %s", err)
+		}
+		attachables = append(attachables, secretsprovider.NewSecretProvider(store))
+	}
+
+	if buildOptions.SSH != "" {
+		agentConfig, err := parseBuildkitSSH(buildOptions.SSH)
+		if err != nil {
+			return client.SolveOpt{}, err
+		}
+
+		sshProvider, err := sshprovider.NewSSHAgentProvider([]sshprovider.AgentConfig{agentConfig})
+		if err != nil {
+			return client.SolveOpt{}, fmt.Errorf("unable to setup ssh forwarding %q: %s", buildOptions.SSH, err)
+		}
+		attachables = append(attachables, sshProvider)
+	}
+
+	solveOpt := client.SolveOpt{
+		Frontend:      "dockerfile.v0",
+		FrontendAttrs: frontendAttrs,
+		LocalDirs: map[string]string{
+			"context":    contextDir,
+			"dockerfile": filepath.Dir(dockerfilePath),
+		},
+		Session:             attachables,
+		AllowedEntitlements: allowedEntitlements,
+	}
+
+	if cacheRef := runtime.cacheRef(cacheID); cacheRef != "" {
+		solveOpt.CacheImports = []client.CacheOptionsEntry{{Type: "registry", Attrs: map[string]string{"ref": cacheRef}}}
+		solveOpt.CacheExports = []client.CacheOptionsEntry{{Type: "registry", Attrs: map[string]string{"ref": cacheRef, "mode": "max"}}}
+	}
+
+	return solveOpt, nil
+}
+
+func (runtime *BuildkitRuntime) cacheRef(cacheID string) string {
+	if runtime.CacheRepo == "" || cacheID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", runtime.CacheRepo, slug.DockerTag(BuildkitCacheTagPrefix+cacheID))
+}
+
+type dockerfileBuildOptions struct {
+	DockerfilePath string
+	Target         string
+	ContextDir     string
+	BuildArgs      map[string]string
+	Labels         map[string]string
+	AddHosts       []string
+	Network        string
+	SSH            string
+	Platform       string
+	Secrets        []secretsprovider.FileSource
+}
+
+// parseDockerBuildArgs converts docker build cli arguments prepared for the docker server into buildkit dockerfile frontend options
+func parseDockerBuildArgs(args []string) (*dockerfileBuildOptions, error) {
+	opts := &dockerfileBuildOptions{
+		BuildArgs: map[string]string{},
+		Labels:    map[string]string{},
+	}
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			if opts.ContextDir != "" {
+				return nil, fmt.Errorf("unexpected docker build argument %q: context %q already specified", arg, opts.ContextDir)
+			}
+			opts.ContextDir = arg
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unsupported docker build argument %q: expected --NAME=VALUE", arg)
+		}
+		name, value := parts[0], parts[1]
+
+		switch name {
+		case "file":
+			opts.DockerfilePath = value
+		case "target":
+			opts.Target = value
+		case "build-arg":
+			key, val := splitKeyValue(value)
+			opts.BuildArgs[key] = val
+		case "label":
+			key, val := splitKeyValue(value)
+			opts.Labels[key] = val
+		case "add-host":
+			opts.AddHosts = append(opts.AddHosts, value)
+		case "network":
+			opts.Network = value
+		case "ssh":
+			opts.SSH = value
+		case "platform":
+			opts.Platform = value
+		case "secret":
+			secret, err := parseBuildkitSecret(value)
+			if err != nil {
+				return nil, err
+			}
+			opts.Secrets = append(opts.Secrets, secret)
+		default:
+			return nil, fmt.Errorf("docker build argument %q is not supported by buildkit runtime", arg)
+		}
+	}
+
+	if opts.ContextDir == "" {
+		return nil, fmt.Errorf("docker build context is not specified")
+	}
+
+	return opts, nil
+}
+
+func splitKeyValue(value string) (string, string) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// parseBuildkitSecret parses secret in the docker build format: id=ID,src=PATH
+func parseBuildkitSecret(spec string) (secretsprovider.FileSource, error) {
+	var secret secretsprovider.FileSource
+
+	for _, field := range strings.Split(spec, ",") {
+		key, value := splitKeyValue(strings.TrimSpace(field))
+		switch strings.ToLower(key) {
+		case "type":
+			if value != "file" {
+				return secret, fmt.Errorf("bad secret %q: unsupported type %q", spec, value)
+			}
+		case "id":
+			secret.ID = value
+		case "src", "source":
+			secret.FilePath = value
+		default:
+			return secret, fmt.Errorf("bad secret %q: unexpected key %q, expected id=ID,src=PATH", spec, key)
+		}
+	}
+
+	if secret.ID == "" {
+		return secret, fmt.Errorf("bad secret %q: id required", spec)
+	}
+
+	if secret.FilePath == "" {
+		secret.FilePath = secret.ID
+	}
+
+	return secret, nil
+}
+
+// parseBuildkitSSH parses ssh in the docker build format: default|ID[=SOCKET|KEY[,KEY]].
+// Default ssh agent is the werf ssh agent when available.
+func parseBuildkitSSH(spec string) (sshprovider.AgentConfig, error) {
+	parts := strings.SplitN(spec, "=", 2)
+
+	config := sshprovider.AgentConfig{ID: parts[0]}
+	if config.ID == "" {
+		return config, fmt.Errorf("bad ssh %q: id required", spec)
+	}
+
+	if len(parts) == 2 && parts[1] != "" {
+		config.Paths = strings.Split(parts[1], ",")
+	} else if ssh_agent.SSHAuthSock != "" {
+		config.Paths = []string{ssh_agent.SSHAuthSock}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,76 @@
+package container_runtime
+
+import (
+	"testing"
+)
+
+func TestParseDockerBuildArgs(t *testing.T) {
+	opts, err := parseDockerBuildArgs([]string{
+		"--file=docker/Dockerfile",
+		"--target=app",
+		"--build-arg=VERSION=1.0=rc",
+		"--label=werf=project",
+		"--add-host=registry:10.0.0.1",
+		"--network=host",
+		"--ssh=default",
+		"--platform=linux/arm64",
+		"--secret=id=npmrc,src=/tmp/.npmrc",
+		"context",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.DockerfilePath != "docker/Dockerfile" || opts.Target != "app" || opts.ContextDir != "context" || opts.Network != "host" || opts.SSH != "default" || opts.Platform != "linux/arm64" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	if opts.BuildArgs["VERSION"] != "1.0=rc" {
+		t.Errorf("unexpected build args: %v", opts.BuildArgs)
+	}
+	if opts.Labels["werf"] != "project" {
+		t.Errorf("unexpected labels: %v", opts.Labels)
+	}
+	if len(opts.Secrets) != 1 || opts.Secrets[0].ID != "npmrc" || opts.Secrets[0].FilePath != "/tmp/.npmrc" {
+		t.Errorf("unexpected secrets: %+v", opts.Secrets)
+	}
+
+	for _, args := range [][]string{
+		{"--squash=true", "context"},
+		{"--file=Dockerfile"},
+		{"context", "other"},
+	} {
+		if _, err := parseDockerBuildArgs(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseBuildkitSecret(t *testing.T) {
+	secret, err := parseBuildkitSecret("id=token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.ID != "token" || secret.FilePath != "token" {
+		t.Errorf("unexpected secret: %+v", secret)
+	}
+
+	for _, spec := range []string{"src=/tmp/token", "id=token,type=env", "id=token,mode=0400"} {
+		if _, err := parseBuildkitSecret(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestParseBuildkitSSH(t *testing.T) {
+	config, err := parseBuildkitSSH("github=/home/user/.ssh/id_rsa,/home/user/.ssh/id_ed25519")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ID != "github" || len(config.Paths) != 2 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	if _, err := parseBuildkitSSH("=/tmp/agent.sock"); err == nil {
+		t.Errorf("expected error for empty id")
+	}
+}
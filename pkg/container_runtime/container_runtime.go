@@ -20,7 +20,10 @@ type ContainerRuntime interface {
 	String() string
 }
 
-type LocalDockerServerRuntime struct{}
+type LocalDockerServerRuntime struct {
+	// DockerfileBuildRuntime builds image-from-dockerfile images instead of the docker server when set
+	DockerfileBuildRuntime DockerfileBuildRuntime
+}
 
 // GetImageInspect only available for LocalDockerServerRuntime
 func (runtime *LocalDockerServerRuntime) GetImageInspect(ctx context.Context, ref string) (*types.ImageInspect, error) {
@@ -3,20 +3,29 @@ package container_runtime
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 
 	"github.com/google/uuid"
 
 	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/werf"
 )
 
 type DockerfileImageBuilder struct {
 	temporalId string
 	isBuilt    bool
 	BuildArgs  []string
+	// CacheID identifies build cache of the image in the stages storage, used by the DockerfileBuildRuntime
+	CacheID string
+	// Dockerfile is the dockerfile modified by werf, it is written to the temporary file and passed with the --file option
+	Dockerfile []byte
+
+	localDockerServerRuntime *LocalDockerServerRuntime
 }
 
-func NewDockerfileImageBuilder() *DockerfileImageBuilder {
-	return &DockerfileImageBuilder{temporalId: uuid.New().String()}
+func NewDockerfileImageBuilder(localDockerServerRuntime *LocalDockerServerRuntime) *DockerfileImageBuilder {
+	return &DockerfileImageBuilder{temporalId: uuid.New().String(), localDockerServerRuntime: localDockerServerRuntime}
 }
 
 func (b *DockerfileImageBuilder) GetBuiltId() string {
@@ -30,11 +39,32 @@ func (b *DockerfileImageBuilder) AppendBuildArgs(buildArgs ...string) {
 	b.BuildArgs = append(b.BuildArgs, buildArgs...)
 }
 
+func (b *DockerfileImageBuilder) SetDockerfile(dockerfile []byte) {
+	b.Dockerfile = dockerfile
+}
+
 func (b *DockerfileImageBuilder) Build(ctx context.Context) error {
-	buildArgs := append(b.BuildArgs, fmt.Sprintf("--tag=%s", b.temporalId))
+	buildArgs := b.BuildArgs
+	if b.Dockerfile != nil {
+		dockerfilePath, err := b.writeDockerfile()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(dockerfilePath)
+
+		buildArgs = append([]string{fmt.Sprintf("--file=%s", dockerfilePath)}, buildArgs...)
+	}
+
+	if b.localDockerServerRuntime != nil && b.localDockerServerRuntime.DockerfileBuildRuntime != nil {
+		if err := b.localDockerServerRuntime.DockerfileBuildRuntime.BuildDockerfileImage(ctx, b.temporalId, b.CacheID, buildArgs); err != nil {
+			return err
+		}
+	} else {
+		buildArgs := append(buildArgs, fmt.Sprintf("--tag=%s", b.temporalId))
 
-	if err := docker.CliBuild_LiveOutput(ctx, buildArgs...); err != nil {
-		return err
+		if err := docker.CliBuild_LiveOutput(ctx, buildArgs...); err != nil {
+			return err
+		}
 	}
 
 	b.isBuilt = true
@@ -42,6 +72,20 @@ func (b *DockerfileImageBuilder) Build(ctx context.Context) error {
 	return nil
 }
 
+func (b *DockerfileImageBuilder) writeDockerfile() (string, error) {
+	f, err := ioutil.TempFile(werf.GetTmpDir(), "werf-dockerfile-")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporal dockerfile: %s", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b.Dockerfile); err != nil {
+		return "", fmt.Errorf("unable to write temporal dockerfile %s: %s", f.Name(), err)
+	}
+
+	return f.Name(), nil
+}
+
 func (b *DockerfileImageBuilder) Cleanup(ctx context.Context) error {
 	if err := docker.CliRmi(ctx, b.temporalId, "--force"); err != nil {
 		return fmt.Errorf("unable to remove temporal dockerfile image %q: %s", b.temporalId, err)
@@ -263,7 +263,7 @@ func (i *StageImage) Export(ctx context.Context, name string) error {
 
 func (i *StageImage) DockerfileImageBuilder() *DockerfileImageBuilder {
 	if i.dockerfileImageBuilder == nil {
-		i.dockerfileImageBuilder = NewDockerfileImageBuilder()
+		i.dockerfileImageBuilder = NewDockerfileImageBuilder(i.LocalDockerServerRuntime)
 	}
 	return i.dockerfileImageBuilder
 }
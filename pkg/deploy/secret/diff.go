@@ -0,0 +1,157 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type SecretValueChangeType string
+
+const (
+	SecretValueAdded   SecretValueChangeType = "+"
+	SecretValueRemoved SecretValueChangeType = "-"
+	SecretValueChanged SecretValueChangeType = "~"
+)
+
+type SecretValueChange struct {
+	Type     SecretValueChangeType
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+// FlattenSecretValues returns the scalar values of the decoded secret values by the keys like mysql.password or hosts[0]
+func FlattenSecretValues(values map[string]interface{}) map[string]string {
+	res := map[string]string{}
+	flattenSecretValue("", values, res)
+	return res
+}
+
+func flattenSecretValue(key string, value interface{}, res map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && key != "" {
+			res[key] = "{}"
+		}
+
+		for k, nestedValue := range v {
+			nestedKey := k
+			if key != "" {
+				nestedKey = fmt.Sprintf("%s.%s", key, k)
+			}
+			flattenSecretValue(nestedKey, nestedValue, res)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			res[key] = "[]"
+		}
+
+		for ind, nestedValue := range v {
+			flattenSecretValue(fmt.Sprintf("%s[%d]", key, ind), nestedValue, res)
+		}
+	case nil:
+		res[key] = "null"
+	default:
+		res[key] = fmt.Sprintf("%v", v)
+	}
+}
+
+// DiffSecretValues returns the changes of the flattened secret values sorted by the key
+func DiffSecretValues(oldValues, newValues map[string]string) []*SecretValueChange {
+	var res []*SecretValueChange
+
+	for key, oldValue := range oldValues {
+		if newValue, exist := newValues[key]; !exist {
+			res = append(res, &SecretValueChange{Type: SecretValueRemoved, Key: key, OldValue: oldValue})
+		} else if newValue != oldValue {
+			res = append(res, &SecretValueChange{Type: SecretValueChanged, Key: key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for key, newValue := range newValues {
+		if _, exist := oldValues[key]; !exist {
+			res = append(res, &SecretValueChange{Type: SecretValueAdded, Key: key, NewValue: newValue})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+
+	return res
+}
+
+// maxSecretFileLinesDiffSize limits the size of the LCS table (the product of the numbers of the changed lines)
+var maxSecretFileLinesDiffSize = 1 << 20
+
+// DiffSecretFileLines returns the removed and added lines of the decoded secret file prefixed with "- " and "+ ".
+// If the changed part of the file is too large, all its old lines are returned as removed and all new lines as added
+func DiffSecretFileLines(oldData, newData string) []string {
+	oldLines := splitLines(oldData)
+	newLines := splitLines(newData)
+
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[0] == newLines[0] {
+		oldLines, newLines = oldLines[1:], newLines[1:]
+	}
+
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[len(oldLines)-1] == newLines[len(newLines)-1] {
+		oldLines, newLines = oldLines[:len(oldLines)-1], newLines[:len(newLines)-1]
+	}
+
+	if len(oldLines)*len(newLines) > maxSecretFileLinesDiffSize {
+		var res []string
+		for _, line := range oldLines {
+			res = append(res, "- "+line)
+		}
+		for _, line := range newLines {
+			res = append(res, "+ "+line)
+		}
+
+		return res
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var res []string
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			i++
+			j++
+		case j == len(newLines) || (i < len(oldLines) && lcs[i+1][j] >= lcs[i][j+1]):
+			res = append(res, "- "+oldLines[i])
+			i++
+		default:
+			res = append(res, "+ "+newLines[j])
+			j++
+		}
+	}
+
+	return res
+}
+
+func splitLines(data string) []string {
+	if data == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+}
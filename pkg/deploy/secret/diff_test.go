@@ -0,0 +1,67 @@
+package secret
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSecretValues(t *testing.T) {
+	oldValues := FlattenSecretValues(map[string]interface{}{
+		"mysql": map[string]interface{}{
+			"user":     "root",
+			"password": "old",
+			"db":       "app",
+		},
+		"hosts": []interface{}{"a", "b"},
+	})
+
+	newValues := FlattenSecretValues(map[string]interface{}{
+		"mysql": map[string]interface{}{
+			"user":     "root",
+			"password": "new",
+			"port":     3306,
+		},
+		"hosts": []interface{}{"a"},
+	})
+
+	expected := []*SecretValueChange{
+		{Type: SecretValueRemoved, Key: "hosts[1]", OldValue: "b"},
+		{Type: SecretValueRemoved, Key: "mysql.db", OldValue: "app"},
+		{Type: SecretValueChanged, Key: "mysql.password", OldValue: "old", NewValue: "new"},
+		{Type: SecretValueAdded, Key: "mysql.port", NewValue: "3306"},
+	}
+
+	if changes := DiffSecretValues(oldValues, newValues); !reflect.DeepEqual(changes, expected) {
+		for _, change := range changes {
+			t.Logf("%#v", change)
+		}
+		t.Errorf("unexpected changes")
+	}
+}
+
+func TestDiffSecretFileLines(t *testing.T) {
+	lines := DiffSecretFileLines("a\nb\nc\n", "a\nc\nd\n")
+	expected := []string{"- b", "+ d"}
+
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, lines)
+	}
+}
+
+func TestDiffSecretFileLinesLimit(t *testing.T) {
+	defer func(size int) { maxSecretFileLinesDiffSize = size }(maxSecretFileLinesDiffSize)
+	maxSecretFileLinesDiffSize = 4
+
+	// the common first and last lines are not counted
+	lines := DiffSecretFileLines("a\nb\nc\nz\n", "a\nc\nd\nz\n")
+	expected := []string{"- b", "+ d"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, lines)
+	}
+
+	lines = DiffSecretFileLines("a\nb\nc\nz\n", "a\nc\nd\ne\nz\n")
+	expected = []string{"- b", "- c", "+ c", "+ d", "+ e"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, lines)
+	}
+}
@@ -0,0 +1,78 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SecretKeyProvider fetches the secret key from the external source, so the raw key is not stored in the environment
+type SecretKeyProvider interface {
+	Name() string
+	// CacheKey identifies the secret key source, the fetched key is cached in-process by this key
+	CacheKey() string
+	FetchSecretKey() ([]byte, error)
+}
+
+var (
+	secretKeyCache      = map[string][]byte{}
+	secretKeyCacheMutex sync.Mutex
+)
+
+// GetSecretKeyProvider returns the provider configured with the environment variables, nil is returned if the provider is not configured.
+// The providers are checked in the following order: $WERF_SECRET_KEY_COMMAND, $WERF_SECRET_KEY_VAULT_ADDR, $WERF_SECRET_KEY_KMS_URL
+func GetSecretKeyProvider(projectDir string) (SecretKeyProvider, error) {
+	if command := os.Getenv("WERF_SECRET_KEY_COMMAND"); command != "" {
+		return NewCommandSecretKeyProvider(command, projectDir), nil
+	}
+
+	if addr := os.Getenv("WERF_SECRET_KEY_VAULT_ADDR"); addr != "" {
+		path := os.Getenv("WERF_SECRET_KEY_VAULT_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("$WERF_SECRET_KEY_VAULT_PATH is required when $WERF_SECRET_KEY_VAULT_ADDR is specified")
+		}
+
+		token := os.Getenv("WERF_SECRET_KEY_VAULT_TOKEN")
+		if token == "" {
+			token = os.Getenv("VAULT_TOKEN")
+		}
+
+		return NewVaultSecretKeyProvider(addr, path, os.Getenv("WERF_SECRET_KEY_VAULT_FIELD"), token), nil
+	}
+
+	if url := os.Getenv("WERF_SECRET_KEY_KMS_URL"); url != "" {
+		ciphertext, err := getKmsSecretKeyCiphertext(projectDir)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewKmsSecretKeyProvider(url, ciphertext, os.Getenv("WERF_SECRET_KEY_KMS_TOKEN")), nil
+	}
+
+	return nil, nil
+}
+
+// FetchSecretKey fetches the secret key with the provider once per process
+func FetchSecretKey(provider SecretKeyProvider) ([]byte, error) {
+	secretKeyCacheMutex.Lock()
+	defer secretKeyCacheMutex.Unlock()
+
+	if secretKey, hasKey := secretKeyCache[provider.CacheKey()]; hasKey {
+		return secretKey, nil
+	}
+
+	secretKey, err := provider.FetchSecretKey()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch secret key from %s: %s", provider.Name(), err)
+	}
+
+	secretKey = []byte(strings.TrimSpace(string(secretKey)))
+	if len(secretKey) == 0 {
+		return nil, fmt.Errorf("unable to fetch secret key from %s: empty secret key", provider.Name())
+	}
+
+	secretKeyCache[provider.CacheKey()] = secretKey
+
+	return secretKey, nil
+}
@@ -0,0 +1,54 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CommandSecretKeyProvider runs the helper command in the shell, the secret key is read from the command stdout
+type CommandSecretKeyProvider struct {
+	Command string
+	Dir     string
+}
+
+func NewCommandSecretKeyProvider(command, dir string) *CommandSecretKeyProvider {
+	return &CommandSecretKeyProvider{Command: command, Dir: dir}
+}
+
+func (p *CommandSecretKeyProvider) Name() string {
+	return "$WERF_SECRET_KEY_COMMAND"
+}
+
+func (p *CommandSecretKeyProvider) CacheKey() string {
+	return fmt.Sprintf("command:%s:%s", p.Dir, p.Command)
+}
+
+func (p *CommandSecretKeyProvider) FetchSecretKey() ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", p.Command)
+	} else {
+		cmd = exec.Command("sh", "-c", p.Command)
+	}
+
+	cmd.Dir = p.Dir
+	cmd.Env = os.Environ()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return nil, fmt.Errorf("command %q failed: %s\n%s", p.Command, err, output)
+		}
+		return nil, fmt.Errorf("command %q failed: %s", p.Command, err)
+	}
+
+	return stdout.Bytes(), nil
+}
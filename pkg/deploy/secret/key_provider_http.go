@@ -0,0 +1,188 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/werf/werf/pkg/util"
+)
+
+const (
+	defaultVaultSecretKeyField = "secret_key"
+
+	// KmsSecretKeyCiphertextFileName is the file in the project root with the secret key encrypted by the KMS
+	KmsSecretKeyCiphertextFileName = ".werf_secret_key.encrypted"
+)
+
+var secretKeyHttpClient = &http.Client{Timeout: 30 * time.Second}
+
+// VaultSecretKeyProvider reads the secret key field of the HashiCorp Vault compatible KV secret (both KV v1 and v2 engines are supported)
+type VaultSecretKeyProvider struct {
+	Addr  string
+	Path  string
+	Field string
+	Token string
+}
+
+func NewVaultSecretKeyProvider(addr, path, field, token string) *VaultSecretKeyProvider {
+	if field == "" {
+		field = defaultVaultSecretKeyField
+	}
+
+	return &VaultSecretKeyProvider{Addr: addr, Path: path, Field: field, Token: token}
+}
+
+func (p *VaultSecretKeyProvider) Name() string {
+	return fmt.Sprintf("vault %s", p.url())
+}
+
+func (p *VaultSecretKeyProvider) CacheKey() string {
+	return fmt.Sprintf("vault:%s#%s", p.url(), p.Field)
+}
+
+func (p *VaultSecretKeyProvider) url() string {
+	return fmt.Sprintf("%s/v1/%s", strings.TrimRight(p.Addr, "/"), strings.TrimLeft(p.Path, "/"))
+}
+
+func (p *VaultSecretKeyProvider) FetchSecretKey() ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, p.url(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Token != "" {
+		req.Header.Set("X-Vault-Token", p.Token)
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := doSecretKeyRequest(req, &response); err != nil {
+		return nil, err
+	}
+
+	data := response.Data
+	// KV v2 engine wraps the secret data and metadata
+	if nestedData, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nestedData
+		}
+	}
+
+	value, ok := data[p.Field].(string)
+	if !ok {
+		return nil, fmt.Errorf("string field %q not found in the secret", p.Field)
+	}
+
+	return []byte(value), nil
+}
+
+// KmsSecretKeyProvider decrypts the secret key encrypted by the KMS: the ciphertext is sent to the decrypt endpoint,
+// {"ciphertext": "..."} is requested and {"plaintext": "<base64>"} is expected in the response
+type KmsSecretKeyProvider struct {
+	URL        string
+	Ciphertext string
+	Token      string
+}
+
+func NewKmsSecretKeyProvider(url, ciphertext, token string) *KmsSecretKeyProvider {
+	return &KmsSecretKeyProvider{URL: url, Ciphertext: ciphertext, Token: token}
+}
+
+func (p *KmsSecretKeyProvider) Name() string {
+	return fmt.Sprintf("kms %s", p.URL)
+}
+
+func (p *KmsSecretKeyProvider) CacheKey() string {
+	return fmt.Sprintf("kms:%s:%s", p.URL, p.Ciphertext)
+}
+
+func (p *KmsSecretKeyProvider) FetchSecretKey() ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"ciphertext": p.Ciphertext})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.Token))
+	}
+
+	var response struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := doSecretKeyRequest(req, &response); err != nil {
+		return nil, err
+	}
+
+	secretKey, err := base64.StdEncoding.DecodeString(response.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode plaintext: %s", err)
+	}
+
+	return secretKey, nil
+}
+
+// getKmsSecretKeyCiphertext returns the ciphertext from $WERF_SECRET_KEY_KMS_CIPHERTEXT or .werf_secret_key.encrypted file
+func getKmsSecretKeyCiphertext(projectDir string) (string, error) {
+	if ciphertext := os.Getenv("WERF_SECRET_KEY_KMS_CIPHERTEXT"); ciphertext != "" {
+		return ciphertext, nil
+	}
+
+	if projectDir != "" {
+		path := filepath.Join(projectDir, KmsSecretKeyCiphertextFileName)
+
+		exist, err := util.FileExists(path)
+		if err != nil {
+			return "", err
+		}
+
+		if exist {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+
+	return "", fmt.Errorf("secret key ciphertext not found in: '$WERF_SECRET_KEY_KMS_CIPHERTEXT', '%s'", KmsSecretKeyCiphertextFileName)
+}
+
+func doSecretKeyRequest(req *http.Request, response interface{}) error {
+	resp, err := secretKeyHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: unexpected status %s: %s", req.Method, req.URL, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("unable to parse response: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package secret
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+)
+
+const testSecretKey = "bfd966688bbe64c1986e356be2d6ba0a"
+
+func TestVaultSecretKeyProvider(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/project":
+			_, _ = w.Write([]byte(`{"data": {"data": {"secret_key": "` + testSecretKey + `"}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/project":
+			_, _ = w.Write([]byte(`{"data": {"key": "` + testSecretKey + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	defer resetSecretKeyCache()
+
+	for _, provider := range []*VaultSecretKeyProvider{
+		NewVaultSecretKeyProvider(srv.URL, "secret/data/project", "", "token"),
+		NewVaultSecretKeyProvider(srv.URL+"/", "/kv/project", "key", "token"),
+	} {
+		for i := 0; i < 2; i++ {
+			secretKey, err := FetchSecretKey(provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(secretKey) != testSecretKey {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", testSecretKey, secretKey)
+			}
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("expected the secret key to be fetched once per provider, got %d requests", requests)
+	}
+
+	if _, err := FetchSecretKey(NewVaultSecretKeyProvider(srv.URL, "secret/data/other", "", "invalid")); err == nil {
+		t.Errorf("expected error for invalid token")
+	}
+}
+
+func TestKmsSecretKeyProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Ciphertext string `json:"ciphertext"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || r.Header.Get("Authorization") != "Bearer token" || request.Ciphertext != "encrypted" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"plaintext": base64.StdEncoding.EncodeToString([]byte(testSecretKey))})
+	}))
+	defer srv.Close()
+	defer resetSecretKeyCache()
+
+	secretKey, err := FetchSecretKey(NewKmsSecretKeyProvider(srv.URL, "encrypted", "token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secretKey) != testSecretKey {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", testSecretKey, secretKey)
+	}
+
+	if _, err := FetchSecretKey(NewKmsSecretKeyProvider(srv.URL, "other", "token")); err == nil {
+		t.Errorf("expected error for invalid ciphertext")
+	}
+}
+
+func TestGetSecretKey_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	defer resetSecretKeyCache()
+	defer os.Unsetenv("WERF_SECRET_KEY_COMMAND")
+
+	if err := os.Setenv("WERF_SECRET_KEY_COMMAND", "echo "+testSecretKey); err != nil {
+		t.Fatal(err)
+	}
+
+	secretKey, err := GetSecretKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secretKey) != testSecretKey {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", testSecretKey, secretKey)
+	}
+
+	if err := os.Setenv("WERF_SECRET_KEY_COMMAND", "echo error >&2; exit 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetSecretKey(""); err == nil {
+		t.Errorf("expected error for failed command")
+	}
+}
+
+func resetSecretKeyCache() {
+	secretKeyCacheMutex.Lock()
+	defer secretKeyCacheMutex.Unlock()
+
+	secretKeyCache = map[string][]byte{}
+}
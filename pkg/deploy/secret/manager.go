@@ -50,6 +50,36 @@ func GetSecretKey(projectDir string) ([]byte, error) {
 	if len(secretKey) == 0 {
 		notFoundIn = append(notFoundIn, "$WERF_SECRET_KEY")
 
+		provider, err := GetSecretKeyProvider(projectDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if provider != nil {
+			return FetchSecretKey(provider)
+		}
+
+		// the secret key is wrapped for each recipient public key and unwrapped with the user identity
+		if projectDir != "" {
+			recipients, err := LoadSecretRecipients(projectDir)
+			if err != nil {
+				return nil, err
+			}
+
+			if recipients != nil {
+				identity, err := GetSecretIdentity()
+				if err != nil {
+					return nil, err
+				}
+
+				if identity != nil {
+					return recipients.UnwrapSecretKey(identity)
+				}
+
+				notFoundIn = append(notFoundIn, "$WERF_SECRET_IDENTITY", GetSecretIdentityFilePath())
+			}
+		}
+
 		var werfSecretKeyPath string
 
 		if projectDir != "" {
@@ -93,6 +123,78 @@ func GetSecretKey(projectDir string) ([]byte, error) {
 	return secretKey, nil
 }
 
+// GetProjectSecretKey returns the secret key from $WERF_SECRET_KEY or .werf_secret_key file of the project,
+// unlike GetSecretKey the key is neither fetched from the provider nor unwrapped for the recipients, nil is returned if the key is not found
+func GetProjectSecretKey(projectDir string) ([]byte, error) {
+	if secretKey := os.Getenv("WERF_SECRET_KEY"); secretKey != "" {
+		return []byte(secretKey), nil
+	}
+
+	path := filepath.Join(projectDir, ".werf_secret_key")
+
+	exist, err := util.FileExists(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exist {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if secretKey := strings.TrimSpace(string(data)); secretKey != "" {
+		return []byte(secretKey), nil
+	}
+
+	return nil, nil
+}
+
+// GetEnvSecretKey returns the secret key of the environment from $WERF_ENV_SECRET_KEY_<ENV> or .werf_secret_key.<env> file,
+// the project secret key is returned if the environment has no separate key
+func GetEnvSecretKey(projectDir, env string) ([]byte, error) {
+	if secretKey := os.Getenv(GetEnvSecretKeyEnvName(env)); secretKey != "" {
+		return []byte(secretKey), nil
+	}
+
+	if projectDir != "" {
+		path := filepath.Join(projectDir, fmt.Sprintf(".werf_secret_key.%s", env))
+
+		exist, err := util.FileExists(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if exist {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+
+			if secretKey := strings.TrimSpace(string(data)); secretKey != "" {
+				return []byte(secretKey), nil
+			}
+		}
+	}
+
+	return GetSecretKey(projectDir)
+}
+
+// GetEnvSecretKeyEnvName returns the name of the environment variable with the secret key of the environment: WERF_ENV_SECRET_KEY_PRODUCTION for production
+func GetEnvSecretKeyEnvName(env string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, env)
+
+	return fmt.Sprintf("WERF_ENV_SECRET_KEY_%s", strings.ToUpper(name))
+}
+
 func NewManager(key []byte) (Manager, error) {
 	ss, err := secret.NewSecret(key)
 	if err != nil {
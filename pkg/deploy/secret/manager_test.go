@@ -0,0 +1,63 @@
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestGetEnvSecretKey(t *testing.T) {
+	projectDir, err := ioutil.TempDir("", "werf-secret-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(projectDir)
+
+	if err := werf.Init(projectDir, filepath.Join(projectDir, ".werf")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(projectDir, ".werf_secret_key"), []byte("common\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(projectDir, ".werf_secret_key.production"), []byte("production\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Unsetenv("WERF_ENV_SECRET_KEY_REVIEW_1")
+	if err := os.Setenv("WERF_ENV_SECRET_KEY_REVIEW_1", "review"); err != nil {
+		t.Fatal(err)
+	}
+
+	for env, expected := range map[string]string{
+		"production": "production",
+		"review-1":   "review",
+		"staging":    "common",
+	} {
+		secretKey, err := GetEnvSecretKey(projectDir, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(secretKey) != expected {
+			t.Errorf("%s:\n[EXPECTED]: %s\n[GOT]: %s", env, expected, secretKey)
+		}
+	}
+}
+
+func TestGetEnvSecretKeyEnvName(t *testing.T) {
+	// the names must not collide with the secret key provider variables, e.g. $WERF_SECRET_KEY_COMMAND
+	for env, expected := range map[string]string{
+		"production": "WERF_ENV_SECRET_KEY_PRODUCTION",
+		"review-1":   "WERF_ENV_SECRET_KEY_REVIEW_1",
+		"command":    "WERF_ENV_SECRET_KEY_COMMAND",
+	} {
+		if name := GetEnvSecretKeyEnvName(env); name != expected {
+			t.Errorf("%s:\n[EXPECTED]: %s\n[GOT]: %s", env, expected, name)
+		}
+	}
+}
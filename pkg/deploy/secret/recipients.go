@@ -0,0 +1,174 @@
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// SecretRecipientsFileName is the file in the project root with the secret key wrapped for each recipient public key
+const SecretRecipientsFileName = ".werf_secret_recipients.yaml"
+
+type SecretRecipient struct {
+	Name       string `yaml:"name"`
+	PublicKey  string `yaml:"publicKey"`
+	WrappedKey string `yaml:"wrappedKey"`
+}
+
+type SecretRecipients struct {
+	Recipients []*SecretRecipient `yaml:"recipients"`
+}
+
+func GetSecretRecipientsFilePath(projectDir string) string {
+	return filepath.Join(projectDir, SecretRecipientsFileName)
+}
+
+// LoadSecretRecipients returns nil if the project has no secret recipients file
+func LoadSecretRecipients(projectDir string) (*SecretRecipients, error) {
+	path := GetSecretRecipientsFilePath(projectDir)
+
+	exist, err := util.FileExists(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exist {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := &SecretRecipients{}
+	if err := yaml.UnmarshalStrict(data, recipients); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", path, err)
+	}
+
+	return recipients, nil
+}
+
+func (r *SecretRecipients) Save(projectDir string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(GetSecretRecipientsFilePath(projectDir), data, 0644)
+}
+
+func (r *SecretRecipients) GetRecipient(name string) *SecretRecipient {
+	for _, recipient := range r.Recipients {
+		if recipient.Name == name {
+			return recipient
+		}
+	}
+
+	return nil
+}
+
+// UnwrapSecretKey returns the secret key wrapped for the identity recipient
+func (r *SecretRecipients) UnwrapSecretKey(identity *secret.X25519Identity) ([]byte, error) {
+	for _, recipient := range r.Recipients {
+		if recipient.PublicKey == identity.Recipient() {
+			key, err := identity.UnwrapKey(recipient.WrappedKey)
+			if err != nil {
+				return nil, fmt.Errorf("unable to unwrap secret key of recipient %q: %s", recipient.Name, err)
+			}
+
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("identity %s is not a secret recipient: ask one of the recipients to add it with werf helm secret recipients add", identity.Recipient())
+}
+
+// AddRecipient wraps the secret key for the recipient public key, only the wrapped secret key is changed, secret files are not re-encrypted
+func (r *SecretRecipients) AddRecipient(name, publicKey string, secretKey []byte) error {
+	if r.GetRecipient(name) != nil {
+		return fmt.Errorf("recipient %q already exists", name)
+	}
+
+	for _, recipient := range r.Recipients {
+		if recipient.PublicKey == publicKey {
+			return fmt.Errorf("public key %s is already used by recipient %q", publicKey, recipient.Name)
+		}
+	}
+
+	wrappedKey, err := secret.WrapKey(secretKey, publicKey)
+	if err != nil {
+		return err
+	}
+
+	r.Recipients = append(r.Recipients, &SecretRecipient{Name: name, PublicKey: publicKey, WrappedKey: wrappedKey})
+
+	return nil
+}
+
+func (r *SecretRecipients) RemoveRecipient(name string) error {
+	for ind, recipient := range r.Recipients {
+		if recipient.Name == name {
+			r.Recipients = append(r.Recipients[:ind], r.Recipients[ind+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("recipient %q not found", name)
+}
+
+// RewrapSecretKey wraps the new secret key for all recipients, the private keys of the recipients are not required
+func (r *SecretRecipients) RewrapSecretKey(secretKey []byte) error {
+	for _, recipient := range r.Recipients {
+		wrappedKey, err := secret.WrapKey(secretKey, recipient.PublicKey)
+		if err != nil {
+			return fmt.Errorf("unable to wrap secret key for recipient %q: %s", recipient.Name, err)
+		}
+
+		recipient.WrappedKey = wrappedKey
+	}
+
+	return nil
+}
+
+// GetSecretIdentity returns the identity from $WERF_SECRET_IDENTITY or ~/.werf/secret_identity file, nil is returned if the identity is not found
+func GetSecretIdentity() (*secret.X25519Identity, error) {
+	data := os.Getenv("WERF_SECRET_IDENTITY")
+	if data == "" {
+		path := GetSecretIdentityFilePath()
+
+		exist, err := util.FileExists(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exist {
+			return nil, nil
+		}
+
+		fileData, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		data = string(fileData)
+	}
+
+	identity, err := secret.ParseX25519Identity(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse secret identity: %s", err)
+	}
+
+	return identity, nil
+}
+
+func GetSecretIdentityFilePath() string {
+	return filepath.Join(werf.GetHomeDir(), "secret_identity")
+}
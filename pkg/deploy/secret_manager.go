@@ -2,6 +2,7 @@ package deploy
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -38,3 +39,34 @@ func GetSafeSecretManager(ctx context.Context, projectDir, helmChartDir string,
 		return secret.NewSafeManager()
 	}
 }
+
+// GetSafeEnvSecretManager returns the manager for the secret values and files of the environment (.helm/secret-values.<env>.yaml and .helm/secret/<env>/),
+// nil is returned if the environment has no secrets
+func GetSafeEnvSecretManager(ctx context.Context, projectDir, helmChartDir, env string, ignoreSecretKey bool) (secret.Manager, error) {
+	if env == "" {
+		return nil, nil
+	}
+
+	isSecretsExists := false
+	if _, err := os.Stat(filepath.Join(helmChartDir, werf_chart.SecretDirName, env)); !os.IsNotExist(err) {
+		isSecretsExists = true
+	}
+	if _, err := os.Stat(filepath.Join(helmChartDir, werf_chart.GetEnvSecretValuesFileName(env))); !os.IsNotExist(err) {
+		isSecretsExists = true
+	}
+
+	if !isSecretsExists {
+		return nil, nil
+	}
+
+	if ignoreSecretKey {
+		return secret.NewSafeManager()
+	}
+
+	key, err := secret.GetEnvSecretKey(projectDir, env)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get secret key of environment %q: %s", env, err)
+	}
+
+	return secret.NewManager(key)
+}
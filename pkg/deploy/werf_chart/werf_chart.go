@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"unicode"
@@ -37,6 +38,13 @@ type WerfChartOptions struct {
 
 	LockManager    *lock_manager.LockManager
 	SecretsManager secret.Manager
+	// EnvSecretsManager decodes .helm/secret-values.<env>.yaml and .helm/secret/<env>/ files, SecretsManager is used if not set
+	EnvSecretsManager secret.Manager
+}
+
+// GetEnvSecretValuesFileName returns the name of the secret values file of the environment, which is layered over the default secret values file
+func GetEnvSecretValuesFileName(env string) string {
+	return fmt.Sprintf("secret-values.%s.yaml", env)
 }
 
 func NewWerfChart(opts WerfChartOptions) *WerfChart {
@@ -50,9 +58,11 @@ func NewWerfChart(opts WerfChartOptions) *WerfChart {
 			nil,
 		),
 
-		LockManager:    opts.LockManager,
-		SecretsManager: opts.SecretsManager,
+		LockManager:       opts.LockManager,
+		SecretsManager:    opts.SecretsManager,
+		EnvSecretsManager: opts.EnvSecretsManager,
 
+		secretFiles:            make(map[string]*secretFile, 0),
 		decodedSecretFilesData: make(map[string]string, 0),
 	}
 
@@ -66,14 +76,17 @@ type WerfChart struct {
 
 	ReleaseName      string
 	ChartDir         string
+	Env              string
 	SecretValueFiles []string
 
 	ExtraAnnotationsAndLabelsPostRenderer *helm.ExtraAnnotationsAndLabelsPostRenderer
 	LockManager                           *lock_manager.LockManager
 	SecretsManager                        secret.Manager
+	EnvSecretsManager                     secret.Manager
 
 	chartMetadataFromWerfConfig *chart.Metadata
 	decodedSecretValues         map[string]interface{}
+	secretFiles                 map[string]*secretFile
 	decodedSecretFilesData      map[string]string
 	secretValuesToMask          []string
 	serviceValues               map[string]interface{}
@@ -84,18 +97,35 @@ func (wc *WerfChart) SetupChart(c *chart.Chart) error {
 	return nil
 }
 
+// secretFile is decoded on the first werf_secret_file call, so the files of other environments encrypted with other keys are not decoded
+type secretFile struct {
+	Path    string
+	Manager secret.Manager
+}
+
 func (wc *WerfChart) AfterLoad() error {
-	secretValuesFiles := []string{}
+	type secretValuesFile struct {
+		Path    string
+		Manager secret.Manager
+	}
+
+	secretValuesFiles := []secretValuesFile{}
 	defaultSecretValuesFile := filepath.Join(wc.ChartDir, DefaultSecretValuesFileName)
 	if _, err := os.Stat(defaultSecretValuesFile); !os.IsNotExist(err) {
-		secretValuesFiles = append(secretValuesFiles, defaultSecretValuesFile)
+		secretValuesFiles = append(secretValuesFiles, secretValuesFile{defaultSecretValuesFile, wc.SecretsManager})
+	}
+	if wc.Env != "" {
+		envSecretValuesFile := filepath.Join(wc.ChartDir, GetEnvSecretValuesFileName(wc.Env))
+		if _, err := os.Stat(envSecretValuesFile); !os.IsNotExist(err) {
+			secretValuesFiles = append(secretValuesFiles, secretValuesFile{envSecretValuesFile, wc.getEnvSecretsManager()})
+		}
 	}
 	for _, path := range wc.SecretValueFiles {
-		secretValuesFiles = append(secretValuesFiles, path)
+		secretValuesFiles = append(secretValuesFiles, secretValuesFile{path, wc.SecretsManager})
 	}
-	for _, path := range secretValuesFiles {
-		if decodedValues, err := DecodeSecretValuesFile(path, wc.SecretsManager); err != nil {
-			return fmt.Errorf("unable to decode secret values file %q: %s", path, err)
+	for _, file := range secretValuesFiles {
+		if decodedValues, err := DecodeSecretValuesFile(file.Path, file.Manager); err != nil {
+			return fmt.Errorf("unable to decode secret values file %q: %s", file.Path, err)
 		} else {
 			wc.decodedSecretValues = chartutil.CoalesceTables(decodedValues, wc.decodedSecretValues)
 			wc.secretValuesToMask = append(wc.secretValuesToMask, secretvalues.ExtractSecretValuesFromMap(decodedValues)...)
@@ -103,37 +133,18 @@ func (wc *WerfChart) AfterLoad() error {
 	}
 
 	secretDir := filepath.Join(wc.ChartDir, SecretDirName)
-	if _, err := os.Stat(secretDir); !os.IsNotExist(err) {
-		if err := filepath.Walk(secretDir, func(path string, info os.FileInfo, accessErr error) error {
-			if accessErr != nil {
-				return fmt.Errorf("error accessing file %s: %s", path, accessErr)
-			}
-
-			if info.Mode().IsDir() {
-				return nil
-			}
-
-			data, err := ioutil.ReadFile(path)
-			if err != nil {
-				return fmt.Errorf("error reading file %s: %s", path, err)
-			}
-
-			decodedData, err := wc.SecretsManager.Decrypt([]byte(strings.TrimRightFunc(string(data), unicode.IsSpace)))
-			if err != nil {
-				return fmt.Errorf("error decoding %s: %s", path, err)
-			}
+	if err := wc.loadSecretFiles(secretDir, "", wc.SecretsManager); err != nil {
+		return err
+	}
 
-			relativePath, err := filepath.Rel(secretDir, path)
-			if err != nil {
-				panic(err)
+	// the files of the environment are still available by the path with the environment directory (<env>/file),
+	// and also override the common files with the same relative path
+	if wc.Env != "" {
+		envSecretDir := filepath.Join(secretDir, wc.Env)
+		for _, relativePathPrefix := range []string{wc.Env + "/", ""} {
+			if err := wc.loadSecretFiles(envSecretDir, relativePathPrefix, wc.getEnvSecretsManager()); err != nil {
+				return err
 			}
-
-			wc.decodedSecretFilesData[filepath.ToSlash(relativePath)] = string(decodedData)
-			wc.secretValuesToMask = append(wc.secretValuesToMask, string(decodedData))
-
-			return nil
-		}); err != nil {
-			return fmt.Errorf("unable to read secrets from %s directory: %s", secretDir, err)
 		}
 	}
 
@@ -149,6 +160,74 @@ func (wc *WerfChart) AfterLoad() error {
 	return nil
 }
 
+func (wc *WerfChart) loadSecretFiles(secretDir, relativePathPrefix string, m secret.Manager) error {
+	if _, err := os.Stat(secretDir); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := filepath.Walk(secretDir, func(path string, info os.FileInfo, accessErr error) error {
+		if accessErr != nil {
+			return fmt.Errorf("error accessing file %s: %s", path, accessErr)
+		}
+
+		if info.Mode().IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(secretDir, path)
+		if err != nil {
+			panic(err)
+		}
+
+		wc.secretFiles[relativePathPrefix+filepath.ToSlash(relativePath)] = &secretFile{Path: path, Manager: m}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to read secrets from %s directory: %s", secretDir, err)
+	}
+
+	return nil
+}
+
+func (wc *WerfChart) getDecodedSecretFileData(secretRelativePath string) (string, error) {
+	if decodedData, ok := wc.decodedSecretFilesData[secretRelativePath]; ok {
+		return decodedData, nil
+	}
+
+	file, ok := wc.secretFiles[secretRelativePath]
+	if !ok {
+		var secretFiles []string
+		for key := range wc.secretFiles {
+			secretFiles = append(secretFiles, key)
+		}
+		sort.Strings(secretFiles)
+
+		return "", fmt.Errorf("secret file '%s' not found, you may use one of the following: '%s'", secretRelativePath, strings.Join(secretFiles, "', '"))
+	}
+
+	data, err := ioutil.ReadFile(file.Path)
+	if err != nil {
+		return "", fmt.Errorf("error reading file %s: %s", file.Path, err)
+	}
+
+	decodedData, err := file.Manager.Decrypt([]byte(strings.TrimRightFunc(string(data), unicode.IsSpace)))
+	if err != nil {
+		return "", fmt.Errorf("error decoding %s: %s", file.Path, err)
+	}
+
+	wc.decodedSecretFilesData[secretRelativePath] = string(decodedData)
+	wc.secretValuesToMask = append(wc.secretValuesToMask, string(decodedData))
+
+	return string(decodedData), nil
+}
+
+func (wc *WerfChart) getEnvSecretsManager() secret.Manager {
+	if wc.EnvSecretsManager != nil {
+		return wc.EnvSecretsManager
+	}
+	return wc.SecretsManager
+}
+
 func (wc *WerfChart) MakeValues(inputVals map[string]interface{}) (map[string]interface{}, error) {
 	vals := make(map[string]interface{})
 	chartutil.CoalesceTables(vals, wc.serviceValues) // NOTE: service values will not be saved into the marshalled release
@@ -163,18 +242,7 @@ func (wc *WerfChart) SetupTemplateFuncs(t *template.Template, funcMap template.F
 			return "", fmt.Errorf("expected relative secret file path, given path %v", secretRelativePath)
 		}
 
-		decodedData, ok := wc.decodedSecretFilesData[secretRelativePath]
-
-		if !ok {
-			var secretFiles []string
-			for key := range wc.decodedSecretFilesData {
-				secretFiles = append(secretFiles, key)
-			}
-
-			return "", fmt.Errorf("secret file '%s' not found, you may use one of the following: '%s'", secretRelativePath, strings.Join(secretFiles, "', '"))
-		}
-
-		return decodedData, nil
+		return wc.getDecodedSecretFileData(secretRelativePath)
 	}
 
 	helmIncludeFunc := funcMap["include"].(func(name string, data interface{}) (string, error))
@@ -208,6 +276,8 @@ func (wc *WerfChart) SetWerfConfig(werfConfig *config.WerfConfig) error {
 }
 
 func (wc *WerfChart) SetEnv(env string) error {
+	wc.Env = env
+
 	wc.ExtraAnnotationsAndLabelsPostRenderer.Add(map[string]string{
 		"project.werf.io/env": env,
 	}, nil)
@@ -0,0 +1,182 @@
+package werf_chart
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+
+	"github.com/werf/werf/pkg/deploy/secret"
+)
+
+func newTestSecretsManager(t *testing.T) secret.Manager {
+	key, err := secret.GenerateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := secret.NewManager(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestSecretFile(t *testing.T, m secret.Manager, path, data string) {
+	encodedData, err := m.Encrypt([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, path, append(encodedData, '\n'))
+}
+
+func writeTestSecretValuesFile(t *testing.T, m secret.Manager, path, data string) {
+	encodedData, err := m.EncryptYamlData([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, path, encodedData)
+}
+
+func newTestWerfChart(t *testing.T, chartDir, env string, m, envM secret.Manager) *WerfChart {
+	wc := NewWerfChart(WerfChartOptions{
+		ChartDir:          chartDir,
+		SecretsManager:    m,
+		EnvSecretsManager: envM,
+	})
+
+	if err := wc.SetEnv(env); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wc.SetupChart(&chart.Chart{Metadata: &chart.Metadata{Name: "test"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wc.AfterLoad(); err != nil {
+		t.Fatal(err)
+	}
+
+	return wc
+}
+
+func TestWerfChartEnvSecretValues(t *testing.T) {
+	chartDir, err := ioutil.TempDir("", "werf-chart-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(chartDir)
+
+	m := newTestSecretsManager(t)
+	productionM := newTestSecretsManager(t)
+
+	writeTestSecretValuesFile(t, m, filepath.Join(chartDir, DefaultSecretValuesFileName), "db:\n  user: app\n  password: common\n")
+	writeTestSecretValuesFile(t, productionM, filepath.Join(chartDir, GetEnvSecretValuesFileName("production")), "db:\n  password: production\n")
+
+	for env, expected := range map[string]map[string]interface{}{
+		"":           {"user": "app", "password": "common"},
+		"production": {"user": "app", "password": "production"},
+		"staging":    {"user": "app", "password": "common"},
+	} {
+		wc := newTestWerfChart(t, chartDir, env, m, productionM)
+
+		vals, err := wc.MakeValues(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(vals["db"], expected) {
+			t.Errorf("env %q: expected db values %v, got %v", env, expected, vals["db"])
+		}
+	}
+}
+
+func TestWerfChartEnvSecretFiles(t *testing.T) {
+	chartDir, err := ioutil.TempDir("", "werf-chart-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(chartDir)
+
+	m := newTestSecretsManager(t)
+	productionM := newTestSecretsManager(t)
+	stagingM := newTestSecretsManager(t)
+
+	secretDir := filepath.Join(chartDir, SecretDirName)
+	writeTestSecretFile(t, m, filepath.Join(secretDir, "tls.key"), "common-tls")
+	writeTestSecretFile(t, m, filepath.Join(secretDir, "ca.crt"), "common-ca")
+	writeTestSecretFile(t, productionM, filepath.Join(secretDir, "production", "tls.key"), "production-tls")
+	writeTestSecretFile(t, stagingM, filepath.Join(secretDir, "staging", "tls.key"), "staging-tls")
+
+	wc := newTestWerfChart(t, chartDir, "production", m, productionM)
+
+	for secretRelativePath, expected := range map[string]string{
+		"tls.key":            "production-tls",
+		"production/tls.key": "production-tls",
+		"ca.crt":             "common-ca",
+	} {
+		data, err := wc.getDecodedSecretFileData(secretRelativePath)
+		if err != nil {
+			t.Fatalf("%s: %s", secretRelativePath, err)
+		}
+
+		if data != expected {
+			t.Errorf("%s: expected %q, got %q", secretRelativePath, expected, data)
+		}
+	}
+
+	// the file of another environment is encrypted with another key and fails only when it is used
+	if _, err := wc.getDecodedSecretFileData("staging/tls.key"); err == nil {
+		t.Errorf("staging/tls.key: expected decoding error")
+	}
+
+	if _, err := wc.getDecodedSecretFileData("absent.key"); err == nil {
+		t.Errorf("absent.key: expected not found error")
+	}
+}
+
+func TestWerfChartSecretFilesWithoutEnv(t *testing.T) {
+	chartDir, err := ioutil.TempDir("", "werf-chart-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(chartDir)
+
+	m := newTestSecretsManager(t)
+
+	secretDir := filepath.Join(chartDir, SecretDirName)
+	writeTestSecretFile(t, m, filepath.Join(secretDir, "tls.key"), "common-tls")
+	writeTestSecretFile(t, m, filepath.Join(secretDir, "production", "tls.key"), "production-tls")
+
+	wc := newTestWerfChart(t, chartDir, "", m, nil)
+
+	for secretRelativePath, expected := range map[string]string{
+		"tls.key":            "common-tls",
+		"production/tls.key": "production-tls",
+	} {
+		data, err := wc.getDecodedSecretFileData(secretRelativePath)
+		if err != nil {
+			t.Fatalf("%s: %s", secretRelativePath, err)
+		}
+
+		if data != expected {
+			t.Errorf("%s: expected %q, got %q", secretRelativePath, expected, data)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package docker
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"strings"
 	"time"
@@ -60,6 +62,21 @@ func ImageInspect(ctx context.Context, ref string) (*types.ImageInspect, error)
 	return &inspect, nil
 }
 
+func ImageSave(ctx context.Context, refs ...string) (io.ReadCloser, error) {
+	return apiCli(ctx).ImageSave(ctx, refs)
+}
+
+func ImageLoad(ctx context.Context, input io.Reader) error {
+	response, err := apiCli(ctx).ImageLoad(ctx, input, true)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, err = io.Copy(ioutil.Discard, response.Body)
+	return err
+}
+
 func doCliPull(c command.Cli, args ...string) error {
 	return prepareCliCmd(image.NewPullCommand(c), args...).Execute()
 }
@@ -3,6 +3,7 @@ package docker_registry
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -12,7 +13,11 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 
 	"github.com/werf/logboek"
 
@@ -111,20 +116,27 @@ func (api *api) GetRepoImage(_ context.Context, reference string) (*image.Info,
 		}
 	}
 
-	parsedReference, err := name.NewTag(reference, api.parseReferenceOptions()...)
+	parsedReference, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
 		return nil, err
 	}
 
+	var tag string
+	if parsedTag, ok := parsedReference.(name.Tag); ok {
+		tag = parsedTag.TagStr()
+	}
+
 	repoImage := &image.Info{
-		Name:       reference,
-		Repository: strings.Join([]string{parsedReference.RegistryStr(), parsedReference.RepositoryStr()}, "/"),
-		ID:         manifest.Config.Digest.String(),
-		Tag:        parsedReference.TagStr(),
-		RepoDigest: digest.String(),
-		ParentID:   configFile.Config.Image,
-		Labels:     configFile.Config.Labels,
-		Size:       totalSize,
+		Name:         reference,
+		Repository:   strings.Join([]string{parsedReference.Context().RegistryStr(), parsedReference.Context().RepositoryStr()}, "/"),
+		ID:           manifest.Config.Digest.String(),
+		Tag:          tag,
+		RepoDigest:   digest.String(),
+		ParentID:     configFile.Config.Image,
+		Labels:       configFile.Config.Labels,
+		Size:         totalSize,
+		OS:           configFile.OS,
+		Architecture: configFile.Architecture,
 	}
 
 	repoImage.SetCreatedAtUnix(configFile.Created.Unix())
@@ -146,6 +158,25 @@ func (api *api) list(reference string) ([]string, error) {
 	return tags, nil
 }
 
+// GetRepoImageManifestDigest returns the digest of the image manifest or the manifest list by the reference
+func (api *api) GetRepoImageManifestDigest(ctx context.Context, reference string) (string, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return "", fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	desc, err := remote.Head(ref,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		return "", fmt.Errorf("reading manifest %q: %v", ref, err)
+	}
+
+	return desc.Digest.String(), nil
+}
+
 func (api *api) deleteImageByReference(reference string) error {
 	r, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
@@ -160,11 +191,319 @@ func (api *api) deleteImageByReference(reference string) error {
 }
 
 func (api *api) PushImage(ctx context.Context, reference string, opts *PushImageOptions) error {
+	return doWithRetries(ctx, "publishing", func() error {
+		return api.pushImage(ctx, reference, opts)
+	})
+}
+
+func (api *api) pushImage(_ context.Context, reference string, opts *PushImageOptions) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	labels := map[string]string{}
+	if opts != nil {
+		labels = opts.Labels
+	}
+
+	img := container_registry_extensions.NewManifestOnlyImage(labels)
+
+	oldDefaultTransport := http.DefaultTransport
+	http.DefaultTransport = api.getHttpTransport()
+	err = remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	http.DefaultTransport = oldDefaultTransport
+
+	if err != nil {
+		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+	}
+
+	return nil
+}
+
+// CopyImage copies the image or the manifest list with all referenced blobs registry-to-registry without the docker server.
+// Blobs which already exist in the destination repo are skipped and blobs of the source repo in the same registry are cross-repo mounted.
+func (api *api) CopyImage(ctx context.Context, sourceReference, destinationReference string, opts CopyImageOptions) error {
+	return doWithRetries(ctx, "copying", func() error {
+		return api.copyImage(ctx, sourceReference, destinationReference, opts)
+	})
+}
+
+func (api *api) copyImage(ctx context.Context, sourceReference, destinationReference string, opts CopyImageOptions) error {
+	destinationApi := newAPI(apiOptions{
+		InsecureRegistry:      opts.DestinationInsecureRegistry,
+		SkipTlsVerifyRegistry: opts.DestinationSkipTlsVerifyRegistry,
+	})
+
+	sourceRef, err := name.ParseReference(sourceReference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", sourceReference, err)
+	}
+
+	destinationRef, err := name.ParseReference(destinationReference, destinationApi.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", destinationReference, err)
+	}
+
+	// the blobs of the source image are read lazily with the source options while writing into the destination repo
+	desc, err := remote.Get(sourceRef,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("reading %q: %v", sourceRef, err)
+	}
+
+	destinationRemoteOptions := []remote.Option{
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(destinationApi.getHttpTransport()),
+		remote.WithContext(ctx),
+	}
+
+	switch desc.MediaType {
+	case types.OCIImageIndex, types.DockerManifestList:
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return fmt.Errorf("reading index %q: %v", sourceRef, err)
+		}
+
+		if err := remote.WriteIndex(destinationRef, index, destinationRemoteOptions...); err != nil {
+			return fmt.Errorf("write to the remote %s have failed: %s", destinationRef.String(), err)
+		}
+	default:
+		// layers of the image are wrapped by the library to be mounted from the source repo when possible
+		img, err := desc.Image()
+		if err != nil {
+			return fmt.Errorf("reading image %q: %v", sourceRef, err)
+		}
+
+		if err := remote.Write(destinationRef, img, destinationRemoteOptions...); err != nil {
+			return fmt.Errorf("write to the remote %s have failed: %s", destinationRef.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// GetRepoImagePlatformReference returns reference by digest to the image for the platform in the OS/ARCH[/VARIANT] format,
+// the reference of the manifest list is resolved to the platform image manifest
+func (api *api) GetRepoImagePlatformReference(ctx context.Context, reference, platform string) (string, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return "", fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	v1Platform, err := ParsePlatform(platform)
+	if err != nil {
+		return "", err
+	}
+
+	img, err := remote.Image(ref,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+		remote.WithPlatform(v1Platform),
+	)
+	if err != nil {
+		return "", fmt.Errorf("reading image %q for platform %s: %v", ref, platform, err)
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return "", fmt.Errorf("reading image %q config: %v", ref, err)
+	}
+
+	if configFile.OS != v1Platform.OS || configFile.Architecture != v1Platform.Architecture {
+		return "", fmt.Errorf("image %q is not available for platform %s", ref, platform)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s@%s", ref.Context().Name(), digest.String()), nil
+}
+
+func (api *api) IsRepoManifestListExists(ctx context.Context, reference string) (bool, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return false, fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	if _, err := remote.Index(ref,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	); err != nil {
+		if IsManifestUnknownError(err) || IsNameUnknownError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("reading manifest list %q: %v", ref, err)
+	}
+
+	return true, nil
+}
+
+// GetRepoManifestList returns the manifest list by the reference, nil is returned when there is no manifest list
+func (api *api) GetRepoManifestList(ctx context.Context, reference string) (*ManifestListInfo, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	index, err := remote.Index(ref,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		if IsManifestUnknownError(err) || IsNameUnknownError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading manifest list %q: %v", ref, err)
+	}
+
+	digest, err := index.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest list %q manifest: %v", ref, err)
+	}
+
+	var tag string
+	if parsedTag, ok := ref.(name.Tag); ok {
+		tag = parsedTag.TagStr()
+	}
+
+	manifestList := &ManifestListInfo{
+		Info: &image.Info{
+			Name:       reference,
+			Repository: strings.Join([]string{ref.Context().RegistryStr(), ref.Context().RepositoryStr()}, "/"),
+			Tag:        tag,
+			RepoDigest: digest.String(),
+		},
+	}
+
+	for _, desc := range indexManifest.Manifests {
+		manifestList.ManifestDigests = append(manifestList.ManifestDigests, desc.Digest.String())
+	}
+
+	return manifestList, nil
+}
+
+// PushManifestList creates manifest list by the specified reference from the platform images, images should be in the same repo
+func (api *api) PushManifestList(ctx context.Context, reference string, imageReferences []string) error {
+	return doWithRetries(ctx, "publishing", func() error {
+		return api.pushManifestList(ctx, reference, imageReferences)
+	})
+}
+
+func (api *api) pushManifestList(ctx context.Context, reference string, imageReferences []string) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	remoteOptions := []remote.Option{
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	}
+
+	var addenda []mutate.IndexAddendum
+	for _, imageReference := range imageReferences {
+		imageRef, err := name.ParseReference(imageReference, api.parseReferenceOptions()...)
+		if err != nil {
+			return fmt.Errorf("parsing reference %q: %v", imageReference, err)
+		}
+
+		img, err := remote.Image(imageRef, remoteOptions...)
+		if err != nil {
+			return fmt.Errorf("reading image %q: %v", imageRef, err)
+		}
+
+		platform, err := getImagePlatform(img)
+		if err != nil {
+			return fmt.Errorf("reading image %q platform: %v", imageRef, err)
+		}
+
+		desc, err := partial.Descriptor(img)
+		if err != nil {
+			return fmt.Errorf("reading image %q descriptor: %v", imageRef, err)
+		}
+		desc.Platform = platform
+
+		addenda = append(addenda, mutate.IndexAddendum{Add: img, Descriptor: *desc})
+	}
+
+	index := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.DockerManifestList), addenda...)
+	if err := remote.WriteIndex(ref, index, remoteOptions...); err != nil {
+		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+	}
+
+	return nil
+}
+
+// getImagePlatform returns the platform of the manifest list entry from the image config,
+// the variant is read from the raw config because v1.ConfigFile does not contain it
+func getImagePlatform(img v1.Image) (*v1.Platform, error) {
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	rawConfigFile, err := img.RawConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var variantConfig struct {
+		Variant string `json:"variant,omitempty"`
+	}
+	if err := json.Unmarshal(rawConfigFile, &variantConfig); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal config: %s", err)
+	}
+
+	return &v1.Platform{
+		OS:           configFile.OS,
+		Architecture: configFile.Architecture,
+		Variant:      variantConfig.Variant,
+		OSVersion:    configFile.OSVersion,
+	}, nil
+}
+
+// ParsePlatform parses platform in the OS/ARCH[/VARIANT] format
+func ParsePlatform(platform string) (v1.Platform, error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v1.Platform{}, fmt.Errorf("invalid platform %q: expected OS/ARCH[/VARIANT]", platform)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return v1.Platform{}, fmt.Errorf("invalid platform %q: expected OS/ARCH[/VARIANT]", platform)
+		}
+	}
+
+	v1Platform := v1.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		v1Platform.Variant = parts[2]
+	}
+
+	return v1Platform, nil
+}
+
+func doWithRetries(ctx context.Context, operation string, f func() error) error {
 	retriesLimit := 5
 
 attemptLoop:
 	for attempt := 1; attempt <= retriesLimit; attempt++ {
-		if err := api.pushImage(ctx, reference, opts); err != nil {
+		if err := f(); err != nil {
 			for _, substr := range []string{
 				"REDACTED: UNKNOWN",
 				"http2: server sent GOAWAY and closed the connection",
@@ -173,7 +512,7 @@ attemptLoop:
 				if strings.Contains(err.Error(), substr) {
 					seconds := rand.Intn(5) + 1
 
-					msg := fmt.Sprintf("Retrying publishing in %d seconds (%d/%d) ...\n", seconds, attempt, retriesLimit)
+					msg := fmt.Sprintf("Retrying %s in %d seconds (%d/%d) ...\n", operation, seconds, attempt, retriesLimit)
 					logboek.Context(ctx).Warn().LogLn(msg)
 
 					time.Sleep(time.Duration(seconds) * time.Second)
@@ -190,31 +529,6 @@ attemptLoop:
 	return nil
 }
 
-func (api *api) pushImage(_ context.Context, reference string, opts *PushImageOptions) error {
-	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
-	if err != nil {
-		return fmt.Errorf("parsing reference %q: %v", reference, err)
-	}
-
-	labels := map[string]string{}
-	if opts != nil {
-		labels = opts.Labels
-	}
-
-	img := container_registry_extensions.NewManifestOnlyImage(labels)
-
-	oldDefaultTransport := http.DefaultTransport
-	http.DefaultTransport = api.getHttpTransport()
-	err = remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
-	http.DefaultTransport = oldDefaultTransport
-
-	if err != nil {
-		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
-	}
-
-	return nil
-}
-
 func (api *api) image(reference string) (v1.Image, name.Reference, error) {
 	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
@@ -0,0 +1,114 @@
+package docker_registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/werf/werf/pkg/docker_registry/container_registry_extensions"
+)
+
+// ImageSBOMTagSuffix is the suffix of the tag sha256-HEX.sbom, which is used for the SBOM of the image manifest as cosign does
+const ImageSBOMTagSuffix = ".sbom"
+
+// ImageArtifactReference returns the reference of the artifact attached to the image manifest with the digest in the repository
+func ImageArtifactReference(repository, manifestDigest, tagSuffix string) string {
+	return fmt.Sprintf("%s:%s%s", repository, strings.Replace(manifestDigest, ":", "-", 1), tagSuffix)
+}
+
+// GetRepoImageFilesystem returns the tar stream of the flattened image filesystem, the image layers are downloaded while reading
+func (api *api) GetRepoImageFilesystem(ctx context.Context, reference string) (io.ReadCloser, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	img, err := remote.Image(ref,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading image %q: %v", ref, err)
+	}
+
+	return mutate.Extract(img), nil
+}
+
+// GetImageArtifact returns the content of the OCI artifact stored by the reference, nil is returned when there is no artifact
+func (api *api) GetImageArtifact(ctx context.Context, reference string) ([]byte, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	img, err := remote.Image(ref,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		if IsManifestUnknownError(err) || IsNameUnknownError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading image %q: %v", ref, err)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("reading image %q layers: %v", ref, err)
+	}
+
+	if len(layers) != 1 {
+		return nil, fmt.Errorf("unexpected artifact %q: expected 1 layer, got %d", ref, len(layers))
+	}
+
+	content, err := readLayerBlob(layers[0])
+	if err != nil {
+		return nil, fmt.Errorf("reading image %q layer: %v", ref, err)
+	}
+
+	return content, nil
+}
+
+// PushImageArtifact writes the content as the single layer OCI artifact by the reference
+func (api *api) PushImageArtifact(ctx context.Context, reference string, content []byte, mediaType string) error {
+	return doWithRetries(ctx, "publishing", func() error {
+		return api.pushImageArtifact(ctx, reference, content, types.MediaType(mediaType))
+	})
+}
+
+func (api *api) pushImageArtifact(ctx context.Context, reference string, content []byte, mediaType types.MediaType) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	layer, err := container_registry_extensions.NewRawLayer(content, mediaType)
+	if err != nil {
+		return err
+	}
+
+	img, err := mutate.Append(mutate.MediaType(empty.Image, types.OCIManifestSchema1), mutate.Addendum{Layer: layer, MediaType: mediaType})
+	if err != nil {
+		return err
+	}
+
+	if err := remote.Write(ref, img,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	); err != nil {
+		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package container_registry_extensions
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// rawLayer is the layer which blob is stored as is without compression (e.g. cosign signature payload)
+type rawLayer struct {
+	digest    v1.Hash
+	mediaType types.MediaType
+	content   []byte
+}
+
+func NewRawLayer(content []byte, mediaType types.MediaType) (v1.Layer, error) {
+	digest, _, err := v1.SHA256(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	return &rawLayer{digest: digest, mediaType: mediaType, content: content}, nil
+}
+
+func (layer *rawLayer) Digest() (v1.Hash, error) {
+	return layer.digest, nil
+}
+
+func (layer *rawLayer) DiffID() (v1.Hash, error) {
+	return layer.digest, nil
+}
+
+func (layer *rawLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(layer.content)), nil
+}
+
+func (layer *rawLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(layer.content)), nil
+}
+
+func (layer *rawLayer) Size() (int64, error) {
+	return int64(len(layer.content)), nil
+}
+
+func (layer *rawLayer) MediaType() (types.MediaType, error) {
+	return layer.mediaType, nil
+}
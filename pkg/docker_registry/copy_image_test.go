@@ -0,0 +1,90 @@
+package docker_registry_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+var _ = Describe("CopyImage", func() {
+	var server *httptest.Server
+	var registryAddress string
+
+	BeforeEach(func() {
+		server = httptest.NewServer(registry.New())
+		registryAddress = strings.TrimPrefix(server.URL, "http://")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("copies image between repos registry-to-registry", func() {
+		sourceReference := fmt.Sprintf("%s/source:tag", registryAddress)
+		destinationReference := fmt.Sprintf("%s/destination:copied", registryAddress)
+
+		img, err := random.Image(1024, 3)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		sourceRef, err := name.ParseReference(sourceReference)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(sourceRef, img)).Should(Succeed())
+
+		dockerRegistry, err := docker_registry.NewDockerRegistry(registryAddress+"/destination", docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(dockerRegistry.CopyImage(context.Background(), sourceReference, destinationReference, docker_registry.CopyImageOptions{})).Should(Succeed())
+
+		sourceInfo, err := dockerRegistry.GetRepoImage(context.Background(), sourceReference)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		destinationInfo, err := dockerRegistry.GetRepoImage(context.Background(), destinationReference)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(destinationInfo.RepoDigest).Should(Equal(sourceInfo.RepoDigest))
+		Ω(destinationInfo.ID).Should(Equal(sourceInfo.ID))
+	})
+
+	It("returns error when source image does not exist", func() {
+		dockerRegistry, err := docker_registry.NewDockerRegistry(registryAddress+"/destination", docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		err = dockerRegistry.CopyImage(context.Background(), registryAddress+"/source:absent", registryAddress+"/destination:copied", docker_registry.CopyImageOptions{})
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("uses destination registry options", func() {
+		destinationServer := httptest.NewTLSServer(registry.New())
+		defer destinationServer.Close()
+
+		sourceReference := fmt.Sprintf("%s/source:tag", registryAddress)
+		destinationReference := fmt.Sprintf("%s/destination:copied", strings.TrimPrefix(destinationServer.URL, "https://"))
+
+		img, err := random.Image(1024, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		sourceRef, err := name.ParseReference(sourceReference)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(sourceRef, img)).Should(Succeed())
+
+		// the source registry options do not skip tls verification, so only the destination options allow the self-signed certificate
+		dockerRegistry, err := docker_registry.NewDockerRegistry(registryAddress+"/source", docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		err = dockerRegistry.CopyImage(context.Background(), sourceReference, destinationReference, docker_registry.CopyImageOptions{})
+		Ω(err).Should(HaveOccurred())
+
+		Ω(dockerRegistry.CopyImage(context.Background(), sourceReference, destinationReference, docker_registry.CopyImageOptions{DestinationSkipTlsVerifyRegistry: true})).Should(Succeed())
+	})
+})
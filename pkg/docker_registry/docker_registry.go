@@ -3,6 +3,7 @@ package docker_registry
 import (
 	"context"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
 
@@ -24,8 +25,18 @@ type DockerRegistry interface {
 	GetRepoImage(ctx context.Context, reference string) (*image.Info, error)
 	TryGetRepoImage(ctx context.Context, reference string) (*image.Info, error)
 	IsRepoImageExists(ctx context.Context, reference string) (bool, error)
+	GetRepoImageManifestDigest(ctx context.Context, reference string) (string, error)
 	DeleteRepoImage(ctx context.Context, repoImage *image.Info) error
 	PushImage(ctx context.Context, reference string, opts *PushImageOptions) error
+	CopyImage(ctx context.Context, sourceReference, destinationReference string, opts CopyImageOptions) error
+	PushManifestList(ctx context.Context, reference string, imageReferences []string) error
+	IsRepoManifestListExists(ctx context.Context, reference string) (bool, error)
+	GetRepoManifestList(ctx context.Context, reference string) (*ManifestListInfo, error)
+	GetImageSignatures(ctx context.Context, reference string) ([]*ImageSignature, error)
+	PushImageSignatures(ctx context.Context, reference string, signatures []*ImageSignature) error
+	GetRepoImageFilesystem(ctx context.Context, reference string) (io.ReadCloser, error)
+	GetImageArtifact(ctx context.Context, reference string) ([]byte, error)
+	PushImageArtifact(ctx context.Context, reference string, content []byte, mediaType string) error
 
 	ResolveRepoMode(ctx context.Context, registryOrRepositoryAddress, repoMode string) (string, error)
 	String() string
@@ -35,6 +46,19 @@ type PushImageOptions struct {
 	Labels map[string]string
 }
 
+// CopyImageOptions are the options of the destination registry, which could differ from the options of the source one
+type CopyImageOptions struct {
+	DestinationInsecureRegistry      bool
+	DestinationSkipTlsVerifyRegistry bool
+}
+
+type ManifestListInfo struct {
+	// Info of the manifest list can be passed to DeleteRepoImage
+	Info *image.Info
+	// ManifestDigests are the digests of the platform images manifests
+	ManifestDigests []string
+}
+
 type DockerRegistryOptions struct {
 	InsecureRegistry      bool
 	SkipTlsVerifyRegistry bool
@@ -0,0 +1,85 @@
+package docker_registry_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+var _ = Describe("ManifestList", func() {
+	var server *httptest.Server
+	var repoAddress string
+	var dockerRegistry docker_registry.DockerRegistry
+
+	BeforeEach(func() {
+		server = httptest.NewServer(registry.New())
+		repoAddress = fmt.Sprintf("%s/stages", strings.TrimPrefix(server.URL, "http://"))
+
+		var err error
+		dockerRegistry, err = docker_registry.NewDockerRegistry(repoAddress, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	pushPlatformImage := func(tag, os, arch string) string {
+		img, err := random.Image(1024, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		configFile, err := img.ConfigFile()
+		Ω(err).ShouldNot(HaveOccurred())
+		configFile.OS = os
+		configFile.Architecture = arch
+
+		img, err = mutate.ConfigFile(img, configFile)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		reference := fmt.Sprintf("%s:%s", repoAddress, tag)
+		ref, err := name.ParseReference(reference)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, img)).Should(Succeed())
+
+		return reference
+	}
+
+	It("returns platform images manifests digests of the pushed manifest list", func() {
+		amd64Reference := pushPlatformImage("amd64", "linux", "amd64")
+		arm64Reference := pushPlatformImage("arm64", "linux", "arm64")
+		manifestListReference := fmt.Sprintf("%s:manifest-list-test", repoAddress)
+
+		Ω(dockerRegistry.PushManifestList(context.Background(), manifestListReference, []string{amd64Reference, arm64Reference})).Should(Succeed())
+
+		manifestList, err := dockerRegistry.GetRepoManifestList(context.Background(), manifestListReference)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(manifestList).ShouldNot(BeNil())
+		Ω(manifestList.Info.Tag).Should(Equal("manifest-list-test"))
+
+		var expectedDigests []string
+		for _, reference := range []string{amd64Reference, arm64Reference} {
+			info, err := dockerRegistry.GetRepoImage(context.Background(), reference)
+			Ω(err).ShouldNot(HaveOccurred())
+			expectedDigests = append(expectedDigests, info.RepoDigest)
+		}
+		Ω(manifestList.ManifestDigests).Should(ConsistOf(expectedDigests))
+	})
+
+	It("returns nil when manifest list does not exist", func() {
+		manifestList, err := dockerRegistry.GetRepoManifestList(context.Background(), fmt.Sprintf("%s:manifest-list-absent", repoAddress))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(manifestList).Should(BeNil())
+	})
+})
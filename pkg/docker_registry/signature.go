@@ -0,0 +1,135 @@
+package docker_registry
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/werf/werf/pkg/docker_registry/container_registry_extensions"
+)
+
+const (
+	// CosignSignatureTagSuffix is the suffix of the tag sha256-HEX.sig, which cosign uses for the signatures of the image manifest
+	CosignSignatureTagSuffix = ".sig"
+
+	CosignSimpleSigningMediaType types.MediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	CosignSignatureAnnotation                    = "dev.cosignproject.cosign/signature"
+)
+
+// ImageSignature is the cosign compatible signature: the simple signing payload and the base64 encoded signature of the payload
+type ImageSignature struct {
+	Payload   []byte
+	Signature string
+}
+
+// ImageSignatureReference returns the reference of the cosign signatures of the image manifest with the digest in the repository
+func ImageSignatureReference(repository, manifestDigest string) string {
+	return ImageArtifactReference(repository, manifestDigest, CosignSignatureTagSuffix)
+}
+
+// GetImageSignatures returns the signatures stored by the signature reference, nil is returned when there are no signatures
+func (api *api) GetImageSignatures(ctx context.Context, reference string) ([]*ImageSignature, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	img, err := remote.Image(ref,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		if IsManifestUnknownError(err) || IsNameUnknownError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading image %q: %v", ref, err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading image %q manifest: %v", ref, err)
+	}
+
+	var res []*ImageSignature
+	for _, desc := range manifest.Layers {
+		if desc.MediaType != CosignSimpleSigningMediaType {
+			continue
+		}
+
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("reading image %q layer %s: %v", ref, desc.Digest, err)
+		}
+
+		payload, err := readLayerBlob(layer)
+		if err != nil {
+			return nil, fmt.Errorf("reading image %q layer %s: %v", ref, desc.Digest, err)
+		}
+
+		res = append(res, &ImageSignature{Payload: payload, Signature: desc.Annotations[CosignSignatureAnnotation]})
+	}
+
+	return res, nil
+}
+
+// PushImageSignatures writes the signatures by the signature reference, the existing signatures are replaced
+func (api *api) PushImageSignatures(ctx context.Context, reference string, signatures []*ImageSignature) error {
+	return doWithRetries(ctx, "publishing", func() error {
+		return api.pushImageSignatures(ctx, reference, signatures)
+	})
+}
+
+func (api *api) pushImageSignatures(ctx context.Context, reference string, signatures []*ImageSignature) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	var addenda []mutate.Addendum
+	for _, signature := range signatures {
+		layer, err := container_registry_extensions.NewRawLayer(signature.Payload, CosignSimpleSigningMediaType)
+		if err != nil {
+			return err
+		}
+
+		addenda = append(addenda, mutate.Addendum{
+			Layer:       layer,
+			MediaType:   CosignSimpleSigningMediaType,
+			Annotations: map[string]string{CosignSignatureAnnotation: signature.Signature},
+		})
+	}
+
+	img, err := mutate.Append(mutate.MediaType(empty.Image, types.OCIManifestSchema1), addenda...)
+	if err != nil {
+		return err
+	}
+
+	if err := remote.Write(ref, img,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(api.getHttpTransport()),
+		remote.WithContext(ctx),
+	); err != nil {
+		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+	}
+
+	return nil
+}
+
+func readLayerBlob(layer v1.Layer) ([]byte, error) {
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
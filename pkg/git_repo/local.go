@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
 
 	"github.com/werf/logboek"
 
@@ -219,6 +222,96 @@ func (repo *Local) RemoteBranchesList(ctx context.Context) ([]string, error) {
 	return repo.remoteBranchesList(repo.Path)
 }
 
+// ResolveCommit returns the commit of the revision (branch, tag, commit or expression like HEAD~1)
+func (repo *Local) ResolveCommit(_ context.Context, rev string) (string, error) {
+	repository, err := repo.PlainOpen()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := repository.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve revision %q: %s", rev, err)
+	}
+
+	return hash.String(), nil
+}
+
+// ReadCommitFiles returns the content of the commit files by the paths relative to the repository root,
+// the directory paths are read recursively ("." is the repository root) and the paths that do not exist in the commit are skipped
+func (repo *Local) ReadCommitFiles(_ context.Context, commit string, paths []string) (map[string][]byte, error) {
+	repository, err := repo.PlainOpen()
+	if err != nil {
+		return nil, err
+	}
+
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commit %s: %s", commit, err)
+	}
+
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commit %s tree: %s", commit, err)
+	}
+
+	res := map[string][]byte{}
+	for _, path := range paths {
+		path = filepath.ToSlash(filepath.Clean(path))
+
+		var subtree *object.Tree
+		if path == "." {
+			subtree = tree
+		} else {
+			entry, err := tree.FindEntry(path)
+			if err == object.ErrEntryNotFound || err == object.ErrDirectoryNotFound {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("unable to find %s in commit %s: %s", path, commit, err)
+			}
+
+			if entry.Mode != filemode.Dir {
+				file, err := tree.File(path)
+				if err != nil {
+					return nil, fmt.Errorf("unable to get file %s from commit %s: %s", path, commit, err)
+				}
+
+				content, err := file.Contents()
+				if err != nil {
+					return nil, fmt.Errorf("unable to read file %s from commit %s: %s", path, commit, err)
+				}
+
+				res[path] = []byte(content)
+				continue
+			}
+
+			subtree, err = tree.Tree(path)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get directory %s from commit %s: %s", path, commit, err)
+			}
+		}
+
+		if err := subtree.Files().ForEach(func(file *object.File) error {
+			filePath := file.Name
+			if path != "." {
+				filePath = path + "/" + file.Name
+			}
+
+			content, err := file.Contents()
+			if err != nil {
+				return fmt.Errorf("unable to read file %s from commit %s: %s", filePath, commit, err)
+			}
+
+			res[filePath] = []byte(content)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
 func (repo *Local) getRepoWorkTreeCacheDir() string {
 	absPath, err := filepath.Abs(repo.Path)
 	if err != nil {
@@ -0,0 +1,93 @@
+package git_repo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestLocalReadCommitFiles(t *testing.T) {
+	repoDir, err := ioutil.TempDir("", "werf-git-repo-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoDir)
+
+	repository, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, data := range map[string]string{
+		"README.md":                     "readme",
+		"secret-values.yaml":            "values",
+		".helm/secret/tls.key":          "key",
+		".helm/secret/production/a.key": "production key",
+	} {
+		absPath := filepath.Join(repoDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(absPath, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := worktree.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := worktree.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &Local{Base: Base{Name: "own"}, Path: repoDir}
+
+	for _, tc := range []struct {
+		paths    []string
+		expected map[string][]byte
+	}{
+		{
+			paths: []string{"."},
+			expected: map[string][]byte{
+				"README.md":                     []byte("readme"),
+				"secret-values.yaml":            []byte("values"),
+				".helm/secret/tls.key":          []byte("key"),
+				".helm/secret/production/a.key": []byte("production key"),
+			},
+		},
+		{
+			paths: []string{".helm/secret/", "README.md", "absent"},
+			expected: map[string][]byte{
+				"README.md":                     []byte("readme"),
+				".helm/secret/tls.key":          []byte("key"),
+				".helm/secret/production/a.key": []byte("production key"),
+			},
+		},
+	} {
+		files, err := repo.ReadCommitFiles(context.Background(), hash.String(), tc.paths)
+		if err != nil {
+			t.Fatalf("%v: %s", tc.paths, err)
+		}
+
+		if !reflect.DeepEqual(files, tc.expected) {
+			t.Errorf("%v: expected files %v, got %v", tc.paths, tc.expected, files)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/werf/logboek"
 
+	"github.com/werf/werf/pkg/cache_mounts"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/tmp_manager"
@@ -49,6 +50,10 @@ func HostCleanup(ctx context.Context, options HostCleanupOptions) error {
 				return fmt.Errorf("tmp files gc failed: %s", err)
 			}
 
+			if err := cache_mounts.GC(ctx, cache_mounts.DefaultKeepPeriod, commonOptions.DryRun); err != nil {
+				return fmt.Errorf("cache mounts gc failed: %s", err)
+			}
+
 			return nil
 		})
 	})
@@ -11,6 +11,8 @@ const (
 	WerfProjectRepoCommitLabel    = "werf-project-repo-commit"
 	WerfImportChecksumLabelPrefix = "werf-import-checksum-"
 
+	WerfDockerfileDependencyStageLabelPrefix = "werf-dockerfile-dependency-stage-"
+
 	WerfImportMetadataChecksumLabel       = "checksum"
 	WerfImportMetadataSourceImageIDLabel  = "source-image-id"
 	WerfImportMetadataImportSourceIDLabel = "import-source-id"
@@ -21,6 +21,24 @@ type Info struct {
 	Labels            map[string]string `json:"labels"`
 	Size              int64             `json:"size"`
 	CreatedAtUnixNano int64             `json:"createdAtUnixNano"`
+
+	OS           string `json:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// GetPlatform returns image platform in the OS/ARCH[/VARIANT] format or empty string when unknown
+func (info *Info) GetPlatform() string {
+	if info.OS == "" || info.Architecture == "" {
+		return ""
+	}
+
+	platform := fmt.Sprintf("%s/%s", info.OS, info.Architecture)
+	if info.Variant != "" {
+		platform = fmt.Sprintf("%s/%s", platform, info.Variant)
+	}
+
+	return platform
 }
 
 func (info *Info) SetCreatedAtUnix(seconds int64) {
@@ -54,6 +72,9 @@ func NewInfoFromInspect(ref string, inspect *types.ImageInspect) *Info {
 		ID:                inspect.ID,
 		ParentID:          inspect.Parent,
 		Size:              inspect.Size,
+		OS:                inspect.Os,
+		Architecture:      inspect.Architecture,
+		Variant:           inspect.Variant,
 	}
 }
 
@@ -66,6 +87,11 @@ func MustParseTimestampString(timestampString string) time.Time {
 }
 
 func ParseRepositoryAndTag(ref string) (string, string) {
+	if parts := strings.SplitN(ref, "@", 2); len(parts) == 2 {
+		// reference by digest has no tag
+		return parts[0], ""
+	}
+
 	parts := strings.SplitN(stringutil.Reverse(ref), ":", 2)
 	if len(parts) == 2 {
 		tag := stringutil.Reverse(parts[0])
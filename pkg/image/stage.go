@@ -3,6 +3,7 @@ package image
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -31,3 +32,18 @@ func ParseUniqueIDAsTimestamp(uniqueID string) (int64, error) {
 		return timestamp, nil
 	}
 }
+
+// ParseStageID parses stage ID in the DIGEST-UNIQUEID format
+func ParseStageID(stageID string) (StageID, error) {
+	ind := strings.LastIndex(stageID, "-")
+	if ind == -1 {
+		return StageID{}, fmt.Errorf("unexpected stage ID %q: expected DIGEST-UNIQUEID", stageID)
+	}
+
+	uniqueID, err := ParseUniqueIDAsTimestamp(stageID[ind+1:])
+	if err != nil {
+		return StageID{}, fmt.Errorf("unexpected stage ID %q: unable to parse unique ID: %s", stageID, err)
+	}
+
+	return StageID{Digest: stageID[:ind], UniqueID: uniqueID}, nil
+}
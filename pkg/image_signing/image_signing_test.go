@@ -0,0 +1,155 @@
+package image_signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+const testManifestDigest = "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b"
+
+func TestSignAndVerifyPayload(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := NewPayload("registry.example.com/project", testManifestDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := SignPayload(privateKey, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageSignature := &docker_registry.ImageSignature{Payload: payload, Signature: signature}
+	if err := VerifySignature(&privateKey.PublicKey, imageSignature, testManifestDigest); err != nil {
+		t.Errorf("unexpected verification error: %s", err)
+	}
+
+	if err := VerifySignature(&privateKey.PublicKey, imageSignature, "sha256:0000"); err == nil {
+		t.Errorf("expected error for another manifest digest")
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifySignature(&otherKey.PublicKey, imageSignature, testManifestDigest); err == nil {
+		t.Errorf("expected error for another key")
+	}
+}
+
+func TestDerivePrivateKey(t *testing.T) {
+	key1, err := DerivePrivateKey([]byte("11ac8312520b5ff037bae386ea2e8a07"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key2, err := DerivePrivateKey([]byte("11ac8312520b5ff037bae386ea2e8a07"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key3, err := DerivePrivateKey([]byte("c12a9d0b3de0e5c8e0a7b4b0d1bdd3a8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key1.D.Cmp(key2.D) != 0 {
+		t.Errorf("expected the same key for the same secret key")
+	}
+
+	if key1.D.Cmp(key3.D) == 0 {
+		t.Errorf("expected different keys for different secret keys")
+	}
+
+	if !key1.Curve.IsOnCurve(key1.X, key1.Y) {
+		t.Errorf("derived public key is not on the curve")
+	}
+}
+
+func TestLoadEncryptedPrivateKey(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password := []byte("password")
+
+	var encrypted encryptedPrivateKey
+	encrypted.KDF.Name = "scrypt"
+	encrypted.KDF.Params.N, encrypted.KDF.Params.R, encrypted.KDF.Params.P = 1024, 8, 1
+	encrypted.KDF.Salt = []byte("0123456789abcdef0123456789abcdef")
+	encrypted.Cipher.Name = "nacl/secretbox"
+	encrypted.Cipher.Nonce = []byte("0123456789abcdef01234567")
+
+	key, err := scrypt.Key(password, encrypted.KDF.Salt, 1024, 8, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var boxKey [32]byte
+	var nonce [24]byte
+	copy(boxKey[:], key)
+	copy(nonce[:], encrypted.Cipher.Nonce)
+	encrypted.Ciphertext = secretbox.Seal(nil, der, &nonce, &boxKey)
+
+	data, err := json.Marshal(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "werf-image-signing-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	keyPath := filepath.Join(tmpDir, "cosign.key")
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: cosignEncryptedPrivateKeyPemType, Bytes: data}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loadedKey, err := LoadPrivateKey(keyPath, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loadedKey.D.Cmp(privateKey.D) != 0 {
+		t.Errorf("loaded key does not match the original key")
+	}
+
+	if _, err := LoadPrivateKey(keyPath, []byte("wrong")); err == nil {
+		t.Errorf("expected error for the wrong password")
+	}
+
+	publicKey, err := LoadPublicKey(keyPath, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if publicKey.X.Cmp(privateKey.X) != 0 || publicKey.Y.Cmp(privateKey.Y) != 0 {
+		t.Errorf("loaded public key does not match the original key")
+	}
+}
@@ -0,0 +1,194 @@
+package image_signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	cosignEncryptedPrivateKeyPemType   = "ENCRYPTED COSIGN PRIVATE KEY"
+	sigstoreEncryptedPrivateKeyPemType = "ENCRYPTED SIGSTORE PRIVATE KEY"
+	ecPrivateKeyPemType                = "EC PRIVATE KEY"
+	pkcs8PrivateKeyPemType             = "PRIVATE KEY"
+	publicKeyPemType                   = "PUBLIC KEY"
+
+	secretKeyDerivationInfo = "werf image signing key"
+)
+
+// LoadPrivateKey loads ECDSA private key from the PEM file,
+// the encrypted cosign private key (cosign generate-key-pair) is decrypted with the password
+func LoadPrivateKey(path string, password []byte) (*ecdsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signing key file %s: %s", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("unable to decode signing key file %s: PEM block is not found", path)
+	}
+
+	privateKey, err := parsePrivateKey(block, password)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse signing key file %s: %s", path, err)
+	}
+
+	return privateKey, nil
+}
+
+// LoadPublicKey loads ECDSA public key from the PEM file, the public key of the private key is used for the private key file
+func LoadPublicKey(path string, password []byte) (*ecdsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read verification key file %s: %s", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("unable to decode verification key file %s: PEM block is not found", path)
+	}
+
+	if block.Type != publicKeyPemType {
+		privateKey, err := parsePrivateKey(block, password)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse verification key file %s: %s", path, err)
+		}
+
+		return &privateKey.PublicKey, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse verification key file %s: %s", path, err)
+	}
+
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unable to parse verification key file %s: only ECDSA keys are supported", path)
+	}
+
+	return publicKey, nil
+}
+
+// DerivePrivateKey derives ECDSA P-256 private key from the project secret key, the same secret key gives the same signing key
+func DerivePrivateKey(secretKey []byte) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	params := curve.Params()
+
+	// the extra 64 bits make the bias of the modular reduction negligible (see FIPS 186-4 B.4.1)
+	b := make([]byte, params.BitSize/8+8)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secretKey, nil, []byte(secretKeyDerivationInfo)), b); err != nil {
+		return nil, fmt.Errorf("unable to derive signing key: %s", err)
+	}
+
+	one := big.NewInt(1)
+	d := new(big.Int).SetBytes(b)
+	d.Mod(d, new(big.Int).Sub(params.N, one))
+	d.Add(d, one)
+
+	privateKey := &ecdsa.PrivateKey{D: d}
+	privateKey.PublicKey.Curve = curve
+	privateKey.PublicKey.X, privateKey.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+
+	return privateKey, nil
+}
+
+func parsePrivateKey(block *pem.Block, password []byte) (*ecdsa.PrivateKey, error) {
+	switch block.Type {
+	case cosignEncryptedPrivateKeyPemType, sigstoreEncryptedPrivateKeyPemType:
+		der, err := decryptPrivateKey(block.Bytes, password)
+		if err != nil {
+			return nil, err
+		}
+
+		return parsePKCS8PrivateKey(der)
+	case pkcs8PrivateKeyPemType:
+		return parsePKCS8PrivateKey(block.Bytes)
+	case ecPrivateKeyPemType:
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+func parsePKCS8PrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("only ECDSA keys are supported")
+	}
+
+	return privateKey, nil
+}
+
+// encryptedPrivateKey is the format of the encrypted cosign private key: the key is encrypted with nacl/secretbox
+// and the encryption key is derived from the password with scrypt
+type encryptedPrivateKey struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func decryptPrivateKey(data, password []byte) ([]byte, error) {
+	var encrypted encryptedPrivateKey
+	if err := json.Unmarshal(data, &encrypted); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal encrypted key: %s", err)
+	}
+
+	if encrypted.KDF.Name != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation function %q", encrypted.KDF.Name)
+	}
+
+	if encrypted.Cipher.Name != "nacl/secretbox" {
+		return nil, fmt.Errorf("unsupported cipher %q", encrypted.Cipher.Name)
+	}
+
+	if len(encrypted.Cipher.Nonce) != 24 {
+		return nil, fmt.Errorf("invalid nonce length %d", len(encrypted.Cipher.Nonce))
+	}
+
+	key, err := scrypt.Key(password, encrypted.KDF.Salt, encrypted.KDF.Params.N, encrypted.KDF.Params.R, encrypted.KDF.Params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive encryption key: %s", err)
+	}
+
+	var boxKey [32]byte
+	var nonce [24]byte
+	copy(boxKey[:], key)
+	copy(nonce[:], encrypted.Cipher.Nonce)
+
+	res, ok := secretbox.Open(nil, encrypted.Ciphertext, &nonce, &boxKey)
+	if !ok {
+		return nil, errors.New("unable to decrypt key: invalid password")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,201 @@
+package image_signing
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+const cosignSignatureType = "cosign container image signature"
+
+// simpleSigningPayload is the payload signed by cosign, it binds the signature to the image manifest digest
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func NewPayload(dockerReference, manifestDigest string) ([]byte, error) {
+	payload := simpleSigningPayload{}
+	payload.Critical.Identity.DockerReference = dockerReference
+	payload.Critical.Image.DockerManifestDigest = manifestDigest
+	payload.Critical.Type = cosignSignatureType
+
+	return json.Marshal(payload)
+}
+
+// SignPayload returns the base64 encoded ASN.1 ECDSA signature of the payload SHA-256 hash
+func SignPayload(privateKey *ecdsa.PrivateKey, payload []byte) (string, error) {
+	hash := sha256.Sum256(payload)
+
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// VerifySignature checks the signature of the payload and that the payload is created for the image manifest digest
+func VerifySignature(publicKey *ecdsa.PublicKey, signature *docker_registry.ImageSignature, manifestDigest string) error {
+	rawSignature, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return fmt.Errorf("unable to decode signature: %s", err)
+	}
+
+	var sig ecdsaSignature
+	if _, err := asn1.Unmarshal(rawSignature, &sig); err != nil {
+		return fmt.Errorf("unable to unmarshal signature: %s", err)
+	}
+
+	hash := sha256.Sum256(signature.Payload)
+	if !ecdsa.Verify(publicKey, hash[:], sig.R, sig.S) {
+		return errors.New("invalid signature")
+	}
+
+	var payload simpleSigningPayload
+	if err := json.Unmarshal(signature.Payload, &payload); err != nil {
+		return fmt.Errorf("unable to unmarshal signature payload: %s", err)
+	}
+
+	if payload.Critical.Type != cosignSignatureType {
+		return fmt.Errorf("unexpected signature payload type %q", payload.Critical.Type)
+	}
+
+	if payload.Critical.Image.DockerManifestDigest != manifestDigest {
+		return fmt.Errorf("signature is created for the manifest %s", payload.Critical.Image.DockerManifestDigest)
+	}
+
+	return nil
+}
+
+type Signer struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+func NewSigner(privateKey *ecdsa.PrivateKey) *Signer {
+	return &Signer{privateKey: privateKey}
+}
+
+func (s *Signer) PublicKey() *ecdsa.PublicKey {
+	return &s.privateKey.PublicKey
+}
+
+// SignImage stores the signature of the image manifest in the same repo by the sha256-HEX.sig tag as cosign does,
+// the image is not signed again if there is the valid signature of the signer
+func (s *Signer) SignImage(ctx context.Context, dockerRegistry docker_registry.DockerRegistry, reference string) error {
+	repository, manifestDigest, err := getRepositoryAndManifestDigest(ctx, dockerRegistry, reference)
+	if err != nil {
+		return err
+	}
+
+	signatureReference := docker_registry.ImageSignatureReference(repository, manifestDigest)
+
+	signatures, err := dockerRegistry.GetImageSignatures(ctx, signatureReference)
+	if err != nil {
+		return fmt.Errorf("unable to get image %s signatures: %s", reference, err)
+	}
+
+	for _, signature := range signatures {
+		if VerifySignature(s.PublicKey(), signature, manifestDigest) == nil {
+			logboek.Context(ctx).Info().LogF("Image %s is already signed: %s\n", reference, signatureReference)
+			return nil
+		}
+	}
+
+	payload, err := NewPayload(repository, manifestDigest)
+	if err != nil {
+		return err
+	}
+
+	signature, err := SignPayload(s.privateKey, payload)
+	if err != nil {
+		return fmt.Errorf("unable to sign image %s: %s", reference, err)
+	}
+
+	signatures = append(signatures, &docker_registry.ImageSignature{Payload: payload, Signature: signature})
+	if err := dockerRegistry.PushImageSignatures(ctx, signatureReference, signatures); err != nil {
+		return fmt.Errorf("unable to push image %s signatures: %s", reference, err)
+	}
+
+	logboek.Context(ctx).Info().LogF("Image %s signature: %s\n", reference, signatureReference)
+
+	return nil
+}
+
+type Verifier struct {
+	publicKey *ecdsa.PublicKey
+}
+
+func NewVerifier(publicKey *ecdsa.PublicKey) *Verifier {
+	return &Verifier{publicKey: publicKey}
+}
+
+// VerifyImage returns an error if there is no valid signature of the image manifest
+func (v *Verifier) VerifyImage(ctx context.Context, dockerRegistry docker_registry.DockerRegistry, reference string) error {
+	repository, manifestDigest, err := getRepositoryAndManifestDigest(ctx, dockerRegistry, reference)
+	if err != nil {
+		return err
+	}
+
+	signatures, err := dockerRegistry.GetImageSignatures(ctx, docker_registry.ImageSignatureReference(repository, manifestDigest))
+	if err != nil {
+		return fmt.Errorf("unable to get image %s signatures: %s", reference, err)
+	}
+
+	if len(signatures) == 0 {
+		return fmt.Errorf("image %s is not signed", reference)
+	}
+
+	var lastErr error
+	for _, signature := range signatures {
+		if lastErr = VerifySignature(v.publicKey, signature, manifestDigest); lastErr == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("image %s has no valid signature: %s", reference, lastErr)
+}
+
+func getRepositoryAndManifestDigest(ctx context.Context, dockerRegistry docker_registry.DockerRegistry, reference string) (string, string, error) {
+	ref, err := name.ParseReference(reference, name.WeakValidation)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	manifestDigest, err := dockerRegistry.GetRepoImageManifestDigest(ctx, reference)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to get image %s manifest digest: %s", reference, err)
+	}
+
+	return ref.Context().Name(), manifestDigest, nil
+}
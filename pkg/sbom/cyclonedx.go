@@ -0,0 +1,156 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+const CycloneDXMediaType = "application/vnd.cyclonedx+json"
+
+type cycloneDXDocument struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    cycloneDXMetadata    `json:"metadata"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Licenses   []cycloneDXLicense  `json:"licenses,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDX renders the result as the CycloneDX JSON document of the image,
+// the packages with the same package URL are listed once
+func (res *Result) CycloneDX(imageName string) ([]byte, error) {
+	doc := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Vendor: "werf", Name: "werf", Version: werf.Version}},
+			Component: cycloneDXComponent{Type: "container", Name: imageName},
+		},
+		Components: []cycloneDXComponent{},
+	}
+
+	if res.Distro != nil && res.Distro.ID != "" {
+		doc.Components = append(doc.Components, cycloneDXComponent{Type: "operating-system", Name: res.Distro.ID, Version: res.Distro.VersionID})
+	}
+
+	processed := map[string]bool{}
+	for _, pkg := range res.sortedPackages() {
+		purl := pkg.PURL(res.Distro)
+		if processed[purl] {
+			continue
+		}
+		processed[purl] = true
+
+		component := cycloneDXComponent{
+			Type:       "library",
+			Name:       pkg.Name,
+			Version:    pkg.Version,
+			PURL:       purl,
+			Properties: []cycloneDXProperty{{Name: "werf:package:path", Value: "/" + pkg.Path}},
+		}
+
+		if pkg.License != "" {
+			component.Licenses = []cycloneDXLicense{{Expression: pkg.License}}
+		}
+
+		doc.Components = append(doc.Components, component)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (res *Result) sortedPackages() []*Package {
+	packages := append([]*Package{}, res.Packages...)
+	sort.SliceStable(packages, func(i, j int) bool {
+		if packages[i].Type != packages[j].Type {
+			return packages[i].Type < packages[j].Type
+		}
+		if packages[i].Name != packages[j].Name {
+			return packages[i].Name < packages[j].Name
+		}
+		return packages[i].Version < packages[j].Version
+	})
+
+	return packages
+}
+
+// PURL returns the package URL (https://github.com/package-url/purl-spec), the distro is used as the namespace of the OS packages
+func (pkg *Package) PURL(distro *Distro) string {
+	var namespace string
+	name := pkg.Name
+
+	switch pkg.Type {
+	case DebPackage, ApkPackage, RpmPackage:
+		if distro != nil {
+			namespace = distro.ID
+		}
+	case NpmPackage, ComposerPackage:
+		if idx := strings.LastIndex(name, "/"); idx > 0 {
+			namespace, name = name[:idx], name[idx+1:]
+		}
+	}
+
+	purl := fmt.Sprintf("pkg:%s/", pkg.Type)
+	if namespace != "" {
+		purl += purlEscape(namespace) + "/"
+	}
+	purl += purlEscape(name) + "@" + purlEscape(pkg.Version)
+
+	var qualifiers []string
+	if pkg.Arch != "" {
+		qualifiers = append(qualifiers, "arch="+url.QueryEscape(pkg.Arch))
+	}
+	if distro != nil && distro.ID != "" && (pkg.Type == DebPackage || pkg.Type == ApkPackage || pkg.Type == RpmPackage) {
+		qualifiers = append(qualifiers, "distro="+url.QueryEscape(strings.Trim(distro.ID+"-"+distro.VersionID, "-")))
+	}
+	if pkg.Epoch != "" && pkg.Epoch != "0" {
+		qualifiers = append(qualifiers, "epoch="+pkg.Epoch)
+	}
+
+	if len(qualifiers) != 0 {
+		purl += "?" + strings.Join(qualifiers, "&")
+	}
+
+	return purl
+}
+
+func purlEscape(value string) string {
+	return strings.Replace(url.PathEscape(value), "@", "%40", -1)
+}
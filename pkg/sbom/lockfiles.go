@@ -0,0 +1,275 @@
+package sbom
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+func parseNpmPackageLock(filePath string, data []byte) ([]*Package, error) {
+	type npmDependency struct {
+		Version      string                    `json:"version"`
+		Dependencies map[string]*npmDependency `json:"dependencies"`
+	}
+
+	var lock struct {
+		Packages map[string]struct {
+			Version string      `json:"version"`
+			License interface{} `json:"license"`
+			Link    bool        `json:"link"`
+		} `json:"packages"`
+		Dependencies map[string]*npmDependency `json:"dependencies"`
+	}
+
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	var res []*Package
+
+	// lockfileVersion 2 and 3: the packages are stored by the node_modules path, the root project is stored by the empty path
+	if len(lock.Packages) != 0 {
+		for _, packagePath := range sortedKeys(lock.Packages) {
+			pkg := lock.Packages[packagePath]
+			idx := strings.LastIndex(packagePath, "node_modules/")
+			if idx < 0 || pkg.Link || pkg.Version == "" {
+				continue
+			}
+
+			license, _ := pkg.License.(string)
+			res = append(res, &Package{
+				Type:    NpmPackage,
+				Name:    packagePath[idx+len("node_modules/"):],
+				Version: pkg.Version,
+				License: license,
+				Path:    filePath,
+			})
+		}
+
+		return res, nil
+	}
+
+	var collect func(dependencies map[string]*npmDependency)
+	collect = func(dependencies map[string]*npmDependency) {
+		for _, name := range sortedKeys(dependencies) {
+			dependency := dependencies[name]
+			if dependency == nil {
+				continue
+			}
+
+			if dependency.Version != "" {
+				res = append(res, &Package{Type: NpmPackage, Name: name, Version: dependency.Version, Path: filePath})
+			}
+
+			collect(dependency.Dependencies)
+		}
+	}
+	collect(lock.Dependencies)
+
+	return res, nil
+}
+
+// parseYarnLock parses yarn.lock v1 and berry formats:
+//
+//	"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+//	  version "7.10.4"
+func parseYarnLock(filePath string, data []byte) ([]*Package, error) {
+	var res []*Package
+
+	var name string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") {
+			name = ""
+			if !strings.HasSuffix(line, ":") {
+				continue
+			}
+
+			spec := strings.Trim(strings.TrimSpace(strings.Split(strings.TrimSuffix(line, ":"), ",")[0]), `"`)
+			if idx := strings.LastIndex(spec, "@"); idx > 0 {
+				name = spec[:idx]
+			}
+			continue
+		}
+
+		trimmedLine := strings.TrimSpace(line)
+		if name == "" || !strings.HasPrefix(trimmedLine, "version") {
+			continue
+		}
+
+		version := strings.Trim(strings.TrimSpace(strings.TrimLeft(strings.TrimPrefix(trimmedLine, "version"), ": ")), `"`)
+		res = append(res, &Package{Type: NpmPackage, Name: name, Version: version, Path: filePath})
+		name = ""
+	}
+
+	return res, nil
+}
+
+func parseComposerLock(filePath string, data []byte) ([]*Package, error) {
+	type composerPackage struct {
+		Name    string   `json:"name"`
+		Version string   `json:"version"`
+		License []string `json:"license"`
+	}
+
+	var lock struct {
+		Packages    []composerPackage `json:"packages"`
+		PackagesDev []composerPackage `json:"packages-dev"`
+	}
+
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	var res []*Package
+	for _, pkg := range append(lock.Packages, lock.PackagesDev...) {
+		res = append(res, &Package{
+			Type:    ComposerPackage,
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			License: strings.Join(pkg.License, " OR "),
+			Path:    filePath,
+		})
+	}
+
+	return res, nil
+}
+
+// parseGemfileLock parses the specs of the GEM section, the dependencies of the gems are indented with 6 spaces and skipped:
+//
+//	GEM
+//	  specs:
+//	    rack (2.2.3)
+func parseGemfileLock(filePath string, data []byte) ([]*Package, error) {
+	var res []*Package
+
+	var inSpecs bool
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		switch {
+		case line == "  specs:":
+			inSpecs = true
+		case !strings.HasPrefix(line, " "):
+			inSpecs = false
+		case inSpecs && strings.HasPrefix(line, "    ") && !strings.HasPrefix(line, "     "):
+			parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			res = append(res, &Package{
+				Type:    GemPackage,
+				Name:    parts[0],
+				Version: strings.TrimSuffix(strings.TrimPrefix(parts[1], "("), ")"),
+				Path:    filePath,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+func parsePipfileLock(filePath string, data []byte) ([]*Package, error) {
+	type pipfilePackage struct {
+		Version string `json:"version"`
+	}
+
+	var lock struct {
+		Default map[string]pipfilePackage `json:"default"`
+		Develop map[string]pipfilePackage `json:"develop"`
+	}
+
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	var res []*Package
+	for _, packages := range []map[string]pipfilePackage{lock.Default, lock.Develop} {
+		for _, name := range sortedKeys(packages) {
+			version := strings.TrimPrefix(packages[name].Version, "==")
+			if version == "" {
+				continue
+			}
+
+			res = append(res, &Package{Type: PypiPackage, Name: name, Version: version, Path: filePath})
+		}
+	}
+
+	return res, nil
+}
+
+// newTomlPackagesParser returns the parser of the [[package]] tables with the name and version keys (Cargo.lock, poetry.lock)
+func newTomlPackagesParser(packageType PackageType) fileAnalyzer {
+	return func(filePath string, data []byte) ([]*Package, error) {
+		var res []*Package
+
+		var current *Package
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+
+			if strings.HasPrefix(line, "[") {
+				current = nil
+				if line == "[[package]]" {
+					current = &Package{Type: packageType, Path: filePath}
+					res = append(res, current)
+				}
+				continue
+			}
+
+			parts := strings.SplitN(line, "=", 2)
+			if current == nil || len(parts) != 2 {
+				continue
+			}
+
+			value := strings.Trim(strings.TrimSpace(parts[1]), `"`)
+			switch strings.TrimSpace(parts[0]) {
+			case "name":
+				current.Name = value
+			case "version":
+				current.Version = value
+			}
+		}
+
+		var filtered []*Package
+		for _, pkg := range res {
+			if pkg.Name != "" && pkg.Version != "" {
+				filtered = append(filtered, pkg)
+			}
+		}
+
+		return filtered, nil
+	}
+}
+
+// parsePythonPackageMetadata parses the metadata of the installed python package (*.dist-info/METADATA or *.egg-info/PKG-INFO)
+func parsePythonPackageMetadata(filePath string, data []byte) ([]*Package, error) {
+	records := parseParagraphs(data, ":")
+	if len(records) == 0 || records[0]["Name"] == "" || records[0]["Version"] == "" {
+		return nil, nil
+	}
+
+	return []*Package{{
+		Type:    PypiPackage,
+		Name:    records[0]["Name"],
+		Version: records[0]["Version"],
+		License: records[0]["License"],
+		Path:    filePath,
+	}}, nil
+}
+
+// sortedKeys returns the sorted keys of the map with string keys
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,87 @@
+package sbom
+
+import (
+	"strings"
+)
+
+// parseParagraphs parses the "Key: Value" records separated by empty lines,
+// the lines starting with the space are continuation lines and are skipped
+func parseParagraphs(data []byte, separator string) []map[string]string {
+	var res []map[string]string
+
+	current := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.TrimSpace(line) == "" {
+			if len(current) != 0 {
+				res = append(res, current)
+				current = map[string]string{}
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		parts := strings.SplitN(line, separator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if _, exist := current[parts[0]]; !exist {
+			current[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	if len(current) != 0 {
+		res = append(res, current)
+	}
+
+	return res
+}
+
+func parseDpkgStatus(filePath string, data []byte) ([]*Package, error) {
+	var res []*Package
+	for _, record := range parseParagraphs(data, ":") {
+		if record["Package"] == "" || record["Version"] == "" {
+			continue
+		}
+
+		// the status files of distroless images (status.d) have no Status field
+		if status, exist := record["Status"]; exist && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+
+		res = append(res, &Package{
+			Type:    DebPackage,
+			Name:    record["Package"],
+			Version: record["Version"],
+			Arch:    record["Architecture"],
+			Path:    filePath,
+		})
+	}
+
+	return res, nil
+}
+
+func parseApkInstalled(filePath string, data []byte) ([]*Package, error) {
+	var res []*Package
+	for _, record := range parseParagraphs(data, ":") {
+		if record["P"] == "" || record["V"] == "" {
+			continue
+		}
+
+		res = append(res, &Package{
+			Type:    ApkPackage,
+			Name:    record["P"],
+			Version: record["V"],
+			Arch:    record["A"],
+			License: record["L"],
+			Path:    filePath,
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,222 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The legacy rpm database (var/lib/rpm/Packages) is the Berkeley DB hash database,
+// the values are the rpm headers of the installed packages.
+// The newer rpm databases are the ndb (Packages.db) and the sqlite (rpmdb.sqlite) databases, see rpm_ndb.go and rpm_sqlite.go.
+const (
+	bdbHashMagic            = 0x061561
+	bdbMetadataSize         = 36
+	bdbPageHeaderSize       = 26
+	bdbHashUnsortedPageType = 2
+	bdbHashPageType         = 13
+	bdbOverflowValueType    = 3
+)
+
+const (
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagLicense = 1014
+	rpmTagArch    = 1022
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+func parseRpmPackages(filePath string, data []byte) ([]*Package, error) {
+	headers, err := readBerkeleyDBHashValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRpmHeaders(filePath, headers)
+}
+
+func parseRpmHeaders(filePath string, headers [][]byte) ([]*Package, error) {
+	var res []*Package
+	for _, header := range headers {
+		pkg, err := parseRpmHeader(header)
+		if err != nil {
+			return nil, err
+		}
+
+		// the imported gpg keys are stored as the packages
+		if pkg.Name == "" || pkg.Name == "gpg-pubkey" {
+			continue
+		}
+
+		pkg.Path = filePath
+		res = append(res, pkg)
+	}
+
+	return res, nil
+}
+
+func readBerkeleyDBHashValues(data []byte) ([][]byte, error) {
+	if len(data) < bdbMetadataSize {
+		return nil, errors.New("unexpected database size")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[12:16]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(data[12:16]) != bdbHashMagic {
+			return nil, errors.New("database is not a Berkeley DB hash database")
+		}
+	}
+
+	pageSize := int(order.Uint32(data[20:24]))
+	lastPageNo := int(order.Uint32(data[32:36]))
+	if pageSize < bdbPageHeaderSize {
+		return nil, fmt.Errorf("unexpected page size %d", pageSize)
+	}
+
+	getPage := func(pageNo int) ([]byte, error) {
+		start := pageNo * pageSize
+		if pageNo < 0 || start+pageSize > len(data) {
+			return nil, fmt.Errorf("page %d is out of the database", pageNo)
+		}
+		return data[start : start+pageSize], nil
+	}
+
+	var res [][]byte
+	for pageNo := 1; pageNo <= lastPageNo; pageNo++ {
+		page, err := getPage(pageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		if pageType := page[25]; pageType != bdbHashPageType && pageType != bdbHashUnsortedPageType {
+			continue
+		}
+
+		// the entries are key-value pairs, only the values stored on the overflow pages are the rpm headers
+		numEntries := int(order.Uint16(page[20:22]))
+		for i := 1; i < numEntries; i += 2 {
+			offsetPos := bdbPageHeaderSize + i*2
+			if offsetPos+2 > len(page) {
+				break
+			}
+
+			offset := int(order.Uint16(page[offsetPos : offsetPos+2]))
+			if offset+12 > len(page) || page[offset] != bdbOverflowValueType {
+				continue
+			}
+
+			valuePageNo := int(order.Uint32(page[offset+4 : offset+8]))
+			valueLength := int(order.Uint32(page[offset+8 : offset+12]))
+
+			value, err := readBerkeleyDBOverflowValue(getPage, order, valuePageNo, valueLength)
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, value)
+		}
+	}
+
+	return res, nil
+}
+
+func readBerkeleyDBOverflowValue(getPage func(int) ([]byte, error), order binary.ByteOrder, pageNo, length int) ([]byte, error) {
+	var value []byte
+	for pageNo != 0 && len(value) < length {
+		page, err := getPage(pageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		nextPageNo := int(order.Uint32(page[16:20]))
+		end := len(page)
+		if nextPageNo == 0 {
+			end = bdbPageHeaderSize + int(order.Uint16(page[22:24]))
+			if end > len(page) {
+				end = len(page)
+			}
+		}
+
+		value = append(value, page[bdbPageHeaderSize:end]...)
+		pageNo = nextPageNo
+	}
+
+	if len(value) < length {
+		return nil, errors.New("unexpected end of the overflow value")
+	}
+
+	return value[:length], nil
+}
+
+// parseRpmHeader parses the rpm header blob: the index entries count, the data size, the index entries and the data
+func parseRpmHeader(blob []byte) (*Package, error) {
+	if len(blob) < 8 {
+		return nil, errors.New("unexpected rpm header size")
+	}
+
+	indexLength := int(binary.BigEndian.Uint32(blob[0:4]))
+	dataLength := int(binary.BigEndian.Uint32(blob[4:8]))
+	dataStart := 8 + indexLength*16
+	if indexLength < 0 || dataLength < 0 || dataStart+dataLength > len(blob) {
+		return nil, errors.New("unexpected rpm header size")
+	}
+
+	store := blob[dataStart : dataStart+dataLength]
+
+	pkg := &Package{Type: RpmPackage}
+	var release string
+	for i := 0; i < indexLength; i++ {
+		entry := blob[8+i*16 : 8+(i+1)*16]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		typ := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+
+		if offset < 0 || offset >= len(store) {
+			continue
+		}
+
+		switch tag {
+		case rpmTagName, rpmTagVersion, rpmTagRelease, rpmTagLicense, rpmTagArch:
+			if typ != rpmTypeString && typ != rpmTypeStringArray && typ != rpmTypeI18NString {
+				continue
+			}
+
+			value := store[offset:]
+			if end := bytes.IndexByte(value, 0); end >= 0 {
+				value = value[:end]
+			}
+
+			switch tag {
+			case rpmTagName:
+				pkg.Name = string(value)
+			case rpmTagVersion:
+				pkg.Version = string(value)
+			case rpmTagRelease:
+				release = string(value)
+			case rpmTagLicense:
+				pkg.License = string(value)
+			case rpmTagArch:
+				pkg.Arch = string(value)
+			}
+		case rpmTagEpoch:
+			if typ == rpmTypeInt32 && offset+4 <= len(store) {
+				pkg.Epoch = strconv.FormatUint(uint64(binary.BigEndian.Uint32(store[offset:offset+4])), 10)
+			}
+		}
+	}
+
+	if release != "" {
+		pkg.Version = fmt.Sprintf("%s-%s", pkg.Version, release)
+	}
+
+	return pkg, nil
+}
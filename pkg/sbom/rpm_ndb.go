@@ -0,0 +1,85 @@
+package sbom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The ndb rpm database (Packages.db) consists of the slot pages and the blobs,
+// each used slot refers to the blob with the rpm header of the installed package.
+const (
+	ndbHeaderMagic     = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic       = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic       = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	ndbVersion         = 0
+	ndbPageSize        = 4096
+	ndbSlotSize        = 16
+	ndbHeaderSize      = 2 * ndbSlotSize
+	ndbBlockSize       = 16
+	ndbBlobHeaderSize  = 16
+	ndbMaxSlotPagesNum = 1 << 16
+)
+
+func parseRpmNdbPackages(filePath string, data []byte) ([]*Package, error) {
+	headers, err := readNdbBlobs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRpmHeaders(filePath, headers)
+}
+
+func readNdbBlobs(data []byte) ([][]byte, error) {
+	order := binary.LittleEndian
+
+	if len(data) < ndbHeaderSize {
+		return nil, errors.New("unexpected database size")
+	}
+
+	if order.Uint32(data[0:4]) != ndbHeaderMagic {
+		return nil, errors.New("database is not a ndb database")
+	}
+
+	if version := order.Uint32(data[4:8]); version != ndbVersion {
+		return nil, fmt.Errorf("unsupported ndb database version %d", version)
+	}
+
+	slotPagesNum := int(order.Uint32(data[12:16]))
+	if slotPagesNum <= 0 || slotPagesNum > ndbMaxSlotPagesNum || slotPagesNum*ndbPageSize > len(data) {
+		return nil, fmt.Errorf("unexpected slot pages number %d", slotPagesNum)
+	}
+
+	var res [][]byte
+	for slotOffset := ndbHeaderSize; slotOffset < slotPagesNum*ndbPageSize; slotOffset += ndbSlotSize {
+		slot := data[slotOffset : slotOffset+ndbSlotSize]
+		if order.Uint32(slot[0:4]) != ndbSlotMagic {
+			return nil, fmt.Errorf("unexpected slot magic at offset %d", slotOffset)
+		}
+
+		pkgIndex := order.Uint32(slot[4:8])
+		if pkgIndex == 0 {
+			continue
+		}
+
+		blobOffset := int(order.Uint32(slot[8:12])) * ndbBlockSize
+		if blobOffset < 0 || blobOffset+ndbBlobHeaderSize > len(data) {
+			return nil, fmt.Errorf("blob of package %d is out of the database", pkgIndex)
+		}
+
+		blobHeader := data[blobOffset : blobOffset+ndbBlobHeaderSize]
+		if order.Uint32(blobHeader[0:4]) != ndbBlobMagic || order.Uint32(blobHeader[4:8]) != pkgIndex {
+			return nil, fmt.Errorf("unexpected blob header of package %d", pkgIndex)
+		}
+
+		blobLength := int(order.Uint32(blobHeader[12:16]))
+		blobStart := blobOffset + ndbBlobHeaderSize
+		if blobLength < 0 || blobStart+blobLength > len(data) {
+			return nil, fmt.Errorf("blob of package %d is out of the database", pkgIndex)
+		}
+
+		res = append(res, data[blobStart:blobStart+blobLength])
+	}
+
+	return res, nil
+}
@@ -0,0 +1,334 @@
+package sbom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The sqlite rpm database (rpmdb.sqlite) stores the rpm headers of the installed packages
+// in the blob column of the Packages table, the table b-tree is read directly from the database file.
+const (
+	sqliteHeader                = "SQLite format 3\x00"
+	sqliteHeaderSize            = 100
+	sqliteInteriorTablePageType = 0x05
+	sqliteLeafTablePageType     = 0x0d
+	sqliteRpmPackagesTable      = "Packages"
+)
+
+func parseRpmSqlitePackages(filePath string, data []byte) ([]*Package, error) {
+	db, err := newSqliteDB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rootPageNo, err := db.tableRootPage(sqliteRpmPackagesTable)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers [][]byte
+	if err := db.forEachTableRecord(rootPageNo, func(values []interface{}) error {
+		for _, value := range values {
+			if blob, ok := value.([]byte); ok {
+				headers = append(headers, blob)
+				break
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return parseRpmHeaders(filePath, headers)
+}
+
+type sqliteDB struct {
+	data       []byte
+	pageSize   int
+	usableSize int
+}
+
+func newSqliteDB(data []byte) (*sqliteDB, error) {
+	if len(data) < sqliteHeaderSize || string(data[:len(sqliteHeader)]) != sqliteHeader {
+		return nil, errors.New("database is not a sqlite database")
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 {
+		return nil, fmt.Errorf("unexpected page size %d", pageSize)
+	}
+
+	return &sqliteDB{data: data, pageSize: pageSize, usableSize: pageSize - int(data[20])}, nil
+}
+
+func (db *sqliteDB) getPage(pageNo int) ([]byte, error) {
+	start := (pageNo - 1) * db.pageSize
+	if pageNo < 1 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("page %d is out of the database", pageNo)
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// tableRootPage finds the table in the sqlite_master table: type, name, tbl_name, rootpage, sql
+func (db *sqliteDB) tableRootPage(tableName string) (int, error) {
+	rootPageNo := 0
+	if err := db.forEachTableRecord(1, func(values []interface{}) error {
+		if len(values) < 4 || values[0] != "table" || values[1] != tableName {
+			return nil
+		}
+
+		if pageNo, ok := values[3].(int64); ok {
+			rootPageNo = int(pageNo)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	if rootPageNo == 0 {
+		return 0, fmt.Errorf("table %s is not found", tableName)
+	}
+
+	return rootPageNo, nil
+}
+
+func (db *sqliteDB) forEachTableRecord(rootPageNo int, f func(values []interface{}) error) error {
+	isVisited := map[int]bool{}
+
+	var walk func(pageNo int) error
+	walk = func(pageNo int) error {
+		if isVisited[pageNo] {
+			return fmt.Errorf("page %d is referred several times", pageNo)
+		}
+		isVisited[pageNo] = true
+
+		page, err := db.getPage(pageNo)
+		if err != nil {
+			return err
+		}
+
+		// the first page contains the database header before the b-tree page header
+		headerOffset := 0
+		if pageNo == 1 {
+			headerOffset = sqliteHeaderSize
+		}
+
+		if headerOffset+8 > len(page) {
+			return fmt.Errorf("unexpected page %d size", pageNo)
+		}
+
+		pageType := page[headerOffset]
+		cellsNum := int(binary.BigEndian.Uint16(page[headerOffset+3 : headerOffset+5]))
+
+		cellPointersOffset := headerOffset + 8
+		if pageType == sqliteInteriorTablePageType {
+			cellPointersOffset = headerOffset + 12
+		} else if pageType != sqliteLeafTablePageType {
+			return fmt.Errorf("unexpected page %d type %d", pageNo, pageType)
+		}
+
+		if cellPointersOffset+cellsNum*2 > len(page) {
+			return fmt.Errorf("unexpected page %d cells number %d", pageNo, cellsNum)
+		}
+
+		for i := 0; i < cellsNum; i++ {
+			cellOffset := int(binary.BigEndian.Uint16(page[cellPointersOffset+i*2 : cellPointersOffset+i*2+2]))
+			if cellOffset >= len(page) {
+				return fmt.Errorf("unexpected page %d cell offset %d", pageNo, cellOffset)
+			}
+
+			if pageType == sqliteInteriorTablePageType {
+				if cellOffset+4 > len(page) {
+					return fmt.Errorf("unexpected page %d cell offset %d", pageNo, cellOffset)
+				}
+
+				if err := walk(int(binary.BigEndian.Uint32(page[cellOffset : cellOffset+4]))); err != nil {
+					return err
+				}
+				continue
+			}
+
+			payload, err := db.readLeafTableCellPayload(page, cellOffset)
+			if err != nil {
+				return fmt.Errorf("unable to read page %d cell %d: %s", pageNo, i, err)
+			}
+
+			values, err := parseSqliteRecord(payload)
+			if err != nil {
+				return fmt.Errorf("unable to parse page %d cell %d record: %s", pageNo, i, err)
+			}
+
+			if err := f(values); err != nil {
+				return err
+			}
+		}
+
+		if pageType == sqliteInteriorTablePageType {
+			return walk(int(binary.BigEndian.Uint32(page[headerOffset+8 : headerOffset+12])))
+		}
+
+		return nil
+	}
+
+	return walk(rootPageNo)
+}
+
+// readLeafTableCellPayload reads the cell payload, the payload which does not fit the page is continued on the overflow pages
+func (db *sqliteDB) readLeafTableCellPayload(page []byte, cellOffset int) ([]byte, error) {
+	payloadSize, n := readSqliteVarint(page[cellOffset:])
+	if n == 0 {
+		return nil, errors.New("unexpected payload size")
+	}
+	cellOffset += n
+
+	// rowid
+	if _, n = readSqliteVarint(page[cellOffset:]); n == 0 {
+		return nil, errors.New("unexpected rowid")
+	}
+	cellOffset += n
+
+	size := int(payloadSize)
+	maxLocal := db.usableSize - 35
+	localSize := size
+	if size > maxLocal {
+		minLocal := (db.usableSize-12)*32/255 - 23
+		localSize = minLocal + (size-minLocal)%(db.usableSize-4)
+		if localSize > maxLocal {
+			localSize = minLocal
+		}
+	}
+
+	if size < 0 || cellOffset+localSize > len(page) {
+		return nil, errors.New("unexpected payload size")
+	}
+
+	payload := append([]byte{}, page[cellOffset:cellOffset+localSize]...)
+	if localSize == size {
+		return payload, nil
+	}
+
+	if cellOffset+localSize+4 > len(page) {
+		return nil, errors.New("unexpected overflow page number")
+	}
+
+	isVisited := map[int]bool{}
+	overflowPageNo := int(binary.BigEndian.Uint32(page[cellOffset+localSize : cellOffset+localSize+4]))
+	for len(payload) < size {
+		if overflowPageNo == 0 || isVisited[overflowPageNo] {
+			return nil, errors.New("unexpected end of the overflow payload")
+		}
+		isVisited[overflowPageNo] = true
+
+		overflowPage, err := db.getPage(overflowPageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		end := db.usableSize
+		if rest := size - len(payload); 4+rest < end {
+			end = 4 + rest
+		}
+
+		payload = append(payload, overflowPage[4:end]...)
+		overflowPageNo = int(binary.BigEndian.Uint32(overflowPage[0:4]))
+	}
+
+	return payload, nil
+}
+
+// parseSqliteRecord returns the record values: nil, int64, float64, string or []byte
+func parseSqliteRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := readSqliteVarint(payload)
+	if n == 0 || int(headerSize) > len(payload) || int(headerSize) < n {
+		return nil, errors.New("unexpected record header size")
+	}
+
+	var serialTypes []uint64
+	for offset := n; offset < int(headerSize); {
+		serialType, n := readSqliteVarint(payload[offset:headerSize])
+		if n == 0 {
+			return nil, errors.New("unexpected record header")
+		}
+		serialTypes = append(serialTypes, serialType)
+		offset += n
+	}
+
+	var values []interface{}
+	body := payload[headerSize:]
+	for _, serialType := range serialTypes {
+		var size int
+		switch {
+		case serialType == 0, serialType == 8, serialType == 9:
+			size = 0
+		case serialType >= 1 && serialType <= 4:
+			size = int(serialType)
+		case serialType == 5:
+			size = 6
+		case serialType == 6, serialType == 7:
+			size = 8
+		case serialType >= 12:
+			size = int((serialType - 12) / 2)
+		default:
+			return nil, fmt.Errorf("unexpected serial type %d", serialType)
+		}
+
+		if size < 0 || size > len(body) {
+			return nil, errors.New("unexpected record size")
+		}
+		value := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			values = append(values, nil)
+		case serialType == 8:
+			values = append(values, int64(0))
+		case serialType == 9:
+			values = append(values, int64(1))
+		case serialType <= 6:
+			// big-endian two's complement integer
+			var v int64
+			if value[0]&0x80 != 0 {
+				v = -1
+			}
+			for _, b := range value {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		case serialType == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(value)))
+		case serialType%2 == 0:
+			values = append(values, value)
+		default:
+			values = append(values, string(value))
+		}
+	}
+
+	return values, nil
+}
+
+// readSqliteVarint returns the value and the number of the read bytes, 0 bytes are returned for the invalid varint
+func readSqliteVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(data) {
+			return 0, 0
+		}
+
+		if i == 8 {
+			return v<<8 | uint64(data[i]), 9
+		}
+
+		v = v<<7 | uint64(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+
+	return v, 9
+}
@@ -0,0 +1,154 @@
+package sbom
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+type PackageType string
+
+const (
+	DebPackage      PackageType = "deb"
+	ApkPackage      PackageType = "apk"
+	RpmPackage      PackageType = "rpm"
+	NpmPackage      PackageType = "npm"
+	ComposerPackage PackageType = "composer"
+	GemPackage      PackageType = "gem"
+	PypiPackage     PackageType = "pypi"
+	CargoPackage    PackageType = "cargo"
+)
+
+// maxFileSize limits the size of the files read from the image filesystem, bigger files are skipped
+const maxFileSize = 256 * 1024 * 1024
+
+type Package struct {
+	Type    PackageType
+	Name    string
+	Version string
+	Epoch   string
+	Arch    string
+	License string
+	// Path is the file of the image filesystem where the package is found
+	Path string
+}
+
+type Distro struct {
+	ID        string
+	VersionID string
+}
+
+type Result struct {
+	Distro   *Distro
+	Packages []*Package
+}
+
+type fileAnalyzer func(filePath string, data []byte) ([]*Package, error)
+
+// getFileAnalyzer returns the analyzer for the OS package database or the language lockfile, nil is returned for other files
+func getFileAnalyzer(filePath string) fileAnalyzer {
+	dir, base := path.Split(filePath)
+
+	switch {
+	case filePath == "var/lib/dpkg/status" || dir == "var/lib/dpkg/status.d/":
+		return parseDpkgStatus
+	case filePath == "lib/apk/db/installed":
+		return parseApkInstalled
+	case filePath == "var/lib/rpm/Packages" || filePath == "usr/lib/sysimage/rpm/Packages":
+		return parseRpmPackages
+	case filePath == "var/lib/rpm/Packages.db" || filePath == "usr/lib/sysimage/rpm/Packages.db":
+		return parseRpmNdbPackages
+	case filePath == "var/lib/rpm/rpmdb.sqlite" || filePath == "usr/lib/sysimage/rpm/rpmdb.sqlite":
+		return parseRpmSqlitePackages
+	case base == "package-lock.json":
+		return parseNpmPackageLock
+	case base == "yarn.lock":
+		return parseYarnLock
+	case base == "composer.lock":
+		return parseComposerLock
+	case base == "Gemfile.lock":
+		return parseGemfileLock
+	case base == "Pipfile.lock":
+		return parsePipfileLock
+	case base == "poetry.lock":
+		return newTomlPackagesParser(PypiPackage)
+	case base == "Cargo.lock":
+		return newTomlPackagesParser(CargoPackage)
+	case base == "METADATA" && strings.HasSuffix(dir, ".dist-info/"), base == "PKG-INFO" && strings.HasSuffix(dir, ".egg-info/"):
+		return parsePythonPackageMetadata
+	default:
+		return nil
+	}
+}
+
+// Scan inspects the flattened image filesystem tar stream: OS package databases (dpkg, apk, rpm) and language lockfiles
+func Scan(r io.Reader) (*Result, error) {
+	res := &Result{}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image filesystem: %s", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		filePath := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+
+		isOSRelease := filePath == "etc/os-release" || filePath == "usr/lib/os-release"
+		analyzer := getFileAnalyzer(filePath)
+		if (!isOSRelease && analyzer == nil) || hdr.Size > maxFileSize {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %s", filePath, err)
+		}
+
+		if isOSRelease {
+			if res.Distro == nil || filePath == "etc/os-release" {
+				res.Distro = parseOSRelease(data)
+			}
+			continue
+		}
+
+		packages, err := analyzer(filePath, data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %s", filePath, err)
+		}
+
+		res.Packages = append(res.Packages, packages...)
+	}
+
+	return res, nil
+}
+
+func parseOSRelease(data []byte) *Distro {
+	distro := &Distro{}
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		value := strings.Trim(parts[1], `"'`)
+		switch parts[0] {
+		case "ID":
+			distro.ID = value
+		case "VERSION_ID":
+			distro.VersionID = value
+		}
+	}
+
+	return distro
+}
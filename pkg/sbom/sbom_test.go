@@ -0,0 +1,281 @@
+package sbom
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func newTestTar(t *testing.T, files map[string]string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf
+}
+
+func TestScan(t *testing.T) {
+	buf := newTestTar(t, map[string]string{
+		"etc/os-release": "ID=debian\nVERSION_ID=\"10\"\n",
+		"var/lib/dpkg/status": `Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.28-10
+Description: GNU C Library
+ continuation line
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0
+`,
+		"app/package-lock.json": `{"lockfileVersion": 2, "packages": {"": {"name": "app"}, "node_modules/@babel/core": {"version": "7.12.3", "license": "MIT"}}}`,
+		"app/Gemfile.lock": `GEM
+  remote: https://rubygems.org/
+  specs:
+    rack (2.2.3)
+    rails (6.0.3)
+      rack (>= 2.0)
+
+PLATFORMS
+  ruby
+`,
+		"app/Cargo.lock": "[[package]]\nname = \"serde\"\nversion = \"1.0.117\"\n\n[metadata]\nname = \"ignored\"\n",
+		"usr/lib/python3/site-packages/requests-2.24.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: requests\nVersion: 2.24.0\nLicense: Apache 2.0\n\nLong description\n",
+		"app/README.md": "Version: 1.0\n",
+	})
+
+	res, err := Scan(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Distro == nil || res.Distro.ID != "debian" || res.Distro.VersionID != "10" {
+		t.Errorf("unexpected distro: %#v", res.Distro)
+	}
+
+	expected := map[string]bool{
+		"pkg:deb/debian/libc6@2.28-10?arch=amd64&distro=debian-10": true,
+		"pkg:npm/%40babel/core@7.12.3":                             true,
+		"pkg:gem/rack@2.2.3":                                       true,
+		"pkg:gem/rails@6.0.3":                                      true,
+		"pkg:cargo/serde@1.0.117":                                  true,
+		"pkg:pypi/requests@2.24.0":                                 true,
+	}
+
+	if len(res.Packages) != len(expected) {
+		t.Errorf("expected %d packages, got %d", len(expected), len(res.Packages))
+	}
+
+	for _, pkg := range res.Packages {
+		if purl := pkg.PURL(res.Distro); !expected[purl] {
+			t.Errorf("unexpected package %s", purl)
+		}
+	}
+
+	data, err := res.CycloneDX("registry.example.com/project:tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc cycloneDXDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.BOMFormat != "CycloneDX" || len(doc.Components) != len(expected)+1 || doc.Components[0].Type != "operating-system" {
+		t.Errorf("unexpected CycloneDX document:\n%s", data)
+	}
+}
+
+func TestParseApkInstalled(t *testing.T) {
+	packages, err := parseApkInstalled("lib/apk/db/installed", []byte("C:Q1abc=\nP:musl\nV:1.1.24-r10\nA:x86_64\nL:MIT\n\nP:busybox\nV:1.31.1-r19\nA:x86_64\nL:GPL-2.0-only\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packages) != 2 || packages[0].Name != "musl" || packages[0].Version != "1.1.24-r10" || packages[1].License != "GPL-2.0-only" {
+		t.Errorf("unexpected packages: %#v", packages)
+	}
+}
+
+func TestParseYarnLock(t *testing.T) {
+	packages, err := parseYarnLock("yarn.lock", []byte(`# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+  version "7.10.4"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.10.4.tgz"
+
+lodash@^4.17.19:
+  version "4.17.20"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packages) != 2 || packages[0].Name != "@babel/code-frame" || packages[0].Version != "7.10.4" || packages[1].Name != "lodash" {
+		t.Errorf("unexpected packages: %#v", packages)
+	}
+}
+
+func TestParseRpmHeader(t *testing.T) {
+	type entry struct {
+		tag, typ uint32
+		data     []byte
+	}
+
+	epoch := make([]byte, 4)
+	binary.BigEndian.PutUint32(epoch, 1)
+
+	entries := []entry{
+		{rpmTagName, rpmTypeString, []byte("openssl\x00")},
+		{rpmTagVersion, rpmTypeString, []byte("1.1.1g\x00")},
+		{rpmTagRelease, rpmTypeString, []byte("12.el8_3\x00")},
+		{rpmTagEpoch, rpmTypeInt32, epoch},
+		{rpmTagLicense, rpmTypeString, []byte("OpenSSL\x00")},
+		{rpmTagArch, rpmTypeString, []byte("x86_64\x00")},
+	}
+
+	var index, store bytes.Buffer
+	for _, e := range entries {
+		for _, v := range []uint32{e.tag, e.typ, uint32(store.Len()), 1} {
+			_ = binary.Write(&index, binary.BigEndian, v)
+		}
+		store.Write(e.data)
+	}
+
+	var blob bytes.Buffer
+	_ = binary.Write(&blob, binary.BigEndian, uint32(len(entries)))
+	_ = binary.Write(&blob, binary.BigEndian, uint32(store.Len()))
+	blob.Write(index.Bytes())
+	blob.Write(store.Bytes())
+
+	pkg, err := parseRpmHeader(blob.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if purl := pkg.PURL(&Distro{ID: "rhel", VersionID: "8.3"}); purl != "pkg:rpm/rhel/openssl@1.1.1g-12.el8_3?arch=x86_64&distro=rhel-8.3&epoch=1" {
+		t.Errorf("unexpected package %s", purl)
+	}
+
+	if pkg.License != "OpenSSL" {
+		t.Errorf("unexpected license %q", pkg.License)
+	}
+
+	if _, err := parseRpmHeader(blob.Bytes()[:20]); err == nil {
+		t.Errorf("expected error for truncated header")
+	}
+}
+
+func newTestRpmHeader(name, version string) []byte {
+	var index, store bytes.Buffer
+	for _, e := range []struct {
+		tag   uint32
+		value string
+	}{{rpmTagName, name}, {rpmTagVersion, version}} {
+		for _, v := range []uint32{e.tag, rpmTypeString, uint32(store.Len()), 1} {
+			_ = binary.Write(&index, binary.BigEndian, v)
+		}
+		store.WriteString(e.value + "\x00")
+	}
+
+	var blob bytes.Buffer
+	_ = binary.Write(&blob, binary.BigEndian, uint32(2))
+	_ = binary.Write(&blob, binary.BigEndian, uint32(store.Len()))
+	blob.Write(index.Bytes())
+	blob.Write(store.Bytes())
+
+	return blob.Bytes()
+}
+
+func TestParseRpmNdbPackages(t *testing.T) {
+	headers := [][]byte{newTestRpmHeader("bash", "5.1.8"), newTestRpmHeader("gpg-pubkey", "1"), newTestRpmHeader("zlib", "1.2.11")}
+
+	data := make([]byte, ndbPageSize)
+	order := binary.LittleEndian
+	order.PutUint32(data[0:4], ndbHeaderMagic)
+	order.PutUint32(data[4:8], ndbVersion)
+	order.PutUint32(data[12:16], 1)
+
+	for slotOffset := ndbHeaderSize; slotOffset < ndbPageSize; slotOffset += ndbSlotSize {
+		order.PutUint32(data[slotOffset:slotOffset+4], ndbSlotMagic)
+	}
+
+	for i, header := range headers {
+		pkgIndex := uint32(i + 1)
+		slot := data[ndbHeaderSize+i*ndbSlotSize:]
+		order.PutUint32(slot[4:8], pkgIndex)
+		order.PutUint32(slot[8:12], uint32(len(data)/ndbBlockSize))
+
+		blob := make([]byte, ndbBlobHeaderSize+len(header))
+		order.PutUint32(blob[0:4], ndbBlobMagic)
+		order.PutUint32(blob[4:8], pkgIndex)
+		order.PutUint32(blob[12:16], uint32(len(header)))
+		copy(blob[ndbBlobHeaderSize:], header)
+
+		// blobs are aligned to the blocks
+		for len(blob)%ndbBlockSize != 0 {
+			blob = append(blob, 0)
+		}
+		data = append(data, blob...)
+	}
+
+	packages, err := parseRpmNdbPackages("var/lib/rpm/Packages.db", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packages) != 2 || packages[0].Name != "bash" || packages[0].Version != "5.1.8" || packages[1].Name != "zlib" {
+		t.Errorf("unexpected packages: %#v", packages)
+	}
+
+	if _, err := parseRpmNdbPackages("var/lib/rpm/Packages.db", data[:ndbPageSize-1]); err == nil {
+		t.Errorf("expected error for truncated database")
+	}
+}
+
+func TestParseRpmSqlitePackages(t *testing.T) {
+	// the database with the page size 1024 contains the interior table pages and the overflow pages
+	data, err := ioutil.ReadFile("testdata/rpmdb.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packages, err := parseRpmSqlitePackages("var/lib/rpm/rpmdb.sqlite", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packages) != 31 {
+		t.Fatalf("unexpected packages number %d", len(packages))
+	}
+
+	for i := 0; i < 30; i++ {
+		if packages[i].Name != fmt.Sprintf("package-%d", i) || packages[i].Version != fmt.Sprintf("1.%d-1.el9", i) {
+			t.Errorf("unexpected package %#v", packages[i])
+		}
+	}
+
+	if pkg := packages[30]; pkg.Name != "big-license" || pkg.Version != "2.0-3.el9" || len(pkg.License) != 4000 {
+		t.Errorf("unexpected package %s %s with license size %d", pkg.Name, pkg.Version, len(pkg.License))
+	}
+
+	if _, err := parseRpmSqlitePackages("var/lib/rpm/rpmdb.sqlite", data[:2048]); err == nil {
+		t.Errorf("expected error for truncated database")
+	}
+}
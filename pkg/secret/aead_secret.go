@@ -0,0 +1,102 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// AeadSecretPrefix is the version prefix of the authenticated encryption format: v2:HEX(NONCE+CIPHERTEXT+TAG),
+// the data in the legacy format (AesSecret) is the hex string without any prefix
+const AeadSecretPrefix = "v2:"
+
+const aeadSecretKeyInfo = "werf secret v2 aes-gcm key"
+
+// AeadSecret encrypts data with AES-256-GCM, the tampered data is rejected on decryption.
+// The encryption key is derived from the secret key with HKDF-SHA256, so the same secret key could be used with AesSecret
+type AeadSecret struct {
+	AEAD cipher.AEAD
+}
+
+func NewAeadSecret(key []byte) (*AeadSecret, error) {
+	key, err := hexToBinary(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+
+	aeadKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(aeadSecretKeyInfo)), aeadKey); err != nil {
+		return nil, err
+	}
+
+	c, err := aes.NewCipher(aeadKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AeadSecret{AEAD: aead}, nil
+}
+
+func (s *AeadSecret) Encrypt(data []byte) ([]byte, error) {
+	nonce := make([]byte, s.AEAD.NonceSize(), s.AEAD.NonceSize()+len(data)+s.AEAD.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	cipherData := s.AEAD.Seal(nonce, nonce, data, []byte(AeadSecretPrefix))
+
+	result := make([]byte, len(AeadSecretPrefix)+hex.EncodedLen(len(cipherData)))
+	copy(result, AeadSecretPrefix)
+	hex.Encode(result[len(AeadSecretPrefix):], cipherData)
+
+	return result, nil
+}
+
+func (s *AeadSecret) Decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	if !IsAeadSecretData(data) {
+		return nil, fmt.Errorf("unsupported data format: %s prefix expected", AeadSecretPrefix)
+	}
+
+	dataToExtract, err := hexToBinary(data[len(AeadSecretPrefix):])
+	if err != nil {
+		return nil, err
+	}
+
+	minimalDataBinarySize := s.AEAD.NonceSize() + s.AEAD.Overhead()
+	if len(dataToExtract) < minimalDataBinarySize {
+		return nil, fmt.Errorf("minimum required data length: '%v'", len(AeadSecretPrefix)+minimalDataBinarySize*2)
+	}
+
+	nonce := dataToExtract[:s.AEAD.NonceSize()]
+	cipherData := dataToExtract[s.AEAD.NonceSize():]
+
+	result, err := s.AEAD.Open(nil, nonce, cipherData, []byte(AeadSecretPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("data authentication failed: %s", err)
+	}
+
+	return result, nil
+}
+
+func IsAeadSecretData(data []byte) bool {
+	return len(data) >= len(AeadSecretPrefix) && string(data[:len(AeadSecretPrefix)]) == AeadSecretPrefix
+}
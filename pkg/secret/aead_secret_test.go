@@ -0,0 +1,107 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAeadSecret(t *testing.T) {
+	s, err := NewAeadSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []string{"", "value"} {
+		t.Run(test, func(t *testing.T) {
+			encodedData, err := s.Encrypt([]byte(test))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(string(encodedData), AeadSecretPrefix) {
+				t.Errorf("expected %s prefix: %s", AeadSecretPrefix, encodedData)
+			}
+
+			result, err := s.Decrypt(encodedData)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test != string(result) {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test, result)
+			}
+		})
+	}
+}
+
+func TestAeadSecret_Decrypt_tampered(t *testing.T) {
+	s, err := NewAeadSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := s.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := len(encodedData) - 1
+	if encodedData[last] == '0' {
+		encodedData[last] = '1'
+	} else {
+		encodedData[last] = '0'
+	}
+
+	if _, err := s.Decrypt(encodedData); err == nil || !strings.HasPrefix(err.Error(), "data authentication failed") {
+		t.Errorf("expected authentication error, got: %v", err)
+	}
+
+	anotherKeySecret, err := NewAeadSecret([]byte("c12a9d0b3de0e5c8e0a7b4b0d1bdd3a8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err = s.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := anotherKeySecret.Decrypt(encodedData); err == nil {
+		t.Errorf("expected error for another key")
+	}
+}
+
+func TestNewSecret_legacyFormat(t *testing.T) {
+	s, err := NewSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacySecret, err := NewAesSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyEncodedData, err := legacySecret.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Decrypt(legacyEncodedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "value" {
+		t.Errorf("unexpected legacy data decryption result: %s", result)
+	}
+
+	encodedData, err := s.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsAeadSecretData(encodedData) {
+		t.Errorf("expected data in the authenticated encryption format: %s", encodedData)
+	}
+}
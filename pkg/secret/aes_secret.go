@@ -134,6 +134,7 @@ func IsExtractDataError(err error) bool {
 	dataErrorPrefixs := []string{
 		"minimum required data length",
 		"encoding/hex: odd length hex string",
+		"unsupported data format",
 	}
 
 	for _, prefix := range dataErrorPrefixs {
@@ -5,11 +5,35 @@ type Secret interface {
 	Decrypt(encodedData []byte) ([]byte, error)
 }
 
+// NewSecret returns the secret which encrypts data in the authenticated encryption format (AeadSecret)
+// and decrypts data both in the authenticated and the legacy (AesSecret) formats
 func NewSecret(key []byte) (Secret, error) {
-	s, err := NewAesSecret(key)
+	aeadSecret, err := NewAeadSecret(key)
 	if err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	legacySecret, err := NewAesSecret(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &versionedSecret{current: aeadSecret, legacy: legacySecret}, nil
+}
+
+type versionedSecret struct {
+	current *AeadSecret
+	legacy  *AesSecret
+}
+
+func (s *versionedSecret) Encrypt(data []byte) ([]byte, error) {
+	return s.current.Encrypt(data)
+}
+
+func (s *versionedSecret) Decrypt(data []byte) ([]byte, error) {
+	if IsAeadSecretData(data) {
+		return s.current.Decrypt(data)
+	}
+
+	return s.legacy.Decrypt(data)
 }
@@ -0,0 +1,174 @@
+package secret
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// X25519RecipientPrefix is the prefix of the recipient public key string
+	X25519RecipientPrefix = "werf-x25519:"
+	// X25519IdentityPrefix is the prefix of the identity (private key) string
+	X25519IdentityPrefix = "WERF-X25519-IDENTITY:"
+
+	x25519WrapKeyInfo = "werf secret x25519 key wrap"
+	// x25519WrapOverhead is the size of the Poly1305 authentication tag
+	x25519WrapOverhead = 16
+)
+
+// X25519Identity is the private key used to unwrap the secret key wrapped for the recipient public key
+type X25519Identity struct {
+	privateKey []byte
+	publicKey  []byte
+}
+
+func GenerateX25519Identity() (*X25519Identity, error) {
+	privateKey := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(privateKey); err != nil {
+		return nil, err
+	}
+
+	return newX25519Identity(privateKey)
+}
+
+// ParseX25519Identity parses the identity string, the empty lines and the lines starting with # are skipped
+func ParseX25519Identity(data string) (*X25519Identity, error) {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, X25519IdentityPrefix) {
+			return nil, fmt.Errorf("unsupported identity format: %s prefix expected", X25519IdentityPrefix)
+		}
+
+		privateKey, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, X25519IdentityPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode identity: %s", err)
+		}
+
+		if len(privateKey) != curve25519.ScalarSize {
+			return nil, fmt.Errorf("unexpected identity length %d", len(privateKey))
+		}
+
+		return newX25519Identity(privateKey)
+	}
+
+	return nil, errors.New("identity not found")
+}
+
+func newX25519Identity(privateKey []byte) (*X25519Identity, error) {
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &X25519Identity{privateKey: privateKey, publicKey: publicKey}, nil
+}
+
+func (i *X25519Identity) String() string {
+	return X25519IdentityPrefix + base64.StdEncoding.EncodeToString(i.privateKey)
+}
+
+// Recipient returns the public key string of the identity
+func (i *X25519Identity) Recipient() string {
+	return X25519RecipientPrefix + base64.StdEncoding.EncodeToString(i.publicKey)
+}
+
+// UnwrapKey decrypts the key wrapped for the identity recipient with WrapKey
+func (i *X25519Identity) UnwrapKey(wrappedKey string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode wrapped key: %s", err)
+	}
+
+	if len(data) < curve25519.PointSize+x25519WrapOverhead {
+		return nil, errors.New("unexpected wrapped key length")
+	}
+
+	ephemeralPublicKey := data[:curve25519.PointSize]
+	sharedSecret, err := curve25519.X25519(i.privateKey, ephemeralPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newX25519WrapAEAD(sharedSecret, ephemeralPublicKey, i.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), data[curve25519.PointSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unwrap key: %s", err)
+	}
+
+	return key, nil
+}
+
+func ParseX25519Recipient(recipient string) ([]byte, error) {
+	if !strings.HasPrefix(recipient, X25519RecipientPrefix) {
+		return nil, fmt.Errorf("unsupported recipient format: %s prefix expected", X25519RecipientPrefix)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(recipient, X25519RecipientPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode recipient: %s", err)
+	}
+
+	if len(publicKey) != curve25519.PointSize {
+		return nil, fmt.Errorf("unexpected recipient length %d", len(publicKey))
+	}
+
+	return publicKey, nil
+}
+
+// WrapKey encrypts the key for the recipient public key: the shared secret of the ephemeral X25519 key and the recipient key
+// is used to derive the ChaCha20-Poly1305 key, the result is base64(EPHEMERAL_PUBLIC_KEY+CIPHERTEXT)
+func WrapKey(key []byte, recipient string) (string, error) {
+	recipientPublicKey, err := ParseX25519Recipient(recipient)
+	if err != nil {
+		return "", err
+	}
+
+	ephemeralIdentity, err := GenerateX25519Identity()
+	if err != nil {
+		return "", err
+	}
+
+	sharedSecret, err := curve25519.X25519(ephemeralIdentity.privateKey, recipientPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newX25519WrapAEAD(sharedSecret, ephemeralIdentity.publicKey, recipientPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	// the wrap key is unique for each ephemeral key, so the zero nonce is safe
+	data := aead.Seal(append([]byte{}, ephemeralIdentity.publicKey...), make([]byte, chacha20poly1305.NonceSize), key, nil)
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func newX25519WrapAEAD(sharedSecret, ephemeralPublicKey, recipientPublicKey []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeralPublicKey...), recipientPublicKey...)
+
+	wrapKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, salt, []byte(x25519WrapKeyInfo)), wrapKey); err != nil {
+		return nil, err
+	}
+
+	return chacha20poly1305.New(wrapKey)
+}
@@ -0,0 +1,64 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestX25519WrapKey(t *testing.T) {
+	identity, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherIdentity, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(identity.Recipient(), X25519RecipientPrefix) {
+		t.Errorf("expected %s prefix: %s", X25519RecipientPrefix, identity.Recipient())
+	}
+
+	wrappedKey, err := WrapKey(AesSecretKey, identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := identity.UnwrapKey(wrappedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(key) != string(AesSecretKey) {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", AesSecretKey, key)
+	}
+
+	if _, err := otherIdentity.UnwrapKey(wrappedKey); err == nil {
+		t.Errorf("expected error for the identity of another recipient")
+	}
+
+	if _, err := WrapKey(AesSecretKey, "werf-x25519:invalid"); err == nil {
+		t.Errorf("expected error for invalid recipient")
+	}
+}
+
+func TestParseX25519Identity(t *testing.T) {
+	identity, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsedIdentity, err := ParseX25519Identity("# public key: " + identity.Recipient() + "\n\n" + identity.String() + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsedIdentity.Recipient() != identity.Recipient() {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", identity.Recipient(), parsedIdentity.Recipient())
+	}
+
+	if _, err := ParseX25519Identity(identity.Recipient()); err == nil {
+		t.Errorf("expected error for public key")
+	}
+}
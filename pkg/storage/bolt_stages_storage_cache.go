@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+// BoltStagesStorageCache stores records in the nested buckets of the bolt database: BucketPath/PROJECT_NAME/DIGEST
+type BoltStagesStorageCache struct {
+	DB         *bolt.DB
+	BucketPath []string
+}
+
+func NewBoltStagesStorageCache(db *bolt.DB, bucketPath ...string) *BoltStagesStorageCache {
+	return &BoltStagesStorageCache{DB: db, BucketPath: bucketPath}
+}
+
+func (cache *BoltStagesStorageCache) String() string {
+	return fmt.Sprintf("bolt:%s:%v", cache.DB.Path(), cache.BucketPath)
+}
+
+func (cache *BoltStagesStorageCache) GetAllStages(_ context.Context, projectName string) (bool, []image.StageID, error) {
+	var found bool
+	var res []image.StageID
+
+	err := cache.DB.View(func(tx *bolt.Tx) error {
+		bucket := cache.getProjectBucket(tx, projectName)
+		if bucket == nil {
+			return nil
+		}
+		found = true
+
+		return bucket.ForEach(func(digest, data []byte) error {
+			record := &StagesStorageCacheRecord{}
+			if err := json.Unmarshal(data, record); err != nil {
+				return fmt.Errorf("unable to unmarshal digest %s record: %s", digest, err)
+			}
+			res = append(res, record.Stages...)
+			return nil
+		})
+	})
+	if err != nil {
+		return false, nil, fmt.Errorf("error reading %s: %s", cache.String(), err)
+	}
+
+	return found, res, nil
+}
+
+func (cache *BoltStagesStorageCache) DeleteAllStages(_ context.Context, projectName string) error {
+	return cache.DB.Update(func(tx *bolt.Tx) error {
+		bucket := cache.getBucket(tx)
+		if bucket == nil || bucket.Bucket([]byte(projectName)) == nil {
+			return nil
+		}
+
+		if err := bucket.DeleteBucket([]byte(projectName)); err != nil {
+			return fmt.Errorf("unable to delete project %s bucket from %s: %s", projectName, cache.String(), err)
+		}
+		return nil
+	})
+}
+
+func (cache *BoltStagesStorageCache) GetStagesByDigest(ctx context.Context, projectName, digest string) (bool, []image.StageID, error) {
+	var data []byte
+
+	if err := cache.DB.View(func(tx *bolt.Tx) error {
+		if bucket := cache.getProjectBucket(tx, projectName); bucket != nil {
+			if value := bucket.Get([]byte(digest)); value != nil {
+				data = append([]byte{}, value...)
+			}
+		}
+		return nil
+	}); err != nil {
+		return false, nil, fmt.Errorf("error reading %s: %s", cache.String(), err)
+	}
+
+	if data == nil {
+		return false, nil, nil
+	}
+
+	res := &StagesStorageCacheRecord{}
+	if err := json.Unmarshal(data, res); err != nil {
+		logboek.Context(ctx).Error().LogF("Error unmarshalling json of project %s digest %s from %s: %s: will ignore cache\n", projectName, digest, cache.String(), err)
+		return false, nil, nil
+	}
+
+	return true, res.Stages, nil
+}
+
+func (cache *BoltStagesStorageCache) StoreStagesByDigest(_ context.Context, projectName, digest string, stages []image.StageID) error {
+	data, err := json.Marshal(StagesStorageCacheRecord{Stages: stages})
+	if err != nil {
+		return err
+	}
+
+	return cache.DB.Update(func(tx *bolt.Tx) error {
+		bucket, err := cache.createProjectBucketIfNotExists(tx, projectName)
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put([]byte(digest), data); err != nil {
+			return fmt.Errorf("unable to put project %s digest %s record into %s: %s", projectName, digest, cache.String(), err)
+		}
+		return nil
+	})
+}
+
+func (cache *BoltStagesStorageCache) DeleteStagesByDigest(_ context.Context, projectName, digest string) error {
+	return cache.DB.Update(func(tx *bolt.Tx) error {
+		bucket := cache.getProjectBucket(tx, projectName)
+		if bucket == nil {
+			return nil
+		}
+
+		if err := bucket.Delete([]byte(digest)); err != nil {
+			return fmt.Errorf("unable to delete project %s digest %s record from %s: %s", projectName, digest, cache.String(), err)
+		}
+		return nil
+	})
+}
+
+func (cache *BoltStagesStorageCache) getBucket(tx *bolt.Tx) *bolt.Bucket {
+	var bucket *bolt.Bucket
+	for i, name := range cache.BucketPath {
+		if i == 0 {
+			bucket = tx.Bucket([]byte(name))
+		} else {
+			bucket = bucket.Bucket([]byte(name))
+		}
+
+		if bucket == nil {
+			return nil
+		}
+	}
+	return bucket
+}
+
+func (cache *BoltStagesStorageCache) getProjectBucket(tx *bolt.Tx, projectName string) *bolt.Bucket {
+	if bucket := cache.getBucket(tx); bucket != nil {
+		return bucket.Bucket([]byte(projectName))
+	}
+	return nil
+}
+
+func (cache *BoltStagesStorageCache) createProjectBucketIfNotExists(tx *bolt.Tx, projectName string) (*bolt.Bucket, error) {
+	var bucket *bolt.Bucket
+	var err error
+
+	for i, name := range append(append([]string{}, cache.BucketPath...), projectName) {
+		if i == 0 {
+			bucket, err = tx.CreateBucketIfNotExists([]byte(name))
+		} else {
+			bucket, err = bucket.CreateBucketIfNotExists([]byte(name))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to create bucket %q in %s: %s", name, cache.String(), err)
+		}
+	}
+
+	return bucket, nil
+}
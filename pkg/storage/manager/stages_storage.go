@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -92,6 +93,28 @@ func (m *StagesStorageManager) GetStageDescriptionList(ctx context.Context) ([]*
 	return stages, nil
 }
 
+// GetImageStagesIDs returns IDs of the image last stages referred by the image metadata, the most recent stage goes first
+func (m *StagesStorageManager) GetImageStagesIDs(ctx context.Context, imageName string) ([]image.StageID, error) {
+	imageMetadataByImageName, _, err := m.StagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, m.ProjectName, []string{imageName})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image %s metadata from %s: %s", imageName, m.StagesStorage.String(), err)
+	}
+
+	var stageIDs []image.StageID
+	for stageID := range imageMetadataByImageName[imageName] {
+		id, err := image.ParseStageID(stageID)
+		if err != nil {
+			logboek.Context(ctx).Debug().LogLn(err.Error())
+			continue
+		}
+		stageIDs = append(stageIDs, id)
+	}
+
+	sort.Slice(stageIDs, func(i, j int) bool { return stageIDs[i].UniqueID > stageIDs[j].UniqueID })
+
+	return stageIDs, nil
+}
+
 type ForEachDeleteStageOptions struct {
 	storage.DeleteImageOptions
 	storage.FilterStagesAndProcessRelatedDataOptions
@@ -231,22 +254,27 @@ func (m *StagesStorageManager) GetStagesByDigestFromStagesStorage(ctx context.Co
 }
 
 func (m *StagesStorageManager) CopySuitableByDigestStage(ctx context.Context, stageDesc *image.StageDescription, sourceStagesStorage, destinationStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime) (*image.StageDescription, error) {
-	img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
+	logboek.Context(ctx).Info().LogF("Copying %s from %s to %s\n", stageDesc.Info.Name, sourceStagesStorage.String(), destinationStagesStorage.String())
+	if copied, err := storage.CopyStageDirectly(ctx, m.ProjectName, stageDesc, sourceStagesStorage, destinationStagesStorage); err != nil {
+		return nil, err
+	} else if !copied {
+		img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
 
-	logboek.Context(ctx).Info().LogF("Fetching %s\n", img.Name())
-	if err := sourceStagesStorage.FetchImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-		return nil, fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, sourceStagesStorage.String(), err)
-	}
+		logboek.Context(ctx).Info().LogF("Fetching %s\n", img.Name())
+		if err := sourceStagesStorage.FetchImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
+			return nil, fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, sourceStagesStorage.String(), err)
+		}
 
-	newImageName := destinationStagesStorage.ConstructStageImageName(m.ProjectName, stageDesc.StageID.Digest, stageDesc.StageID.UniqueID)
-	logboek.Context(ctx).Info().LogF("Renaming image %s to %s\n", img.Name(), newImageName)
-	if err := containerRuntime.RenameImage(ctx, &container_runtime.DockerImage{Image: img}, newImageName, false); err != nil {
-		return nil, err
-	}
+		newImageName := destinationStagesStorage.ConstructStageImageName(m.ProjectName, stageDesc.StageID.Digest, stageDesc.StageID.UniqueID)
+		logboek.Context(ctx).Info().LogF("Renaming image %s to %s\n", img.Name(), newImageName)
+		if err := containerRuntime.RenameImage(ctx, &container_runtime.DockerImage{Image: img}, newImageName, false); err != nil {
+			return nil, err
+		}
 
-	logboek.Context(ctx).Info().LogF("Storing %s\n", newImageName)
-	if err := destinationStagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-		return nil, fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, destinationStagesStorage.String(), err)
+		logboek.Context(ctx).Info().LogF("Storing %s\n", newImageName)
+		if err := destinationStagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
+			return nil, fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, destinationStagesStorage.String(), err)
+		}
 	}
 
 	if destinationStageDesc, err := getStageDescription(ctx, m.ProjectName, *stageDesc.StageID, destinationStagesStorage, getStageDescriptionOptions{StageShouldExist: true, WithManifestCache: true}); err != nil {
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+const syncProgressLogHeaderPrefix = "# werf stages sync"
+
+// syncProgressLog is an append-only file with the list of already synced stages.
+// Nil syncProgressLog is valid and does nothing.
+type syncProgressLog struct {
+	file   *os.File
+	mutex  sync.Mutex
+	synced map[string]bool
+}
+
+func openSyncProgressLog(path, projectName string, fromStagesStorage, toStagesStorage storage.StagesStorage) (*syncProgressLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	header := fmt.Sprintf("%s %s %s %s", syncProgressLogHeaderPrefix, projectName, fromStagesStorage.Address(), toStagesStorage.Address())
+	log := &syncProgressLog{synced: map[string]bool{}}
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+
+			switch {
+			case line == "":
+			case strings.HasPrefix(line, syncProgressLogHeaderPrefix):
+				if line != header {
+					return nil, fmt.Errorf("progress log %s belongs to another sync: expected %q, got %q", path, header, line)
+				}
+			case strings.HasPrefix(line, "synced "):
+				log.synced[strings.TrimPrefix(line, "synced ")] = true
+			default:
+				return nil, fmt.Errorf("unexpected progress log %s line %q", path, line)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to read progress log %s: %s", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open progress log %s: %s", path, err)
+	}
+	log.file = file
+
+	if err := log.writeLine(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return log, nil
+}
+
+func (log *syncProgressLog) IsSynced(stageID image.StageID) bool {
+	if log == nil {
+		return false
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	return log.synced[stageID.String()]
+}
+
+func (log *syncProgressLog) RecordSynced(stageID image.StageID) error {
+	if log == nil {
+		return nil
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	log.synced[stageID.String()] = true
+	return log.writeLine(fmt.Sprintf("synced %s", stageID.String()))
+}
+
+func (log *syncProgressLog) Close() error {
+	if log == nil {
+		return nil
+	}
+
+	return log.file.Close()
+}
+
+func (log *syncProgressLog) writeLine(line string) error {
+	if _, err := fmt.Fprintln(log.file, line); err != nil {
+		return fmt.Errorf("unable to write progress log %s: %s", log.file.Name(), err)
+	}
+
+	return log.file.Sync()
+}
@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+func TestSyncProgressLog(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-sync-progress-log-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "progress.log")
+	from, _ := storage.NewOCILayoutStagesStorage("oci-layout:"+filepath.Join(tmpDir, "from"), nil)
+	to, _ := storage.NewOCILayoutStagesStorage("oci-layout:"+filepath.Join(tmpDir, "to"), nil)
+	stageID := image.StageID{Digest: "abc", UniqueID: 1600000000000}
+
+	log, err := openSyncProgressLog(path, "myproject", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.IsSynced(stageID) {
+		t.Errorf("stage %s should not be synced yet", stageID.String())
+	}
+	if err := log.RecordSynced(stageID); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	log, err = openSyncProgressLog(path, "myproject", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !log.IsSynced(stageID) {
+		t.Errorf("stage %s should be loaded from the progress log as synced", stageID.String())
+	}
+	log.Close()
+
+	if _, err := openSyncProgressLog(path, "myproject", to, from); err == nil {
+		t.Errorf("expected error for progress log of another sync")
+	}
+
+	var nilLog *syncProgressLog
+	if nilLog.IsSynced(stageID) || nilLog.RecordSynced(stageID) != nil || nilLog.Close() != nil {
+		t.Errorf("nil progress log should do nothing")
+	}
+}
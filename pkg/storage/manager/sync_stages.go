@@ -3,6 +3,7 @@ package manager
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/werf/logboek"
 	"github.com/werf/werf/pkg/container_runtime"
@@ -12,13 +13,26 @@ import (
 
 type SyncStagesOptions struct {
 	RemoveSource      bool
+	RemoveAbsent      bool
 	CleanupLocalCache bool
 	WithoutLock       bool
+
+	// ImageNames and Commits limit synced stages to the stages referred by the image metadata of specified images and git commits (including all parent stages)
+	ImageNames []string
+	Commits    []string
+
+	// OlderThan and NewerThan limit synced stages by the stage creation time
+	OlderThan time.Duration
+	NewerThan time.Duration
+
+	// ProgressLogPath is a file to record already processed stages, so that interrupted sync could be continued from the same point
+	ProgressLogPath string
 }
 
 // SyncStages will make sure, that destination storage contains all stages from source storage.
 // Repeatedly calling SyncStages will copy stages from source storage to destination, that already exists in the destination.
-// SyncStages will not delete excess stages from destination storage, that does not exists in the source.
+// SyncStages will not delete excess stages from destination storage, that does not exists in the source, unless RemoveAbsent option is specified.
+// Managed images and image metadata records of synced stages are copied to the destination storage as well.
 func SyncStages(ctx context.Context, projectName string, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, storageLockManager storage.LockManager, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions) error {
 	isOk := false
 	logProcess := logboek.Context(ctx).Default().LogProcess("Sync %q project stages", projectName)
@@ -73,18 +87,26 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 		existingDestinationStages = stages
 	}
 
+	progressLog, err := openSyncProgressLog(opts.ProgressLogPath, projectName, fromStagesStorage, toStagesStorage)
+	if err != nil {
+		return err
+	}
+	defer progressLog.Close()
+
+	selectedSourceStages, err := selectStagesToSync(ctx, projectName, fromStagesStorage, existingSourceStages, opts)
+	if err != nil {
+		return err
+	}
+
 	var stagesToSync []image.StageID
 
-	for _, sourceStageDesc := range existingSourceStages {
-		stageExistsInDestination := false
-		for _, destStageDesc := range existingDestinationStages {
-			if sourceStageDesc.Digest == destStageDesc.Digest && sourceStageDesc.UniqueID == destStageDesc.UniqueID {
-				stageExistsInDestination = true
-				break
-			}
+	existingDestinationStagesSet := newStageIDsSet(existingDestinationStages)
+	for _, sourceStageDesc := range selectedSourceStages {
+		if progressLog.IsSynced(sourceStageDesc) {
+			continue
 		}
 
-		if !stageExistsInDestination || opts.RemoveSource {
+		if !existingDestinationStagesSet[sourceStageDesc.String()] || opts.RemoveSource {
 			stagesToSync = append(stagesToSync, sourceStageDesc)
 		}
 	}
@@ -119,6 +141,10 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 		} else {
 			succeededCounter++
 			logboek.Context(ctx).Default().LogF("%5d/%d synced\n", succeededCounter, len(stagesToSync))
+
+			if err := progressLog.RecordSynced(desc.StageID); err != nil {
+				errors = append(errors, err)
+			}
 		}
 	}
 
@@ -133,6 +159,20 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 		return fmt.Errorf("%s", errorMsg)
 	}
 
+	var destinationStages []image.StageID
+	destinationStages = append(destinationStages, existingDestinationStages...)
+	destinationStages = append(destinationStages, stagesToSync...)
+
+	if err := syncImagesMetadata(ctx, projectName, fromStagesStorage, toStagesStorage, destinationStages, opts); err != nil {
+		return err
+	}
+
+	if opts.RemoveAbsent {
+		if err := removeAbsentStages(ctx, projectName, existingSourceStages, existingDestinationStages, toStagesStorage); err != nil {
+			return err
+		}
+	}
+
 	isOk = true
 	return nil
 }
@@ -168,26 +208,10 @@ func syncStage(ctx context.Context, projectName string, stageID image.StageID, f
 	if destStageDesc, err := toStagesStorage.GetStageDescription(ctx, projectName, stageID.Digest, stageID.UniqueID); err != nil {
 		return fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), toStagesStorage.String(), err)
 	} else if destStageDesc == nil {
-		img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
-
-		logboek.Context(ctx).Info().LogF("Fetching %s\n", img.Name())
-		if err := fromStagesStorage.FetchImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-			return fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, fromStagesStorage.String(), err)
-		}
-
-		newImageName := toStagesStorage.ConstructStageImageName(projectName, stageDesc.StageID.Digest, stageDesc.StageID.UniqueID)
-		logboek.Context(ctx).Info().LogF("Renaming image %s to %s\n", img.Name(), newImageName)
-		if err := containerRuntime.RenameImage(ctx, &container_runtime.DockerImage{Image: img}, newImageName, opts.CleanupLocalCache); err != nil {
-			return err
-		}
-
-		logboek.Context(ctx).Info().LogF("Storing %s\n", newImageName)
-		if err := toStagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-			return fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, toStagesStorage.String(), err)
-		}
-
-		if opts.CleanupLocalCache {
-			if err := containerRuntime.RemoveImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
+		if copied, err := storage.CopyStageDirectly(ctx, projectName, stageDesc, fromStagesStorage, toStagesStorage); err != nil {
+			return fmt.Errorf("unable to copy %s from %s to %s: %s", stageDesc.Info.Name, fromStagesStorage.String(), toStagesStorage.String(), err)
+		} else if !copied {
+			if err := syncStageThroughContainerRuntime(ctx, projectName, stageDesc, fromStagesStorage, toStagesStorage, containerRuntime, opts); err != nil {
 				return err
 			}
 		}
@@ -210,3 +234,319 @@ func syncStage(ctx context.Context, projectName string, stageID image.StageID, f
 
 	return nil
 }
+
+func syncStageThroughContainerRuntime(ctx context.Context, projectName string, stageDesc *image.StageDescription, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions) error {
+	img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
+
+	logboek.Context(ctx).Info().LogF("Fetching %s\n", img.Name())
+	if err := fromStagesStorage.FetchImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
+		return fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, fromStagesStorage.String(), err)
+	}
+
+	newImageName := toStagesStorage.ConstructStageImageName(projectName, stageDesc.StageID.Digest, stageDesc.StageID.UniqueID)
+	logboek.Context(ctx).Info().LogF("Renaming image %s to %s\n", img.Name(), newImageName)
+	if err := containerRuntime.RenameImage(ctx, &container_runtime.DockerImage{Image: img}, newImageName, opts.CleanupLocalCache); err != nil {
+		return err
+	}
+
+	logboek.Context(ctx).Info().LogF("Storing %s\n", newImageName)
+	if err := toStagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
+		return fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, toStagesStorage.String(), err)
+	}
+
+	if opts.CleanupLocalCache {
+		if err := containerRuntime.RemoveImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func selectStagesToSync(ctx context.Context, projectName string, fromStagesStorage storage.StagesStorage, existingSourceStages []image.StageID, opts SyncStagesOptions) ([]image.StageID, error) {
+	stages := filterStagesByAge(existingSourceStages, opts)
+
+	if len(opts.ImageNames) == 0 && len(opts.Commits) == 0 {
+		return stages, nil
+	}
+
+	imageNames := opts.ImageNames
+	if len(imageNames) == 0 {
+		if managedImages, err := fromStagesStorage.GetManagedImages(ctx, projectName); err != nil {
+			return nil, fmt.Errorf("unable to get managed images from %s: %s", fromStagesStorage.String(), err)
+		} else {
+			imageNames = managedImages
+		}
+	}
+
+	imageMetadataByImageName, _, err := fromStagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, projectName, imageNames)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image metadata from %s: %s", fromStagesStorage.String(), err)
+	}
+
+	selectedStageIDs := map[string]bool{}
+	for _, stageIDCommitList := range imageMetadataByImageName {
+		for stageID, commitList := range stageIDCommitList {
+			if isMetadataCommitListMatched(commitList, opts.Commits) {
+				selectedStageIDs[stageID] = true
+			}
+		}
+	}
+
+	stageDescriptions, err := getStagesDescriptions(ctx, projectName, fromStagesStorage, existingSourceStages)
+	if err != nil {
+		return nil, err
+	}
+
+	stageDescriptionByImageID := map[string]*image.StageDescription{}
+	for _, stageDesc := range stageDescriptions {
+		stageDescriptionByImageID[stageDesc.Info.ID] = stageDesc
+	}
+
+	// image metadata refers only to the last stage of the image, parent stages are needed as well
+	stagesSet := newStageIDsSet(stages)
+	res := map[string]image.StageID{}
+	for _, stageDesc := range stageDescriptions {
+		if !selectedStageIDs[stageDesc.StageID.String()] || !stagesSet[stageDesc.StageID.String()] {
+			continue
+		}
+
+		for currentStageDesc := stageDesc; currentStageDesc != nil; currentStageDesc = stageDescriptionByImageID[currentStageDesc.Info.ParentID] {
+			if _, hasKey := res[currentStageDesc.StageID.String()]; hasKey {
+				break
+			}
+			res[currentStageDesc.StageID.String()] = *currentStageDesc.StageID
+		}
+	}
+
+	var selectedStages []image.StageID
+	for _, stageID := range existingSourceStages {
+		if _, hasKey := res[stageID.String()]; hasKey {
+			selectedStages = append(selectedStages, stageID)
+		}
+	}
+
+	logboek.Context(ctx).Default().LogFDetails("Selected stages: %d\n", len(selectedStages))
+
+	return selectedStages, nil
+}
+
+func filterStagesByAge(stages []image.StageID, opts SyncStagesOptions) []image.StageID {
+	if opts.OlderThan == 0 && opts.NewerThan == 0 {
+		return stages
+	}
+
+	now := time.Now()
+
+	var res []image.StageID
+	for _, stageID := range stages {
+		createdAt := stageID.UniqueIDAsTime()
+
+		if opts.OlderThan != 0 && createdAt.After(now.Add(-opts.OlderThan)) {
+			continue
+		}
+		if opts.NewerThan != 0 && createdAt.Before(now.Add(-opts.NewerThan)) {
+			continue
+		}
+
+		res = append(res, stageID)
+	}
+
+	return res
+}
+
+func newStageIDsSet(stages []image.StageID) map[string]bool {
+	res := make(map[string]bool, len(stages))
+	for _, stageID := range stages {
+		res[stageID.String()] = true
+	}
+
+	return res
+}
+
+func isMetadataCommitListMatched(commitList, commits []string) bool {
+	if len(commits) == 0 {
+		return true
+	}
+
+	for _, commit := range commitList {
+		if isStringInList(commit, commits) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getStagesDescriptions(ctx context.Context, projectName string, stagesStorage storage.StagesStorage, stages []image.StageID) ([]*image.StageDescription, error) {
+	logProcess := logboek.Context(ctx).Default().LogProcess("Getting stages descriptions from %s", stagesStorage.String())
+	logProcess.Start()
+
+	maxWorkers := 10
+	resultsChan := make(chan struct {
+		*image.StageDescription
+		error
+	}, len(stages))
+	jobsChan := make(chan image.StageID, len(stages))
+
+	for w := 0; w < maxWorkers; w++ {
+		go func() {
+			for stageID := range jobsChan {
+				stageDesc, err := stagesStorage.GetStageDescription(ctx, projectName, stageID.Digest, stageID.UniqueID)
+				if err != nil {
+					err = fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), stagesStorage.String(), err)
+				}
+
+				resultsChan <- struct {
+					*image.StageDescription
+					error
+				}{stageDesc, err}
+			}
+		}()
+	}
+
+	for _, stageID := range stages {
+		jobsChan <- stageID
+	}
+	close(jobsChan)
+
+	var res []*image.StageDescription
+	var resErr error
+	for i := 0; i < len(stages); i++ {
+		desc := <-resultsChan
+
+		if desc.error != nil {
+			resErr = desc.error
+		} else if desc.StageDescription != nil {
+			res = append(res, desc.StageDescription)
+		}
+	}
+
+	if resErr != nil {
+		logProcess.Fail()
+		return nil, resErr
+	}
+
+	logProcess.End()
+	return res, nil
+}
+
+func syncImagesMetadata(ctx context.Context, projectName string, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, destinationStages []image.StageID, opts SyncStagesOptions) error {
+	return logboek.Context(ctx).Default().LogProcess("Syncing managed images and images metadata").DoError(func() error {
+		sourceManagedImages, err := fromStagesStorage.GetManagedImages(ctx, projectName)
+		if err != nil {
+			return fmt.Errorf("unable to get managed images from %s: %s", fromStagesStorage.String(), err)
+		}
+
+		destinationManagedImages, err := toStagesStorage.GetManagedImages(ctx, projectName)
+		if err != nil {
+			return fmt.Errorf("unable to get managed images from %s: %s", toStagesStorage.String(), err)
+		}
+
+		var imageNames []string
+		for _, imageName := range sourceManagedImages {
+			if len(opts.ImageNames) > 0 && !isStringInList(imageName, opts.ImageNames) {
+				continue
+			}
+			imageNames = append(imageNames, imageName)
+
+			if isStringInList(imageName, destinationManagedImages) {
+				continue
+			}
+
+			logboek.Context(ctx).Info().LogF("Adding managed image %q\n", imageName)
+			if err := toStagesStorage.AddManagedImage(ctx, projectName, imageName); err != nil {
+				return fmt.Errorf("unable to add managed image %q to %s: %s", imageName, toStagesStorage.String(), err)
+			}
+		}
+
+		sourceImageMetadata, _, err := fromStagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, projectName, imageNames)
+		if err != nil {
+			return fmt.Errorf("unable to get image metadata from %s: %s", fromStagesStorage.String(), err)
+		}
+
+		destinationImageMetadata, _, err := toStagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, projectName, imageNames)
+		if err != nil {
+			return fmt.Errorf("unable to get image metadata from %s: %s", toStagesStorage.String(), err)
+		}
+
+		destinationStageIDs := map[string]bool{}
+		for _, stageID := range destinationStages {
+			destinationStageIDs[stageID.String()] = true
+		}
+
+		for imageName, stageIDCommitList := range sourceImageMetadata {
+			for stageID, commitList := range stageIDCommitList {
+				if !destinationStageIDs[stageID] {
+					continue
+				}
+
+				for _, commit := range commitList {
+					if len(opts.Commits) > 0 && !isStringInList(commit, opts.Commits) {
+						continue
+					}
+
+					if isStringInList(commit, destinationImageMetadata[imageName][stageID]) {
+						continue
+					}
+
+					logboek.Context(ctx).Info().LogF("Adding image %q metadata for commit %s and stage %s\n", imageName, commit, stageID)
+					if err := toStagesStorage.PutImageMetadata(ctx, projectName, imageName, commit, stageID); err != nil {
+						return fmt.Errorf("unable to put image %q metadata to %s: %s", imageName, toStagesStorage.String(), err)
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func removeAbsentStages(ctx context.Context, projectName string, existingSourceStages, existingDestinationStages []image.StageID, toStagesStorage storage.StagesStorage) error {
+	existingSourceStagesSet := newStageIDsSet(existingSourceStages)
+
+	var stagesToRemove []image.StageID
+	for _, stageID := range existingDestinationStages {
+		if !existingSourceStagesSet[stageID.String()] {
+			stagesToRemove = append(stagesToRemove, stageID)
+		}
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Removing %d stages absent in the source storage from %s", len(stagesToRemove), toStagesStorage.String()).DoError(func() error {
+		for i, stageID := range stagesToRemove {
+			stageDesc, err := toStagesStorage.GetStageDescription(ctx, projectName, stageID.Digest, stageID.UniqueID)
+			if err != nil {
+				return fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), toStagesStorage.String(), err)
+			} else if stageDesc == nil {
+				continue
+			}
+
+			if _, err := toStagesStorage.FilterStagesAndProcessRelatedData(ctx, []*image.StageDescription{stageDesc}, storage.FilterStagesAndProcessRelatedDataOptions{
+				RmForce:                  true,
+				RmContainersThatUseImage: true,
+			}); err != nil {
+				return fmt.Errorf("unable to remove related stage data %s from %s: %s", stageDesc.Info.Name, toStagesStorage.String(), err)
+			}
+
+			if err := toStagesStorage.DeleteStage(ctx, stageDesc, storage.DeleteImageOptions{
+				RmiForce: true,
+			}); err != nil {
+				return fmt.Errorf("unable to remove %s from %s: %s", stageDesc.Info.Name, toStagesStorage.String(), err)
+			}
+
+			logboek.Context(ctx).Default().LogF("%5d/%d removed\n", i+1, len(stagesToRemove))
+		}
+
+		return nil
+	})
+}
+
+func isStringInList(s string, list []string) bool {
+	for _, elm := range list {
+		if elm == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,403 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/util/parallel"
+)
+
+type StagesIntegrityProblemType string
+
+const (
+	// Storage cache contains stage which does not exist in the stages storage
+	CacheStageNotFound StagesIntegrityProblemType = "cache-stage-not-found"
+	// Storage cache contains record for the digest, but does not contain some stage with this digest existing in the stages storage
+	CacheStageMissing StagesIntegrityProblemType = "cache-stage-missing"
+	// Manifest cache contains image info which differs from the actual image in the stages storage
+	ManifestCacheMismatch StagesIntegrityProblemType = "manifest-cache-mismatch"
+	// Stage is listed in the stages storage, but stage description cannot be got
+	StageDescriptionNotFound StagesIntegrityProblemType = "stage-description-not-found"
+	// Stage image labels do not correspond to the stage id or project
+	StageLabelsMismatch StagesIntegrityProblemType = "stage-labels-mismatch"
+	// Stage parent is a stage which has been created later than its child or parents chain is looped
+	StageParentChainBroken StagesIntegrityProblemType = "stage-parent-chain-broken"
+	// Image metadata record refers to the stage which does not exist in the stages storage
+	OrphanedImageMetadata StagesIntegrityProblemType = "orphaned-image-metadata"
+	// Import metadata record refers to the source image which does not exist in the stages storage
+	OrphanedImportMetadata StagesIntegrityProblemType = "orphaned-import-metadata"
+)
+
+type StagesIntegrityProblem struct {
+	Type      StagesIntegrityProblemType `json:"type"`
+	StageID   string                     `json:"stageID,omitempty"`
+	ImageName string                     `json:"imageName,omitempty"`
+	Commit    string                     `json:"commit,omitempty"`
+	ImportID  string                     `json:"importID,omitempty"`
+	Message   string                     `json:"message"`
+	Repaired  bool                       `json:"repaired"`
+}
+
+type VerifyStagesReport struct {
+	ProjectName        string                    `json:"projectName"`
+	StagesStorage      string                    `json:"stagesStorage"`
+	StagesStorageCache string                    `json:"stagesStorageCache"`
+	StagesCount        int                       `json:"stagesCount"`
+	Problems           []*StagesIntegrityProblem `json:"problems"`
+}
+
+func (report *VerifyStagesReport) UnrepairedProblemsCount() int {
+	var count int
+	for _, problem := range report.Problems {
+		if !problem.Repaired {
+			count++
+		}
+	}
+	return count
+}
+
+type VerifyStagesOptions struct {
+	RepairCache          bool
+	DropOrphanedMetadata bool
+}
+
+// VerifyStages cross-checks storage cache records, manifest cache records, stages storage stages, stages labels and parents chains, image and import metadata records.
+// Found inconsistencies are returned in the report, storage cache and metadata records could be optionally repaired.
+func (m *StagesStorageManager) VerifyStages(ctx context.Context, opts VerifyStagesOptions) (*VerifyStagesReport, error) {
+	report := &VerifyStagesReport{
+		ProjectName:        m.ProjectName,
+		StagesStorage:      m.StagesStorage.String(),
+		StagesStorageCache: m.StagesStorageCache.String(),
+		Problems:           []*StagesIntegrityProblem{},
+	}
+
+	stageIDs, err := m.StagesStorage.GetStagesIDs(ctx, m.ProjectName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stages ids from %s: %s", m.StagesStorage.String(), err)
+	}
+	report.StagesCount = len(stageIDs)
+
+	stageDescriptions, err := m.verifyStagesDescriptions(ctx, stageIDs, opts, report)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyStagesParentChains(stageDescriptions, report)
+
+	if err := m.verifyStagesStorageCache(ctx, stageIDs, opts, report); err != nil {
+		return nil, err
+	}
+
+	if err := m.verifyImageMetadata(ctx, stageIDs, opts, report); err != nil {
+		return nil, err
+	}
+
+	if err := m.verifyImportMetadata(ctx, stageDescriptions, opts, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (m *StagesStorageManager) verifyStagesDescriptions(ctx context.Context, stageIDs []image.StageID, opts VerifyStagesOptions, report *VerifyStagesReport) ([]*image.StageDescription, error) {
+	var mutex sync.Mutex
+	var stageDescriptions []*image.StageDescription
+
+	addProblem := func(problem *StagesIntegrityProblem) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		report.Problems = append(report.Problems, problem)
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Verifying %d stages descriptions", len(stageIDs)).DoError(func() error {
+		return parallel.DoTasks(ctx, len(stageIDs), parallel.DoTasksOptions{
+			MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+		}, func(ctx context.Context, taskId int) error {
+			stageID := stageIDs[taskId]
+			stageImageName := m.StagesStorage.ConstructStageImageName(m.ProjectName, stageID.Digest, stageID.UniqueID)
+
+			stageDesc, err := m.StagesStorage.GetStageDescription(ctx, m.ProjectName, stageID.Digest, stageID.UniqueID)
+			if err != nil {
+				return fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), m.StagesStorage.String(), err)
+			} else if stageDesc == nil {
+				addProblem(&StagesIntegrityProblem{
+					Type:    StageDescriptionNotFound,
+					StageID: stageID.String(),
+					Message: fmt.Sprintf("stage image %s is listed but cannot be got from %s", stageImageName, m.StagesStorage.String()),
+				})
+				return nil
+			}
+
+			if projectLabel, hasKey := stageDesc.Info.Labels[image.WerfLabel]; hasKey && projectLabel != m.ProjectName {
+				addProblem(&StagesIntegrityProblem{
+					Type:    StageLabelsMismatch,
+					StageID: stageID.String(),
+					Message: fmt.Sprintf("stage image %s label %s=%q does not match project %q", stageImageName, image.WerfLabel, projectLabel, m.ProjectName),
+				})
+			}
+
+			if digestLabel, hasKey := stageDesc.Info.Labels[image.WerfStageDigestLabel]; hasKey && digestLabel != stageID.Digest {
+				addProblem(&StagesIntegrityProblem{
+					Type:    StageLabelsMismatch,
+					StageID: stageID.String(),
+					Message: fmt.Sprintf("stage image %s label %s=%q does not match stage digest %q", stageImageName, image.WerfStageDigestLabel, digestLabel, stageID.Digest),
+				})
+			}
+
+			if cachedInfo, err := image.CommonManifestCache.GetImageInfo(ctx, m.StagesStorage.String(), stageImageName); err != nil {
+				return fmt.Errorf("error getting image %s info from manifest cache: %s", stageImageName, err)
+			} else if cachedInfo != nil && (cachedInfo.ID != stageDesc.Info.ID || cachedInfo.RepoDigest != stageDesc.Info.RepoDigest) {
+				problem := &StagesIntegrityProblem{
+					Type:    ManifestCacheMismatch,
+					StageID: stageID.String(),
+					Message: fmt.Sprintf("manifest cache image %s ID %q repo digest %q does not match actual ID %q repo digest %q", stageImageName, cachedInfo.ID, cachedInfo.RepoDigest, stageDesc.Info.ID, stageDesc.Info.RepoDigest),
+				}
+
+				if opts.RepairCache {
+					if err := image.CommonManifestCache.StoreImageInfo(ctx, m.StagesStorage.String(), stageDesc.Info); err != nil {
+						return fmt.Errorf("unable to store image %s info into manifest cache: %s", stageImageName, err)
+					}
+					problem.Repaired = true
+				}
+
+				addProblem(problem)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			stageDescriptions = append(stageDescriptions, stageDesc)
+
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return stageDescriptions, nil
+}
+
+func verifyStagesParentChains(stageDescriptions []*image.StageDescription, report *VerifyStagesReport) {
+	stageDescriptionByImageID := map[string]*image.StageDescription{}
+	for _, stageDesc := range stageDescriptions {
+		stageDescriptionByImageID[stageDesc.Info.ID] = stageDesc
+	}
+
+	for _, stageDesc := range stageDescriptions {
+		visited := map[string]bool{}
+
+		for currentStageDesc := stageDesc; currentStageDesc != nil; {
+			visited[currentStageDesc.Info.ID] = true
+
+			// parent could be a base image or an image built outside of werf which are not stored in the stages storage
+			parentStageDesc := stageDescriptionByImageID[currentStageDesc.Info.ParentID]
+			if parentStageDesc == nil {
+				break
+			}
+
+			if visited[parentStageDesc.Info.ID] {
+				report.Problems = append(report.Problems, &StagesIntegrityProblem{
+					Type:    StageParentChainBroken,
+					StageID: stageDesc.StageID.String(),
+					Message: fmt.Sprintf("stage parents chain is looped on stage %s", parentStageDesc.StageID.String()),
+				})
+				break
+			}
+
+			if currentStageDesc == stageDesc && parentStageDesc.StageID.UniqueID > stageDesc.StageID.UniqueID {
+				report.Problems = append(report.Problems, &StagesIntegrityProblem{
+					Type:    StageParentChainBroken,
+					StageID: stageDesc.StageID.String(),
+					Message: fmt.Sprintf("parent stage %s has been created later than its child stage", parentStageDesc.StageID.String()),
+				})
+				break
+			}
+
+			currentStageDesc = parentStageDesc
+		}
+	}
+}
+
+func (m *StagesStorageManager) verifyStagesStorageCache(ctx context.Context, stageIDs []image.StageID, opts VerifyStagesOptions, report *VerifyStagesReport) error {
+	return logboek.Context(ctx).Default().LogProcess("Verifying storage cache %s", m.StagesStorageCache.String()).DoError(func() error {
+		cacheExists, cacheStageIDs, err := m.StagesStorageCache.GetAllStages(ctx, m.ProjectName)
+		if err != nil {
+			return fmt.Errorf("unable to get stages from storage cache %s: %s", m.StagesStorageCache.String(), err)
+		} else if !cacheExists {
+			logboek.Context(ctx).Default().LogLn("Storage cache is empty")
+			return nil
+		}
+
+		stageIDsByDigest := map[string][]image.StageID{}
+		for _, stageID := range stageIDs {
+			stageIDsByDigest[stageID.Digest] = append(stageIDsByDigest[stageID.Digest], stageID)
+		}
+
+		cacheStageIDsByDigest := map[string][]image.StageID{}
+		for _, stageID := range cacheStageIDs {
+			cacheStageIDsByDigest[stageID.Digest] = append(cacheStageIDsByDigest[stageID.Digest], stageID)
+		}
+
+		stageIDsSet := newStageIDsSet(stageIDs)
+		cacheStageIDsSet := newStageIDsSet(cacheStageIDs)
+
+		var digests []string
+		for digest := range cacheStageIDsByDigest {
+			digests = append(digests, digest)
+		}
+		sort.Strings(digests)
+
+		for _, digest := range digests {
+			var problems []*StagesIntegrityProblem
+
+			for _, stageID := range cacheStageIDsByDigest[digest] {
+				if !stageIDsSet[stageID.String()] {
+					problems = append(problems, &StagesIntegrityProblem{
+						Type:    CacheStageNotFound,
+						StageID: stageID.String(),
+						Message: fmt.Sprintf("storage cache contains stage which does not exist in %s", m.StagesStorage.String()),
+					})
+				}
+			}
+
+			for _, stageID := range stageIDsByDigest[digest] {
+				if !cacheStageIDsSet[stageID.String()] {
+					problems = append(problems, &StagesIntegrityProblem{
+						Type:    CacheStageMissing,
+						StageID: stageID.String(),
+						Message: fmt.Sprintf("storage cache record for digest %s does not contain stage existing in %s", digest, m.StagesStorage.String()),
+					})
+				}
+			}
+
+			if len(problems) > 0 && opts.RepairCache {
+				// storage cache record will be recreated from the stages storage on the next access
+				if err := m.atomicDeleteStagesByDigestFromCache(ctx, digest); err != nil {
+					return err
+				}
+
+				for _, problem := range problems {
+					problem.Repaired = true
+				}
+			}
+
+			report.Problems = append(report.Problems, problems...)
+		}
+
+		return nil
+	})
+}
+
+func (m *StagesStorageManager) atomicDeleteStagesByDigestFromCache(ctx context.Context, digest string) error {
+	if lock, err := m.StorageLockManager.LockStageCache(ctx, m.ProjectName, digest); err != nil {
+		return fmt.Errorf("error locking project %s stage %s cache: %s", m.ProjectName, digest, err)
+	} else {
+		defer m.StorageLockManager.Unlock(ctx, lock)
+	}
+
+	if err := m.StagesStorageCache.DeleteStagesByDigest(ctx, m.ProjectName, digest); err != nil {
+		return fmt.Errorf("unable to delete storage cache record (%s): %s", digest, err)
+	}
+
+	return nil
+}
+
+func (m *StagesStorageManager) verifyImageMetadata(ctx context.Context, stageIDs []image.StageID, opts VerifyStagesOptions, report *VerifyStagesReport) error {
+	return logboek.Context(ctx).Default().LogProcess("Verifying images metadata").DoError(func() error {
+		managedImages, err := m.StagesStorage.GetManagedImages(ctx, m.ProjectName)
+		if err != nil {
+			return fmt.Errorf("unable to get managed images from %s: %s", m.StagesStorage.String(), err)
+		}
+
+		imageMetadataByImageName, imageMetadataByNotManagedImageName, err := m.StagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, m.ProjectName, managedImages)
+		if err != nil {
+			return fmt.Errorf("unable to get images metadata from %s: %s", m.StagesStorage.String(), err)
+		}
+
+		existingStageIDs := map[string]bool{}
+		for _, stageID := range stageIDs {
+			existingStageIDs[stageID.String()] = true
+		}
+
+		for _, imageMetadata := range []map[string]map[string][]string{imageMetadataByImageName, imageMetadataByNotManagedImageName} {
+			var imageNames []string
+			for imageName := range imageMetadata {
+				imageNames = append(imageNames, imageName)
+			}
+			sort.Strings(imageNames)
+
+			for _, imageName := range imageNames {
+				for stageID, commitList := range imageMetadata[imageName] {
+					if existingStageIDs[stageID] {
+						continue
+					}
+
+					for _, commit := range commitList {
+						problem := &StagesIntegrityProblem{
+							Type:      OrphanedImageMetadata,
+							StageID:   stageID,
+							ImageName: imageName,
+							Commit:    commit,
+							Message:   fmt.Sprintf("image metadata refers to the stage which does not exist in %s", m.StagesStorage.String()),
+						}
+
+						if opts.DropOrphanedMetadata {
+							if err := m.StagesStorage.RmImageMetadata(ctx, m.ProjectName, imageName, commit, stageID); err != nil {
+								return fmt.Errorf("unable to remove image %q metadata for commit %s and stage %s: %s", imageName, commit, stageID, err)
+							}
+							problem.Repaired = true
+						}
+
+						report.Problems = append(report.Problems, problem)
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *StagesStorageManager) verifyImportMetadata(ctx context.Context, stageDescriptions []*image.StageDescription, opts VerifyStagesOptions, report *VerifyStagesReport) error {
+	return logboek.Context(ctx).Default().LogProcess("Verifying import metadata").DoError(func() error {
+		ids, err := m.StagesStorage.GetImportMetadataIDs(ctx, m.ProjectName)
+		if err != nil {
+			return fmt.Errorf("unable to get import metadata ids from %s: %s", m.StagesStorage.String(), err)
+		}
+
+		existingImageIDs := map[string]bool{}
+		for _, stageDesc := range stageDescriptions {
+			existingImageIDs[stageDesc.Info.ID] = true
+		}
+
+		for _, id := range ids {
+			metadata, err := m.StagesStorage.GetImportMetadata(ctx, m.ProjectName, id)
+			if err != nil {
+				return fmt.Errorf("unable to get import metadata %s from %s: %s", id, m.StagesStorage.String(), err)
+			} else if metadata == nil || existingImageIDs[metadata.SourceImageID] {
+				continue
+			}
+
+			problem := &StagesIntegrityProblem{
+				Type:     OrphanedImportMetadata,
+				ImportID: id,
+				Message:  fmt.Sprintf("import metadata refers to the source image %s which does not exist in %s", metadata.SourceImageID, m.StagesStorage.String()),
+			}
+
+			if opts.DropOrphanedMetadata {
+				if err := m.StagesStorage.RmImportMetadata(ctx, m.ProjectName, id); err != nil {
+					return fmt.Errorf("unable to remove import metadata %s: %s", id, err)
+				}
+				problem.Repaired = true
+			}
+
+			report.Problems = append(report.Problems, problem)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,268 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func newTestStageDescription(digest string, uniqueID int64, id, parentID string) *image.StageDescription {
+	return &image.StageDescription{
+		StageID: &image.StageID{Digest: digest, UniqueID: uniqueID},
+		Info:    &image.Info{ID: id, ParentID: parentID},
+	}
+}
+
+func TestVerifyStagesParentChains(t *testing.T) {
+	report := &VerifyStagesReport{}
+	verifyStagesParentChains([]*image.StageDescription{
+		newTestStageDescription("from", 1, "sha256:1", "sha256:base"),
+		newTestStageDescription("install", 2, "sha256:2", "sha256:1"),
+		newTestStageDescription("setup", 3, "sha256:3", "sha256:2"),
+	}, report)
+	if len(report.Problems) != 0 {
+		t.Errorf("expected no problems for valid chain, got %d: %s", len(report.Problems), report.Problems[0].Message)
+	}
+
+	report = &VerifyStagesReport{}
+	verifyStagesParentChains([]*image.StageDescription{
+		newTestStageDescription("from", 5, "sha256:1", "sha256:base"),
+		newTestStageDescription("install", 2, "sha256:2", "sha256:1"),
+	}, report)
+	if len(report.Problems) != 1 || report.Problems[0].Type != StageParentChainBroken || report.Problems[0].StageID != "install-2" {
+		t.Errorf("expected broken chain problem for stage install-2, got %#v", report.Problems)
+	}
+
+	report = &VerifyStagesReport{}
+	verifyStagesParentChains([]*image.StageDescription{
+		newTestStageDescription("a", 1, "sha256:1", "sha256:2"),
+		newTestStageDescription("b", 1, "sha256:2", "sha256:1"),
+	}, report)
+	if len(report.Problems) != 2 {
+		t.Errorf("expected looped chain problems for both stages, got %#v", report.Problems)
+	}
+}
+
+type verifyTestStagesStorage struct {
+	storage.StagesStorage
+
+	stages         map[string]*image.StageDescription
+	imageMetadata  map[string]map[string][]string
+	importMetadata map[string]*storage.ImportMetadata
+}
+
+func (s *verifyTestStagesStorage) String() string {
+	return "test-repo"
+}
+
+func (s *verifyTestStagesStorage) ConstructStageImageName(_, digest string, uniqueID int64) string {
+	return fmt.Sprintf("test-repo:%s-%d", digest, uniqueID)
+}
+
+func (s *verifyTestStagesStorage) GetStagesIDs(_ context.Context, _ string) ([]image.StageID, error) {
+	var res []image.StageID
+	for _, stageDesc := range s.stages {
+		res = append(res, *stageDesc.StageID)
+	}
+	return res, nil
+}
+
+func (s *verifyTestStagesStorage) GetStageDescription(_ context.Context, _, digest string, uniqueID int64) (*image.StageDescription, error) {
+	return s.stages[image.StageID{Digest: digest, UniqueID: uniqueID}.String()], nil
+}
+
+func (s *verifyTestStagesStorage) GetManagedImages(_ context.Context, _ string) ([]string, error) {
+	var res []string
+	for imageName := range s.imageMetadata {
+		res = append(res, imageName)
+	}
+	return res, nil
+}
+
+func (s *verifyTestStagesStorage) GetAllAndGroupImageMetadataByImageName(_ context.Context, _ string, _ []string) (map[string]map[string][]string, map[string]map[string][]string, error) {
+	return s.imageMetadata, map[string]map[string][]string{}, nil
+}
+
+func (s *verifyTestStagesStorage) RmImageMetadata(_ context.Context, _, imageName, commit, stageID string) error {
+	var commits []string
+	for _, c := range s.imageMetadata[imageName][stageID] {
+		if c != commit {
+			commits = append(commits, c)
+		}
+	}
+
+	if len(commits) == 0 {
+		delete(s.imageMetadata[imageName], stageID)
+	} else {
+		s.imageMetadata[imageName][stageID] = commits
+	}
+
+	return nil
+}
+
+func (s *verifyTestStagesStorage) GetImportMetadataIDs(_ context.Context, _ string) ([]string, error) {
+	var res []string
+	for id := range s.importMetadata {
+		res = append(res, id)
+	}
+	return res, nil
+}
+
+func (s *verifyTestStagesStorage) GetImportMetadata(_ context.Context, _, id string) (*storage.ImportMetadata, error) {
+	return s.importMetadata[id], nil
+}
+
+func (s *verifyTestStagesStorage) RmImportMetadata(_ context.Context, _, id string) error {
+	delete(s.importMetadata, id)
+	return nil
+}
+
+func newVerifyTestStorageManager(t *testing.T) (*StagesStorageManager, *verifyTestStagesStorage, func()) {
+	tmpDir, err := ioutil.TempDir("", "werf-verify-stages-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.Init(tmpDir, tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := image.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	stagesStorage := &verifyTestStagesStorage{
+		stages: map[string]*image.StageDescription{},
+		imageMetadata: map[string]map[string][]string{
+			"backend": {},
+		},
+		importMetadata: map[string]*storage.ImportMetadata{},
+	}
+	for _, stageDesc := range []*image.StageDescription{
+		newTestStageDescription("from", 1, "sha256:1", "sha256:base"),
+		newTestStageDescription("install", 2, "sha256:2", "sha256:1"),
+	} {
+		stagesStorage.stages[stageDesc.StageID.String()] = stageDesc
+	}
+
+	stagesStorageCache := storage.NewFileStagesStorageCache(filepath.Join(tmpDir, "stages_storage_cache"))
+	lockManager := storage.NewGenericLockManager(werf.GetHostLocker())
+
+	return newStagesStorageManager("project", stagesStorage, nil, lockManager, stagesStorageCache), stagesStorage, func() { os.RemoveAll(tmpDir) }
+}
+
+func getProblemsTypes(report *VerifyStagesReport) []string {
+	var res []string
+	for _, problem := range report.Problems {
+		res = append(res, fmt.Sprintf("%s %s repaired=%v", problem.Type, problem.StageID+problem.ImportID, problem.Repaired))
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestVerifyStagesRepairCache(t *testing.T) {
+	m, _, cleanup := newVerifyTestStorageManager(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// "from" record contains stage absent in the stages storage, "install" record misses the existing stage
+	if err := m.StagesStorageCache.StoreStagesByDigest(ctx, "project", "from", []image.StageID{{Digest: "from", UniqueID: 1}, {Digest: "from", UniqueID: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.StagesStorageCache.StoreStagesByDigest(ctx, "project", "install", []image.StageID{{Digest: "install", UniqueID: 4}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedProblems := []string{
+		"cache-stage-missing install-2 repaired=false",
+		"cache-stage-not-found from-3 repaired=false",
+		"cache-stage-not-found install-4 repaired=false",
+	}
+
+	report, err := m.VerifyStages(ctx, VerifyStagesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := getProblemsTypes(report); !reflect.DeepEqual(problems, expectedProblems) {
+		t.Fatalf("expected problems %v, got %v", expectedProblems, problems)
+	}
+
+	report, err = m.VerifyStages(ctx, VerifyStagesOptions{RepairCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UnrepairedProblemsCount() != 0 || len(report.Problems) != len(expectedProblems) {
+		t.Fatalf("expected all %d problems to be repaired, got %v", len(expectedProblems), getProblemsTypes(report))
+	}
+
+	for _, digest := range []string{"from", "install"} {
+		if exists, stages, err := m.StagesStorageCache.GetStagesByDigest(ctx, "project", digest); err != nil {
+			t.Fatal(err)
+		} else if exists {
+			t.Errorf("expected repaired storage cache record %s to be deleted, got %v", digest, stages)
+		}
+	}
+
+	report, err = m.VerifyStages(ctx, VerifyStagesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("expected no problems after repair, got %v", getProblemsTypes(report))
+	}
+}
+
+func TestVerifyStagesDropOrphanedMetadata(t *testing.T) {
+	m, stagesStorage, cleanup := newVerifyTestStorageManager(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	stagesStorage.imageMetadata["backend"]["install-2"] = []string{"commit1"}
+	stagesStorage.imageMetadata["backend"]["install-5"] = []string{"commit2", "commit3"}
+	stagesStorage.importMetadata["import1"] = &storage.ImportMetadata{ImportSourceID: "source1", SourceImageID: "sha256:2"}
+	stagesStorage.importMetadata["import2"] = &storage.ImportMetadata{ImportSourceID: "source2", SourceImageID: "sha256:absent"}
+
+	expectedProblems := []string{
+		"orphaned-image-metadata install-5 repaired=false",
+		"orphaned-image-metadata install-5 repaired=false",
+		"orphaned-import-metadata import2 repaired=false",
+	}
+
+	report, err := m.VerifyStages(ctx, VerifyStagesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := getProblemsTypes(report); !reflect.DeepEqual(problems, expectedProblems) {
+		t.Fatalf("expected problems %v, got %v", expectedProblems, problems)
+	}
+	if len(stagesStorage.imageMetadata["backend"]) != 2 || len(stagesStorage.importMetadata) != 2 {
+		t.Fatalf("expected metadata not to be changed without --drop-orphaned-metadata")
+	}
+
+	report, err = m.VerifyStages(ctx, VerifyStagesOptions{DropOrphanedMetadata: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UnrepairedProblemsCount() != 0 || len(report.Problems) != len(expectedProblems) {
+		t.Fatalf("expected all %d problems to be repaired, got %v", len(expectedProblems), getProblemsTypes(report))
+	}
+
+	expectedImageMetadata := map[string][]string{"install-2": {"commit1"}}
+	if !reflect.DeepEqual(stagesStorage.imageMetadata["backend"], expectedImageMetadata) {
+		t.Errorf("expected image metadata %v, got %v", expectedImageMetadata, stagesStorage.imageMetadata["backend"])
+	}
+	if _, hasKey := stagesStorage.importMetadata["import1"]; !hasKey || len(stagesStorage.importMetadata) != 1 {
+		t.Errorf("expected only import1 metadata to be kept, got %v", stagesStorage.importMetadata)
+	}
+}
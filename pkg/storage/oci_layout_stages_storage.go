@@ -0,0 +1,713 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/example/stringutil"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/werf/lockgate"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/docker_registry/container_registry_extensions"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	OCILayoutStorageAddressPrefix = "oci-layout:"
+
+	// OCILayoutRefNameAnnotation is the standard OCI annotation used to store tags in the index.json
+	OCILayoutRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+	OCILayoutStage_TagFormat = "%s-%d"
+
+	// NOTE: metadata records use the same tags as RepoStagesStorage does,
+	// NOTE: so that the layout could be replayed into a docker registry tag-by-tag.
+	OCILayoutManagedImageRecord_TagFormat          = RepoManagedImageRecord_ImageTagPrefix + "%s"
+	OCILayoutImageMetadataByCommitRecord_TagFormat = RepoImageMetadataByCommitRecord_TagFormat
+	OCILayoutImportMetadata_TagFormat              = RepoImportMetadata_ImageTagPrefix + "%s"
+	OCILayoutClientIDRecord_TagFormat              = RepoClientIDRecrod_ImageTagPrefix + "%s-%d"
+)
+
+func IsOCILayoutStorageAddress(address string) bool {
+	return strings.HasPrefix(address, OCILayoutStorageAddressPrefix)
+}
+
+func ParseOCILayoutStorageAddress(address string) (string, error) {
+	if !IsOCILayoutStorageAddress(address) {
+		return "", fmt.Errorf("bad oci layout storage address %q: expected %sPATH", address, OCILayoutStorageAddressPrefix)
+	}
+
+	dir := strings.TrimPrefix(address, OCILayoutStorageAddressPrefix)
+	if dir == "" {
+		return "", fmt.Errorf("bad oci layout storage address %q: directory path required", address)
+	}
+
+	return filepath.Abs(dir)
+}
+
+type OCILayoutStagesStorage struct {
+	StorageAddress   string
+	LayoutDir        string
+	ContainerRuntime container_runtime.ContainerRuntime
+}
+
+func NewOCILayoutStagesStorage(address string, containerRuntime container_runtime.ContainerRuntime) (*OCILayoutStagesStorage, error) {
+	layoutDir, err := ParseOCILayoutStorageAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OCILayoutStagesStorage{
+		StorageAddress:   address,
+		LayoutDir:        layoutDir,
+		ContainerRuntime: containerRuntime,
+	}, nil
+}
+
+func (storage *OCILayoutStagesStorage) ConstructStageImageName(projectName, digest string, uniqueID int64) string {
+	return fmt.Sprintf(LocalStage_ImageFormat, projectName, digest, uniqueID)
+}
+
+func (storage *OCILayoutStagesStorage) GetStagesIDs(ctx context.Context, _ string) ([]image.StageID, error) {
+	return storage.getStagesIDsByTagPrefix(ctx, "")
+}
+
+func (storage *OCILayoutStagesStorage) GetStagesIDsByDigest(ctx context.Context, _, digest string) ([]image.StageID, error) {
+	res, err := storage.getStagesIDsByTagPrefix(ctx, digest+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetStagesIDsByDigest result for %q: %#v\n", storage.StorageAddress, res)
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) getStagesIDsByTagPrefix(ctx context.Context, tagPrefix string) ([]image.StageID, error) {
+	tags, err := storage.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []image.StageID
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, tagPrefix) {
+			continue
+		}
+
+		if digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag); err != nil {
+			if isUnexpectedTagFormatError(err) {
+				logboek.Context(ctx).Debug().LogLn(err.Error())
+				continue
+			}
+			return nil, err
+		} else {
+			res = append(res, image.StageID{Digest: digest, UniqueID: uniqueID})
+		}
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) GetStageDescription(ctx context.Context, projectName, digest string, uniqueID int64) (*image.StageDescription, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetStageDescription %s %s %d\n", projectName, digest, uniqueID)
+
+	tag := fmt.Sprintf(OCILayoutStage_TagFormat, digest, uniqueID)
+
+	img, err := storage.getImage(tag)
+	if err != nil {
+		return nil, err
+	} else if img == nil {
+		return nil, nil
+	}
+
+	info, err := newInfoFromLayoutImage(storage.ConstructStageImageName(projectName, digest, uniqueID), img)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stage %s info: %s", tag, err)
+	}
+
+	return &image.StageDescription{
+		StageID: &image.StageID{Digest: digest, UniqueID: uniqueID},
+		Info:    info,
+	}, nil
+}
+
+func (storage *OCILayoutStagesStorage) DeleteStage(ctx context.Context, stageDescription *image.StageDescription, _ DeleteImageOptions) error {
+	return storage.removeTag(ctx, fmt.Sprintf(OCILayoutStage_TagFormat, stageDescription.StageID.Digest, stageDescription.StageID.UniqueID))
+}
+
+func (storage *OCILayoutStagesStorage) FilterStagesAndProcessRelatedData(_ context.Context, stageDescriptions []*image.StageDescription, _ FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
+	return stageDescriptions, nil
+}
+
+func (storage *OCILayoutStagesStorage) CreateRepo(ctx context.Context) error {
+	return storage.withLayoutLock(ctx, func() error {
+		_, err := storage.layoutPath()
+		return err
+	})
+}
+
+func (storage *OCILayoutStagesStorage) DeleteRepo(ctx context.Context) error {
+	return storage.withLayoutLock(ctx, func() error {
+		return os.RemoveAll(storage.LayoutDir)
+	})
+}
+
+func (storage *OCILayoutStagesStorage) ShouldFetchImage(_ context.Context, img container_runtime.Image) (bool, error) {
+	switch storage.ContainerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+		return !dockerImage.Image.IsExistsLocally(), nil
+	default:
+		panic("not implemented")
+	}
+}
+
+func (storage *OCILayoutStagesStorage) FetchImage(ctx context.Context, img container_runtime.Image) error {
+	switch containerRuntime := storage.ContainerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+
+		_, tag := image.ParseRepositoryAndTag(dockerImage.Image.Name())
+
+		layoutImage, err := storage.getImage(tag)
+		if err != nil {
+			return err
+		} else if layoutImage == nil {
+			return fmt.Errorf("image %s not found in %s", tag, storage.StorageAddress)
+		}
+
+		ref, err := name.NewTag(dockerImage.Image.Name())
+		if err != nil {
+			return fmt.Errorf("unable to parse image name %q: %s", dockerImage.Image.Name(), err)
+		}
+
+		if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Loading %s from %s", dockerImage.Image.Name(), storage.StorageAddress)).DoError(func() error {
+			reader, writer := io.Pipe()
+			go func() {
+				writer.CloseWithError(tarball.Write(ref, layoutImage, writer))
+			}()
+			defer reader.Close()
+
+			return docker.ImageLoad(ctx, reader)
+		}); err != nil {
+			return err
+		}
+
+		return containerRuntime.RefreshImageObject(ctx, img)
+	default:
+		panic("not implemented")
+	}
+}
+
+func (storage *OCILayoutStagesStorage) StoreImage(ctx context.Context, img container_runtime.Image) error {
+	switch containerRuntime := storage.ContainerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+
+		if err := containerRuntime.TagImageByName(ctx, img); err != nil {
+			return err
+		}
+
+		_, tag := image.ParseRepositoryAndTag(dockerImage.Image.Name())
+
+		return logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Saving %s into %s", dockerImage.Image.Name(), storage.StorageAddress)).DoError(func() error {
+			archiveFile, err := ioutil.TempFile(werf.GetTmpDir(), "oci-layout-image-")
+			if err != nil {
+				return fmt.Errorf("unable to create temporary file: %s", err)
+			}
+			defer os.Remove(archiveFile.Name())
+
+			archive, err := docker.ImageSave(ctx, dockerImage.Image.Name())
+			if err != nil {
+				archiveFile.Close()
+				return fmt.Errorf("unable to save image %s: %s", dockerImage.Image.Name(), err)
+			}
+
+			_, err = io.Copy(archiveFile, archive)
+			archive.Close()
+			archiveFile.Close()
+			if err != nil {
+				return fmt.Errorf("unable to save image %s: %s", dockerImage.Image.Name(), err)
+			}
+
+			layoutImage, err := tarball.ImageFromPath(archiveFile.Name(), nil)
+			if err != nil {
+				return fmt.Errorf("unable to read saved image %s: %s", dockerImage.Image.Name(), err)
+			}
+
+			return storage.putImage(ctx, tag, layoutImage)
+		})
+	default:
+		panic("not implemented")
+	}
+}
+
+func (storage *OCILayoutStagesStorage) AddManagedImage(ctx context.Context, projectName, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.AddManagedImage %s %s\n", projectName, imageName)
+
+	if validateImageName(imageName) != nil {
+		return nil
+	}
+
+	return storage.putRecord(ctx, fmt.Sprintf(OCILayoutManagedImageRecord_TagFormat, slugImageNameAsDockerImageTag(imageName)), nil)
+}
+
+func (storage *OCILayoutStagesStorage) RmManagedImage(ctx context.Context, projectName, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmManagedImage %s %s\n", projectName, imageName)
+
+	return storage.removeTag(ctx, fmt.Sprintf(OCILayoutManagedImageRecord_TagFormat, slugImageNameAsDockerImageTag(imageName)))
+}
+
+func (storage *OCILayoutStagesStorage) GetManagedImages(ctx context.Context, projectName string) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetManagedImages %s\n", projectName)
+
+	tags, err := storage.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) {
+			continue
+		}
+
+		managedImageName := unslugDockerImageTagAsImageName(strings.TrimPrefix(tag, RepoManagedImageRecord_ImageTagPrefix))
+
+		if validateImageName(managedImageName) != nil {
+			continue
+		}
+
+		res = append(res, managedImageName)
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) PutImageMetadata(ctx context.Context, projectName, imageName, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutImageMetadata %s %s %s %s\n", projectName, imageName, commit, stageID)
+
+	if err := storage.putRecord(ctx, fmt.Sprintf(OCILayoutImageMetadataByCommitRecord_TagFormat, imageNameID(imageName), commit, stageID), nil); err != nil {
+		return err
+	}
+	logboek.Context(ctx).Info().LogF("Put image %s commit %s stage ID %s\n", imageName, commit, stageID)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RmImageMetadata(ctx context.Context, projectName, imageNameOrID, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmImageMetadata %s %s %s %s\n", projectName, imageNameOrID, commit, stageID)
+
+	tags, err := storage.Tags()
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range []string{
+		fmt.Sprintf(OCILayoutImageMetadataByCommitRecord_TagFormat, imageNameID(imageNameOrID), commit, stageID),
+		fmt.Sprintf(OCILayoutImageMetadataByCommitRecord_TagFormat, imageNameOrID, commit, stageID),
+	} {
+		if !util.IsStringsContainValue(tags, tag) {
+			continue
+		}
+
+		if err := storage.removeTag(ctx, tag); err != nil {
+			return err
+		}
+
+		logboek.Context(ctx).Info().LogF("Removed image %s commit %s stage ID %s\n", imageNameOrID, commit, stageID)
+		return nil
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) IsImageMetadataExist(ctx context.Context, projectName, imageName, commit, stageID string) (bool, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.IsImageMetadataExist %s %s %s %s\n", projectName, imageName, commit, stageID)
+
+	tags, err := storage.Tags()
+	if err != nil {
+		return false, err
+	}
+
+	return util.IsStringsContainValue(tags, fmt.Sprintf(OCILayoutImageMetadataByCommitRecord_TagFormat, imageNameID(imageName), commit, stageID)), nil
+}
+
+func (storage *OCILayoutStagesStorage) GetAllAndGroupImageMetadataByImageName(ctx context.Context, projectName string, imageNameList []string) (map[string]map[string][]string, map[string]map[string][]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetAllAndGroupImageMetadataByImageName %s %v\n", projectName, imageNameList)
+
+	tags, err := storage.Tags()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return groupImageMetadataTagsByImageName(ctx, imageNameList, tags, RepoImageMetadataByCommitRecord_ImageTagPrefix)
+}
+
+func (storage *OCILayoutStagesStorage) GetImportMetadata(ctx context.Context, projectName, id string) (*ImportMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetImportMetadata %s %s\n", projectName, id)
+
+	img, err := storage.getImage(fmt.Sprintf(OCILayoutImportMetadata_TagFormat, id))
+	if err != nil {
+		return nil, err
+	} else if img == nil {
+		return nil, nil
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get import metadata %s config: %s", id, err)
+	}
+
+	return newImportMetadataFromLabels(configFile.Config.Labels), nil
+}
+
+func (storage *OCILayoutStagesStorage) PutImportMetadata(ctx context.Context, projectName string, metadata *ImportMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutImportMetadata %s %v\n", projectName, metadata)
+
+	return storage.putRecord(ctx, fmt.Sprintf(OCILayoutImportMetadata_TagFormat, metadata.ImportSourceID), metadata.ToLabels())
+}
+
+func (storage *OCILayoutStagesStorage) RmImportMetadata(ctx context.Context, projectName, id string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmImportMetadata %s %s\n", projectName, id)
+
+	return storage.removeTag(ctx, fmt.Sprintf(OCILayoutImportMetadata_TagFormat, id))
+}
+
+func (storage *OCILayoutStagesStorage) GetImportMetadataIDs(ctx context.Context, projectName string) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetImportMetadataIDs %s\n", projectName)
+
+	tags, err := storage.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoImportMetadata_ImageTagPrefix) {
+			continue
+		}
+
+		ids = append(ids, strings.TrimPrefix(tag, RepoImportMetadata_ImageTagPrefix))
+	}
+
+	return ids, nil
+}
+
+func (storage *OCILayoutStagesStorage) GetClientIDRecords(ctx context.Context, projectName string) ([]*ClientIDRecord, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetClientIDRecords for project %s\n", projectName)
+
+	tags, err := storage.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*ClientIDRecord
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoClientIDRecrod_ImageTagPrefix) {
+			continue
+		}
+
+		dataParts := strings.SplitN(stringutil.Reverse(strings.TrimPrefix(tag, RepoClientIDRecrod_ImageTagPrefix)), "-", 2)
+		if len(dataParts) != 2 {
+			continue
+		}
+
+		clientID, timestampMillisecStr := stringutil.Reverse(dataParts[1]), stringutil.Reverse(dataParts[0])
+
+		timestampMillisec, err := strconv.ParseInt(timestampMillisecStr, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		rec := &ClientIDRecord{ClientID: clientID, TimestampMillisec: timestampMillisec}
+		res = append(res, rec)
+
+		logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetClientIDRecords got clientID record: %s\n", rec)
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) PostClientIDRecord(ctx context.Context, projectName string, rec *ClientIDRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PostClientIDRecord %s for project %s\n", rec.ClientID, projectName)
+
+	if err := storage.putRecord(ctx, fmt.Sprintf(OCILayoutClientIDRecord_TagFormat, rec.ClientID, rec.TimestampMillisec), nil); err != nil {
+		return err
+	}
+
+	logboek.Context(ctx).Info().LogF("Posted new clientID %q for project %s\n", rec.ClientID, projectName)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) String() string {
+	return storage.StorageAddress
+}
+
+func (storage *OCILayoutStagesStorage) Address() string {
+	return storage.StorageAddress
+}
+
+// Tags returns all tags of the layout index.json in the same form as docker registry tags of the RepoStagesStorage
+func (storage *OCILayoutStagesStorage) Tags() ([]string, error) {
+	indexManifest, err := storage.readIndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, desc := range indexManifest.Manifests {
+		if tag, ok := desc.Annotations[OCILayoutRefNameAnnotation]; ok {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+func (storage *OCILayoutStagesStorage) indexFilePath() string {
+	return filepath.Join(storage.LayoutDir, "index.json")
+}
+
+func (storage *OCILayoutStagesStorage) readIndexManifest() (*v1.IndexManifest, error) {
+	data, err := ioutil.ReadFile(storage.indexFilePath())
+	if os.IsNotExist(err) {
+		return &v1.IndexManifest{SchemaVersion: 2}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %s", storage.indexFilePath(), err)
+	}
+
+	indexManifest := &v1.IndexManifest{}
+	if err := json.Unmarshal(data, indexManifest); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", storage.indexFilePath(), err)
+	}
+
+	return indexManifest, nil
+}
+
+func (storage *OCILayoutStagesStorage) writeIndexManifest(indexManifest *v1.IndexManifest) error {
+	data, err := json.MarshalIndent(indexManifest, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile := storage.indexFilePath() + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("unable to write %s: %s", tmpFile, err)
+	}
+
+	return os.Rename(tmpFile, storage.indexFilePath())
+}
+
+// layoutPath initializes an empty layout in the LayoutDir when needed
+func (storage *OCILayoutStagesStorage) layoutPath() (layout.Path, error) {
+	if _, err := os.Stat(storage.indexFilePath()); os.IsNotExist(err) {
+		if err := os.MkdirAll(storage.LayoutDir, os.ModePerm); err != nil {
+			return "", fmt.Errorf("unable to create dir %s: %s", storage.LayoutDir, err)
+		}
+
+		return layout.Write(storage.LayoutDir, empty.Index)
+	} else if err != nil {
+		return "", err
+	}
+
+	return layout.Path(storage.LayoutDir), nil
+}
+
+func (storage *OCILayoutStagesStorage) withLayoutLock(ctx context.Context, f func() error) error {
+	lockName := fmt.Sprintf("oci-layout.%s", util.MurmurHash(storage.LayoutDir))
+	return werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: 600 * time.Second}, f)
+}
+
+// getImage returns nil image without an error when there is no such tag in the layout
+func (storage *OCILayoutStagesStorage) getImage(tag string) (v1.Image, error) {
+	indexManifest, err := storage.readIndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, desc := range indexManifest.Manifests {
+		if desc.Annotations[OCILayoutRefNameAnnotation] != tag {
+			continue
+		}
+
+		img, err := layout.Path(storage.LayoutDir).Image(desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image %s from %s: %s", tag, storage.StorageAddress, err)
+		}
+
+		return img, nil
+	}
+
+	return nil, nil
+}
+
+func (storage *OCILayoutStagesStorage) putRecord(ctx context.Context, tag string, labels map[string]string) error {
+	return storage.putImage(ctx, tag, container_registry_extensions.NewManifestOnlyImage(labels))
+}
+
+func (storage *OCILayoutStagesStorage) putImage(ctx context.Context, tag string, img v1.Image) error {
+	return storage.withLayoutLock(ctx, func() error {
+		layoutPath, err := storage.layoutPath()
+		if err != nil {
+			return err
+		}
+
+		if err := storage.removeTagFromIndex(tag); err != nil {
+			return err
+		}
+
+		if err := layoutPath.AppendImage(img, layout.WithAnnotations(map[string]string{OCILayoutRefNameAnnotation: tag})); err != nil {
+			return fmt.Errorf("unable to write image %s into %s: %s", tag, storage.StorageAddress, err)
+		}
+
+		return nil
+	})
+}
+
+func (storage *OCILayoutStagesStorage) removeTag(ctx context.Context, tag string) error {
+	return storage.withLayoutLock(ctx, func() error {
+		if err := storage.removeTagFromIndex(tag); err != nil {
+			return err
+		}
+
+		return storage.removeUnreferencedBlobs(ctx)
+	})
+}
+
+func (storage *OCILayoutStagesStorage) removeTagFromIndex(tag string) error {
+	indexManifest, err := storage.readIndexManifest()
+	if err != nil {
+		return err
+	}
+
+	var manifests []v1.Descriptor
+	for _, desc := range indexManifest.Manifests {
+		if desc.Annotations[OCILayoutRefNameAnnotation] == tag {
+			continue
+		}
+		manifests = append(manifests, desc)
+	}
+
+	if len(manifests) == len(indexManifest.Manifests) {
+		return nil
+	}
+
+	indexManifest.Manifests = manifests
+	return storage.writeIndexManifest(indexManifest)
+}
+
+// removeUnreferencedBlobs removes manifests, configs and layers that are not reachable from the index.json anymore
+func (storage *OCILayoutStagesStorage) removeUnreferencedBlobs(ctx context.Context) error {
+	indexManifest, err := storage.readIndexManifest()
+	if err != nil {
+		return err
+	}
+
+	layoutPath := layout.Path(storage.LayoutDir)
+	referencedBlobs := map[string]bool{}
+	for _, desc := range indexManifest.Manifests {
+		referencedBlobs[desc.Digest.String()] = true
+
+		img, err := layoutPath.Image(desc.Digest)
+		if err != nil {
+			return fmt.Errorf("unable to read image %s from %s: %s", desc.Digest, storage.StorageAddress, err)
+		}
+
+		manifest, err := img.Manifest()
+		if err != nil {
+			return fmt.Errorf("unable to read manifest %s from %s: %s", desc.Digest, storage.StorageAddress, err)
+		}
+
+		referencedBlobs[manifest.Config.Digest.String()] = true
+		for _, layer := range manifest.Layers {
+			referencedBlobs[layer.Digest.String()] = true
+		}
+	}
+
+	blobsDir := filepath.Join(storage.LayoutDir, "blobs")
+	algorithmDirs, err := ioutil.ReadDir(blobsDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, algorithmDir := range algorithmDirs {
+		blobs, err := ioutil.ReadDir(filepath.Join(blobsDir, algorithmDir.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, blob := range blobs {
+			if referencedBlobs[fmt.Sprintf("%s:%s", algorithmDir.Name(), blob.Name())] {
+				continue
+			}
+
+			logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.removeUnreferencedBlobs removing %s:%s\n", algorithmDir.Name(), blob.Name())
+			if err := os.Remove(filepath.Join(blobsDir, algorithmDir.Name(), blob.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func newInfoFromLayoutImage(ref string, img v1.Image) (*image.Info, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	var totalSize int64
+	for _, layer := range manifest.Layers {
+		totalSize += layer.Size
+	}
+
+	repository, tag := image.ParseRepositoryAndTag(ref)
+
+	info := &image.Info{
+		Name:       ref,
+		Repository: repository,
+		Tag:        tag,
+		RepoDigest: digest.String(),
+		ID:         manifest.Config.Digest.String(),
+		ParentID:   configFile.Config.Image,
+		Labels:     configFile.Config.Labels,
+		Size:       totalSize,
+	}
+	info.SetCreatedAtUnixNano(configFile.Created.UnixNano())
+
+	return info, nil
+}
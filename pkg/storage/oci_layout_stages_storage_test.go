@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestParseOCILayoutStorageAddress(t *testing.T) {
+	if _, err := ParseOCILayoutStorageAddress("oci-layout:"); err == nil {
+		t.Errorf("expected error for empty layout path")
+	}
+
+	if _, err := ParseOCILayoutStorageAddress("/tmp/layout"); err == nil {
+		t.Errorf("expected error for address without oci-layout: prefix")
+	}
+
+	if dir, err := ParseOCILayoutStorageAddress("oci-layout:/tmp/layout"); err != nil {
+		t.Error(err)
+	} else if dir != "/tmp/layout" {
+		t.Errorf("expected /tmp/layout, got %q", dir)
+	}
+}
+
+func TestOCILayoutStagesStorage_MetadataRecords(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-oci-layout-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := werf.Init(tmpDir, filepath.Join(tmpDir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	s, err := NewOCILayoutStagesStorage("oci-layout:"+filepath.Join(tmpDir, "layout"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CreateRepo(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AddManagedImage(ctx, "myproject", "frontend/app"); err != nil {
+		t.Fatal(err)
+	}
+	// repeated records should not be duplicated in the index
+	if err := s.AddManagedImage(ctx, "myproject", "frontend/app"); err != nil {
+		t.Fatal(err)
+	}
+
+	if managedImages, err := s.GetManagedImages(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if len(managedImages) != 1 || managedImages[0] != "frontend/app" {
+		t.Errorf("unexpected managed images: %#v", managedImages)
+	}
+
+	if err := s.PutImageMetadata(ctx, "myproject", "frontend/app", "c0ffee", "stage-1"); err != nil {
+		t.Fatal(err)
+	}
+	metadata, _, err := s.GetAllAndGroupImageMetadataByImageName(ctx, "myproject", []string{"frontend/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commits := metadata["frontend/app"]["stage-1"]; len(commits) != 1 || commits[0] != "c0ffee" {
+		t.Errorf("unexpected image metadata: %#v", metadata)
+	}
+
+	if err := s.PutImportMetadata(ctx, "myproject", &ImportMetadata{ImportSourceID: "source", SourceImageID: "sha256:abc", Checksum: "sum"}); err != nil {
+		t.Fatal(err)
+	}
+	if importMetadata, err := s.GetImportMetadata(ctx, "myproject", "source"); err != nil {
+		t.Fatal(err)
+	} else if importMetadata == nil || importMetadata.SourceImageID != "sha256:abc" || importMetadata.Checksum != "sum" {
+		t.Errorf("unexpected import metadata: %#v", importMetadata)
+	}
+
+	if err := s.PostClientIDRecord(ctx, "myproject", &ClientIDRecord{ClientID: "a-b-c", TimestampMillisec: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := s.GetClientIDRecords(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if len(recs) != 1 || recs[0].ClientID != "a-b-c" || recs[0].TimestampMillisec != 42 {
+		t.Errorf("unexpected client id records: %v", recs)
+	}
+
+	for _, f := range []func() error{
+		func() error { return s.RmManagedImage(ctx, "myproject", "frontend/app") },
+		func() error { return s.RmImageMetadata(ctx, "myproject", "frontend/app", "c0ffee", "stage-1") },
+		func() error { return s.RmImportMetadata(ctx, "myproject", "source") },
+	} {
+		if err := f(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if tags, err := s.Tags(); err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 1 {
+		t.Errorf("expected only client id record to remain, got %v", tags)
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -31,6 +32,9 @@ const (
 	RepoClientIDRecrod_ImageTagPrefix  = "client-id-"
 	RepoClientIDRecrod_ImageNameFormat = "%s:client-id-%s-%d"
 
+	RepoManifestList_ImageTagPrefix  = "manifest-list-"
+	RepoManifestList_ImageNameFormat = "%s:manifest-list-%s"
+
 	UnexpectedTagFormatErrorPrefix = "unexpected tag format"
 )
 
@@ -53,9 +57,10 @@ func isUnexpectedTagFormatError(err error) bool {
 }
 
 type RepoStagesStorage struct {
-	RepoAddress      string
-	DockerRegistry   docker_registry.DockerRegistry
-	ContainerRuntime container_runtime.ContainerRuntime
+	RepoAddress           string
+	DockerRegistry        docker_registry.DockerRegistry
+	DockerRegistryOptions docker_registry.DockerRegistryOptions
+	ContainerRuntime      container_runtime.ContainerRuntime
 }
 
 type RepoStagesStorageOptions struct {
@@ -72,9 +77,10 @@ func NewRepoStagesStorage(repoAddress string, containerRuntime container_runtime
 	}
 
 	return &RepoStagesStorage{
-		RepoAddress:      repoAddress,
-		DockerRegistry:   dockerRegistry,
-		ContainerRuntime: containerRuntime,
+		RepoAddress:           repoAddress,
+		DockerRegistry:        dockerRegistry,
+		DockerRegistryOptions: options.DockerRegistryOptions,
+		ContainerRuntime:      containerRuntime,
 	}, nil
 }
 
@@ -82,6 +88,52 @@ func (storage *RepoStagesStorage) ConstructStageImageName(_, digest string, uniq
 	return fmt.Sprintf(RepoStage_ImageFormat, storage.RepoAddress, digest, uniqueID)
 }
 
+// ConstructManifestListName returns the same name for the same set of the platform stages images
+func (storage *RepoStagesStorage) ConstructManifestListName(stageImageNames []string) string {
+	names := append([]string{}, stageImageNames...)
+	sort.Strings(names)
+
+	return fmt.Sprintf(RepoManifestList_ImageNameFormat, storage.RepoAddress, util.Sha256Hash(names...))
+}
+
+func (storage *RepoStagesStorage) PublishManifestList(ctx context.Context, manifestListName string, stageImageNames []string) error {
+	return storage.DockerRegistry.PushManifestList(ctx, manifestListName, stageImageNames)
+}
+
+func (storage *RepoStagesStorage) IsManifestListExist(ctx context.Context, manifestListName string) (bool, error) {
+	return storage.DockerRegistry.IsRepoManifestListExists(ctx, manifestListName)
+}
+
+// GetManifestListsNames returns names of all manifest lists published into the repo
+func (storage *RepoStagesStorage) GetManifestListsNames(ctx context.Context) ([]string, error) {
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tags for repo %q: %s", storage.RepoAddress, err)
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, RepoManifestList_ImageTagPrefix) {
+			res = append(res, fmt.Sprintf("%s:%s", storage.RepoAddress, tag))
+		}
+	}
+
+	return res, nil
+}
+
+// IsManifestListName returns true if the image name is the name of the manifest list in the repo
+func (storage *RepoStagesStorage) IsManifestListName(imageName string) bool {
+	return strings.HasPrefix(imageName, fmt.Sprintf("%s:%s", storage.RepoAddress, RepoManifestList_ImageTagPrefix))
+}
+
+func (storage *RepoStagesStorage) GetManifestList(ctx context.Context, manifestListName string) (*docker_registry.ManifestListInfo, error) {
+	return storage.DockerRegistry.GetRepoManifestList(ctx, manifestListName)
+}
+
+func (storage *RepoStagesStorage) DeleteManifestList(ctx context.Context, manifestList *docker_registry.ManifestListInfo) error {
+	return storage.DockerRegistry.DeleteRepoImage(ctx, manifestList.Info)
+}
+
 func (storage *RepoStagesStorage) GetStagesIDs(ctx context.Context, projectName string) ([]image.StageID, error) {
 	var res []image.StageID
 
@@ -91,7 +143,7 @@ func (storage *RepoStagesStorage) GetStagesIDs(ctx context.Context, projectName
 		logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetRepoImagesByDigest fetched tags for %q: %#v\n", storage.RepoAddress, tags)
 
 		for _, tag := range tags {
-			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) {
+			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) || strings.HasPrefix(tag, container_runtime.BuildkitCacheTagPrefix) || strings.HasPrefix(tag, RepoManifestList_ImageTagPrefix) || strings.HasSuffix(tag, docker_registry.CosignSignatureTagSuffix) || strings.HasSuffix(tag, docker_registry.ImageSBOMTagSuffix) {
 				continue
 			}
 
@@ -116,6 +168,64 @@ func (storage *RepoStagesStorage) DeleteStage(ctx context.Context, stageDescript
 	return storage.DockerRegistry.DeleteRepoImage(ctx, stageDescription.Info)
 }
 
+// GetBuildkitCacheImagesNames returns names of the build cache records exported into the repo by the buildkit runtime
+func (storage *RepoStagesStorage) GetBuildkitCacheImagesNames(ctx context.Context) ([]string, error) {
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tags for repo %q: %s", storage.RepoAddress, err)
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, container_runtime.BuildkitCacheTagPrefix) {
+			res = append(res, fmt.Sprintf("%s:%s", storage.RepoAddress, tag))
+		}
+	}
+
+	return res, nil
+}
+
+// GetImageArtifactsNames returns names of the artifacts attached to the image manifests by the sha256-HEX<suffix> tags with the suffixes
+func (storage *RepoStagesStorage) GetImageArtifactsNames(ctx context.Context, tagSuffixes []string) ([]string, error) {
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tags for repo %q: %s", storage.RepoAddress, err)
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "sha256-") {
+			continue
+		}
+
+		for _, tagSuffix := range tagSuffixes {
+			if strings.HasSuffix(tag, tagSuffix) {
+				res = append(res, fmt.Sprintf("%s:%s", storage.RepoAddress, tag))
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// DeleteImageByName deletes the image or the manifest list (e.g. the build cache record) by the name in the repo
+func (storage *RepoStagesStorage) DeleteImageByName(ctx context.Context, imageName string) error {
+	repoDigest, err := storage.DockerRegistry.GetRepoImageManifestDigest(ctx, imageName)
+	if err != nil {
+		return err
+	}
+
+	_, tag := image.ParseRepositoryAndTag(imageName)
+
+	return storage.DockerRegistry.DeleteRepoImage(ctx, &image.Info{
+		Name:       imageName,
+		Repository: storage.RepoAddress,
+		Tag:        tag,
+		RepoDigest: repoDigest,
+	})
+}
+
 func (storage *RepoStagesStorage) FilterStagesAndProcessRelatedData(_ context.Context, stageDescriptions []*image.StageDescription, _ FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
 	return stageDescriptions, nil
 }
@@ -273,6 +383,24 @@ func (storage *RepoStagesStorage) StoreImage(ctx context.Context, img container_
 	}
 }
 
+// CopyStage copies the stage image into the destination repo registry-to-registry without the container runtime
+func (storage *RepoStagesStorage) CopyStage(ctx context.Context, projectName string, stageDescription *image.StageDescription, destination *RepoStagesStorage) error {
+	destinationImageName := destination.ConstructStageImageName(projectName, stageDescription.StageID.Digest, stageDescription.StageID.UniqueID)
+
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.CopyStage %s -> %s\n", stageDescription.Info.Name, destinationImageName)
+
+	copyImageOptions := docker_registry.CopyImageOptions{
+		DestinationInsecureRegistry:      destination.DockerRegistryOptions.InsecureRegistry,
+		DestinationSkipTlsVerifyRegistry: destination.DockerRegistryOptions.SkipTlsVerifyRegistry,
+	}
+
+	if err := storage.DockerRegistry.CopyImage(ctx, stageDescription.Info.Name, destinationImageName, copyImageOptions); err != nil {
+		return fmt.Errorf("unable to copy %s to %s: %s", stageDescription.Info.Name, destinationImageName, err)
+	}
+
+	return nil
+}
+
 func (storage *RepoStagesStorage) ShouldFetchImage(_ context.Context, img container_runtime.Image) (bool, error) {
 	switch storage.ContainerRuntime.(type) {
 	case *container_runtime.LocalDockerServerRuntime:
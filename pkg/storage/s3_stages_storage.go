@@ -0,0 +1,640 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/golang/example/stringutil"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/image"
+)
+
+const (
+	S3StorageAddressPrefix = "s3://"
+
+	S3Stage_ObjectKeyPrefix            = "stages/"
+	S3Stage_ArchiveObjectKeyFormat     = "stages/%s-%d.tar"
+	S3Stage_DescriptionObjectKeyFormat = "stages/%s-%d.json"
+	S3Stage_DescriptionObjectKeySuffix = ".json"
+
+	S3ManagedImageRecord_ObjectKeyPrefix = "managed-images/"
+	S3ManagedImageRecord_ObjectKeyFormat = "managed-images/%s"
+
+	S3ImageMetadataByCommitRecord_ObjectKeyPrefix = "meta/"
+	S3ImageMetadataByCommitRecord_ObjectKeyFormat = "meta/%s_%s_%s"
+
+	S3ImportMetadata_ObjectKeyPrefix = "import-metadata/"
+	S3ImportMetadata_ObjectKeyFormat = "import-metadata/%s"
+
+	S3ClientIDRecord_ObjectKeyPrefix = "client-id/"
+	S3ClientIDRecord_ObjectKeyFormat = "client-id/%s-%d"
+)
+
+func IsS3StorageAddress(address string) bool {
+	return strings.HasPrefix(address, S3StorageAddressPrefix)
+}
+
+// ParseS3StorageAddress splits s3://BUCKET[/PREFIX] address into bucket and objects prefix
+func ParseS3StorageAddress(address string) (string, string, error) {
+	if !IsS3StorageAddress(address) {
+		return "", "", fmt.Errorf("bad s3 storage address %q: expected %sBUCKET[/PREFIX]", address, S3StorageAddressPrefix)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(address, S3StorageAddressPrefix), "/", 2)
+	if parts[0] == "" {
+		return "", "", fmt.Errorf("bad s3 storage address %q: bucket name required", address)
+	}
+
+	var prefix string
+	if len(parts) == 2 {
+		prefix = strings.Trim(parts[1], "/")
+	}
+
+	return parts[0], prefix, nil
+}
+
+type S3StagesStorage struct {
+	StorageAddress   string
+	Bucket           string
+	Prefix           string
+	ContainerRuntime container_runtime.ContainerRuntime
+
+	s3Client *s3.S3
+	uploader *s3manager.Uploader
+}
+
+type S3StagesStorageOptions struct {
+	// Endpoint allows usage of S3-compatible object storages such as MinIO, path-style addressing is used in this case
+	Endpoint string
+	Region   string
+}
+
+func NewS3StagesStorage(address string, containerRuntime container_runtime.ContainerRuntime, options S3StagesStorageOptions) (*S3StagesStorage, error) {
+	bucket, prefix, err := ParseS3StorageAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	awsConfig := aws.NewConfig()
+	if options.Region != "" {
+		awsConfig = awsConfig.WithRegion(options.Region)
+	}
+	if options.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(options.Endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create s3 session for %q: %s", address, err)
+	}
+
+	return &S3StagesStorage{
+		StorageAddress:   address,
+		Bucket:           bucket,
+		Prefix:           prefix,
+		ContainerRuntime: containerRuntime,
+		s3Client:         s3.New(sess),
+		uploader:         s3manager.NewUploader(sess),
+	}, nil
+}
+
+func (storage *S3StagesStorage) objectKey(key string) string {
+	if storage.Prefix == "" {
+		return key
+	}
+	return path.Join(storage.Prefix, key)
+}
+
+func (storage *S3StagesStorage) relativeObjectKey(key string) string {
+	if storage.Prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, storage.Prefix+"/")
+}
+
+func (storage *S3StagesStorage) ConstructStageImageName(projectName, digest string, uniqueID int64) string {
+	return fmt.Sprintf(LocalStage_ImageFormat, projectName, digest, uniqueID)
+}
+
+func (storage *S3StagesStorage) GetStagesIDs(ctx context.Context, _ string) ([]image.StageID, error) {
+	return storage.getStagesIDsByKeyPrefix(ctx, S3Stage_ObjectKeyPrefix)
+}
+
+func (storage *S3StagesStorage) GetStagesIDsByDigest(ctx context.Context, _, digest string) ([]image.StageID, error) {
+	res, err := storage.getStagesIDsByKeyPrefix(ctx, S3Stage_ObjectKeyPrefix+digest+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetStagesIDsByDigest result for %q: %#v\n", storage.StorageAddress, res)
+
+	return res, nil
+}
+
+func (storage *S3StagesStorage) getStagesIDsByKeyPrefix(ctx context.Context, keyPrefix string) ([]image.StageID, error) {
+	keys, err := storage.listObjectKeys(ctx, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []image.StageID
+	for _, key := range keys {
+		if !strings.HasSuffix(key, S3Stage_DescriptionObjectKeySuffix) {
+			continue
+		}
+
+		tag := strings.TrimSuffix(strings.TrimPrefix(key, S3Stage_ObjectKeyPrefix), S3Stage_DescriptionObjectKeySuffix)
+		if digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag); err != nil {
+			if isUnexpectedTagFormatError(err) {
+				logboek.Context(ctx).Debug().LogLn(err.Error())
+				continue
+			}
+			return nil, err
+		} else {
+			res = append(res, image.StageID{Digest: digest, UniqueID: uniqueID})
+		}
+	}
+
+	return res, nil
+}
+
+func (storage *S3StagesStorage) GetStageDescription(ctx context.Context, projectName, digest string, uniqueID int64) (*image.StageDescription, error) {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetStageDescription %s %s %d\n", projectName, digest, uniqueID)
+
+	data, err := storage.getObject(ctx, fmt.Sprintf(S3Stage_DescriptionObjectKeyFormat, digest, uniqueID))
+	if err != nil {
+		return nil, err
+	} else if data == nil {
+		return nil, nil
+	}
+
+	stageDesc := &image.StageDescription{}
+	if err := json.Unmarshal(data, stageDesc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal stage %s-%d description: %s", digest, uniqueID, err)
+	}
+
+	return stageDesc, nil
+}
+
+func (storage *S3StagesStorage) DeleteStage(ctx context.Context, stageDescription *image.StageDescription, _ DeleteImageOptions) error {
+	digest, uniqueID := stageDescription.StageID.Digest, stageDescription.StageID.UniqueID
+
+	// NOTE: description goes first, so the stage will not be selected by the digest when the archive is already removed
+	for _, key := range []string{
+		fmt.Sprintf(S3Stage_DescriptionObjectKeyFormat, digest, uniqueID),
+		fmt.Sprintf(S3Stage_ArchiveObjectKeyFormat, digest, uniqueID),
+	} {
+		if err := storage.deleteObject(ctx, key); err != nil {
+			return fmt.Errorf("unable to delete stage %s: %s", stageDescription.StageID.String(), err)
+		}
+	}
+
+	return nil
+}
+
+func (storage *S3StagesStorage) FilterStagesAndProcessRelatedData(_ context.Context, stageDescriptions []*image.StageDescription, _ FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
+	return stageDescriptions, nil
+}
+
+func (storage *S3StagesStorage) CreateRepo(ctx context.Context) error {
+	if _, err := storage.s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(storage.Bucket)}); err == nil {
+		return nil
+	}
+
+	if _, err := storage.s3Client.CreateBucketWithContext(ctx, &s3.CreateBucketInput{Bucket: aws.String(storage.Bucket)}); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+			return nil
+		}
+		return fmt.Errorf("unable to create bucket %q: %s", storage.Bucket, err)
+	}
+
+	return nil
+}
+
+func (storage *S3StagesStorage) DeleteRepo(ctx context.Context) error {
+	keys, err := storage.listObjectKeys(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := storage.deleteObject(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (storage *S3StagesStorage) ShouldFetchImage(_ context.Context, img container_runtime.Image) (bool, error) {
+	switch storage.ContainerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+		return !dockerImage.Image.IsExistsLocally(), nil
+	default:
+		panic("not implemented")
+	}
+}
+
+func (storage *S3StagesStorage) FetchImage(ctx context.Context, img container_runtime.Image) error {
+	switch containerRuntime := storage.ContainerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+
+		_, tag := image.ParseRepositoryAndTag(dockerImage.Image.Name())
+		digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag)
+		if err != nil {
+			return fmt.Errorf("unable to fetch image %s: %s", dockerImage.Image.Name(), err)
+		}
+
+		key := storage.objectKey(fmt.Sprintf(S3Stage_ArchiveObjectKeyFormat, digest, uniqueID))
+
+		if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Loading %s from s3://%s/%s", dockerImage.Image.Name(), storage.Bucket, key)).DoError(func() error {
+			output, err := storage.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(storage.Bucket), Key: aws.String(key)})
+			if err != nil {
+				return fmt.Errorf("unable to get object %q: %s", key, err)
+			}
+			defer output.Body.Close()
+
+			return docker.ImageLoad(ctx, output.Body)
+		}); err != nil {
+			return err
+		}
+
+		return containerRuntime.RefreshImageObject(ctx, img)
+	default:
+		panic("not implemented")
+	}
+}
+
+func (storage *S3StagesStorage) StoreImage(ctx context.Context, img container_runtime.Image) error {
+	switch containerRuntime := storage.ContainerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+
+		if err := containerRuntime.TagImageByName(ctx, img); err != nil {
+			return err
+		}
+
+		inspect, err := containerRuntime.GetImageInspect(ctx, dockerImage.Image.Name())
+		if err != nil {
+			return fmt.Errorf("unable to get image %s inspect: %s", dockerImage.Image.Name(), err)
+		} else if inspect == nil {
+			return fmt.Errorf("unable to store image %s: image does not exist locally", dockerImage.Image.Name())
+		}
+
+		projectName, tag := image.ParseRepositoryAndTag(dockerImage.Image.Name())
+		digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag)
+		if err != nil {
+			return fmt.Errorf("unable to store image %s: %s", dockerImage.Image.Name(), err)
+		}
+
+		key := storage.objectKey(fmt.Sprintf(S3Stage_ArchiveObjectKeyFormat, digest, uniqueID))
+
+		if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Saving %s into s3://%s/%s", dockerImage.Image.Name(), storage.Bucket, key)).DoError(func() error {
+			archive, err := docker.ImageSave(ctx, dockerImage.Image.Name())
+			if err != nil {
+				return fmt.Errorf("unable to save image %s: %s", dockerImage.Image.Name(), err)
+			}
+			defer archive.Close()
+
+			if _, err := storage.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+				Bucket: aws.String(storage.Bucket),
+				Key:    aws.String(key),
+				Body:   archive,
+			}); err != nil {
+				return fmt.Errorf("unable to upload object %q: %s", key, err)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		stageDesc := &image.StageDescription{
+			StageID: &image.StageID{Digest: digest, UniqueID: uniqueID},
+			Info:    image.NewInfoFromInspect(storage.ConstructStageImageName(projectName, digest, uniqueID), inspect),
+		}
+
+		data, err := json.Marshal(stageDesc)
+		if err != nil {
+			return err
+		}
+
+		return storage.putObject(ctx, fmt.Sprintf(S3Stage_DescriptionObjectKeyFormat, digest, uniqueID), data)
+	default:
+		panic("not implemented")
+	}
+}
+
+func (storage *S3StagesStorage) AddManagedImage(ctx context.Context, projectName, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.AddManagedImage %s %s\n", projectName, imageName)
+
+	if validateImageName(imageName) != nil {
+		return nil
+	}
+
+	return storage.putObject(ctx, fmt.Sprintf(S3ManagedImageRecord_ObjectKeyFormat, slugImageNameAsDockerImageTag(imageName)), nil)
+}
+
+func (storage *S3StagesStorage) RmManagedImage(ctx context.Context, projectName, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.RmManagedImage %s %s\n", projectName, imageName)
+
+	return storage.deleteObject(ctx, fmt.Sprintf(S3ManagedImageRecord_ObjectKeyFormat, slugImageNameAsDockerImageTag(imageName)))
+}
+
+func (storage *S3StagesStorage) GetManagedImages(ctx context.Context, projectName string) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetManagedImages %s\n", projectName)
+
+	keys, err := storage.listObjectKeys(ctx, S3ManagedImageRecord_ObjectKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, key := range keys {
+		managedImageName := unslugDockerImageTagAsImageName(strings.TrimPrefix(key, S3ManagedImageRecord_ObjectKeyPrefix))
+
+		if validateImageName(managedImageName) != nil {
+			continue
+		}
+
+		res = append(res, managedImageName)
+	}
+
+	return res, nil
+}
+
+func (storage *S3StagesStorage) PutImageMetadata(ctx context.Context, projectName, imageName, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.PutImageMetadata %s %s %s %s\n", projectName, imageName, commit, stageID)
+
+	if err := storage.putObject(ctx, fmt.Sprintf(S3ImageMetadataByCommitRecord_ObjectKeyFormat, imageNameID(imageName), commit, stageID), nil); err != nil {
+		return err
+	}
+	logboek.Context(ctx).Info().LogF("Put image %s commit %s stage ID %s\n", imageName, commit, stageID)
+
+	return nil
+}
+
+func (storage *S3StagesStorage) RmImageMetadata(ctx context.Context, projectName, imageNameOrID, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.RmImageMetadata %s %s %s %s\n", projectName, imageNameOrID, commit, stageID)
+
+	for _, key := range []string{
+		fmt.Sprintf(S3ImageMetadataByCommitRecord_ObjectKeyFormat, imageNameID(imageNameOrID), commit, stageID),
+		fmt.Sprintf(S3ImageMetadataByCommitRecord_ObjectKeyFormat, imageNameOrID, commit, stageID),
+	} {
+		if exists, err := storage.isObjectExist(ctx, key); err != nil {
+			return err
+		} else if !exists {
+			continue
+		}
+
+		if err := storage.deleteObject(ctx, key); err != nil {
+			return err
+		}
+
+		logboek.Context(ctx).Info().LogF("Removed image %s commit %s stage ID %s\n", imageNameOrID, commit, stageID)
+		return nil
+	}
+
+	return nil
+}
+
+func (storage *S3StagesStorage) IsImageMetadataExist(ctx context.Context, projectName, imageName, commit, stageID string) (bool, error) {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.IsImageMetadataExist %s %s %s %s\n", projectName, imageName, commit, stageID)
+
+	return storage.isObjectExist(ctx, fmt.Sprintf(S3ImageMetadataByCommitRecord_ObjectKeyFormat, imageNameID(imageName), commit, stageID))
+}
+
+func (storage *S3StagesStorage) GetAllAndGroupImageMetadataByImageName(ctx context.Context, projectName string, imageNameList []string) (map[string]map[string][]string, map[string]map[string][]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetAllAndGroupImageMetadataByImageName %s %v\n", projectName, imageNameList)
+
+	keys, err := storage.listObjectKeys(ctx, S3ImageMetadataByCommitRecord_ObjectKeyPrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return groupImageMetadataTagsByImageName(ctx, imageNameList, keys, S3ImageMetadataByCommitRecord_ObjectKeyPrefix)
+}
+
+func (storage *S3StagesStorage) GetImportMetadata(ctx context.Context, projectName, id string) (*ImportMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetImportMetadata %s %s\n", projectName, id)
+
+	data, err := storage.getObject(ctx, fmt.Sprintf(S3ImportMetadata_ObjectKeyFormat, id))
+	if err != nil {
+		return nil, err
+	} else if data == nil {
+		return nil, nil
+	}
+
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal import metadata %s: %s", id, err)
+	}
+
+	return newImportMetadataFromLabels(labels), nil
+}
+
+func (storage *S3StagesStorage) PutImportMetadata(ctx context.Context, projectName string, metadata *ImportMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.PutImportMetadata %s %v\n", projectName, metadata)
+
+	data, err := json.Marshal(metadata.ToLabels())
+	if err != nil {
+		return err
+	}
+
+	return storage.putObject(ctx, fmt.Sprintf(S3ImportMetadata_ObjectKeyFormat, metadata.ImportSourceID), data)
+}
+
+func (storage *S3StagesStorage) RmImportMetadata(ctx context.Context, projectName, id string) error {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.RmImportMetadata %s %s\n", projectName, id)
+
+	return storage.deleteObject(ctx, fmt.Sprintf(S3ImportMetadata_ObjectKeyFormat, id))
+}
+
+func (storage *S3StagesStorage) GetImportMetadataIDs(ctx context.Context, projectName string) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetImportMetadataIDs %s\n", projectName)
+
+	keys, err := storage.listObjectKeys(ctx, S3ImportMetadata_ObjectKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, strings.TrimPrefix(key, S3ImportMetadata_ObjectKeyPrefix))
+	}
+
+	return ids, nil
+}
+
+func (storage *S3StagesStorage) GetClientIDRecords(ctx context.Context, projectName string) ([]*ClientIDRecord, error) {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetClientIDRecords for project %s\n", projectName)
+
+	keys, err := storage.listObjectKeys(ctx, S3ClientIDRecord_ObjectKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*ClientIDRecord
+	for _, key := range keys {
+		dataParts := strings.SplitN(stringutil.Reverse(strings.TrimPrefix(key, S3ClientIDRecord_ObjectKeyPrefix)), "-", 2)
+		if len(dataParts) != 2 {
+			continue
+		}
+
+		clientID, timestampMillisecStr := stringutil.Reverse(dataParts[1]), stringutil.Reverse(dataParts[0])
+
+		timestampMillisec, err := strconv.ParseInt(timestampMillisecStr, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		rec := &ClientIDRecord{ClientID: clientID, TimestampMillisec: timestampMillisec}
+		res = append(res, rec)
+
+		logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.GetClientIDRecords got clientID record: %s\n", rec)
+	}
+
+	return res, nil
+}
+
+func (storage *S3StagesStorage) PostClientIDRecord(ctx context.Context, projectName string, rec *ClientIDRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.PostClientIDRecord %s for project %s\n", rec.ClientID, projectName)
+
+	if err := storage.putObject(ctx, fmt.Sprintf(S3ClientIDRecord_ObjectKeyFormat, rec.ClientID, rec.TimestampMillisec), nil); err != nil {
+		return err
+	}
+
+	logboek.Context(ctx).Info().LogF("Posted new clientID %q for project %s\n", rec.ClientID, projectName)
+
+	return nil
+}
+
+func (storage *S3StagesStorage) String() string {
+	return storage.StorageAddress
+}
+
+func (storage *S3StagesStorage) Address() string {
+	return storage.StorageAddress
+}
+
+// listObjectKeys returns object keys relative to the storage prefix
+func (storage *S3StagesStorage) listObjectKeys(ctx context.Context, keyPrefix string) ([]string, error) {
+	var keys []string
+
+	listPrefix := storage.objectKey(keyPrefix)
+	if storage.Prefix != "" && keyPrefix == "" {
+		listPrefix += "/"
+	}
+
+	if err := storage.s3Client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(storage.Bucket),
+		Prefix: aws.String(listPrefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, storage.relativeObjectKey(aws.StringValue(obj.Key)))
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("unable to list objects s3://%s/%s: %s", storage.Bucket, listPrefix, err)
+	}
+
+	logboek.Context(ctx).Debug().LogF("-- S3StagesStorage.listObjectKeys %q: %#v\n", listPrefix, keys)
+
+	return keys, nil
+}
+
+func (storage *S3StagesStorage) isObjectExist(ctx context.Context, key string) (bool, error) {
+	fullKey := storage.objectKey(key)
+
+	if _, err := storage.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: aws.String(storage.Bucket), Key: aws.String(fullKey)}); err != nil {
+		if isS3NotFoundError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to head object s3://%s/%s: %s", storage.Bucket, fullKey, err)
+	}
+
+	return true, nil
+}
+
+// getObject returns nil data without an error when object does not exist
+func (storage *S3StagesStorage) getObject(ctx context.Context, key string) ([]byte, error) {
+	fullKey := storage.objectKey(key)
+
+	output, err := storage.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(storage.Bucket), Key: aws.String(fullKey)})
+	if err != nil {
+		if isS3NotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get object s3://%s/%s: %s", storage.Bucket, fullKey, err)
+	}
+	defer output.Body.Close()
+
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read object s3://%s/%s: %s", storage.Bucket, fullKey, err)
+	}
+
+	return data, nil
+}
+
+func (storage *S3StagesStorage) putObject(ctx context.Context, key string, data []byte) error {
+	fullKey := storage.objectKey(key)
+
+	if _, err := storage.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(storage.Bucket),
+		Key:    aws.String(fullKey),
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		return fmt.Errorf("unable to put object s3://%s/%s: %s", storage.Bucket, fullKey, err)
+	}
+
+	return nil
+}
+
+func (storage *S3StagesStorage) deleteObject(ctx context.Context, key string) error {
+	fullKey := storage.objectKey(key)
+
+	if _, err := storage.s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{Bucket: aws.String(storage.Bucket), Key: aws.String(fullKey)}); err != nil {
+		if isS3NotFoundError(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to delete object s3://%s/%s: %s", storage.Bucket, fullKey, err)
+	}
+
+	return nil
+}
+
+func isS3NotFoundError(err error) bool {
+	if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == 404 {
+		return true
+	}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket, "NotFound":
+			return true
+		}
+	}
+
+	return false
+}
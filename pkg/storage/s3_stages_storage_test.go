@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseS3StorageAddress(t *testing.T) {
+	for _, tc := range []struct {
+		address        string
+		expectedBucket string
+		expectedPrefix string
+		expectedErr    bool
+	}{
+		{address: "s3://werf-stages", expectedBucket: "werf-stages"},
+		{address: "s3://werf-stages/", expectedBucket: "werf-stages"},
+		{address: "s3://werf-stages/myproject", expectedBucket: "werf-stages", expectedPrefix: "myproject"},
+		{address: "s3://werf-stages/group/myproject/", expectedBucket: "werf-stages", expectedPrefix: "group/myproject"},
+		{address: "s3://", expectedErr: true},
+		{address: "registry.example.com/myproject", expectedErr: true},
+	} {
+		bucket, prefix, err := ParseS3StorageAddress(tc.address)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got bucket=%q prefix=%q", tc.address, bucket, prefix)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tc.address, err)
+			continue
+		}
+		if bucket != tc.expectedBucket {
+			t.Errorf("%q: expected bucket %q, got %q", tc.address, tc.expectedBucket, bucket)
+		}
+		if prefix != tc.expectedPrefix {
+			t.Errorf("%q: expected prefix %q, got %q", tc.address, tc.expectedPrefix, prefix)
+		}
+	}
+}
+
+func TestS3StagesStorage_MetadataRecords(t *testing.T) {
+	server := httptest.NewServer(newFakeS3Handler())
+	defer server.Close()
+
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		if value, isSet := os.LookupEnv(name); isSet {
+			defer os.Setenv(name, value)
+		} else {
+			defer os.Unsetenv(name)
+		}
+
+		if err := os.Setenv(name, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewS3StagesStorage("s3://werf-stages/myproject", nil, S3StagesStorageOptions{Endpoint: server.URL, Region: "us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	for _, imageName := range []string{"backend", "frontend/app", ""} {
+		if err := s.AddManagedImage(ctx, "myproject", imageName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.RmManagedImage(ctx, "myproject", "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	managedImages, err := s.GetManagedImages(ctx, "myproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(managedImages)
+	if strings.Join(managedImages, ",") != ",frontend/app" {
+		t.Errorf("unexpected managed images: %#v", managedImages)
+	}
+
+	if err := s.PutImageMetadata(ctx, "myproject", "frontend/app", "c0ffee", "stage-1"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := s.IsImageMetadataExist(ctx, "myproject", "frontend/app", "c0ffee", "stage-1"); err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		t.Errorf("expected image metadata to exist")
+	}
+
+	metadata, _, err := s.GetAllAndGroupImageMetadataByImageName(ctx, "myproject", []string{"frontend/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commits := metadata["frontend/app"]["stage-1"]; len(commits) != 1 || commits[0] != "c0ffee" {
+		t.Errorf("unexpected image metadata: %#v", metadata)
+	}
+
+	if err := s.RmImageMetadata(ctx, "myproject", "frontend/app", "c0ffee", "stage-1"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := s.IsImageMetadataExist(ctx, "myproject", "frontend/app", "c0ffee", "stage-1"); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Errorf("expected image metadata to be removed")
+	}
+
+	if err := s.PutImportMetadata(ctx, "myproject", &ImportMetadata{ImportSourceID: "source", SourceImageID: "sha256:abc", Checksum: "sum"}); err != nil {
+		t.Fatal(err)
+	}
+	if importMetadata, err := s.GetImportMetadata(ctx, "myproject", "source"); err != nil {
+		t.Fatal(err)
+	} else if importMetadata == nil || importMetadata.SourceImageID != "sha256:abc" || importMetadata.Checksum != "sum" {
+		t.Errorf("unexpected import metadata: %#v", importMetadata)
+	}
+	if importMetadata, err := s.GetImportMetadata(ctx, "myproject", "unknown"); err != nil {
+		t.Fatal(err)
+	} else if importMetadata != nil {
+		t.Errorf("expected no import metadata, got %#v", importMetadata)
+	}
+
+	if err := s.PostClientIDRecord(ctx, "myproject", &ClientIDRecord{ClientID: "a-b-c", TimestampMillisec: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := s.GetClientIDRecords(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if len(recs) != 1 || recs[0].ClientID != "a-b-c" || recs[0].TimestampMillisec != 42 {
+		t.Errorf("unexpected client id records: %v", recs)
+	}
+
+	if stageIDs, err := s.GetStagesIDs(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if len(stageIDs) != 0 {
+		t.Errorf("expected no stages, got %v", stageIDs)
+	}
+}
+
+// newFakeS3Handler implements the tiny subset of path-style S3 API used by the S3StagesStorage
+func newFakeS3Handler() http.Handler {
+	var mux sync.Mutex
+	objects := map[string][]byte{}
+
+	type listContent struct {
+		Key string `xml:"Key"`
+	}
+	type listResult struct {
+		XMLName     xml.Name      `xml:"ListBucketResult"`
+		IsTruncated bool          `xml:"IsTruncated"`
+		Contents    []listContent `xml:"Contents"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+		if len(parts) == 1 || parts[1] == "" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			res := listResult{}
+			var keys []string
+			for key := range objects {
+				if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				res.Contents = append(res.Contents, listContent{Key: key})
+			}
+
+			w.Header().Set("Content-Type", "application/xml")
+			_ = xml.NewEncoder(w).Encode(res)
+			return
+		}
+
+		key := parts[1]
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := ioutil.ReadAll(r.Body)
+			objects[key] = data
+		case http.MethodGet, http.MethodHead:
+			data, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				if r.Method == http.MethodGet {
+					_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+				}
+				return
+			}
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(data)
+			}
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
@@ -68,12 +68,33 @@ type ImageMetadata struct {
 
 type StagesStorageOptions struct {
 	RepoStagesStorageOptions
+	S3StagesStorageOptions
 }
 
 func NewStagesStorage(stagesStorageAddress string, containerRuntime container_runtime.ContainerRuntime, options StagesStorageOptions) (StagesStorage, error) {
 	if stagesStorageAddress == LocalStorageAddress {
 		return NewLocalDockerServerStagesStorage(containerRuntime.(*container_runtime.LocalDockerServerRuntime)), nil
+	} else if IsS3StorageAddress(stagesStorageAddress) {
+		return NewS3StagesStorage(stagesStorageAddress, containerRuntime, options.S3StagesStorageOptions)
+	} else if IsOCILayoutStorageAddress(stagesStorageAddress) {
+		return NewOCILayoutStagesStorage(stagesStorageAddress, containerRuntime)
 	} else { // Docker registry based stages storage
 		return NewRepoStagesStorage(stagesStorageAddress, containerRuntime, options.RepoStagesStorageOptions)
 	}
 }
+
+// CopyStageDirectly copies the stage between docker repos registry-to-registry, so the stage image never lands in the local docker server.
+// Returns false when direct copying is not supported by the stages storages and the stage should be fetched and stored through the container runtime.
+func CopyStageDirectly(ctx context.Context, projectName string, stageDescription *image.StageDescription, fromStagesStorage, toStagesStorage StagesStorage) (bool, error) {
+	fromRepoStagesStorage, isFromRepo := fromStagesStorage.(*RepoStagesStorage)
+	toRepoStagesStorage, isToRepo := toStagesStorage.(*RepoStagesStorage)
+	if !isFromRepo || !isToRepo {
+		return false, nil
+	}
+
+	if err := fromRepoStagesStorage.CopyStage(ctx, projectName, stageDescription, toRepoStagesStorage); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
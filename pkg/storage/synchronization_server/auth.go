@@ -0,0 +1,132 @@
+package synchronization_server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// clientIDProjectSeparator separates project name from the random part of the clientID issued by the server with enabled authorization: PROJECT_NAME.UUID
+const clientIDProjectSeparator = "."
+
+// AuthConfig describes principals which are allowed to access the synchronization server and projects available for each principal.
+// Projects are specified as shell patterns, "*" allows all projects including clientIDs issued before authorization has been enabled.
+//
+//	tokens:
+//	- token: TOKEN
+//	  projects: [myproject, team-a-*]
+//	clientCertificates:
+//	- commonName: ci.team-b.example.com
+//	  projects: [team-b-*]
+type AuthConfig struct {
+	Tokens             []TokenAuthConfig             `json:"tokens"`
+	ClientCertificates []ClientCertificateAuthConfig `json:"clientCertificates"`
+}
+
+type TokenAuthConfig struct {
+	Token    string   `json:"token"`
+	Projects []string `json:"projects"`
+}
+
+type ClientCertificateAuthConfig struct {
+	CommonName string   `json:"commonName"`
+	Projects   []string `json:"projects"`
+}
+
+func LoadAuthConfig(path string) (*AuthConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read auth config %s: %s", path, err)
+	}
+
+	config := &AuthConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("unable to parse auth config %s: %s", path, err)
+	}
+
+	for i, tokenConfig := range config.Tokens {
+		if tokenConfig.Token == "" {
+			return nil, fmt.Errorf("bad auth config %s: empty token in tokens[%d]", path, i)
+		}
+	}
+	for i, certConfig := range config.ClientCertificates {
+		if certConfig.CommonName == "" {
+			return nil, fmt.Errorf("bad auth config %s: empty commonName in clientCertificates[%d]", path, i)
+		}
+	}
+
+	return config, nil
+}
+
+// Authorizer authenticates requests by the bearer token or verified client certificate and checks access to the projects.
+// When Config is nil, any client with verified certificate gets access to all projects.
+type Authorizer struct {
+	Config *AuthConfig
+}
+
+// GetAllowedProjects returns project patterns available for the request principal.
+// The bearer token is ignored when no tokens are configured, so such requests are authenticated by the client certificate.
+func (authorizer *Authorizer) GetAllowedProjects(r *http.Request) ([]string, error) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" && authorizer.Config != nil && len(authorizer.Config.Tokens) > 0 {
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return nil, fmt.Errorf("unsupported authorization scheme")
+		}
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+
+		for _, tokenConfig := range authorizer.Config.Tokens {
+			if subtle.ConstantTimeCompare([]byte(tokenConfig.Token), []byte(token)) == 1 {
+				return tokenConfig.Projects, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		if authorizer.Config == nil {
+			return []string{"*"}, nil
+		}
+
+		commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, certConfig := range authorizer.Config.ClientCertificates {
+			if certConfig.CommonName == commonName {
+				return certConfig.Projects, nil
+			}
+		}
+		return nil, fmt.Errorf("client certificate %q is not allowed", commonName)
+	}
+
+	return nil, fmt.Errorf("bearer token or client certificate required")
+}
+
+func IsProjectAllowed(allowedProjects []string, projectName string) bool {
+	for _, pattern := range allowedProjects {
+		if pattern == "*" {
+			return true
+		}
+		if projectName == "" {
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, projectName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func newClientIDWithProject(projectName, id string) string {
+	return fmt.Sprintf("%s%s%s", projectName, clientIDProjectSeparator, id)
+}
+
+// getClientIDProject returns an empty string for clientIDs issued without authorization
+func getClientIDProject(clientID string) string {
+	parts := strings.SplitN(clientID, clientIDProjectSeparator, 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[0]
+}
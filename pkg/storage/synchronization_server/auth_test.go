@@ -0,0 +1,217 @@
+package synchronization_server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+	"github.com/werf/lockgate/pkg/distributed_locker/optimistic_locking_store"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+func newTestAuthServer() *httptest.Server {
+	handler := NewSynchronizationServerHandler(
+		func(clientID string) (distributed_locker.DistributedLockerBackend, error) {
+			return distributed_locker.NewOptimisticLockingStorageBasedBackend(optimistic_locking_store.NewInMemoryStore()), nil
+		},
+		func(clientID string) (storage.StagesStorageCache, error) {
+			return storage.NewFileStagesStorageCache("/nonexistent"), nil
+		},
+	)
+	handler.Authorizer = &Authorizer{Config: &AuthConfig{
+		Tokens: []TokenAuthConfig{
+			{Token: "team-a-token", Projects: []string{"team-a-*"}},
+			{Token: "admin-token", Projects: []string{"*"}},
+		},
+	}}
+	return httptest.NewServer(handler)
+}
+
+func newTestClient(t *testing.T, token string) *SynchronizationClient {
+	httpClient, err := NewHttpClient(SynchronizationClientOptions{Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSynchronizationClient("", httpClient)
+}
+
+func TestSynchronizationServer_ProjectAuthorization(t *testing.T) {
+	srv := newTestAuthServer()
+	defer srv.Close()
+
+	teamA := newTestClient(t, "team-a-token")
+	teamA.URL = srv.URL
+
+	clientID, err := teamA.NewClientID("team-a-backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(clientID, "team-a-backend.") {
+		t.Errorf("expected clientID bound to the project, got %q", clientID)
+	}
+
+	if _, err := teamA.NewClientID("team-b-backend"); err == nil {
+		t.Errorf("expected forbidden project error")
+	}
+
+	anonymous := newTestClient(t, "")
+	anonymous.URL = srv.URL
+	if _, err := anonymous.NewClientID("team-a-backend"); err == nil {
+		t.Errorf("expected unauthorized error")
+	}
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		token    string
+		clientID string
+		allowed  bool
+	}{
+		{"team-a-token", clientID, true},
+		{"team-a-token", "team-b-backend.uuid", false},
+		{"team-a-token", "legacy-uuid", false},
+		{"admin-token", "legacy-uuid", true},
+		{"wrong-token", clientID, false},
+	} {
+		httpClient, _ := NewHttpClient(SynchronizationClientOptions{Token: tc.token})
+		cache := NewStagesStorageCacheHttpClient(srv.URL+"/"+tc.clientID+"/stages-storage-cache", httpClient)
+
+		err := cache.DeleteStagesByDigest(ctx, "team-a-backend", "digest")
+		if tc.allowed && err != nil && strings.Contains(err.Error(), "bad response") {
+			t.Errorf("token %q clientID %q: expected access, got %s", tc.token, tc.clientID, err)
+		} else if !tc.allowed && err == nil {
+			t.Errorf("token %q clientID %q: expected access to be denied", tc.token, tc.clientID)
+		}
+	}
+}
+
+func TestIsProjectAllowed(t *testing.T) {
+	if !IsProjectAllowed([]string{"team-a-*"}, "team-a-web") {
+		t.Errorf("expected pattern to match")
+	}
+	if IsProjectAllowed([]string{"team-a-*"}, "") {
+		t.Errorf("expected legacy clientID to be denied for the project pattern")
+	}
+	if !IsProjectAllowed([]string{"*"}, "") {
+		t.Errorf("expected legacy clientID to be allowed for *")
+	}
+}
+
+func TestValidateSynchronizationServerOptions(t *testing.T) {
+	authConfig := &AuthConfig{ClientCertificates: []ClientCertificateAuthConfig{{CommonName: "ci.example.com", Projects: []string{"*"}}}}
+
+	if err := validateSynchronizationServerOptions(SynchronizationServerOptions{AuthConfig: authConfig}); err == nil {
+		t.Errorf("expected clientCertificates without client CA to be rejected")
+	}
+
+	if err := validateSynchronizationServerOptions(SynchronizationServerOptions{TLSCertFile: "tls.crt", TLSKeyFile: "tls.key", ClientCAFile: "ca.crt", AuthConfig: authConfig}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := validateSynchronizationServerOptions(SynchronizationServerOptions{AuthConfig: &AuthConfig{Tokens: []TokenAuthConfig{{Token: "token", Projects: []string{"*"}}}}}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestAuthorizer_GetAllowedProjectsWithoutTokens(t *testing.T) {
+	newRequest := func(commonName string) *http.Request {
+		r := httptest.NewRequest("GET", "/new-client-id", nil)
+		r.Header.Set("Authorization", "Bearer some-token")
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}}}
+		return r
+	}
+
+	// mTLS only mode
+	if projects, err := (&Authorizer{}).GetAllowedProjects(newRequest("ci.example.com")); err != nil {
+		t.Errorf("expected bearer token to be ignored without configured tokens, got %s", err)
+	} else if !reflect.DeepEqual(projects, []string{"*"}) {
+		t.Errorf("expected access to all projects, got %v", projects)
+	}
+
+	authorizer := &Authorizer{Config: &AuthConfig{ClientCertificates: []ClientCertificateAuthConfig{{CommonName: "ci.team-b.example.com", Projects: []string{"team-b-*"}}}}}
+	if projects, err := authorizer.GetAllowedProjects(newRequest("ci.team-b.example.com")); err != nil {
+		t.Errorf("expected bearer token to be ignored without configured tokens, got %s", err)
+	} else if !reflect.DeepEqual(projects, []string{"team-b-*"}) {
+		t.Errorf("expected client certificate projects, got %v", projects)
+	}
+
+	if _, err := authorizer.GetAllowedProjects(newRequest("unknown.example.com")); err == nil {
+		t.Errorf("expected unknown client certificate to be denied")
+	}
+}
+
+type clientIDTestStagesStorage struct {
+	storage.StagesStorage
+	records []*storage.ClientIDRecord
+}
+
+func (s *clientIDTestStagesStorage) String() string {
+	return "test-repo"
+}
+
+func (s *clientIDTestStagesStorage) GetClientIDRecords(_ context.Context, _ string) ([]*storage.ClientIDRecord, error) {
+	return s.records, nil
+}
+
+func (s *clientIDTestStagesStorage) PostClientIDRecord(_ context.Context, _ string, rec *storage.ClientIDRecord) error {
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func TestGetOrCreateClientID(t *testing.T) {
+	defer func(timeout time.Duration) { clientIDCollisionTimeout = timeout }(clientIDCollisionTimeout)
+	clientIDCollisionTimeout = 0
+
+	srv := newTestAuthServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	stagesStorage := &clientIDTestStagesStorage{records: []*storage.ClientIDRecord{{ClientID: "legacy-uuid", TimestampMillisec: 1}}}
+
+	// the legacy clientID is not allowed for the project token, so new clientID bound to the project is created
+	teamA := newTestClient(t, "team-a-token")
+	teamA.URL = srv.URL
+	teamA.Authenticated = true
+
+	clientID, err := GetOrCreateClientID(ctx, "team-a-backend", teamA, stagesStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(clientID, "team-a-backend.") {
+		t.Fatalf("expected clientID bound to the project, got %q", clientID)
+	}
+
+	if sameClientID, err := GetOrCreateClientID(ctx, "team-a-backend", teamA, stagesStorage); err != nil {
+		t.Fatal(err)
+	} else if sameClientID != clientID || len(stagesStorage.records) != 2 {
+		t.Errorf("expected existing clientID %q to be selected, got %q (%d records)", clientID, sameClientID, len(stagesStorage.records))
+	}
+
+	// not authenticated client keeps using the oldest clientID
+	anonymous := newTestClient(t, "")
+	if legacyClientID, err := GetOrCreateClientID(ctx, "team-a-backend", anonymous, stagesStorage); err != nil {
+		t.Fatal(err)
+	} else if legacyClientID != "legacy-uuid" {
+		t.Errorf("expected legacy clientID, got %q", legacyClientID)
+	}
+
+	// server without authorization issues clientIDs which are not bound to the project, so the legacy clientID is used
+	noAuthSrv := httptest.NewServer(NewSynchronizationServerHandler(nil, nil))
+	defer noAuthSrv.Close()
+
+	teamA.URL = noAuthSrv.URL
+	stagesStorage.records = []*storage.ClientIDRecord{{ClientID: "legacy-uuid", TimestampMillisec: 1}}
+	if legacyClientID, err := GetOrCreateClientID(ctx, "team-a-backend", teamA, stagesStorage); err != nil {
+		t.Fatal(err)
+	} else if legacyClientID != "legacy-uuid" || len(stagesStorage.records) != 1 {
+		t.Errorf("expected legacy clientID without new records, got %q (%d records)", legacyClientID, len(stagesStorage.records))
+	}
+}
@@ -0,0 +1,271 @@
+package synchronization_server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+	"github.com/werf/lockgate/pkg/distributed_locker/optimistic_locking_store"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+const (
+	boltStagesStorageCacheBucket = "stages-storage-cache"
+	boltLockerBucket             = "locker"
+)
+
+// PersistentBackend keeps synchronization server state between restarts
+type PersistentBackend interface {
+	NewDistributedLockerBackend(clientID string) (distributed_locker.DistributedLockerBackend, error)
+	NewStagesStorageCache(clientID string) (storage.StagesStorageCache, error)
+
+	// Snapshot makes consistent copy of the backend state, which will be used on the next start if the main state is lost
+	Snapshot(ctx context.Context) error
+	Close() error
+}
+
+type BoltBackendOptions struct {
+	// SnapshotPath defaults to the PATH.snapshot
+	SnapshotPath string
+	// OpenTimeout is a time to wait until the previous server instance releases the database during restart
+	OpenTimeout time.Duration
+}
+
+type BoltBackend struct {
+	DB           *bolt.DB
+	SnapshotPath string
+
+	mux           sync.Mutex
+	lockersStores map[string]*boltOptimisticLockingStore
+}
+
+func NewBoltBackend(ctx context.Context, path string, opts BoltBackendOptions) (*BoltBackend, error) {
+	snapshotPath := opts.SnapshotPath
+	if snapshotPath == "" {
+		snapshotPath = path + ".snapshot"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create dir %s: %s", filepath.Dir(path), err)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(snapshotPath); err == nil {
+			logboek.Context(ctx).Default().LogF("Restoring synchronization server database %s from snapshot %s\n", path, snapshotPath)
+			if err := copyFile(snapshotPath, path); err != nil {
+				return nil, fmt.Errorf("unable to restore %s from snapshot %s: %s", path, snapshotPath, err)
+			}
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error accessing %s: %s", path, err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: opts.OpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open bolt database %s: %s", path, err)
+	}
+
+	return &BoltBackend{
+		DB:            db,
+		SnapshotPath:  snapshotPath,
+		lockersStores: make(map[string]*boltOptimisticLockingStore),
+	}, nil
+}
+
+func (backend *BoltBackend) NewDistributedLockerBackend(clientID string) (distributed_locker.DistributedLockerBackend, error) {
+	backend.mux.Lock()
+	defer backend.mux.Unlock()
+
+	store, hasKey := backend.lockersStores[clientID]
+	if !hasKey {
+		var err error
+		if store, err = newBoltOptimisticLockingStore(backend.DB, boltLockerBucket, clientID); err != nil {
+			return nil, err
+		}
+		backend.lockersStores[clientID] = store
+	}
+
+	return distributed_locker.NewOptimisticLockingStorageBasedBackend(store), nil
+}
+
+func (backend *BoltBackend) NewStagesStorageCache(clientID string) (storage.StagesStorageCache, error) {
+	return storage.NewBoltStagesStorageCache(backend.DB, boltStagesStorageCacheBucket, clientID), nil
+}
+
+func (backend *BoltBackend) Snapshot(ctx context.Context) error {
+	tmpPath := backend.SnapshotPath + ".tmp"
+
+	if err := backend.DB.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(tmpPath, 0600)
+	}); err != nil {
+		return fmt.Errorf("unable to write snapshot %s: %s", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, backend.SnapshotPath); err != nil {
+		return fmt.Errorf("unable to rename %s to %s: %s", tmpPath, backend.SnapshotPath, err)
+	}
+
+	logboek.Context(ctx).Debug().LogF("BoltBackend -- Snapshot %s saved\n", backend.SnapshotPath)
+	return nil
+}
+
+// RunPeriodicSnapshots saves snapshot every interval until ctx is done
+func (backend *BoltBackend) RunPeriodicSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := backend.Snapshot(ctx); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: %s\n", err)
+			}
+		}
+	}
+}
+
+func (backend *BoltBackend) Close() error {
+	return backend.DB.Close()
+}
+
+// boltOptimisticLockingStore keeps versions of records in memory and persists records data into the bolt database.
+// Versions are not persisted: after restart all records are loaded with the initial version.
+type boltOptimisticLockingStore struct {
+	mux         sync.Mutex
+	memoryStore *optimistic_locking_store.InMemoryStore
+
+	db         *bolt.DB
+	bucketPath []string
+}
+
+func newBoltOptimisticLockingStore(db *bolt.DB, bucketPath ...string) (*boltOptimisticLockingStore, error) {
+	store := &boltOptimisticLockingStore{
+		memoryStore: optimistic_locking_store.NewInMemoryStore(),
+		db:          db,
+		bucketPath:  bucketPath,
+	}
+
+	if err := db.View(func(tx *bolt.Tx) error {
+		bucket := getBoltBucket(tx, bucketPath)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(key, data []byte) error {
+			value, err := store.memoryStore.GetValue(string(key))
+			if err != nil {
+				return err
+			}
+			value.Data = string(data)
+			return store.memoryStore.PutValue(string(key), value)
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("unable to load locker records from %s: %s", db.Path(), err)
+	}
+
+	return store, nil
+}
+
+// GetValue returns a copy of the record, so the in-memory record is changed only by PutValue
+func (store *boltOptimisticLockingStore) GetValue(key string) (*optimistic_locking_store.Value, error) {
+	value, err := store.memoryStore.GetValue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	valueCopy := *value
+	return &valueCopy, nil
+}
+
+// PutValue checks the record version, writes the record to the database and only then updates the in-memory record,
+// so the in-memory records do not diverge from the database records if the write fails
+func (store *boltOptimisticLockingStore) PutValue(key string, value *optimistic_locking_store.Value) error {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	validationStore := optimistic_locking_store.NewInMemoryStore()
+	store.memoryStore.Mux.Lock()
+	if existingValue, hasKey := store.memoryStore.Values[key]; hasKey {
+		validationStore.Values[key] = existingValue
+	}
+	store.memoryStore.Mux.Unlock()
+
+	if err := validationStore.PutValue(key, value); err != nil {
+		return err
+	}
+
+	if err := store.putDBValue(key, value); err != nil {
+		return err
+	}
+
+	return store.memoryStore.PutValue(key, value)
+}
+
+func (store *boltOptimisticLockingStore) putDBValue(key string, value *optimistic_locking_store.Value) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		var bucket *bolt.Bucket
+		var err error
+		for i, name := range store.bucketPath {
+			if i == 0 {
+				bucket, err = tx.CreateBucketIfNotExists([]byte(name))
+			} else {
+				bucket, err = bucket.CreateBucketIfNotExists([]byte(name))
+			}
+			if err != nil {
+				return fmt.Errorf("unable to create bucket %q: %s", name, err)
+			}
+		}
+
+		if value.Data == "" {
+			return bucket.Delete([]byte(key))
+		}
+		return bucket.Put([]byte(key), []byte(value.Data))
+	})
+}
+
+func getBoltBucket(tx *bolt.Tx, bucketPath []string) *bolt.Bucket {
+	var bucket *bolt.Bucket
+	for i, name := range bucketPath {
+		if i == 0 {
+			bucket = tx.Bucket([]byte(name))
+		} else {
+			bucket = bucket.Bucket([]byte(name))
+		}
+
+		if bucket == nil {
+			return nil
+		}
+	}
+	return bucket
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
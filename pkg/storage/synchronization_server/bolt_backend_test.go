@@ -0,0 +1,137 @@
+package synchronization_server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/lockgate/pkg/distributed_locker/optimistic_locking_store"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+func TestBoltBackend_RestoreAfterRestart(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-bolt-backend-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx := context.Background()
+	dbPath := filepath.Join(tmpDir, "synchronization.db")
+
+	backend, err := NewBoltBackend(ctx, dbPath, BoltBackendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, _ := backend.NewStagesStorageCache("client-1")
+	if err := cache.StoreStagesByDigest(ctx, "myproject", "digest", []image.StageID{{Digest: "digest", UniqueID: 42}}); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := newBoltOptimisticLockingStore(backend.DB, boltLockerBucket, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := store.GetValue("lock")
+	value.Data = "lease"
+	if err := store.PutValue("lock", value); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backend.Snapshot(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// main database is lost, state should be restored from the snapshot
+	if err := os.Remove(dbPath); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err = NewBoltBackend(ctx, dbPath, BoltBackendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	cache, _ = backend.NewStagesStorageCache("client-1")
+	if found, stages, err := cache.GetStagesByDigest(ctx, "myproject", "digest"); err != nil {
+		t.Fatal(err)
+	} else if !found || len(stages) != 1 || stages[0].UniqueID != 42 {
+		t.Errorf("unexpected stages storage cache record: found=%v stages=%v", found, stages)
+	}
+
+	if found, _, err := cache.GetAllStages(ctx, "otherproject"); err != nil {
+		t.Fatal(err)
+	} else if found {
+		t.Errorf("unexpected stages storage cache record for other project")
+	}
+
+	store, err = newBoltOptimisticLockingStore(backend.DB, boltLockerBucket, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.GetValue("lock"); err != nil {
+		t.Fatal(err)
+	} else if value.Data != "lease" {
+		t.Errorf("expected restored locker record, got %q", value.Data)
+	}
+}
+
+func TestBoltOptimisticLockingStore_PutValue(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-bolt-backend-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "synchronization.db")
+
+	db, err := bolt.Open(dbPath, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the write fails on the read-only database
+	db, err = bolt.Open(dbPath, 0644, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := newBoltOptimisticLockingStore(db, boltLockerBucket, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, _ := store.GetValue("lock")
+	value.Data = "lease"
+	if err := store.PutValue("lock", value); err == nil {
+		t.Fatal("expected database write error")
+	}
+
+	if value, _ := store.GetValue("lock"); value.Data != "" {
+		t.Errorf("expected in-memory record not to be changed after failed write, got %q", value.Data)
+	}
+
+	// the stale record version is rejected before the write
+	staleValue, _ := store.GetValue("lock")
+	currentValue, _ := store.GetValue("lock")
+	if err := store.memoryStore.PutValue("lock", currentValue); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.PutValue("lock", staleValue); err != optimistic_locking_store.ErrRecordVersionChanged {
+		t.Errorf("expected record version changed error, got %v", err)
+	}
+}
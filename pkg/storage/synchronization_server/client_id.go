@@ -9,45 +9,72 @@ import (
 	"github.com/werf/werf/pkg/storage"
 )
 
+// clientIDCollisionTimeout is the time between posting new clientID and getting the current one
+var clientIDCollisionTimeout = 2 * time.Second
+
+// GetOrCreateClientID selects the oldest clientID of the project from the stages storage or creates new one.
+// Authenticated client selects only the clientIDs bound to the project, because the clientIDs issued before the authorization has been enabled
+// are allowed only for the principals with access to all projects.
 func GetOrCreateClientID(ctx context.Context, projectName string, synchronizationClient *SynchronizationClient, stagesStorage storage.StagesStorage) (string, error) {
-	if clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName); err != nil {
+	var clientIDProject string
+	if synchronizationClient.Authenticated {
+		clientIDProject = projectName
+	}
+
+	clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName)
+	if err != nil {
 		return "", err
-	} else if len(clientIDRecords) > 0 {
-		res := selectOldestClientIDRecord(clientIDRecords)
+	}
+
+	if res := selectOldestClientIDRecord(clientIDRecords, clientIDProject); res != nil {
 		logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s selected clientID: %s\n", projectName, res.String())
 		return res.ClientID, nil
-	} else {
-		newClientID, err := synchronizationClient.NewClientID()
-		if err != nil {
-			return "", err
-		}
-
-		now := time.Now()
-		timestampMillisec := now.Unix()*1000 + now.UnixNano()/1000_000
-		rec := &storage.ClientIDRecord{ClientID: newClientID, TimestampMillisec: timestampMillisec}
+	}
 
-		if err := stagesStorage.PostClientIDRecord(ctx, projectName, rec); err != nil {
-			return "", err
-		}
+	newClientID, err := synchronizationClient.NewClientID(projectName)
+	if err != nil {
+		return "", err
+	}
 
-		// wait between posting new id and getting current id to lower probability of collision with another process posting new client-id
-		time.Sleep(2 * time.Second)
+	// server without authorization issues clientIDs which are not bound to the project
+	if clientIDProject != "" && getClientIDProject(newClientID) != clientIDProject {
+		clientIDProject = ""
 
-		if clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName); err != nil {
-			return "", err
-		} else if len(clientIDRecords) > 0 {
-			res := selectOldestClientIDRecord(clientIDRecords)
+		if res := selectOldestClientIDRecord(clientIDRecords, clientIDProject); res != nil {
 			logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s selected clientID: %s\n", projectName, res.String())
 			return res.ClientID, nil
-		} else {
-			return "", fmt.Errorf("could not find clientID in storage %s after successful creation", stagesStorage.String())
 		}
 	}
+
+	now := time.Now()
+	timestampMillisec := now.Unix()*1000 + now.UnixNano()/1000_000
+	rec := &storage.ClientIDRecord{ClientID: newClientID, TimestampMillisec: timestampMillisec}
+
+	if err := stagesStorage.PostClientIDRecord(ctx, projectName, rec); err != nil {
+		return "", err
+	}
+
+	// wait between posting new id and getting current id to lower probability of collision with another process posting new client-id
+	time.Sleep(clientIDCollisionTimeout)
+
+	if clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName); err != nil {
+		return "", err
+	} else if res := selectOldestClientIDRecord(clientIDRecords, clientIDProject); res != nil {
+		logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s selected clientID: %s\n", projectName, res.String())
+		return res.ClientID, nil
+	} else {
+		return "", fmt.Errorf("could not find clientID in storage %s after successful creation", stagesStorage.String())
+	}
 }
 
-func selectOldestClientIDRecord(records []*storage.ClientIDRecord) *storage.ClientIDRecord {
+// selectOldestClientIDRecord selects the oldest record among the records bound to the project, any record is selected when the project is not specified
+func selectOldestClientIDRecord(records []*storage.ClientIDRecord, projectName string) *storage.ClientIDRecord {
 	var foundRec *storage.ClientIDRecord
 	for _, rec := range records {
+		if projectName != "" && getClientIDProject(rec.ClientID) != projectName {
+			continue
+		}
+
 		if foundRec == nil || rec.TimestampMillisec < foundRec.TimestampMillisec {
 			foundRec = rec
 		}
@@ -0,0 +1,241 @@
+package synchronization_server
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/lockgate/pkg/distributed_locker"
+)
+
+const (
+	// lock lease expires when the holder does not renew it during this period
+	trackedLockLeaseTTL = distributed_locker.DistributedLockLeaseTTLSeconds * time.Second
+	// client is considered active when it has made requests during this period
+	activeClientPeriod = 5 * time.Minute
+)
+
+type LockHolder struct {
+	ClientID      string    `json:"clientID"`
+	Project       string    `json:"project"`
+	LockName      string    `json:"lockName"`
+	LockUUID      string    `json:"lockUUID"`
+	Shared        bool      `json:"shared"`
+	Holders       []string  `json:"holders"`
+	AcquiredAt    time.Time `json:"acquiredAt"`
+	LastRenewedAt time.Time `json:"lastRenewedAt"`
+}
+
+type LockWaiter struct {
+	ClientID      string    `json:"clientID"`
+	Project       string    `json:"project"`
+	LockName      string    `json:"lockName"`
+	Waiter        string    `json:"waiter"`
+	WaitingSince  time.Time `json:"waitingSince"`
+	LastAttemptAt time.Time `json:"lastAttemptAt"`
+	Attempts      int       `json:"attempts"`
+}
+
+type LocksState struct {
+	Locks   []*LockHolder `json:"locks"`
+	Waiters []*LockWaiter `json:"waiters"`
+}
+
+// LocksTracker records lock holders and waiters observed by the synchronization server.
+// Lockgate http clients poll the server while waiting for the lock, so the waiter is tracked from the first unsuccessful attempt.
+type LocksTracker struct {
+	mux             sync.Mutex
+	locks           map[string]*LockHolder
+	waiters         map[string]*LockWaiter
+	clientsLastSeen map[string]time.Time
+}
+
+func NewLocksTracker() *LocksTracker {
+	return &LocksTracker{
+		locks:           make(map[string]*LockHolder),
+		waiters:         make(map[string]*LockWaiter),
+		clientsLastSeen: make(map[string]time.Time),
+	}
+}
+
+func (tracker *LocksTracker) ClientSeen(clientID string) {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	tracker.clientsLastSeen[clientID] = time.Now()
+}
+
+func (tracker *LocksTracker) ActiveClientsCount() int {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	var res int
+	for clientID, lastSeen := range tracker.clientsLastSeen {
+		if time.Since(lastSeen) > activeClientPeriod {
+			delete(tracker.clientsLastSeen, clientID)
+			continue
+		}
+		res++
+	}
+	return res
+}
+
+func (tracker *LocksTracker) LockWaiting(clientID, lockName, waiter string) {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	now := time.Now()
+	key := lockWaiterKey(clientID, lockName, waiter)
+
+	w, hasKey := tracker.waiters[key]
+	if !hasKey || now.Sub(w.LastAttemptAt) > trackedLockLeaseTTL {
+		w = &LockWaiter{
+			ClientID:     clientID,
+			Project:      getLockProject(clientID, lockName),
+			LockName:     lockName,
+			Waiter:       waiter,
+			WaitingSince: now,
+		}
+		tracker.waiters[key] = w
+	}
+
+	w.LastAttemptAt = now
+	w.Attempts++
+}
+
+// LockAcquired returns time spent by the waiter since the first unsuccessful attempt
+func (tracker *LocksTracker) LockAcquired(clientID string, handle lockgate.LockHandle, shared bool, waiter string) time.Duration {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	now := time.Now()
+
+	var waitDuration time.Duration
+	waiterKey := lockWaiterKey(clientID, handle.LockName, waiter)
+	if w, hasKey := tracker.waiters[waiterKey]; hasKey {
+		if now.Sub(w.LastAttemptAt) <= trackedLockLeaseTTL {
+			waitDuration = now.Sub(w.WaitingSince)
+		}
+		delete(tracker.waiters, waiterKey)
+	}
+
+	key := lockHolderKey(clientID, handle.UUID)
+	if holder, hasKey := tracker.locks[key]; hasKey && shared {
+		holder.Holders = append(holder.Holders, waiter)
+		holder.LastRenewedAt = now
+	} else {
+		tracker.locks[key] = &LockHolder{
+			ClientID:      clientID,
+			Project:       getLockProject(clientID, handle.LockName),
+			LockName:      handle.LockName,
+			LockUUID:      handle.UUID,
+			Shared:        shared,
+			Holders:       []string{waiter},
+			AcquiredAt:    now,
+			LastRenewedAt: now,
+		}
+	}
+
+	return waitDuration
+}
+
+func (tracker *LocksTracker) LockRenewed(clientID string, handle lockgate.LockHandle) {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	if holder, hasKey := tracker.locks[lockHolderKey(clientID, handle.UUID)]; hasKey {
+		holder.LastRenewedAt = time.Now()
+	}
+}
+
+func (tracker *LocksTracker) LockReleased(clientID string, handle lockgate.LockHandle, waiter string) {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	key := lockHolderKey(clientID, handle.UUID)
+	holder, hasKey := tracker.locks[key]
+	if !hasKey {
+		return
+	}
+
+	for i, h := range holder.Holders {
+		if h == waiter {
+			holder.Holders = append(holder.Holders[:i], holder.Holders[i+1:]...)
+			break
+		}
+	}
+
+	if !holder.Shared || len(holder.Holders) == 0 {
+		delete(tracker.locks, key)
+	}
+}
+
+// State returns current lock holders and waiters sorted by project and lock name, expired records are dropped
+func (tracker *LocksTracker) State() *LocksState {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	now := time.Now()
+	res := &LocksState{Locks: []*LockHolder{}, Waiters: []*LockWaiter{}}
+
+	for key, holder := range tracker.locks {
+		if now.Sub(holder.LastRenewedAt) > trackedLockLeaseTTL {
+			delete(tracker.locks, key)
+			continue
+		}
+
+		h := *holder
+		h.Holders = append([]string{}, holder.Holders...)
+		res.Locks = append(res.Locks, &h)
+	}
+
+	for key, waiter := range tracker.waiters {
+		if now.Sub(waiter.LastAttemptAt) > trackedLockLeaseTTL {
+			delete(tracker.waiters, key)
+			continue
+		}
+
+		w := *waiter
+		res.Waiters = append(res.Waiters, &w)
+	}
+
+	sort.Slice(res.Locks, func(i, j int) bool {
+		return lockSortKey(res.Locks[i].Project, res.Locks[i].LockName, res.Locks[i].ClientID) < lockSortKey(res.Locks[j].Project, res.Locks[j].LockName, res.Locks[j].ClientID)
+	})
+	sort.Slice(res.Waiters, func(i, j int) bool {
+		return lockSortKey(res.Waiters[i].Project, res.Waiters[i].LockName, res.Waiters[i].ClientID) < lockSortKey(res.Waiters[j].Project, res.Waiters[j].LockName, res.Waiters[j].ClientID)
+	})
+
+	return res
+}
+
+// getLockProject uses project of the clientID issued with enabled authorization,
+// otherwise project is taken from the lock name created by the storage.GenericLockManager: PROJECT_NAME.LOCK_ID
+func getLockProject(clientID, lockName string) string {
+	if project := getClientIDProject(clientID); project != "" {
+		return project
+	}
+	return strings.SplitN(lockName, ".", 2)[0]
+}
+
+func getRequestWaiter(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+func lockHolderKey(clientID, uuid string) string {
+	return clientID + "/" + uuid
+}
+
+func lockWaiterKey(clientID, lockName, waiter string) string {
+	return clientID + "/" + lockName + "/" + waiter
+}
+
+func lockSortKey(project, lockName, clientID string) string {
+	return project + "\x00" + lockName + "\x00" + clientID
+}
@@ -0,0 +1,209 @@
+package synchronization_server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/lockgate/pkg/distributed_locker"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+const metricsNamespace = "werf_synchronization_server"
+
+type Metrics struct {
+	Registry     *prometheus.Registry
+	LocksTracker *LocksTracker
+
+	LockAcquireDuration          *prometheus.HistogramVec
+	LockContentions              *prometheus.CounterVec
+	StagesStorageCacheLookups    *prometheus.CounterVec
+	StagesStorageCacheOperations *prometheus.CounterVec
+}
+
+func NewMetrics(locksTracker *LocksTracker) *Metrics {
+	metrics := &Metrics{
+		Registry:     prometheus.NewRegistry(),
+		LocksTracker: locksTracker,
+
+		LockAcquireDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "lock_acquire_duration_seconds",
+			Help:      "Time between the first acquire attempt and successful lock acquisition.",
+			Buckets:   []float64{0.1, 1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+		}, []string{"project", "shared"}),
+		LockContentions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "lock_contentions_total",
+			Help:      "Number of acquire attempts rejected because the lock is held by another holder.",
+		}, []string{"project"}),
+		StagesStorageCacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "stages_storage_cache_lookups_total",
+			Help:      "Number of stages storage cache lookups by result: hit, miss or error.",
+		}, []string{"project", "operation", "result"}),
+		StagesStorageCacheOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "stages_storage_cache_operations_total",
+			Help:      "Number of stages storage cache modifications by result: ok or error.",
+		}, []string{"project", "operation", "result"}),
+	}
+
+	metrics.Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		metrics.LockAcquireDuration,
+		metrics.LockContentions,
+		metrics.StagesStorageCacheLookups,
+		metrics.StagesStorageCacheOperations,
+		&locksTrackerCollector{LocksTracker: locksTracker},
+	)
+
+	return metrics
+}
+
+func (metrics *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+}
+
+var (
+	activeClientsDesc = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "active_clients"), "Number of clientIDs which have made requests during the last 5 minutes.", nil, nil)
+	heldLocksDesc     = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "held_locks"), "Number of currently held locks.", []string{"project"}, nil)
+	lockWaitersDesc   = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "lock_waiters"), "Number of holders currently waiting for the lock.", []string{"project"}, nil)
+)
+
+// locksTrackerCollector exposes current state of the LocksTracker on each scrape
+type locksTrackerCollector struct {
+	LocksTracker *LocksTracker
+}
+
+func (collector *locksTrackerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeClientsDesc
+	ch <- heldLocksDesc
+	ch <- lockWaitersDesc
+}
+
+func (collector *locksTrackerCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(activeClientsDesc, prometheus.GaugeValue, float64(collector.LocksTracker.ActiveClientsCount()))
+
+	state := collector.LocksTracker.State()
+
+	heldLocks := make(map[string]int)
+	for _, lock := range state.Locks {
+		heldLocks[lock.Project]++
+	}
+	for project, count := range heldLocks {
+		ch <- prometheus.MustNewConstMetric(heldLocksDesc, prometheus.GaugeValue, float64(count), project)
+	}
+
+	lockWaiters := make(map[string]int)
+	for _, waiter := range state.Waiters {
+		lockWaiters[waiter.Project]++
+	}
+	for project, count := range lockWaiters {
+		ch <- prometheus.MustNewConstMetric(lockWaitersDesc, prometheus.GaugeValue, float64(count), project)
+	}
+}
+
+// instrumentedDistributedLockerBackend is created for each request to know the requester address
+type instrumentedDistributedLockerBackend struct {
+	distributed_locker.DistributedLockerBackend
+
+	ClientID string
+	Waiter   string
+	Metrics  *Metrics
+}
+
+func (backend *instrumentedDistributedLockerBackend) Acquire(lockName string, opts distributed_locker.AcquireOptions) (lockgate.LockHandle, error) {
+	handle, err := backend.DistributedLockerBackend.Acquire(lockName, opts)
+
+	switch {
+	case distributed_locker.IsErrShouldWait(err):
+		backend.Metrics.LockContentions.WithLabelValues(getLockProject(backend.ClientID, lockName)).Inc()
+		backend.Metrics.LocksTracker.LockWaiting(backend.ClientID, lockName, backend.Waiter)
+	case err == nil:
+		waitDuration := backend.Metrics.LocksTracker.LockAcquired(backend.ClientID, handle, opts.Shared, backend.Waiter)
+		shared := "false"
+		if opts.Shared {
+			shared = "true"
+		}
+		backend.Metrics.LockAcquireDuration.WithLabelValues(getLockProject(backend.ClientID, lockName), shared).Observe(waitDuration.Seconds())
+	}
+
+	return handle, err
+}
+
+func (backend *instrumentedDistributedLockerBackend) RenewLease(handle lockgate.LockHandle) error {
+	err := backend.DistributedLockerBackend.RenewLease(handle)
+	if err == nil {
+		backend.Metrics.LocksTracker.LockRenewed(backend.ClientID, handle)
+	}
+	return err
+}
+
+func (backend *instrumentedDistributedLockerBackend) Release(handle lockgate.LockHandle) error {
+	err := backend.DistributedLockerBackend.Release(handle)
+	if err == nil {
+		backend.Metrics.LocksTracker.LockReleased(backend.ClientID, handle, backend.Waiter)
+	}
+	return err
+}
+
+type instrumentedStagesStorageCache struct {
+	storage.StagesStorageCache
+
+	Metrics *Metrics
+}
+
+func (cache *instrumentedStagesStorageCache) GetAllStages(ctx context.Context, projectName string) (bool, []image.StageID, error) {
+	found, stages, err := cache.StagesStorageCache.GetAllStages(ctx, projectName)
+	cache.observeLookup(projectName, "get-all-stages", found, err)
+	return found, stages, err
+}
+
+func (cache *instrumentedStagesStorageCache) GetStagesByDigest(ctx context.Context, projectName, digest string) (bool, []image.StageID, error) {
+	found, stages, err := cache.StagesStorageCache.GetStagesByDigest(ctx, projectName, digest)
+	cache.observeLookup(projectName, "get-stages-by-digest", found, err)
+	return found, stages, err
+}
+
+func (cache *instrumentedStagesStorageCache) DeleteAllStages(ctx context.Context, projectName string) error {
+	err := cache.StagesStorageCache.DeleteAllStages(ctx, projectName)
+	cache.observeOperation(projectName, "delete-all-stages", err)
+	return err
+}
+
+func (cache *instrumentedStagesStorageCache) StoreStagesByDigest(ctx context.Context, projectName, digest string, stages []image.StageID) error {
+	err := cache.StagesStorageCache.StoreStagesByDigest(ctx, projectName, digest, stages)
+	cache.observeOperation(projectName, "store-stages-by-digest", err)
+	return err
+}
+
+func (cache *instrumentedStagesStorageCache) DeleteStagesByDigest(ctx context.Context, projectName, digest string) error {
+	err := cache.StagesStorageCache.DeleteStagesByDigest(ctx, projectName, digest)
+	cache.observeOperation(projectName, "delete-stages-by-digest", err)
+	return err
+}
+
+func (cache *instrumentedStagesStorageCache) observeLookup(projectName, operation string, found bool, err error) {
+	result := "miss"
+	if err != nil {
+		result = "error"
+	} else if found {
+		result = "hit"
+	}
+	cache.Metrics.StagesStorageCacheLookups.WithLabelValues(projectName, operation, result).Inc()
+}
+
+func (cache *instrumentedStagesStorageCache) observeOperation(projectName, operation string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	cache.Metrics.StagesStorageCacheOperations.WithLabelValues(projectName, operation, result).Inc()
+}
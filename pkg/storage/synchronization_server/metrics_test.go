@@ -0,0 +1,97 @@
+package synchronization_server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+)
+
+func TestSynchronizationServer_DebugLocksAndMetrics(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-synchronization-server-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	boltBackend, err := NewBoltBackend(context.Background(), filepath.Join(tmpDir, "synchronization.db"), BoltBackendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer boltBackend.Close()
+
+	srv := httptest.NewServer(NewSynchronizationServerHandler(boltBackend.NewDistributedLockerBackend, boltBackend.NewStagesStorageCache))
+	defer srv.Close()
+
+	backend := distributed_locker.NewHttpBackend(srv.URL + "/client-1/locker")
+
+	handle, err := backend.Acquire("myproject.stages_and_images", distributed_locker.AcquireOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.Acquire("myproject.stages_and_images", distributed_locker.AcquireOptions{}); !distributed_locker.IsErrShouldWait(err) {
+		t.Fatalf("expected should wait error, got %v", err)
+	}
+
+	state := &LocksState{}
+	getJSON(t, srv.URL+"/debug/locks", state)
+	if len(state.Locks) != 1 || state.Locks[0].LockName != "myproject.stages_and_images" || state.Locks[0].ClientID != "client-1" || state.Locks[0].Project != "myproject" {
+		t.Errorf("unexpected locks: %#v", state.Locks)
+	}
+	if len(state.Waiters) != 1 || state.Waiters[0].Attempts != 1 {
+		t.Errorf("unexpected waiters: %#v", state.Waiters)
+	}
+
+	cache := NewStagesStorageCacheHttpClient(srv.URL+"/client-1/stages-storage-cache", &http.Client{})
+	if _, _, err := cache.GetStagesByDigest(context.Background(), "myproject", "digest"); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := getBody(t, srv.URL+"/metrics")
+	for _, expected := range []string{
+		`werf_synchronization_server_active_clients 1`,
+		`werf_synchronization_server_held_locks{project="myproject"} 1`,
+		`werf_synchronization_server_lock_waiters{project="myproject"} 1`,
+		`werf_synchronization_server_lock_contentions_total{project="myproject"} 1`,
+		`werf_synchronization_server_stages_storage_cache_lookups_total{operation="get-stages-by-digest",project="myproject",result="miss"} 1`,
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("expected metrics to contain %q", expected)
+		}
+	}
+
+	if err := backend.Release(handle); err != nil {
+		t.Fatal(err)
+	}
+	getJSON(t, srv.URL+"/debug/locks", state)
+	if len(state.Locks) != 0 {
+		t.Errorf("expected no locks after release, got %#v", state.Locks)
+	}
+}
+
+func getBody(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func getJSON(t *testing.T, url string, obj interface{}) {
+	if err := json.Unmarshal([]byte(getBody(t, url)), obj); err != nil {
+		t.Fatal(err)
+	}
+}
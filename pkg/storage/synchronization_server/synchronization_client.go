@@ -1,24 +1,98 @@
 package synchronization_server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 )
 
+type SynchronizationClientOptions struct {
+	// Token is passed in the Authorization: Bearer header of each request
+	Token string
+
+	// CACertFile is used to verify server certificate instead of the system CAs
+	CACertFile string
+	// ClientCertFile and ClientKeyFile are used for mTLS
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// IsAuthenticated returns true if the options contain the token or the client certificate to authenticate the requests
+func (opts SynchronizationClientOptions) IsAuthenticated() bool {
+	return opts.Token != "" || opts.ClientCertFile != ""
+}
+
+// NewHttpClient creates http client for the synchronization server, which could be used by the SynchronizationClient, StagesStorageCacheHttpClient and distributed_locker.HttpBackend
+func NewHttpClient(opts SynchronizationClientOptions) (*http.Client, error) {
+	if opts.Token == "" && opts.CACertFile == "" && opts.ClientCertFile == "" && opts.ClientKeyFile == "" {
+		return &http.Client{}, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if opts.CACertFile != "" {
+		data, err := ioutil.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate %s: %s", opts.CACertFile, err)
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACertFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if opts.ClientCertFile != "" || opts.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s and key %s: %s", opts.ClientCertFile, opts.ClientKeyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	var roundTripper http.RoundTripper = transport
+	if opts.Token != "" {
+		roundTripper = &bearerTokenRoundTripper{Token: opts.Token, Base: transport}
+	}
+
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+type bearerTokenRoundTripper struct {
+	Token string
+	Base  http.RoundTripper
+}
+
+func (rt *bearerTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", rt.Token))
+	return rt.Base.RoundTrip(req)
+}
+
 type SynchronizationClient struct {
 	HttpClient *http.Client
 	URL        string
+
+	// Authenticated client passes the token or the client certificate, so it uses the clientIDs bound to the project
+	Authenticated bool
 }
 
-func NewSynchronizationClient(url string) *SynchronizationClient {
+func NewSynchronizationClient(url string, httpClient *http.Client) *SynchronizationClient {
 	return &SynchronizationClient{
 		URL:        url,
-		HttpClient: &http.Client{},
+		HttpClient: httpClient,
 	}
 }
 
-func (client *SynchronizationClient) NewClientID() (string, error) {
-	var request = NewClientIDRequest{}
+// NewClientID requests new clientID for the project, server with enabled authorization binds clientID to the project
+func (client *SynchronizationClient) NewClientID(projectName string) (string, error) {
+	var request = NewClientIDRequest{ProjectName: projectName}
 	var response = NewClientIDResponse{}
 	if err := PerformPost(client.HttpClient, fmt.Sprintf("%s/%s", client.URL, "new-client-id"), request, &response); err != nil {
 		return "", err
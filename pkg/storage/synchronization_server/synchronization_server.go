@@ -2,10 +2,15 @@ package synchronization_server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -17,9 +22,82 @@ import (
 	"github.com/werf/werf/pkg/storage"
 )
 
-func RunSynchronizationServer(_ context.Context, ip, port string, distributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error), stagesStorageCacheFactoryFunc func(clientID string) (storage.StagesStorageCache, error)) error {
+const shutdownTimeout = 30 * time.Second
+
+type SynchronizationServerOptions struct {
+	// TLSCertFile and TLSKeyFile enable https
+	TLSCertFile string
+	TLSKeyFile  string
+	// ClientCAFile enables mTLS: clients are required to present certificate signed by one of the specified CAs
+	ClientCAFile string
+
+	// AuthConfig enables bearer token authentication and per-project authorization
+	AuthConfig *AuthConfig
+}
+
+// RunSynchronizationServer serves requests until ctx is done, then waits for the active requests to be completed
+func RunSynchronizationServer(ctx context.Context, ip, port string, distributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error), stagesStorageCacheFactoryFunc func(clientID string) (storage.StagesStorageCache, error), opts SynchronizationServerOptions) error {
+	if err := validateSynchronizationServerOptions(opts); err != nil {
+		return err
+	}
+
 	handler := NewSynchronizationServerHandler(distributedLockerBackendFactoryFunc, stagesStorageCacheFactoryFunc)
-	return http.ListenAndServe(fmt.Sprintf("%s:%s", ip, port), handler)
+	if opts.AuthConfig != nil || opts.ClientCAFile != "" {
+		handler.Authorizer = &Authorizer{Config: opts.AuthConfig}
+	}
+
+	server := &http.Server{Addr: fmt.Sprintf("%s:%s", ip, port), Handler: handler}
+
+	if opts.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("unable to read client CA %s: %s", opts.ClientCAFile, err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in client CA %s", opts.ClientCAFile)
+		}
+
+		server.TLSConfig = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if opts.TLSCertFile != "" || opts.TLSKeyFile != "" {
+			errCh <- server.ListenAndServeTLS(opts.TLSCertFile, opts.TLSKeyFile)
+		} else {
+			errCh <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		logboek.Context(ctx).Default().LogF("Shutting down synchronization server\n")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("unable to shutdown synchronization server gracefully: %s", err)
+		}
+		return nil
+	}
+}
+
+func validateSynchronizationServerOptions(opts SynchronizationServerOptions) error {
+	if opts.ClientCAFile != "" && (opts.TLSCertFile == "" || opts.TLSKeyFile == "") {
+		return fmt.Errorf("client CA requires server TLS certificate and key to be specified")
+	}
+
+	// client certificates are not verified without client CA, so such config would never allow any client
+	if opts.AuthConfig != nil && len(opts.AuthConfig.ClientCertificates) > 0 && opts.ClientCAFile == "" {
+		return fmt.Errorf("clientCertificates of the auth config require client CA to be specified")
+	}
+
+	return nil
 }
 
 type SynchronizationServerHandler struct {
@@ -28,6 +106,10 @@ type SynchronizationServerHandler struct {
 	DistributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error)
 	StagesStorageCacheFactoryFunc       func(clientID string) (storage.StagesStorageCache, error)
 
+	// Authorizer is optional, all requests are allowed when it is not set
+	Authorizer *Authorizer
+	Metrics    *Metrics
+
 	mux                             sync.Mutex
 	SynchronizationServerByClientID map[string]*SynchronizationServerHandlerByClientID
 }
@@ -38,9 +120,12 @@ func NewSynchronizationServerHandler(distributedLockerBackendFactoryFunc func(cl
 		DistributedLockerBackendFactoryFunc: distributedLockerBackendFactoryFunc,
 		StagesStorageCacheFactoryFunc:       stagesStorageCacheFactoryFunc,
 		SynchronizationServerByClientID:     make(map[string]*SynchronizationServerHandlerByClientID),
+		Metrics:                             NewMetrics(NewLocksTracker()),
 	}
 	srv.HandleFunc("/health", srv.handleHealth)
 	srv.HandleFunc("/new-client-id", srv.handleNewClientID)
+	srv.HandleFunc("/metrics", srv.handleMetrics)
+	srv.HandleFunc("/debug/locks", srv.handleDebugLocks)
 	srv.HandleFunc("/", srv.handleRequestByClientID)
 	return srv
 }
@@ -66,7 +151,9 @@ func (server *SynchronizationServerHandler) handleHealth(w http.ResponseWriter,
 	})
 }
 
-type NewClientIDRequest struct{}
+type NewClientIDRequest struct {
+	ProjectName string `json:"projectName,omitempty"`
+}
 type NewClientIDResponse struct {
 	Err      util.SerializableError `json:"err"`
 	ClientID string                 `json:"clientID"`
@@ -75,13 +162,81 @@ type NewClientIDResponse struct {
 func (server *SynchronizationServerHandler) handleNewClientID(w http.ResponseWriter, r *http.Request) {
 	var request NewClientIDRequest
 	var response NewClientIDResponse
+
+	var allowedProjects []string
+	if server.Authorizer != nil {
+		if projects, err := server.Authorizer.GetAllowedProjects(r); err != nil {
+			http.Error(w, fmt.Sprintf("Unauthorized: %s", err), http.StatusUnauthorized)
+			return
+		} else {
+			allowedProjects = projects
+		}
+	}
+
 	HandleRequest(w, r, &request, &response, func() {
 		logboek.Debug().LogF("SynchronizationServerHandler -- NewClientID request %#v\n", request)
-		response.ClientID = uuid.New().String()
+
+		if server.Authorizer == nil {
+			response.ClientID = uuid.New().String()
+		} else if request.ProjectName == "" || strings.Contains(request.ProjectName, clientIDProjectSeparator) {
+			response.Err = util.SerializableError{Error: fmt.Errorf("bad project name %q", request.ProjectName)}
+		} else if !IsProjectAllowed(allowedProjects, request.ProjectName) {
+			response.Err = util.SerializableError{Error: fmt.Errorf("access to the project %q is forbidden", request.ProjectName)}
+		} else {
+			response.ClientID = newClientIDWithProject(request.ProjectName, uuid.New().String())
+		}
 		logboek.Debug().LogF("SynchronizationServerHandler -- NewClientID response %#v\n", response)
 	})
 }
 
+func (server *SynchronizationServerHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if server.Authorizer != nil {
+		if allowedProjects, err := server.Authorizer.GetAllowedProjects(r); err != nil {
+			http.Error(w, fmt.Sprintf("Unauthorized: %s", err), http.StatusUnauthorized)
+			return
+		} else if !IsProjectAllowed(allowedProjects, "") {
+			http.Error(w, "Forbidden: access to all projects required", http.StatusForbidden)
+			return
+		}
+	}
+
+	server.Metrics.Handler().ServeHTTP(w, r)
+}
+
+// handleDebugLocks lists current lock holders and waiters, only locks of the allowed projects are listed when authorization is enabled
+func (server *SynchronizationServerHandler) handleDebugLocks(w http.ResponseWriter, r *http.Request) {
+	var allowedProjects []string
+	if server.Authorizer != nil {
+		if projects, err := server.Authorizer.GetAllowedProjects(r); err != nil {
+			http.Error(w, fmt.Sprintf("Unauthorized: %s", err), http.StatusUnauthorized)
+			return
+		} else {
+			allowedProjects = projects
+		}
+	}
+
+	state := server.Metrics.LocksTracker.State()
+	if server.Authorizer != nil {
+		res := &LocksState{Locks: []*LockHolder{}, Waiters: []*LockWaiter{}}
+		for _, lock := range state.Locks {
+			if IsProjectAllowed(allowedProjects, getClientIDProject(lock.ClientID)) {
+				res.Locks = append(res.Locks, lock)
+			}
+		}
+		for _, waiter := range state.Waiters {
+			if IsProjectAllowed(allowedProjects, getClientIDProject(waiter.ClientID)) {
+				res.Waiters = append(res.Waiters, waiter)
+			}
+		}
+		state = res
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (server *SynchronizationServerHandler) handleLanding(w http.ResponseWriter, r *http.Request) {
 	rawPage := ` <!doctype html>
 <html>
@@ -119,6 +274,18 @@ func (server *SynchronizationServerHandler) handleRequestByClientID(w http.Respo
 		return
 	}
 
+	if server.Authorizer != nil {
+		if allowedProjects, err := server.Authorizer.GetAllowedProjects(r); err != nil {
+			http.Error(w, fmt.Sprintf("Unauthorized: %s", err), http.StatusUnauthorized)
+			return
+		} else if !IsProjectAllowed(allowedProjects, getClientIDProject(clientID)) {
+			http.Error(w, fmt.Sprintf("Forbidden: access to the clientID %q is not allowed", clientID), http.StatusForbidden)
+			return
+		}
+	}
+
+	server.Metrics.LocksTracker.ClientSeen(clientID)
+
 	if clientServer, err := server.getOrCreateHandlerByClientID(clientID); err != nil {
 		http.Error(w, fmt.Sprintf("Internal error: %s", err), http.StatusInternalServerError)
 		return
@@ -144,7 +311,7 @@ func (server *SynchronizationServerHandler) getOrCreateHandlerByClientID(clientI
 			return nil, fmt.Errorf("unable to create stages storage cache for clientID %q: %s", clientID, err)
 		}
 
-		handler := NewSynchronizationServerHandlerByClientID(clientID, distributedLockerBackend, stagesStorageCache)
+		handler := NewSynchronizationServerHandlerByClientID(clientID, distributedLockerBackend, stagesStorageCache, server.Metrics)
 		server.SynchronizationServerByClientID[clientID] = handler
 
 		logboek.Debug().LogF("SynchronizationServerHandler -- Created new synchronization server handler by clientID %q: %v\n", clientID, handler)
@@ -158,17 +325,38 @@ type SynchronizationServerHandlerByClientID struct {
 
 	DistributedLockerBackend distributed_locker.DistributedLockerBackend
 	StagesStorageCache       storage.StagesStorageCache
+	Metrics                  *Metrics
 }
 
-func NewSynchronizationServerHandlerByClientID(clientID string, distributedLockerBackend distributed_locker.DistributedLockerBackend, stagesStorageCache storage.StagesStorageCache) *SynchronizationServerHandlerByClientID {
+// NewSynchronizationServerHandlerByClientID instruments locker and stages storage cache when metrics are specified
+func NewSynchronizationServerHandlerByClientID(clientID string, distributedLockerBackend distributed_locker.DistributedLockerBackend, stagesStorageCache storage.StagesStorageCache, metrics *Metrics) *SynchronizationServerHandlerByClientID {
+	if metrics != nil {
+		stagesStorageCache = &instrumentedStagesStorageCache{StagesStorageCache: stagesStorageCache, Metrics: metrics}
+	}
+
 	srv := &SynchronizationServerHandlerByClientID{
 		ServeMux:                 http.NewServeMux(),
 		ClientID:                 clientID,
 		DistributedLockerBackend: distributedLockerBackend,
 		StagesStorageCache:       stagesStorageCache,
+		Metrics:                  metrics,
 	}
-	srv.Handle("/locker/", http.StripPrefix("/locker", distributed_locker.NewHttpBackendHandler(srv.DistributedLockerBackend)))
+	srv.Handle("/locker/", http.StripPrefix("/locker", http.HandlerFunc(srv.handleLocker)))
 	srv.Handle("/stages-storage-cache/v1/", http.StripPrefix("/stages-storage-cache/v1", NewStagesStorageCacheHttpHandler(stagesStorageCache)))
 	srv.Handle("/stages-storage-cache/", http.StripPrefix("/stages-storage-cache", NewStagesStorageCacheHttpHandlerLegacy(stagesStorageCache)))
 	return srv
 }
+
+func (server *SynchronizationServerHandlerByClientID) handleLocker(w http.ResponseWriter, r *http.Request) {
+	var backend distributed_locker.DistributedLockerBackend = server.DistributedLockerBackend
+	if server.Metrics != nil {
+		backend = &instrumentedDistributedLockerBackend{
+			DistributedLockerBackend: server.DistributedLockerBackend,
+			ClientID:                 server.ClientID,
+			Waiter:                   getRequestWaiter(r.RemoteAddr),
+			Metrics:                  server.Metrics,
+		}
+	}
+
+	distributed_locker.NewHttpBackendHandler(backend).ServeHTTP(w, r)
+}